@@ -0,0 +1,87 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package chartcheck provides a health check that verifies that the charts
+// of an operator can still be loaded.
+package chartcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"helm.sh/helm/v3/pkg/chart/loader"
+)
+
+// Checker periodically loads a set of charts and reports an error from its
+// Check method if any of them cannot be loaded, e.g. because the volume the
+// charts are mounted from disappeared.
+type Checker struct {
+	paths    []string
+	interval time.Duration
+
+	mu  sync.RWMutex
+	err error
+}
+
+// New returns a Checker for the charts at paths that loads the charts every
+// interval.
+func New(paths []string, interval time.Duration) *Checker {
+	return &Checker{paths: paths, interval: interval}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, so that the
+// charts are checked on all replicas.
+func (c *Checker) NeedLeaderElection() bool {
+	return false
+}
+
+// Start implements manager.Runnable. It checks the charts immediately and
+// then every interval until ctx is done.
+func (c *Checker) Start(ctx context.Context) error {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		c.check()
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check implements healthz.Checker. It returns the error of the last check.
+func (c *Checker) Check(_ *http.Request) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.err
+}
+
+func (c *Checker) check() {
+	var errs []error
+	for _, path := range c.paths {
+		if _, err := loader.Load(path); err != nil {
+			errs = append(errs, fmt.Errorf("chart %s: %w", path, err))
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.err = errors.Join(errs...)
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartcheck_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/operator-framework/helm-operator-plugins/internal/chartcheck"
+)
+
+var _ = Describe("Checker", func() {
+	var (
+		chartDir string
+		ctx      context.Context
+		cancel   context.CancelFunc
+	)
+
+	BeforeEach(func() {
+		chartDir = filepath.Join(GinkgoT().TempDir(), "test-chart")
+		Expect(os.MkdirAll(chartDir, 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte("apiVersion: v2\nname: test-chart\nversion: 0.1.0\n"), 0o644)).To(Succeed())
+		ctx, cancel = context.WithCancel(context.Background())
+	})
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("should fail once a chart disappears", func() {
+		c := chartcheck.New([]string{chartDir}, 10*time.Millisecond)
+		go func() {
+			defer GinkgoRecover()
+			Expect(c.Start(ctx)).To(Succeed())
+		}()
+		Consistently(func() error { return c.Check(nil) }, 50*time.Millisecond).Should(Succeed())
+
+		Expect(os.RemoveAll(chartDir)).To(Succeed())
+		Eventually(func() error { return c.Check(nil) }).Should(MatchError(ContainSubstring(chartDir)))
+	})
+})
@@ -15,21 +15,28 @@
 package run
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"runtime"
 	"strings"
+	"syscall"
+	"time"
 
+	"github.com/operator-framework/helm-operator-plugins/internal/chartcheck"
+	"github.com/operator-framework/helm-operator-plugins/internal/crdwait"
 	"github.com/operator-framework/helm-operator-plugins/internal/flags"
 	"github.com/operator-framework/helm-operator-plugins/internal/metrics"
 	"github.com/operator-framework/helm-operator-plugins/internal/version"
 	"github.com/operator-framework/helm-operator-plugins/pkg/annotation"
+	"github.com/operator-framework/helm-operator-plugins/pkg/chartrepo"
 	helmmgr "github.com/operator-framework/helm-operator-plugins/pkg/manager"
 	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler"
 	"github.com/operator-framework/helm-operator-plugins/pkg/watches"
 	"github.com/spf13/cobra"
+	"k8s.io/client-go/discovery"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
@@ -53,7 +60,25 @@ func printVersion() {
 		"commit", version.GitCommit)
 }
 
-func NewCmd() *cobra.Command {
+// OptionsFunc adjusts the manager options right before the manager is
+// created, e.g. to configure settings that are not exposed as flags.
+type OptionsFunc func(manager.Options) manager.Options
+
+// WithBaseContext returns an OptionsFunc that sets the base context of the
+// manager to the context returned by f, so that its values, e.g. a tenant ID
+// or trace baggage, are available in every reconciliation and its hooks and
+// observers. The manager still stops when the process receives a termination
+// signal.
+func WithBaseContext(f manager.BaseContextFunc) OptionsFunc {
+	return func(o manager.Options) manager.Options {
+		o.BaseContext = f
+		return o
+	}
+}
+
+// NewCmd returns the run command. The optionsFuncs are applied in order to the
+// manager options, after all flags and configuration files were processed.
+func NewCmd(optionsFuncs ...OptionsFunc) *cobra.Command {
 	f := &flags.Flags{}
 	zapfs := flag.NewFlagSet("zap", flag.ExitOnError)
 	opts := &zapf.Options{}
@@ -64,7 +89,7 @@ func NewCmd() *cobra.Command {
 		Short: "Run the operator",
 		Run: func(cmd *cobra.Command, _ []string) {
 			logf.SetLogger(zapf.New(zapf.UseFlagOptions(opts)))
-			run(cmd, f)
+			run(cmd, f, optionsFuncs...)
 		},
 	}
 
@@ -73,7 +98,7 @@ func NewCmd() *cobra.Command {
 	return cmd
 }
 
-func run(cmd *cobra.Command, f *flags.Flags) {
+func run(cmd *cobra.Command, f *flags.Flags, optionsFuncs ...OptionsFunc) {
 	printVersion()
 	metrics.RegisterBuildInfo(crmetrics.Registry)
 
@@ -96,12 +121,6 @@ func run(cmd *cobra.Command, f *flags.Flags) {
 	}
 	exitIfUnsupported(options)
 
-	cfg, err := config.GetConfig()
-	if err != nil {
-		log.Error(err, "Failed to get config.")
-		os.Exit(1)
-	}
-
 	// TODO(2.0.0): remove
 	// Deprecated: OPERATOR_NAME environment variable is an artifact of the
 	// legacy operator-sdk project scaffolding. Flag `--leader-election-id`
@@ -128,14 +147,28 @@ func run(cmd *cobra.Command, f *flags.Flags) {
 		os.Exit(1)
 	}
 
+	if err := f.Validate(); err != nil {
+		log.Error(err, "invalid flags usage")
+		os.Exit(1)
+	}
+
 	// Set default manager options
 	options = f.ToManagerOptions(options)
 
+	// The metrics server of the manager cannot serve HTTPS, so it is replaced
+	// by a secure metrics server that is added to the manager below.
+	var secureMetricsAddr string
+	if f.SecureMetrics() {
+		secureMetricsAddr = options.MetricsBindAddress
+		options.MetricsBindAddress = "0"
+	}
+
 	// Log manager option flags
 	optionsLog := map[string]interface{}{
 		"MetricsBindAddress": options.MetricsBindAddress,
 		"HealthProbeAddress": options.HealthProbeBindAddress,
 		"LeaderElection":     options.LeaderElection,
+		"CacheSyncTimeout":   options.Controller.CacheSyncTimeout,
 	}
 	if options.LeaderElectionID != "" {
 		optionsLog["LeaderElectionId"] = options.LeaderElectionID
@@ -143,9 +176,56 @@ func run(cmd *cobra.Command, f *flags.Flags) {
 	if options.LeaderElectionNamespace != "" {
 		optionsLog["LeaderElectionNamespace"] = options.LeaderElectionNamespace
 	}
+	if options.PprofBindAddress != "" {
+		optionsLog["PprofBindAddress"] = options.PprofBindAddress
+	}
+	if secureMetricsAddr != "" {
+		optionsLog["MetricsBindAddress"] = secureMetricsAddr
+		optionsLog["MetricsSecure"] = true
+		optionsLog["MetricsRequireRBAC"] = f.MetricsRequireRBAC
+	}
 	log.Info("Setting manager options", "Options", optionsLog)
 
-	helmmgr.ConfigureWatchNamespaces(&options, log)
+	var namespacesFile *helmmgr.NamespacesFile
+	if f.WatchNamespacesFile != "" {
+		var err error
+		if namespacesFile, err = helmmgr.NewNamespacesFile(f.WatchNamespacesFile, helmmgr.DefaultNamespacesFileInterval, log); err != nil {
+			log.Error(err, "Failed to read watch namespaces file")
+			os.Exit(1)
+		}
+		helmmgr.ConfigureWatchNamespacesFile(&options, namespacesFile)
+	} else {
+		helmmgr.ConfigureWatchNamespaces(&options, log)
+	}
+	if len(f.ExcludeNamespaces) > 0 {
+		log.Info("Excluding namespaces.", "ExcludedNamespaces", f.ExcludeNamespaces)
+		helmmgr.ExcludeWatchNamespaces(&options, f.ExcludeNamespaces)
+	}
+
+	for _, fn := range optionsFuncs {
+		options = fn(options)
+	}
+
+	// The values of the base context are also available while the
+	// reconcilers are set up.
+	baseCtx := context.Background()
+	if options.BaseContext != nil {
+		baseCtx = options.BaseContext()
+	}
+
+	if f.PrintConfig {
+		if err := f.PrintEffectiveConfig(os.Stdout, optionsLog); err != nil {
+			log.Error(err, "Failed to print config")
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		log.Error(err, "Failed to get config.")
+		os.Exit(1)
+	}
 
 	mgr, err := manager.New(cfg, options)
 	if err != nil {
@@ -153,6 +233,35 @@ func run(cmd *cobra.Command, f *flags.Flags) {
 		os.Exit(1)
 	}
 
+	if secureMetricsAddr != "" {
+		metricsServer := &metrics.SecureServer{
+			BindAddress: secureMetricsAddr,
+			CertFile:    f.MetricsCertFile,
+			KeyFile:     f.MetricsKeyFile,
+			Gatherer:    crmetrics.Registry,
+			Log:         log.WithName("metrics"),
+		}
+		if f.MetricsRequireRBAC {
+			metricsServer.Client = mgr.GetClient()
+		}
+		if err := mgr.Add(metricsServer); err != nil {
+			log.Error(err, "Unable to set up secure metrics server")
+			os.Exit(1)
+		}
+	}
+
+	if namespacesFile != nil {
+		if err := mgr.Add(namespacesFile); err != nil {
+			log.Error(err, "Unable to set up watch namespaces file")
+			os.Exit(1)
+		}
+	}
+
+	if err := mgr.Add(metrics.NewLeaderReporter(crmetrics.Registry, mgr.Elected())); err != nil {
+		log.Error(err, "Unable to set up leader metric")
+		os.Exit(1)
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		log.Error(err, "Unable to set up health check")
 		os.Exit(1)
@@ -162,13 +271,87 @@ func run(cmd *cobra.Command, f *flags.Flags) {
 		os.Exit(1)
 	}
 
-	ws, err := watches.Load(f.WatchesFile)
+	// Feature gates were validated with the other flags above.
+	featureGates, _ := f.ParseFeatureGates()
+
+	ws, err := watches.Load(f.WatchesFile,
+		watches.WithMaxConcurrentChartLoads(f.MaxConcurrentChartLoads),
+		watches.WithWarningHandler(func(msg string) { log.Info("Warning loading watches", "warning", msg) }),
+	)
 	if err != nil {
 		log.Error(err, "unable to load watches.yaml", "path", f.WatchesFile)
 		os.Exit(1)
 	}
 
+	if f.ChartCheckInterval > 0 {
+		var chartPaths []string
+		for _, w := range ws {
+			if w.ChartPath != "" {
+				chartPaths = append(chartPaths, w.ChartPath)
+			}
+		}
+		checker := chartcheck.New(chartPaths, f.ChartCheckInterval)
+		if err := mgr.Add(checker); err != nil {
+			log.Error(err, "Unable to set up chart check")
+			os.Exit(1)
+		}
+		if err := mgr.AddHealthzCheck("charts", checker.Check); err != nil {
+			log.Error(err, "Unable to set up chart health check")
+			os.Exit(1)
+		}
+		if err := mgr.AddReadyzCheck("charts", checker.Check); err != nil {
+			log.Error(err, "Unable to set up chart ready check")
+			os.Exit(1)
+		}
+	}
+
+	var fairScheduler *reconciler.FairScheduler
+	if f.MaxTotalReconciles > 0 {
+		if fairScheduler, err = reconciler.NewFairScheduler(f.MaxTotalReconciles); err != nil {
+			log.Error(err, "Unable to create the fair scheduler")
+			os.Exit(1)
+		}
+	}
+
+	var operationLimiter *reconciler.OperationLimiter
+	if f.MaxHelmOperations > 0 {
+		if operationLimiter, err = reconciler.NewOperationLimiter(f.MaxHelmOperations); err != nil {
+			log.Error(err, "Unable to create the Helm operation limiter")
+			os.Exit(1)
+		}
+	}
+
+	var resync *reconciler.Resync
+	if f.ResyncOnSIGHUP {
+		resync = reconciler.NewResync()
+		if err := mgr.Add(resync.OnSignal(log.WithName("resync"), syscall.SIGHUP)); err != nil {
+			log.Error(err, "Unable to set up resync signal handler")
+			os.Exit(1)
+		}
+	}
+
+	var crdWaiter *crdwait.Waiter
+	crdWaitCtx := baseCtx
+	if f.CRDWaitTimeout > 0 {
+		dc, err := discovery.NewDiscoveryClientForConfig(cfg)
+		if err != nil {
+			log.Error(err, "Unable to create discovery client")
+			os.Exit(1)
+		}
+		crdWaiter = &crdwait.Waiter{Client: dc, Log: log.WithName("crdwait")}
+		var cancel context.CancelFunc
+		crdWaitCtx, cancel = context.WithTimeout(crdWaitCtx, f.CRDWaitTimeout)
+		defer cancel()
+	}
+
 	for _, w := range ws {
+		if crdWaiter != nil {
+			if err := crdWaiter.WaitFor(crdWaitCtx, w.GroupVersionKind); err != nil {
+				log.Error(err, "Kind of watch is not served", "gvk", w.GroupVersionKind)
+				os.Exit(1)
+			}
+		}
+
 		reconcilePeriod := f.ReconcilePeriod
 		if w.ReconcilePeriod != nil {
 			reconcilePeriod = w.ReconcilePeriod.Duration
@@ -179,18 +362,73 @@ func run(cmd *cobra.Command, f *flags.Flags) {
 			maxConcurrentReconciles = *w.MaxConcurrentReconciles
 		}
 
-		r, err := reconciler.New(
-			reconciler.WithChart(*w.Chart),
+		maxReleaseHistory := f.MaxReleaseHistory
+		if w.MaxReleaseHistory != nil {
+			maxReleaseHistory = *w.MaxReleaseHistory
+		}
+
+		opts := []reconciler.Option{
 			reconciler.WithGroupVersionKind(w.GroupVersionKind),
+			reconciler.WithOverrideValuesLayers(w.OverrideValuesLayers...),
 			reconciler.WithOverrideValues(w.OverrideValues),
 			reconciler.WithSelector(*w.Selector),
 			reconciler.SkipDependentWatches(w.WatchDependentResources != nil && !*w.WatchDependentResources),
 			reconciler.WithMaxConcurrentReconciles(maxConcurrentReconciles),
 			reconciler.WithReconcilePeriod(reconcilePeriod),
+			reconciler.WithMaxReleaseHistory(maxReleaseHistory),
 			reconciler.WithInstallAnnotations(annotation.DefaultInstallAnnotations...),
 			reconciler.WithUpgradeAnnotations(annotation.DefaultUpgradeAnnotations...),
 			reconciler.WithUninstallAnnotations(annotation.DefaultUninstallAnnotations...),
-		)
+			reconciler.WithReinstallAnnotations(annotation.DefaultReinstallAnnotations...),
+			reconciler.WithFeatureGates(featureGates),
+			reconciler.WithObserveOnly(f.ObserveOnly),
+		}
+		if w.Git != nil {
+			src, err := w.Git.Source(baseCtx, mgr.GetAPIReader())
+			if err != nil {
+				log.Error(err, "unable to configure git chart source", "repository", w.Git.Repository)
+				os.Exit(1)
+			}
+			var interval time.Duration
+			if w.Git.Interval != nil {
+				interval = w.Git.Interval.Duration
+			}
+			opts = append(opts, reconciler.WithChartSource(src, interval))
+		} else if f.ChartWatchInterval > 0 {
+			opts = append(opts, reconciler.WithChartSource(reconciler.NewChartPathSource(w.ChartPath), f.ChartWatchInterval))
+		} else {
+			opts = append(opts, reconciler.WithChart(*w.Chart))
+		}
+		if w.UpgradeCheck != nil {
+			var (
+				interval time.Duration
+				repoOpts []chartrepo.Option
+			)
+			if w.UpgradeCheck.Interval != nil {
+				interval = w.UpgradeCheck.Interval.Duration
+			}
+			if w.UpgradeCheck.TLS != nil {
+				tlsConfig, err := w.UpgradeCheck.TLS.Config(baseCtx, mgr.GetAPIReader())
+				if err != nil {
+					log.Error(err, "unable to load chart repository TLS config", "repository", w.UpgradeCheck.Repository)
+					os.Exit(1)
+				}
+				repoOpts = append(repoOpts, chartrepo.WithTLSConfig(tlsConfig))
+			}
+			opts = append(opts, reconciler.WithChartUpgradeCheck(w.UpgradeCheck.Repository, interval, repoOpts...))
+		}
+
+		if fairScheduler != nil {
+			opts = append(opts, reconciler.WithFairScheduler(fairScheduler))
+		}
+		if operationLimiter != nil {
+			opts = append(opts, reconciler.WithOperationLimiter(operationLimiter))
+		}
+		if resync != nil {
+			opts = append(opts, reconciler.WithResync(resync))
+		}
+
+		r, err := reconciler.New(opts...)
 		if err != nil {
 			log.Error(err, "unable to create helm reconciler", "controller", "Helm")
 			os.Exit(1)
@@ -200,7 +438,7 @@ func run(cmd *cobra.Command, f *flags.Flags) {
 			log.Error(err, "unable to create controller", "controller", "Helm")
 			os.Exit(1)
 		}
-		log.Info("configured watch", "gvk", w.GroupVersionKind, "chartPath", w.ChartPath, "maxConcurrentReconciles", f.MaxConcurrentReconciles, "reconcilePeriod", f.ReconcilePeriod)
+		log.Info("configured watch", "gvk", w.GroupVersionKind, "chartPath", w.ChartPath, "maxConcurrentReconciles", maxConcurrentReconciles, "reconcilePeriod", reconcilePeriod, "maxReleaseHistory", maxReleaseHistory)
 	}
 
 	log.Info("starting manager")
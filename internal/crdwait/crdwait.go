@@ -0,0 +1,91 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package crdwait waits for the API server to serve the kinds watched by an
+// operator, e.g. because the CRDs of the kinds are installed together with
+// the operator and may not be established yet when the operator starts.
+package crdwait
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery"
+)
+
+// DefaultInterval is the interval at which the discovery API is polled.
+const DefaultInterval = 2 * time.Second
+
+// Waiter waits for kinds to be served by the API server.
+type Waiter struct {
+	Client   discovery.DiscoveryInterface
+	Interval time.Duration
+	Log      logr.Logger
+}
+
+// WaitFor polls the discovery API until the API server serves gvk or ctx is
+// done. Discovery errors are logged and retried, so that a CRD that is not
+// established yet does not fail the operator.
+func (w *Waiter) WaitFor(ctx context.Context, gvk schema.GroupVersionKind) error {
+	interval := w.Interval
+	if interval == 0 {
+		interval = DefaultInterval
+	}
+	var lastErr error
+	err := wait.PollUntilContextCancel(ctx, interval, true, func(ctx context.Context) (bool, error) {
+		served, err := w.served(gvk)
+		if err != nil {
+			lastErr = err
+			w.Log.V(1).Info("Unable to discover kind, retrying", "gvk", gvk, "error", err.Error())
+			return false, nil
+		}
+		if !served {
+			w.Log.Info("Waiting for kind to be served", "gvk", gvk)
+		}
+		return served, nil
+	})
+	if err != nil {
+		if lastErr != nil {
+			return fmt.Errorf("kind %s is not served: %v", gvk, lastErr)
+		}
+		return fmt.Errorf("kind %s is not served: %w", gvk, err)
+	}
+	return nil
+}
+
+func (w *Waiter) served(gvk schema.GroupVersionKind) (bool, error) {
+	resources, err := w.Client.ServerResourcesForGroupVersion(gvk.GroupVersion().String())
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	for _, r := range resources.APIResources {
+		// Subresources have the kind of their parent, e.g. the status
+		// subresource.
+		if r.Kind == gvk.Kind && !strings.Contains(r.Name, "/") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
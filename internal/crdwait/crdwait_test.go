@@ -0,0 +1,73 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crdwait_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"github.com/operator-framework/helm-operator-plugins/internal/crdwait"
+)
+
+var _ = Describe("Waiter", func() {
+	var (
+		dc  *fakediscovery.FakeDiscovery
+		w   *crdwait.Waiter
+		gvk = schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Memcached"}
+	)
+
+	BeforeEach(func() {
+		dc = &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{}}
+		w = &crdwait.Waiter{Client: dc, Interval: 10 * time.Millisecond, Log: logr.Discard()}
+	})
+
+	served := func(kind string) []*metav1.APIResourceList {
+		return []*metav1.APIResourceList{{
+			GroupVersion: "example.com/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "memcacheds/status", Kind: "Memcached"},
+				{Name: "others", Kind: kind},
+			},
+		}}
+	}
+
+	It("should return once the kind is served", func() {
+		dc.Resources = served("Memcached")
+		Expect(w.WaitFor(context.Background(), gvk)).To(Succeed())
+	})
+
+	It("should fail if the kind is not served in time", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		Expect(w.WaitFor(ctx, gvk)).NotTo(Succeed())
+	})
+
+	It("should not treat a subresource as the kind", func() {
+		dc.Resources = served("Other")
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		Expect(w.WaitFor(ctx, gvk)).NotTo(Succeed())
+	})
+})
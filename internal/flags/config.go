@@ -0,0 +1,94 @@
+// Copyright 2023 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flags
+
+import (
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+)
+
+const maskedValue = "********"
+
+// configEnvVars are the environment variables that affect the configuration
+// of the operator.
+var configEnvVars = []string{
+	"WATCH_NAMESPACE",
+	"OPERATOR_NAME",
+	"KUBECONFIG",
+	"HTTP_PROXY",
+	"HTTPS_PROXY",
+	"NO_PROXY",
+}
+
+var sensitiveName = regexp.MustCompile(`(?i)(password|passwd|secret|token|credential)`)
+
+// effectiveConfig is the configuration printed with --print-config.
+type effectiveConfig struct {
+	Flags       map[string]string      `json:"flags"`
+	Manager     map[string]interface{} `json:"manager"`
+	Environment map[string]string      `json:"environment,omitempty"`
+}
+
+// PrintEffectiveConfig writes the effective configuration of the operator as
+// YAML to w. It consists of the values of all flags, the given manager
+// options, which must be resolved from the flags, the manager config file and
+// environment variables, and the environment variables that affect the
+// configuration. Values of sensitive flags and passwords in URLs are masked.
+func (f *Flags) PrintEffectiveConfig(w io.Writer, managerOptions map[string]interface{}) error {
+	cfg := effectiveConfig{
+		Flags:       map[string]string{},
+		Manager:     managerOptions,
+		Environment: map[string]string{},
+	}
+	if f.flagSet != nil {
+		f.flagSet.VisitAll(func(fl *pflag.Flag) {
+			cfg.Flags[fl.Name] = maskValue(fl.Name, fl.Value.String())
+		})
+	}
+	for _, name := range configEnvVars {
+		if v, ok := os.LookupEnv(name); ok {
+			cfg.Environment[name] = maskValue(name, v)
+		}
+	}
+
+	out, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// maskValue masks value if name indicates that it is sensitive, and masks the
+// password of value if it is a URL.
+func maskValue(name, value string) string {
+	if value == "" {
+		return value
+	}
+	if sensitiveName.MatchString(name) {
+		return maskedValue
+	}
+	if u, err := url.Parse(value); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			return u.Redacted()
+		}
+	}
+	return value
+}
@@ -15,24 +15,50 @@
 package flags
 
 import (
+	"errors"
+	"fmt"
 	"runtime"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/spf13/pflag"
 	"k8s.io/client-go/tools/leaderelection/resourcelock"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler"
 )
 
 // Flags - Options to be used by a helm operator
 type Flags struct {
-	ReconcilePeriod         time.Duration
-	WatchesFile             string
-	MetricsBindAddress      string
-	LeaderElection          bool
-	LeaderElectionID        string
-	LeaderElectionNamespace string
-	MaxConcurrentReconciles int
-	ProbeAddr               string
+	ReconcilePeriod            time.Duration
+	WatchesFile                string
+	MetricsBindAddress         string
+	LeaderElection             bool
+	LeaderElectionID           string
+	LeaderElectionNamespace    string
+	LeaderElectionResourceLock string
+	MaxConcurrentReconciles    int
+	MaxTotalReconciles         int
+	MaxHelmOperations          int
+	MaxConcurrentChartLoads    int
+	MaxReleaseHistory          int
+	ProbeAddr                  string
+	CacheSyncTimeout           time.Duration
+	PprofAddr                  string
+	MetricsCertFile            string
+	MetricsKeyFile             string
+	MetricsRequireRBAC         bool
+	FeatureGates               map[string]string
+	ChartCheckInterval         time.Duration
+	ChartWatchInterval         time.Duration
+	CRDWaitTimeout             time.Duration
+	ResyncOnSIGHUP             bool
+	ObserveOnly                bool
+	ExcludeNamespaces          []string
+	WatchNamespacesFile        string
+	PrintConfig                bool
 
 	// Path to a controller-runtime componentconfig file.
 	// If this is empty, use default values.
@@ -51,7 +77,7 @@ func (f *Flags) AddTo(flagSet *pflag.FlagSet) {
 	flagSet.StringVar(&f.WatchesFile,
 		"watches-file",
 		"./watches.yaml",
-		"Path to the watches file to use",
+		"Path to the watches file to use, or to a directory of watches files",
 	)
 	// Controller flags.
 	flagSet.DurationVar(&f.ReconcilePeriod,
@@ -64,6 +90,71 @@ func (f *Flags) AddTo(flagSet *pflag.FlagSet) {
 		runtime.NumCPU(),
 		"Maximum number of concurrent reconciles for controllers.",
 	)
+	flagSet.IntVar(&f.MaxTotalReconciles,
+		"max-total-concurrent-reconciles",
+		0,
+		"Maximum number of concurrent reconciles across all controllers. When"+
+			" all are busy, free workers are shared round-robin between the"+
+			" controllers of the watches, so that a burst of one kind does not"+
+			" starve the others. 0 means no limit.",
+	)
+	flagSet.IntVar(&f.MaxHelmOperations,
+		"max-concurrent-helm-operations",
+		0,
+		"Maximum number of concurrent Helm operations across all controllers."+
+			" When the limit is reached, reconciles are requeued instead of"+
+			" blocking workers. 0 means no limit.",
+	)
+	flagSet.IntVar(&f.MaxConcurrentChartLoads,
+		"max-concurrent-chart-loads",
+		runtime.NumCPU(),
+		"Maximum number of charts of the watches file that are loaded"+
+			" concurrently on startup.",
+	)
+	flagSet.IntVar(&f.MaxReleaseHistory,
+		"max-release-history",
+		0,
+		"Default maximum number of Helm release versions kept per custom"+
+			" resource. Older versions are pruned on upgrade. Can be"+
+			" overridden per watch with maxReleaseHistory. 0 means no limit.",
+	)
+	flagSet.DurationVar(&f.CacheSyncTimeout,
+		"cache-sync-timeout",
+		2*time.Minute,
+		"Maximum time to wait for the informer caches of controllers to sync"+
+			" on startup.",
+	)
+	flagSet.StringToStringVar(&f.FeatureGates,
+		"feature-gates",
+		nil,
+		"Comma-separated list of feature gates of the reconcilers to enable or"+
+			" disable, e.g. DriftCorrection=false. Known gates and their"+
+			" defaults: "+featureGatesHelp()+".",
+	)
+	flagSet.StringSliceVar(&f.ExcludeNamespaces,
+		"exclude-namespaces",
+		nil,
+		"Comma-separated list of namespaces in which custom resources are"+
+			" never reconciled. Applies to all watched namespaces, including"+
+			" those set with the WATCH_NAMESPACE environment variable; a"+
+			" namespace that is both watched and excluded is not watched.",
+	)
+	flagSet.StringVar(&f.WatchNamespacesFile,
+		"watch-namespaces-file",
+		"",
+		"Path to a file, e.g. a mounted ConfigMap, that lists the namespaces"+
+			" to watch, separated by commas or whitespace. The file is checked"+
+			" for changes periodically and the watched namespaces are updated"+
+			" without a restart. Takes precedence over the WATCH_NAMESPACE"+
+			" environment variable.",
+	)
+	flagSet.BoolVar(&f.PrintConfig,
+		"print-config",
+		false,
+		"Print the effective configuration, resolved from flags, the manager"+
+			" config file and environment variables, and exit. Sensitive"+
+			" values are masked.",
+	)
 	// Controller manager flags.
 	flagSet.StringVar(&f.ManagerConfigPath,
 		"config",
@@ -84,6 +175,25 @@ func (f *Flags) AddTo(flagSet *pflag.FlagSet) {
 		":8080",
 		"The address the metric endpoint binds to",
 	)
+	flagSet.StringVar(&f.MetricsCertFile,
+		"metrics-cert-file",
+		"",
+		"Path to the PEM encoded certificate used to serve metrics over"+
+			" HTTPS. Requires --metrics-key-file.",
+	)
+	flagSet.StringVar(&f.MetricsKeyFile,
+		"metrics-key-file",
+		"",
+		"Path to the PEM encoded key used to serve metrics over HTTPS."+
+			" Requires --metrics-cert-file.",
+	)
+	flagSet.BoolVar(&f.MetricsRequireRBAC,
+		"metrics-require-rbac",
+		false,
+		"Authenticate metrics requests with a TokenReview and authorize them"+
+			" with a SubjectAccessReview for the metrics path. Requires"+
+			" --metrics-cert-file and --metrics-key-file.",
+	)
 	// TODO(2.0.0): for Go/Helm the port used is: 8081
 	// update it to keep the project aligned to the other
 	flagSet.StringVar(&f.ProbeAddr,
@@ -91,6 +201,50 @@ func (f *Flags) AddTo(flagSet *pflag.FlagSet) {
 		":8081",
 		"The address the probe endpoint binds to.",
 	)
+	flagSet.DurationVar(&f.ChartCheckInterval,
+		"chart-check-interval",
+		time.Minute,
+		"Interval at which the charts of the watches file are loaded to"+
+			" verify that they are still available. The health and readiness"+
+			" probes fail while a chart cannot be loaded. Set to 0 to disable.",
+	)
+	flagSet.DurationVar(&f.ChartWatchInterval,
+		"chart-watch-interval",
+		0,
+		"Interval at which the charts of the watches are reloaded from disk to"+
+			" detect changes of their content. When a chart changes, all custom"+
+			" resources of its watch are reconciled with the new chart. Set to 0"+
+			" to disable watching the charts.",
+	)
+	flagSet.DurationVar(&f.CRDWaitTimeout,
+		"crd-wait-timeout",
+		0,
+		"Maximum time to wait at startup for the API server to serve the"+
+			" kinds of the watches file, e.g. while their CRDs are not yet"+
+			" established. The operator exits if a kind is not served in time."+
+			" Set to 0 to disable waiting.",
+	)
+	flagSet.BoolVar(&f.ResyncOnSIGHUP,
+		"resync-on-sighup",
+		false,
+		"Reconcile all custom resources of the watches immediately when the"+
+			" operator receives SIGHUP, e.g. after an upgrade of the operator."+
+			" The reconciles are subject to the configured concurrency limits.",
+	)
+	flagSet.BoolVar(&f.ObserveOnly,
+		"observe-only",
+		false,
+		"Only observe the releases of custom resources and report in their"+
+			" status and the metrics whether they are missing or out of sync,"+
+			" without ever installing, upgrading or uninstalling releases.",
+	)
+	flagSet.StringVar(&f.PprofAddr,
+		"pprof-addr",
+		"",
+		"The address the pprof endpoint binds to, e.g. localhost:6060. Since"+
+			" profiles may contain sensitive information, prefer binding to"+
+			" localhost. Omit this flag to disable pprof.",
+	)
 	// TODO(2.0.0): remove
 	flagSet.BoolVar(&f.LeaderElection,
 		"enable-leader-election",
@@ -117,7 +271,93 @@ func (f *Flags) AddTo(flagSet *pflag.FlagSet) {
 			" holding the leader lock (required if running locally with leader"+
 			" election enabled).",
 	)
+	flagSet.StringVar(&f.LeaderElectionResourceLock,
+		"leader-election-resource-lock",
+		resourcelock.ConfigMapsLeasesResourceLock,
+		"Type of the resource used for holding the leader lock. One of: "+
+			strings.Join(supportedResourceLocks, ", ")+".",
+	)
+
+}
+
+func featureGatesHelp() string {
+	gates := make([]string, 0, len(reconciler.DefaultFeatureGates))
+	for gate, enabled := range reconciler.DefaultFeatureGates {
+		gates = append(gates, fmt.Sprintf("%s=%t", gate, enabled))
+	}
+	sort.Strings(gates)
+	return strings.Join(gates, ", ")
+}
+
+// ParseFeatureGates returns the feature gates set with --feature-gates.
+func (f *Flags) ParseFeatureGates() (map[string]bool, error) {
+	gates := make(map[string]bool, len(f.FeatureGates))
+	for name, value := range f.FeatureGates {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q for feature gate %s: %w", value, name, err)
+		}
+		gates[name] = enabled
+	}
+	return gates, nil
+}
+
+// supportedResourceLocks are the leader election resource lock types that can
+// be selected with --leader-election-resource-lock.
+var supportedResourceLocks = []string{
+	resourcelock.LeasesResourceLock,
+	resourcelock.ConfigMapsLeasesResourceLock,
+	resourcelock.EndpointsLeasesResourceLock,
+}
+
+// Validate returns an error if any of the flag values in f are invalid.
+func (f *Flags) Validate() error {
+	if f.CacheSyncTimeout <= 0 {
+		return errors.New("--cache-sync-timeout must be a positive duration")
+	}
+	if f.MaxTotalReconciles < 0 {
+		return errors.New("--max-total-concurrent-reconciles must not be negative")
+	}
+	if f.MaxHelmOperations < 0 {
+		return errors.New("--max-concurrent-helm-operations must not be negative")
+	}
+	if f.MaxConcurrentChartLoads <= 0 {
+		return errors.New("--max-concurrent-chart-loads must be positive")
+	}
+	if f.MaxReleaseHistory < 0 {
+		return errors.New("--max-release-history must not be negative")
+	}
+	if f.ChartCheckInterval < 0 {
+		return errors.New("--chart-check-interval must not be negative")
+	}
+	if f.ChartWatchInterval < 0 {
+		return errors.New("--chart-watch-interval must not be negative")
+	}
+	if f.CRDWaitTimeout < 0 {
+		return errors.New("--crd-wait-timeout must not be negative")
+	}
+	if _, err := f.ParseFeatureGates(); err != nil {
+		return fmt.Errorf("--feature-gates: %w", err)
+	}
+	if (f.MetricsCertFile == "") != (f.MetricsKeyFile == "") {
+		return errors.New("--metrics-cert-file and --metrics-key-file must be set together")
+	}
+	if f.MetricsRequireRBAC && !f.SecureMetrics() {
+		return errors.New("--metrics-require-rbac requires --metrics-cert-file and --metrics-key-file")
+	}
+	for _, l := range supportedResourceLocks {
+		if f.LeaderElectionResourceLock == l {
+			return nil
+		}
+	}
+	return fmt.Errorf("--leader-election-resource-lock must be one of %s, got %q",
+		strings.Join(supportedResourceLocks, ", "), f.LeaderElectionResourceLock)
+}
 
+// SecureMetrics returns true if metrics must be served over HTTPS, which the
+// metrics server of the manager does not support.
+func (f *Flags) SecureMetrics() bool {
+	return f.MetricsCertFile != "" && f.MetricsKeyFile != ""
 }
 
 // ToManagerOptions uses the flag set in f to configure options.
@@ -139,6 +379,9 @@ func (f *Flags) ToManagerOptions(options manager.Options) manager.Options {
 	if changed("health-probe-bind-address") || options.HealthProbeBindAddress == "" {
 		options.HealthProbeBindAddress = f.ProbeAddr
 	}
+	if changed("pprof-addr") || options.PprofBindAddress == "" {
+		options.PprofBindAddress = f.PprofAddr
+	}
 	// TODO(2.0.0): remove enable-leader-election
 	if changed("leader-elect") || changed("enable-leader-election") || !options.LeaderElection {
 		options.LeaderElection = f.LeaderElection
@@ -149,8 +392,11 @@ func (f *Flags) ToManagerOptions(options manager.Options) manager.Options {
 	if changed("leader-election-namespace") || options.LeaderElectionNamespace == "" {
 		options.LeaderElectionNamespace = f.LeaderElectionNamespace
 	}
-	if options.LeaderElectionResourceLock == "" {
-		options.LeaderElectionResourceLock = resourcelock.ConfigMapsLeasesResourceLock
+	if changed("cache-sync-timeout") || options.Controller.CacheSyncTimeout == 0 {
+		options.Controller.CacheSyncTimeout = f.CacheSyncTimeout
+	}
+	if changed("leader-election-resource-lock") || options.LeaderElectionResourceLock == "" {
+		options.LeaderElectionResourceLock = f.LeaderElectionResourceLock
 	}
 	return options
 }
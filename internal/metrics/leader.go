@@ -0,0 +1,66 @@
+// Copyright 2023 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var leader = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Subsystem: subsystem,
+		Name:      "leader",
+		Help:      "Whether this instance is the elected leader (1) or a follower (0)",
+	},
+)
+
+// LeaderReporter is a manager.Runnable that reports in the leader gauge
+// whether this instance is the elected leader. It runs on all instances.
+type LeaderReporter struct {
+	elected <-chan struct{}
+}
+
+// NewLeaderReporter registers the leader gauge with r and returns a
+// LeaderReporter that sets it once elected is closed, e.g. the channel
+// returned by the Elected method of the manager. Without leader election, the
+// channel is closed immediately, so that the instance is reported as leader.
+func NewLeaderReporter(r prometheus.Registerer, elected <-chan struct{}) *LeaderReporter {
+	leader.Set(0)
+	r.MustRegister(leader)
+	return &LeaderReporter{elected: elected}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, so that
+// followers report that they are not the leader.
+func (l *LeaderReporter) NeedLeaderElection() bool {
+	return false
+}
+
+// Start implements manager.Runnable. It sets the leader gauge when this
+// instance is elected, and resets it when the manager is stopped, e.g.
+// because the leadership was lost.
+func (l *LeaderReporter) Start(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-l.elected:
+		leader.Set(1)
+	}
+	<-ctx.Done()
+	leader.Set(0)
+	return nil
+}
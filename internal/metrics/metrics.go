@@ -15,7 +15,10 @@
 package metrics
 
 import (
+	"sync"
+
 	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	helmVersion "github.com/operator-framework/helm-operator-plugins/internal/version"
 )
@@ -36,10 +39,131 @@ var (
 			},
 		},
 	)
+
+	chartUpgradeAvailable = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "chart_upgrade_available",
+			Help:      "Whether a newer version of the chart of a release is available in the chart repository",
+		},
+		[]string{"group", "version", "kind", "namespace", "name"},
+	)
+
+	releaseOutOfSync = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "release_out_of_sync",
+			Help:      "Whether the release of a custom resource is missing or differs from its spec, as observed in observe-only mode",
+		},
+		[]string{"group", "version", "kind", "namespace", "name"},
+	)
+
+	managedResources = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "managed_resources",
+			Help:      "Number of resources of each kind managed by the releases of a custom resource kind",
+		},
+		[]string{"group", "version", "kind", "resource_group", "resource_version", "resource_kind"},
+	)
+
+	registerReconcilerMetricsOnce sync.Once
+
+	inventoryMu sync.Mutex
+	inventory   = map[releaseKey]map[schema.GroupVersionKind]int{}
 )
 
+type releaseKey struct {
+	gvk       schema.GroupVersionKind
+	namespace string
+	name      string
+}
+
 // RegisterBuildInfo registers buildInfo Collector to be included in metrics collection
 func RegisterBuildInfo(r prometheus.Registerer) {
 	buildInfo.Set(1)
 	r.MustRegister(buildInfo)
 }
+
+// RegisterReconcilerMetrics registers the Collectors updated by Helm
+// reconcilers to be included in metrics collection. Only the first call
+// registers the Collectors, subsequent calls are no-ops.
+func RegisterReconcilerMetrics(r prometheus.Registerer) {
+	registerReconcilerMetricsOnce.Do(func() {
+		r.MustRegister(chartUpgradeAvailable, releaseOutOfSync, managedResources)
+	})
+}
+
+// SetChartUpgradeAvailable records whether a newer chart version is available
+// for the release of the custom resource identified by gvk, namespace and name.
+func SetChartUpgradeAvailable(gvk schema.GroupVersionKind, namespace, name string, available bool) {
+	v := 0.0
+	if available {
+		v = 1
+	}
+	chartUpgradeAvailable.WithLabelValues(gvk.Group, gvk.Version, gvk.Kind, namespace, name).Set(v)
+}
+
+// DeleteChartUpgradeAvailable removes the chart upgrade metric recorded for
+// the release of the custom resource identified by gvk, namespace and name,
+// e.g. when it is not known whether a newer chart version is available.
+func DeleteChartUpgradeAvailable(gvk schema.GroupVersionKind, namespace, name string) {
+	chartUpgradeAvailable.DeleteLabelValues(gvk.Group, gvk.Version, gvk.Kind, namespace, name)
+}
+
+// SetReleaseOutOfSync records whether the release of the custom resource
+// identified by gvk, namespace and name is missing or differs from the spec of
+// the custom resource.
+func SetReleaseOutOfSync(gvk schema.GroupVersionKind, namespace, name string, outOfSync bool) {
+	v := 0.0
+	if outOfSync {
+		v = 1
+	}
+	releaseOutOfSync.WithLabelValues(gvk.Group, gvk.Version, gvk.Kind, namespace, name).Set(v)
+}
+
+// SetReleaseResources records the number of resources of each kind in the
+// release of the custom resource identified by gvk, namespace and name. The
+// managed resources gauge is the sum of these counts over all releases of
+// custom resources of the same kind.
+func SetReleaseResources(gvk schema.GroupVersionKind, namespace, name string, counts map[schema.GroupVersionKind]int) {
+	inventoryMu.Lock()
+	defer inventoryMu.Unlock()
+
+	key := releaseKey{gvk: gvk, namespace: namespace, name: name}
+	affected := map[schema.GroupVersionKind]struct{}{}
+	for resGVK := range inventory[key] {
+		affected[resGVK] = struct{}{}
+	}
+	for resGVK := range counts {
+		affected[resGVK] = struct{}{}
+	}
+	if len(counts) == 0 {
+		delete(inventory, key)
+	} else {
+		inventory[key] = counts
+	}
+
+	for resGVK := range affected {
+		total := 0
+		for k, c := range inventory {
+			if k.gvk == gvk {
+				total += c[resGVK]
+			}
+		}
+		labels := []string{gvk.Group, gvk.Version, gvk.Kind, resGVK.Group, resGVK.Version, resGVK.Kind}
+		if total == 0 {
+			managedResources.DeleteLabelValues(labels...)
+			continue
+		}
+		managedResources.WithLabelValues(labels...).Set(float64(total))
+	}
+}
+
+// DeleteReleaseMetrics removes all release metrics recorded for the custom
+// resource identified by gvk, namespace and name.
+func DeleteReleaseMetrics(gvk schema.GroupVersionKind, namespace, name string) {
+	DeleteChartUpgradeAvailable(gvk, namespace, name)
+	releaseOutOfSync.DeleteLabelValues(gvk.Group, gvk.Version, gvk.Kind, namespace, name)
+	SetReleaseResources(gvk, namespace, name, nil)
+}
@@ -0,0 +1,168 @@
+// Copyright 2023 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// SecureServer serves the metrics of Gatherer over HTTPS. It is used instead
+// of the metrics server of the manager when the metrics endpoint must be
+// protected.
+type SecureServer struct {
+	// BindAddress is the address the server listens on.
+	BindAddress string
+
+	// CertFile and KeyFile are the paths of the PEM encoded serving
+	// certificate and key. Both files are reloaded when they change.
+	CertFile string
+	KeyFile  string
+
+	// Gatherer is the source of the served metrics.
+	Gatherer prometheus.Gatherer
+
+	// Client, if not nil, is used to authenticate the bearer token of every
+	// request with a TokenReview, and to authorize the request with a
+	// SubjectAccessReview for the request path and verb.
+	Client client.Client
+
+	Log logr.Logger
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, so that
+// metrics are served by all replicas.
+func (s *SecureServer) NeedLeaderElection() bool {
+	return false
+}
+
+// Start implements manager.Runnable. It serves metrics until ctx is done.
+func (s *SecureServer) Start(ctx context.Context) error {
+	watcher, err := certwatcher.New(s.CertFile, s.KeyFile)
+	if err != nil {
+		return fmt.Errorf("load metrics serving certificate: %w", err)
+	}
+	go func() {
+		if err := watcher.Start(ctx); err != nil {
+			s.Log.Error(err, "certificate watcher failed")
+		}
+	}()
+
+	var handler http.Handler = promhttp.HandlerFor(s.Gatherer, promhttp.HandlerOpts{
+		ErrorHandling: promhttp.HTTPErrorOnError,
+	})
+	if s.Client != nil {
+		handler = s.withAuth(handler)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", handler)
+
+	ln, err := net.Listen("tcp", s.BindAddress)
+	if err != nil {
+		return fmt.Errorf("listen on metrics address %q: %w", s.BindAddress, err)
+	}
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 30 * time.Second,
+		TLSConfig: &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: watcher.GetCertificate,
+		},
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		s.Log.Info("Serving metrics over HTTPS", "address", ln.Addr().String(), "authentication", s.Client != nil)
+		if err := srv.ServeTLS(ln, "", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- err
+		}
+		close(errCh)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
+
+func (s *SecureServer) withAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		status, err := s.authorize(req)
+		if err != nil {
+			s.Log.V(1).Info("Metrics request denied", "reason", err.Error())
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// authorize returns the HTTP status code to respond with and an error if the
+// request must be denied.
+func (s *SecureServer) authorize(req *http.Request) (int, error) {
+	auth := req.Header.Get("Authorization")
+	token := strings.TrimPrefix(auth, "Bearer ")
+	if token == auth || token == "" {
+		return http.StatusUnauthorized, errors.New("missing bearer token")
+	}
+
+	tr := &authenticationv1.TokenReview{Spec: authenticationv1.TokenReviewSpec{Token: token}}
+	if err := s.Client.Create(req.Context(), tr); err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("create token review: %w", err)
+	}
+	if !tr.Status.Authenticated {
+		return http.StatusUnauthorized, errors.New("token is not authenticated")
+	}
+
+	extra := make(map[string]authorizationv1.ExtraValue, len(tr.Status.User.Extra))
+	for k, v := range tr.Status.User.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	sar := &authorizationv1.SubjectAccessReview{Spec: authorizationv1.SubjectAccessReviewSpec{
+		User:   tr.Status.User.Username,
+		UID:    tr.Status.User.UID,
+		Groups: tr.Status.User.Groups,
+		Extra:  extra,
+		NonResourceAttributes: &authorizationv1.NonResourceAttributes{
+			Path: req.URL.Path,
+			Verb: strings.ToLower(req.Method),
+		},
+	}}
+	if err := s.Client.Create(req.Context(), sar); err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("create subject access review: %w", err)
+	}
+	if !sar.Status.Allowed {
+		return http.StatusForbidden, fmt.Errorf("user %q is not allowed to %s %s", tr.Status.User.Username, sar.Spec.NonResourceAttributes.Verb, req.URL.Path)
+	}
+	return http.StatusOK, nil
+}
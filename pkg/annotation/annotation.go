@@ -41,6 +41,7 @@ package annotation
 
 import (
 	"strconv"
+	"strings"
 
 	"helm.sh/helm/v3/pkg/action"
 
@@ -51,6 +52,7 @@ var (
 	DefaultInstallAnnotations   = []Install{InstallDescription{}, InstallDisableHooks{}}
 	DefaultUpgradeAnnotations   = []Upgrade{UpgradeDescription{}, UpgradeDisableHooks{}, UpgradeForce{}}
 	DefaultUninstallAnnotations = []Uninstall{UninstallDescription{}, UninstallDisableHooks{}}
+	DefaultReinstallAnnotations = []Reinstall{ReinstallRelease{}}
 )
 
 // Install configures an install annotation.
@@ -71,6 +73,136 @@ type Uninstall interface {
 	UninstallOption(string) helmclient.UninstallOption
 }
 
+// Reinstall configures a reinstall annotation. If Reinstall returns true for
+// the value of the annotation, the release is uninstalled and installed again
+// and the annotation is removed from the custom resource.
+type Reinstall interface {
+	Name() string
+	Reinstall(string) bool
+}
+
+// Aliased is implemented by annotations that are also recognized under other
+// names, e.g. while custom resources are migrated from one annotation domain
+// to another. If a custom resource has more than one of the names, the name
+// returned by Name takes precedence, followed by the aliases in order.
+type Aliased interface {
+	Aliases() []string
+}
+
+// LegacyDomain is the annotation domain of earlier versions of the Helm
+// operator.
+const LegacyDomain = "helm.operator-sdk"
+
+// InstallWithDomains returns copies of as that are also recognized with the
+// domain of their names replaced by each of domains, e.g. with LegacyDomain.
+func InstallWithDomains(as []Install, domains ...string) []Install {
+	out := make([]Install, 0, len(as))
+	for _, a := range as {
+		out = append(out, aliasedInstall{a, aliasesFor(a.Name(), domains)})
+	}
+	return out
+}
+
+// UpgradeWithDomains returns copies of as that are also recognized with the
+// domain of their names replaced by each of domains.
+func UpgradeWithDomains(as []Upgrade, domains ...string) []Upgrade {
+	out := make([]Upgrade, 0, len(as))
+	for _, a := range as {
+		out = append(out, aliasedUpgrade{a, aliasesFor(a.Name(), domains)})
+	}
+	return out
+}
+
+// UninstallWithDomains returns copies of as that are also recognized with
+// the domain of their names replaced by each of domains.
+func UninstallWithDomains(as []Uninstall, domains ...string) []Uninstall {
+	out := make([]Uninstall, 0, len(as))
+	for _, a := range as {
+		out = append(out, aliasedUninstall{a, aliasesFor(a.Name(), domains)})
+	}
+	return out
+}
+
+// ReinstallWithDomains returns copies of as that are also recognized with
+// the domain of their names replaced by each of domains.
+func ReinstallWithDomains(as []Reinstall, domains ...string) []Reinstall {
+	out := make([]Reinstall, 0, len(as))
+	for _, a := range as {
+		out = append(out, aliasedReinstall{a, aliasesFor(a.Name(), domains)})
+	}
+	return out
+}
+
+// aliasesFor returns name with its domain, i.e. the part up to the first
+// slash, replaced by each of domains. The aliases of name are kept.
+func aliasesFor(name string, domains []string) []string {
+	base := name
+	if i := strings.Index(name, "/"); i >= 0 {
+		base = name[i+1:]
+	}
+	var aliases []string
+	for _, d := range domains {
+		if alias := d + "/" + base; alias != name {
+			aliases = append(aliases, alias)
+		}
+	}
+	return aliases
+}
+
+type aliasedInstall struct {
+	Install
+	aliases []string
+}
+
+func (a aliasedInstall) Aliases() []string {
+	return append(aliasesOf(a.Install), a.aliases...)
+}
+
+type aliasedUpgrade struct {
+	Upgrade
+	aliases []string
+}
+
+func (a aliasedUpgrade) Aliases() []string {
+	return append(aliasesOf(a.Upgrade), a.aliases...)
+}
+
+type aliasedUninstall struct {
+	Uninstall
+	aliases []string
+}
+
+func (a aliasedUninstall) Aliases() []string {
+	return append(aliasesOf(a.Uninstall), a.aliases...)
+}
+
+// aliasedReinstall cannot embed Reinstall, whose method of the same name
+// would be hidden by the embedded field.
+type aliasedReinstall struct {
+	r       Reinstall
+	aliases []string
+}
+
+func (a aliasedReinstall) Name() string {
+	return a.r.Name()
+}
+
+func (a aliasedReinstall) Reinstall(v string) bool {
+	return a.r.Reinstall(v)
+}
+
+func (a aliasedReinstall) Aliases() []string {
+	return append(aliasesOf(a.r), a.aliases...)
+}
+
+// aliasesOf returns the aliases of a, if it has any.
+func aliasesOf(a interface{}) []string {
+	if aliased, ok := a.(Aliased); ok {
+		return append([]string(nil), aliased.Aliases()...)
+	}
+	return nil
+}
+
 const (
 	defaultDomain                    = "helm.sdk.operatorframework.io"
 	defaultInstallDisableHooksName   = defaultDomain + "/install-disable-hooks"
@@ -79,6 +211,8 @@ const (
 
 	defaultUpgradeForceName = defaultDomain + "/upgrade-force"
 
+	defaultReinstallName = defaultDomain + "/reinstall"
+
 	defaultInstallDescriptionName   = defaultDomain + "/install-description"
 	defaultUpgradeDescriptionName   = defaultDomain + "/upgrade-description"
 	defaultUninstallDescriptionName = defaultDomain + "/uninstall-description"
@@ -236,3 +370,28 @@ func (u UninstallDescription) UninstallOption(v string) helmclient.UninstallOpti
 		return nil
 	}
 }
+
+// ReinstallRelease requests a full reinstall of a release, i.e. an uninstall
+// followed by a fresh install, if its annotation is set to "true". This can
+// recover releases that upgrades cannot fix.
+//
+// WARNING: all resources of the release are deleted and recreated, so any data
+// stored in them, e.g. in PersistentVolumeClaims without a "keep" resource
+// policy, is lost.
+type ReinstallRelease struct {
+	CustomName string
+}
+
+var _ Reinstall = &ReinstallRelease{}
+
+func (r ReinstallRelease) Name() string {
+	if r.CustomName != "" {
+		return r.CustomName
+	}
+	return defaultReinstallName
+}
+
+func (r ReinstallRelease) Reinstall(val string) bool {
+	reinstall, err := strconv.ParseBool(val)
+	return err == nil && reinstall
+}
@@ -255,4 +255,70 @@ var _ = Describe("Annotation", func() {
 			})
 		})
 	})
+	Describe("Reinstall", func() {
+		var a ReinstallRelease
+
+		BeforeEach(func() {
+			a = ReinstallRelease{}
+		})
+
+		It("should return a default name", func() {
+			Expect(a.Name()).To(Equal(defaultReinstallName))
+		})
+
+		It("should return a custom name", func() {
+			const customName = "custom.domain/custom-name"
+			a.CustomName = customName
+			Expect(a.Name()).To(Equal(customName))
+		})
+
+		It("should request a reinstall", func() {
+			Expect(a.Reinstall("true")).To(BeTrue())
+		})
+
+		It("should not request a reinstall", func() {
+			Expect(a.Reinstall("false")).To(BeFalse())
+		})
+
+		It("should default to false with invalid value", func() {
+			Expect(a.Reinstall("invalid")).To(BeFalse())
+		})
+	})
+})
+
+var _ = Describe("WithDomains", func() {
+	It("should alias install annotations in other domains", func() {
+		as := InstallWithDomains([]Install{InstallDisableHooks{}}, LegacyDomain, defaultDomain)
+		Expect(as).To(HaveLen(1))
+		Expect(as[0].Name()).To(Equal(defaultInstallDisableHooksName))
+		Expect(as[0].(Aliased).Aliases()).To(Equal([]string{"helm.operator-sdk/install-disable-hooks"}))
+
+		install := action.Install{}
+		Expect(as[0].InstallOption("true")(&install)).To(Succeed())
+		Expect(install.DisableHooks).To(BeTrue())
+	})
+
+	It("should keep existing aliases", func() {
+		as := UpgradeWithDomains(UpgradeWithDomains([]Upgrade{UpgradeForce{}}, "a.example.com"), "b.example.com")
+		Expect(as[0].(Aliased).Aliases()).To(Equal([]string{"a.example.com/upgrade-force", "b.example.com/upgrade-force"}))
+	})
+
+	It("should alias uninstall and reinstall annotations", func() {
+		u := UninstallWithDomains(DefaultUninstallAnnotations, LegacyDomain)
+		Expect(u).To(HaveLen(len(DefaultUninstallAnnotations)))
+		Expect(u[0].(Aliased).Aliases()).To(Equal([]string{"helm.operator-sdk/uninstall-description"}))
+
+		r := ReinstallWithDomains(DefaultReinstallAnnotations, LegacyDomain)
+		Expect(r[0].(Aliased).Aliases()).To(Equal([]string{"helm.operator-sdk/reinstall"}))
+		Expect(r[0].Reinstall("true")).To(BeTrue())
+	})
+
+	It("should forward reinstall annotations to the original", func() {
+		r := ReinstallWithDomains(ReinstallWithDomains(DefaultReinstallAnnotations, "a.example.com"), "b.example.com")
+		Expect(r).To(HaveLen(len(DefaultReinstallAnnotations)))
+		Expect(r[0].Name()).To(Equal(DefaultReinstallAnnotations[0].Name()))
+		Expect(r[0].(Aliased).Aliases()).To(Equal([]string{"a.example.com/reinstall", "b.example.com/reinstall"}))
+		Expect(r[0].Reinstall("true")).To(BeTrue())
+		Expect(r[0].Reinstall("false")).To(BeFalse())
+	})
 })
@@ -0,0 +1,86 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package chartfs loads Helm charts from an fs.FS, such as an embed.FS, so
+// that operators can be built as a single binary that contains its charts.
+package chartfs
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+)
+
+var utf8bom = []byte{0xEF, 0xBB, 0xBF}
+
+// Load loads the chart at name in fsys. name is either a chart directory or
+// a chart archive. Like the loader of Helm, Load skips hidden files in the
+// templates directory, but it does not evaluate .helmignore files.
+//
+// Note that go:embed omits files whose names begin with "." or "_", such as
+// templates/_helpers.tpl, unless the pattern is prefixed with "all:", e.g.
+//
+//	//go:embed all:charts/nginx
+//	var charts embed.FS
+func Load(fsys fs.FS, name string) (*chart.Chart, error) {
+	fi, err := fs.Stat(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		f, err := fsys.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return loader.LoadArchive(f)
+	}
+
+	var files []*loader.BufferedFile
+	err = fs.WalkDir(fsys, name, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		n := p
+		if name != "." {
+			n = strings.TrimPrefix(p, name+"/")
+		}
+		if path.Dir(n) == "templates" && strings.HasPrefix(path.Base(n), ".") {
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return fmt.Errorf("cannot load irregular file %s", p)
+		}
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return fmt.Errorf("error reading %s: %w", n, err)
+		}
+		files = append(files, &loader.BufferedFile{Name: n, Data: bytes.TrimPrefix(data, utf8bom)})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return loader.LoadFiles(files)
+}
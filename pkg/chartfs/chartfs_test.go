@@ -0,0 +1,81 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartfs_test
+
+import (
+	"os"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/chartutil"
+
+	"github.com/operator-framework/helm-operator-plugins/pkg/chartfs"
+)
+
+var _ = Describe("Load", func() {
+	var fsys fstest.MapFS
+
+	BeforeEach(func() {
+		fsys = fstest.MapFS{
+			"charts/test/Chart.yaml":             {Data: []byte("apiVersion: v2\nname: test\nversion: 0.1.0\n")},
+			"charts/test/values.yaml":            {Data: []byte("replicas: 1\n")},
+			"charts/test/templates/cm.yaml":      {Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test\n")},
+			"charts/test/templates/_helpers.tpl": {Data: []byte(`{{- define "test.name" -}}test{{- end -}}`)},
+			"charts/test/templates/.hidden":      {Data: []byte("hidden")},
+		}
+	})
+
+	It("should load a chart directory", func() {
+		chrt, err := chartfs.Load(fsys, "charts/test")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(chrt.Name()).To(Equal("test"))
+		Expect(chrt.Values).To(HaveKeyWithValue("replicas", float64(1)))
+
+		var names []string
+		for _, t := range chrt.Templates {
+			names = append(names, t.Name)
+		}
+		Expect(names).To(ConsistOf("templates/cm.yaml", "templates/_helpers.tpl"))
+	})
+
+	It("should load a chart archive", func() {
+		chrt, err := chartfs.Load(fsys, "charts/test")
+		Expect(err).NotTo(HaveOccurred())
+
+		dir := GinkgoT().TempDir()
+		archive, err := chartutil.Save(chrt, dir)
+		Expect(err).NotTo(HaveOccurred())
+		data, err := os.ReadFile(archive)
+		Expect(err).NotTo(HaveOccurred())
+
+		loaded, err := chartfs.Load(fstest.MapFS{"test.tgz": {Data: data}}, "test.tgz")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loaded.Name()).To(Equal("test"))
+		Expect(loaded.Templates).To(HaveLen(2))
+	})
+
+	It("should fail if the chart does not exist", func() {
+		_, err := chartfs.Load(fsys, "charts/missing")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should fail if the directory is not a chart", func() {
+		_, err := chartfs.Load(fstest.MapFS{"empty/README.md": {Data: []byte("not a chart")}}, "empty")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,154 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package chartrepo provides read-only access to remote Helm chart
+// repositories and OCI registries.
+package chartrepo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/repo"
+	"sigs.k8s.io/yaml"
+)
+
+// Client reads chart metadata from remote chart repositories and OCI
+// registries.
+type Client struct {
+	httpClient     *http.Client
+	registryClient *registry.Client
+}
+
+// Option configures a Client.
+type Option func(c *Client) error
+
+// WithHTTPClient configures the HTTP client used to reach chart
+// repositories and OCI registries. By default, http.DefaultClient is used.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) error {
+		c.httpClient = hc
+		return nil
+	}
+}
+
+// WithTLSConfig configures the TLS settings used to reach chart repositories
+// and OCI registries. It replaces any HTTP client configured with
+// WithHTTPClient.
+func WithTLSConfig(cfg TLSConfig) Option {
+	return func(c *Client) error {
+		tlsConfig, err := cfg.clientConfig()
+		if err != nil {
+			return err
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		c.httpClient = &http.Client{Transport: transport}
+		return nil
+	}
+}
+
+// New returns a new Client configured with opts.
+func New(opts ...Option) (*Client, error) {
+	c := &Client{}
+	for _, o := range opts {
+		if err := o(c); err != nil {
+			return nil, err
+		}
+	}
+	if c.httpClient == nil {
+		c.httpClient = http.DefaultClient
+	}
+
+	rc, err := registry.NewClient(registry.ClientOptHTTPClient(c.httpClient))
+	if err != nil {
+		return nil, fmt.Errorf("create registry client: %w", err)
+	}
+	c.registryClient = rc
+	return c, nil
+}
+
+// LatestVersion returns the latest stable version of the chart named
+// chartName that is published in the repository at repoURL. If repoURL uses
+// the oci:// scheme, the tags of the chart in the OCI registry are looked up.
+// Otherwise, repoURL is expected to serve a chart repository index.
+func (c *Client) LatestVersion(ctx context.Context, repoURL, chartName string) (string, error) {
+	if registry.IsOCI(repoURL) {
+		return c.latestOCIVersion(repoURL, chartName)
+	}
+
+	idx, err := c.index(ctx, repoURL)
+	if err != nil {
+		return "", err
+	}
+	cv, err := idx.Get(chartName, "")
+	if err != nil {
+		return "", &RepositoryError{Repository: repoURL, Err: fmt.Errorf("chart %q: %w: %v", chartName, ErrNotFound, err)}
+	}
+	return cv.Version, nil
+}
+
+func (c *Client) latestOCIVersion(repoURL, chartName string) (string, error) {
+	ref := strings.TrimSuffix(strings.TrimPrefix(repoURL, registry.OCIScheme+"://"), "/") + "/" + chartName
+	tags, err := c.registryClient.Tags(ref)
+	if err != nil {
+		return "", newRepositoryError(repoURL, fmt.Errorf("list tags of chart %q: %w", chartName, err))
+	}
+
+	// Tags are sorted from newest to oldest.
+	for _, t := range tags {
+		if v, err := semver.ParseTolerant(t); err == nil && len(v.Pre) == 0 {
+			return t, nil
+		}
+	}
+	return "", &RepositoryError{Repository: repoURL, Err: fmt.Errorf("chart %q: %w: no stable version published", chartName, ErrNotFound)}
+}
+
+func (c *Client) index(ctx context.Context, repoURL string) (*repo.IndexFile, error) {
+	indexURL := strings.TrimSuffix(repoURL, "/") + "/index.yaml"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, indexURL, nil)
+	if err != nil {
+		return nil, &RepositoryError{Repository: repoURL, Err: fmt.Errorf("create request: %w", err)}
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, newRepositoryError(repoURL, fmt.Errorf("fetch index: %w", err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, &RepositoryError{Repository: repoURL, Err: fmt.Errorf("fetch index: %w: %s returned %q", ErrNotFound, indexURL, resp.Status)}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &RepositoryError{Repository: repoURL, Err: fmt.Errorf("fetch index: %s returned %q", indexURL, resp.Status)}
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, newRepositoryError(repoURL, fmt.Errorf("read index: %w", err))
+	}
+
+	idx := &repo.IndexFile{}
+	if err := yaml.Unmarshal(data, idx); err != nil {
+		return nil, &RepositoryError{Repository: repoURL, Err: fmt.Errorf("parse index: %w", err)}
+	}
+	idx.SortEntries()
+	return idx, nil
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartrepo_test
+
+import (
+	"context"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	. "github.com/operator-framework/helm-operator-plugins/pkg/chartrepo"
+)
+
+const testIndex = `apiVersion: v1
+entries:
+  test-chart:
+  - name: test-chart
+    version: 1.2.0
+  - name: test-chart
+    version: 1.3.0-rc.1
+  - name: test-chart
+    version: 1.2.3
+`
+
+var _ = Describe("Client", func() {
+	var (
+		srv *httptest.Server
+		c   *Client
+	)
+	BeforeEach(func() {
+		srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/charts/index.yaml" {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write([]byte(testIndex))
+		}))
+		var err error
+		c, err = New(WithHTTPClient(srv.Client()))
+		Expect(err).NotTo(HaveOccurred())
+	})
+	AfterEach(func() {
+		srv.Close()
+	})
+
+	var _ = Describe("LatestVersion", func() {
+		It("should return the latest stable version", func() {
+			v, err := c.LatestVersion(context.Background(), srv.URL+"/charts/", "test-chart")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(v).To(Equal("1.2.3"))
+		})
+		It("should fail if the chart is not in the index", func() {
+			_, err := c.LatestVersion(context.Background(), srv.URL+"/charts", "other-chart")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail if the index cannot be found", func() {
+			_, err := c.LatestVersion(context.Background(), srv.URL+"/missing", "test-chart")
+			Expect(errors.Is(err, ErrNotFound)).To(BeTrue())
+			Expect(errors.Is(err, ErrTLS)).To(BeFalse())
+			Expect(err).To(MatchError(ContainSubstring(srv.URL + "/missing")))
+		})
+	})
+})
+
+var _ = Describe("TLS", func() {
+	var srv *httptest.Server
+	BeforeEach(func() {
+		srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(testIndex))
+		}))
+	})
+	AfterEach(func() {
+		srv.Close()
+	})
+
+	It("should fail with a TLS error if the server certificate is not trusted", func() {
+		c, err := New(WithTLSConfig(TLSConfig{}))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = c.LatestVersion(context.Background(), srv.URL, "test-chart")
+		Expect(errors.Is(err, ErrTLS)).To(BeTrue())
+		Expect(errors.Is(err, ErrNotFound)).To(BeFalse())
+		Expect(err).To(MatchError(ContainSubstring(srv.URL)))
+	})
+	It("should succeed with a trusted CA bundle", func() {
+		ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+		c, err := New(WithTLSConfig(TLSConfig{CA: ca}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.LatestVersion(context.Background(), srv.URL, "test-chart")).To(Equal("1.2.3"))
+	})
+	It("should succeed when skipping TLS verification", func() {
+		c, err := New(WithTLSConfig(TLSConfig{InsecureSkipTLSVerify: true}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.LatestVersion(context.Background(), srv.URL, "test-chart")).To(Equal("1.2.3"))
+	})
+	It("should fail with an invalid CA bundle", func() {
+		_, err := New(WithTLSConfig(TLSConfig{CA: []byte("invalid")}))
+		Expect(err).To(HaveOccurred())
+	})
+	It("should fail with an invalid client certificate", func() {
+		_, err := New(WithTLSConfig(TLSConfig{Cert: []byte("invalid"), Key: []byte("invalid")}))
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("TLSConfigFromSecret", func() {
+	It("should read the CA bundle, certificate and key", func() {
+		secret := &corev1.Secret{Data: map[string][]byte{
+			"ca.crt":  []byte("ca"),
+			"tls.crt": []byte("cert"),
+			"tls.key": []byte("key"),
+		}}
+		Expect(TLSConfigFromSecret(secret)).To(Equal(TLSConfig{
+			CA:   []byte("ca"),
+			Cert: []byte("cert"),
+			Key:  []byte("key"),
+		}))
+	})
+})
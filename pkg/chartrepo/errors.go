@@ -0,0 +1,72 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartrepo
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotFound is wrapped by errors returned when a repository index or
+	// chart does not exist.
+	ErrNotFound = errors.New("not found")
+
+	// ErrTLS is wrapped by errors returned when the TLS connection to a
+	// repository cannot be established or verified.
+	ErrTLS = errors.New("TLS error")
+)
+
+// RepositoryError is returned when a chart repository or OCI registry cannot
+// be read. Use errors.Is with ErrNotFound or ErrTLS to distinguish between
+// the causes.
+type RepositoryError struct {
+	Repository string
+	Err        error
+}
+
+func (e *RepositoryError) Error() string {
+	return fmt.Sprintf("repository %q: %v", e.Repository, e.Err)
+}
+
+func (e *RepositoryError) Unwrap() error {
+	return e.Err
+}
+
+func newRepositoryError(repoURL string, err error) *RepositoryError {
+	if isTLSError(err) {
+		err = fmt.Errorf("%w: %v", ErrTLS, err)
+	}
+	return &RepositoryError{Repository: repoURL, Err: err}
+}
+
+func isTLSError(err error) bool {
+	var (
+		unknownAuthorityErr   x509.UnknownAuthorityError
+		certificateInvalidErr x509.CertificateInvalidError
+		hostnameErr           x509.HostnameError
+		recordHeaderErr       tls.RecordHeaderError
+		verificationErr       *tls.CertificateVerificationError
+	)
+	return errors.As(err, &unknownAuthorityErr) ||
+		errors.As(err, &certificateInvalidErr) ||
+		errors.As(err, &hostnameErr) ||
+		errors.As(err, &recordHeaderErr) ||
+		errors.As(err, &verificationErr)
+}
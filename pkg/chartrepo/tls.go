@@ -0,0 +1,103 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartrepo
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// TLSConfig holds the TLS settings used to connect to a chart repository or
+// OCI registry. All certificates and keys are PEM encoded.
+type TLSConfig struct {
+	// CA is a bundle of CA certificates used to verify the server, in
+	// addition to the system CA certificates.
+	CA []byte
+
+	// Cert and Key are the client certificate and key presented to the
+	// server.
+	Cert []byte
+	Key  []byte
+
+	// InsecureSkipTLSVerify disables verification of the server certificate.
+	InsecureSkipTLSVerify bool
+}
+
+// TLSConfigFromFiles reads a TLSConfig from the given files. Empty file names
+// are ignored.
+func TLSConfigFromFiles(caFile, certFile, keyFile string) (TLSConfig, error) {
+	var (
+		cfg TLSConfig
+		err error
+	)
+	for _, f := range []struct {
+		name string
+		data *[]byte
+	}{
+		{caFile, &cfg.CA},
+		{certFile, &cfg.Cert},
+		{keyFile, &cfg.Key},
+	} {
+		if f.name == "" {
+			continue
+		}
+		if *f.data, err = os.ReadFile(f.name); err != nil {
+			return TLSConfig{}, fmt.Errorf("read TLS file: %w", err)
+		}
+	}
+	return cfg, nil
+}
+
+// TLSConfigFromSecret reads a TLSConfig from the ca.crt, tls.crt and tls.key
+// keys of secret. Missing keys are ignored.
+func TLSConfigFromSecret(secret *corev1.Secret) TLSConfig {
+	return TLSConfig{
+		CA:   secret.Data[corev1.ServiceAccountRootCAKey],
+		Cert: secret.Data[corev1.TLSCertKey],
+		Key:  secret.Data[corev1.TLSPrivateKeyKey],
+	}
+}
+
+func (c TLSConfig) clientConfig() (*tls.Config, error) {
+	// nolint:gosec
+	tlsConfig := &tls.Config{InsecureSkipVerify: c.InsecureSkipTLSVerify}
+
+	if len(c.CA) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(c.CA) {
+			return nil, errors.New("CA bundle does not contain any valid certificates")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if len(c.Cert) > 0 || len(c.Key) > 0 {
+		cert, err := tls.X509KeyPair(c.Cert, c.Key)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
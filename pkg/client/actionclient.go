@@ -18,6 +18,7 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -26,6 +27,7 @@ import (
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chart"
 	helmkube "helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/postrender"
 	"helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/storage/driver"
 	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
@@ -57,11 +59,43 @@ type ActionInterface interface {
 	Reconcile(rel *release.Release) error
 }
 
+// ContextActionInterface is implemented by ActionInterfaces that can run Helm
+// actions with a context. WithContext returns an ActionInterface whose Install
+// and Upgrade actions are stopped when ctx is done.
+type ContextActionInterface interface {
+	ActionInterface
+	WithContext(ctx context.Context) ActionInterface
+}
+
+// PendingReleaseRecoverer is implemented by ActionInterfaces that can recover
+// releases that are stuck in a pending state, e.g. because the process that
+// installed or upgraded the release crashed before recording the result.
+type PendingReleaseRecoverer interface {
+	// MarkFailed records rel, which must be the latest revision of its
+	// release, as failed with the given description.
+	MarkFailed(rel *release.Release, description string) error
+
+	// Rollback rolls back the release with the given name.
+	Rollback(name string, opts ...RollbackOption) error
+}
+
+// ChartTester is implemented by ActionInterfaces that can run the tests of
+// releases, like helm test.
+type ChartTester interface {
+	// Test runs the test hooks of the release with the given name and returns
+	// the release with the results of the test hooks.
+	Test(name string, opts ...TestOption) (*release.Release, error)
+
+	// CleanupTests deletes the resources of the test hooks of rel.
+	CleanupTests(rel *release.Release) error
+}
+
 type GetOption func(*action.Get) error
 type InstallOption func(*action.Install) error
 type UpgradeOption func(*action.Upgrade) error
 type UninstallOption func(*action.Uninstall) error
 type RollbackOption func(*action.Rollback) error
+type TestOption func(*action.ReleaseTesting) error
 
 type ActionClientGetterOption func(*actionClientGetter) error
 
@@ -106,6 +140,44 @@ func AppendUpgradeFailureRollbackOptions(opts ...RollbackOption) ActionClientGet
 	}
 }
 
+// OwnerReferencePolicy controls how the resources of a release are associated
+// with the object that owns the release.
+type OwnerReferencePolicy string
+
+const (
+	// OwnerReferencePolicyController sets a controller owner reference on
+	// release resources where possible. Cluster-scoped resources and resources
+	// in other namespaces than the owner are tracked with owner annotations
+	// instead. This is the default.
+	OwnerReferencePolicyController OwnerReferencePolicy = "Controller"
+
+	// OwnerReferencePolicyAnnotations never sets owner references and tracks
+	// all release resources with owner annotations. Release resources are not
+	// garbage collected by Kubernetes and are only removed by uninstalling the
+	// release.
+	OwnerReferencePolicyAnnotations OwnerReferencePolicy = "Annotations"
+
+	// OwnerReferencePolicyNone does not associate release resources with their
+	// owner at all. Release resources are only removed by uninstalling the
+	// release, and changes to them cannot be mapped back to their owner.
+	OwnerReferencePolicyNone OwnerReferencePolicy = "None"
+)
+
+// WithOwnerReferencePolicy configures how the resources of releases installed
+// or upgraded by the action clients are associated with their owner. By
+// default, OwnerReferencePolicyController is used.
+func WithOwnerReferencePolicy(p OwnerReferencePolicy) ActionClientGetterOption {
+	return func(getter *actionClientGetter) error {
+		switch p {
+		case OwnerReferencePolicyController, OwnerReferencePolicyAnnotations, OwnerReferencePolicyNone:
+		default:
+			return fmt.Errorf("unknown owner reference policy %q", p)
+		}
+		getter.ownerReferencePolicy = p
+		return nil
+	}
+}
+
 func NewActionClientGetter(acg ActionConfigGetter, opts ...ActionClientGetterOption) (ActionClientGetter, error) {
 	actionClientGetter := &actionClientGetter{acg: acg}
 	for _, opt := range opts {
@@ -126,6 +198,8 @@ type actionClientGetter struct {
 
 	installFailureUninstallOpts []UninstallOption
 	upgradeFailureRollbackOpts  []RollbackOption
+
+	ownerReferencePolicy OwnerReferencePolicy
 }
 
 var _ ActionClientGetter = &actionClientGetter{}
@@ -139,7 +213,15 @@ func (hcg *actionClientGetter) ActionClientFor(obj client.Object) (ActionInterfa
 	if err != nil {
 		return nil, err
 	}
-	postRenderer := DefaultPostRendererFunc(rm, actionConfig.KubeClient, obj)
+
+	var postRenderer postrender.PostRenderer
+	switch hcg.ownerReferencePolicy {
+	case OwnerReferencePolicyAnnotations:
+		postRenderer = &ownerPostRenderer{rm: rm, kubeClient: actionConfig.KubeClient, owner: obj, annotationsOnly: true}
+	case OwnerReferencePolicyNone:
+	default:
+		postRenderer = DefaultPostRendererFunc(rm, actionConfig.KubeClient, obj)
+	}
 	return &actionClient{
 		conf: actionConfig,
 
@@ -158,6 +240,7 @@ func (hcg *actionClientGetter) ActionClientFor(obj client.Object) (ActionInterfa
 
 type actionClient struct {
 	conf *action.Configuration
+	ctx  context.Context
 
 	defaultGetOpts       []GetOption
 	defaultInstallOpts   []InstallOption
@@ -168,7 +251,22 @@ type actionClient struct {
 	upgradeFailureRollbackOpts  []RollbackOption
 }
 
-var _ ActionInterface = &actionClient{}
+var _ ContextActionInterface = &actionClient{}
+var _ PendingReleaseRecoverer = &actionClient{}
+var _ ChartTester = &actionClient{}
+
+func (c *actionClient) WithContext(ctx context.Context) ActionInterface {
+	cc := *c
+	cc.ctx = ctx
+	return &cc
+}
+
+func (c *actionClient) actionContext() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
 
 func (c *actionClient) Get(name string, opts ...GetOption) (*release.Release, error) {
 	get := action.NewGet(c.conf)
@@ -190,7 +288,7 @@ func (c *actionClient) Install(name, namespace string, chrt *chart.Chart, vals m
 	install.ReleaseName = name
 	install.Namespace = namespace
 	c.conf.Log("Starting install")
-	rel, err := install.Run(chrt, vals)
+	rel, err := install.RunWithContext(c.actionContext(), chrt, vals)
 	if err != nil {
 		c.conf.Log("Install failed")
 		if rel != nil {
@@ -225,7 +323,7 @@ func (c *actionClient) Upgrade(name, namespace string, chrt *chart.Chart, vals m
 		}
 	}
 	upgrade.Namespace = namespace
-	rel, err := upgrade.Run(name, chrt, vals)
+	rel, err := upgrade.RunWithContext(c.actionContext(), name, chrt, vals)
 	if err != nil {
 		if rel != nil {
 			rollbackOpts := append([]RollbackOption{func(rollback *action.Rollback) error {
@@ -249,6 +347,15 @@ func (c *actionClient) Upgrade(name, namespace string, chrt *chart.Chart, vals m
 	return rel, nil
 }
 
+func (c *actionClient) MarkFailed(rel *release.Release, description string) error {
+	rel.SetStatus(release.StatusFailed, description)
+	return c.conf.Releases.Update(rel)
+}
+
+func (c *actionClient) Rollback(name string, opts ...RollbackOption) error {
+	return c.rollback(name, opts...)
+}
+
 func (c *actionClient) rollback(name string, opts ...RollbackOption) error {
 	rollback := action.NewRollback(c.conf)
 	for _, o := range opts {
@@ -259,6 +366,43 @@ func (c *actionClient) rollback(name string, opts ...RollbackOption) error {
 	return rollback.Run(name)
 }
 
+func (c *actionClient) Test(name string, opts ...TestOption) (*release.Release, error) {
+	test := action.NewReleaseTesting(c.conf)
+	for _, o := range opts {
+		if err := o(test); err != nil {
+			return nil, err
+		}
+	}
+	return test.Run(name)
+}
+
+func (c *actionClient) CleanupTests(rel *release.Release) error {
+	var errs []error
+	for _, h := range rel.Hooks {
+		if !isTestHook(h) {
+			continue
+		}
+		resources, err := c.conf.KubeClient.Build(bytes.NewBufferString(h.Manifest), false)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("build test hook %s: %w", h.Name, err))
+			continue
+		}
+		if _, deleteErrs := c.conf.KubeClient.Delete(resources); len(deleteErrs) > 0 {
+			errs = append(errs, fmt.Errorf("delete test hook %s: %w", h.Name, errors.Join(deleteErrs...)))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func isTestHook(h *release.Hook) bool {
+	for _, e := range h.Events {
+		if e == release.HookTest {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *actionClient) Uninstall(name string, opts ...UninstallOption) (*release.UninstallReleaseResponse, error) {
 	return c.uninstall(name, concat(c.defaultUninstallOpts, opts...)...)
 }
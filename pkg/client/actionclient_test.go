@@ -73,6 +73,13 @@ var _ = Describe("ActionClient", func() {
 			Expect(acg).NotTo(BeNil())
 		})
 
+		It("should fail with an unknown owner reference policy", func() {
+			actionConfigGetter, err := NewActionConfigGetter(cfg, rm, logr.Discard())
+			Expect(err).ShouldNot(HaveOccurred())
+			_, err = NewActionClientGetter(actionConfigGetter, WithOwnerReferencePolicy("Foreground"))
+			Expect(err).To(HaveOccurred())
+		})
+
 		When("options are specified", func() {
 			expectErr := errors.New("expect this error")
 
@@ -458,6 +465,18 @@ var _ = Describe("ActionClient", func() {
 					})
 				})
 			})
+			var _ = Describe("MarkFailed", func() {
+				It("should record the release as failed", func() {
+					rel, err := ac.Get(obj.GetName())
+					Expect(err).To(BeNil())
+					Expect(ac.(PendingReleaseRecoverer).MarkFailed(rel, "stuck")).To(Succeed())
+
+					rel, err = ac.Get(obj.GetName())
+					Expect(err).To(BeNil())
+					Expect(rel.Info.Status).To(Equal(release.StatusFailed))
+					Expect(rel.Info.Description).To(Equal("stuck"))
+				})
+			})
 			var _ = Describe("Uninstall", func() {
 				It("should succeed", func() {
 					var (
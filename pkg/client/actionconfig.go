@@ -19,6 +19,7 @@ package client
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	"github.com/go-logr/logr"
 	"helm.sh/helm/v3/pkg/action"
@@ -28,7 +29,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	v1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -39,7 +40,6 @@ type ActionConfigGetter interface {
 }
 
 func NewActionConfigGetter(cfg *rest.Config, rm meta.RESTMapper, log logr.Logger, opts ...ActionConfigGetterOption) (ActionConfigGetter, error) {
-	rcg := newRESTClientGetter(cfg, rm, "")
 	// Setup the debug log function that Helm will use
 	debugLog := func(format string, v ...interface{}) {
 		if log.GetSink() != nil {
@@ -47,19 +47,14 @@ func NewActionConfigGetter(cfg *rest.Config, rm meta.RESTMapper, log logr.Logger
 		}
 	}
 
-	kc := kube.New(rcg)
-	kc.Log = debugLog
-
-	kcs, err := kc.Factory.KubernetesClientSet()
+	local, err := newCluster("", cfg, rm, debugLog)
 	if err != nil {
-		return nil, fmt.Errorf("creating kubernetes client set: %w", err)
+		return nil, err
 	}
 
 	acg := &actionConfigGetter{
-		kubeClient:       kc,
-		kubeClientSet:    kcs,
-		debugLog:         debugLog,
-		restClientGetter: rcg.restClientGetter,
+		local:    local,
+		debugLog: debugLog,
 	}
 	for _, o := range opts {
 		o(acg)
@@ -97,19 +92,59 @@ func DisableStorageOwnerRefInjection(v bool) ActionConfigGetterOption {
 	}
 }
 
+// KubeClientFactoryFunc returns the Helm Kubernetes client of an action
+// configuration whose resources are managed in namespace.
+type KubeClientFactoryFunc func(cfg *rest.Config, namespace string) (kube.Interface, error)
+
+// KubeClientFactory configures a function that creates the Helm Kubernetes
+// clients of the action configurations instead of the default client, e.g.
+// to customize how resources are applied or to test without a cluster.
+// Resource transforms are only applied if the function returns a
+// *kube.Client.
+func KubeClientFactory(f KubeClientFactoryFunc) ActionConfigGetterOption {
+	return func(getter *actionConfigGetter) {
+		getter.kubeClientFactory = f
+	}
+}
+
+// ServiceAccountMapper configures a function that returns the name of a
+// ServiceAccount in the client namespace whose permissions are used to manage
+// the resources of the release of an object, instead of the permissions of
+// the operator. If the function returns an empty name, the permissions of the
+// operator are used. Release storage always uses the permissions of the
+// operator.
+func ServiceAccountMapper(m ObjectToStringMapper) ActionConfigGetterOption {
+	return func(getter *actionConfigGetter) {
+		getter.objectToServiceAccount = m
+	}
+}
+
 func getObjectNamespace(obj client.Object) (string, error) {
 	return obj.GetNamespace(), nil
 }
 
 type actionConfigGetter struct {
-	kubeClient       *kube.Client
-	kubeClientSet    kubernetes.Interface
-	debugLog         func(string, ...interface{})
-	restClientGetter *restClientGetter
+	local    *cluster
+	debugLog func(string, ...interface{})
 
 	objectToClientNamespace         ObjectToStringMapper
 	objectToStorageNamespace        ObjectToStringMapper
+	sharedStorageNamespace          string
 	disableStorageOwnerRefInjection bool
+	resourceTransforms              []ResourceTransformFunc
+	patchStrategies                 map[schema.GroupVersionKind]PatchStrategy
+	kubeClientFactory               KubeClientFactoryFunc
+	objectToServiceAccount          ObjectToStringMapper
+	clusterResolver                 ClusterResolverFunc
+
+	// impersonatingClients caches the Kubernetes clients that impersonate
+	// service accounts by cluster and user name, so that their discovery
+	// information is reused.
+	impersonatingClients sync.Map
+
+	// clusters caches the clients of the clusters returned by the cluster
+	// resolver by their key.
+	clusters sync.Map
 }
 
 func (acg *actionConfigGetter) ActionConfigFor(obj client.Object) (*action.Configuration, error) {
@@ -118,8 +153,22 @@ func (acg *actionConfigGetter) ActionConfigFor(obj client.Object) (*action.Confi
 		return nil, fmt.Errorf("get storage namespace from object: %v", err)
 	}
 
-	secretClient := acg.kubeClientSet.CoreV1().Secrets(storageNs)
-	if !acg.disableStorageOwnerRefInjection {
+	cl, remote, err := acg.clusterFor(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	var secretClient v1.SecretInterface
+	if acg.sharedStorageNamespace != "" {
+		releaseNs, err := acg.objectToClientNamespace(obj)
+		if err != nil {
+			return nil, fmt.Errorf("get client namespace from object: %v", err)
+		}
+		secretClient = newSharedStorageSecretClient(cl.kubeClientSet.CoreV1().Secrets(acg.sharedStorageNamespace), releaseNs)
+	} else {
+		secretClient = cl.kubeClientSet.CoreV1().Secrets(storageNs)
+	}
+	if !acg.disableStorageOwnerRefInjection && acg.sharedStorageNamespace == "" && !remote {
 		ownerRef := metav1.NewControllerRef(obj, obj.GetObjectKind().GroupVersionKind())
 		secretClient = &ownerRefSecretClient{
 			SecretInterface: secretClient,
@@ -134,20 +183,67 @@ func (acg *actionConfigGetter) ActionConfigFor(obj client.Object) (*action.Confi
 	// Initialize the storage backend
 	s := storage.Init(d)
 
-	kubeClient := *acg.kubeClient
+	kubeClient := *cl.kubeClient
 	kubeClient.Namespace, err = acg.objectToClientNamespace(obj)
 	if err != nil {
 		return nil, fmt.Errorf("get client namespace from object: %v", err)
 	}
 
+	if acg.objectToServiceAccount != nil {
+		sa, err := acg.objectToServiceAccount(obj)
+		if err != nil {
+			return nil, fmt.Errorf("get service account from object: %v", err)
+		}
+		if sa != "" {
+			namespace := kubeClient.Namespace
+			kubeClient = *acg.impersonatingClient(cl, serviceAccountUsername(namespace, sa))
+			kubeClient.Namespace = namespace
+		}
+	}
+
+	var kc kube.Interface = &kubeClient
+	if acg.kubeClientFactory != nil {
+		if kc, err = acg.kubeClientFactory(cl.restConfig, kubeClient.Namespace); err != nil {
+			return nil, fmt.Errorf("create kube client: %v", err)
+		}
+	}
+	if c, ok := kc.(*kube.Client); ok && (len(acg.resourceTransforms) > 0 || len(acg.patchStrategies) > 0) {
+		tc := &transformingKubeClient{Client: c, transforms: acg.resourceTransforms}
+		kc = tc
+		if len(acg.patchStrategies) > 0 {
+			kc = &patchingKubeClient{transformingKubeClient: tc, strategies: acg.patchStrategies}
+		}
+	}
+
 	return &action.Configuration{
-		RESTClientGetter: acg.restClientGetter.ForNamespace(kubeClient.Namespace),
+		RESTClientGetter: cl.restClientGetter.ForNamespace(kubeClient.Namespace),
 		Releases:         s,
-		KubeClient:       &kubeClient,
+		KubeClient:       kc,
 		Log:              acg.debugLog,
 	}, nil
 }
 
+// impersonatingClient returns a Kubernetes client of c that impersonates the
+// user username.
+func (acg *actionConfigGetter) impersonatingClient(c *cluster, username string) *kube.Client {
+	key := c.key + "/" + username
+	if kc, ok := acg.impersonatingClients.Load(key); ok {
+		return kc.(*kube.Client)
+	}
+	cfg := rest.CopyConfig(c.restConfig)
+	cfg.Impersonate = rest.ImpersonationConfig{UserName: username}
+	kc := kube.New(newRESTClientGetter(cfg, c.restMapper, ""))
+	kc.Log = acg.debugLog
+	actual, _ := acg.impersonatingClients.LoadOrStore(key, kc)
+	return actual.(*kube.Client)
+}
+
+// serviceAccountUsername returns the user name of the ServiceAccount name in
+// namespace.
+func serviceAccountUsername(namespace, name string) string {
+	return "system:serviceaccount:" + namespace + ":" + name
+}
+
 var _ v1.SecretInterface = &ownerRefSecretClient{}
 
 type ownerRefSecretClient struct {
@@ -19,6 +19,7 @@ package client
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/go-logr/logr"
@@ -29,6 +30,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/rand"
 	"k8s.io/cli-runtime/pkg/resource"
@@ -74,6 +76,128 @@ var _ = Describe("ActionConfig", func() {
 				Expect(err).To(BeNil())
 			})
 
+			It("should apply resource transforms to built objects", func() {
+				acg, err := NewActionConfigGetter(cfg, rm, logr.Discard(),
+					ResourceTransforms(func(u *unstructured.Unstructured) error {
+						u.SetLabels(map[string]string{"transformed": "true"})
+						return nil
+					}),
+				)
+				Expect(err).To(BeNil())
+				ac, err := acg.ActionConfigFor(obj)
+				Expect(err).To(BeNil())
+				resources, err := ac.KubeClient.Build(bytes.NewBufferString(`---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: sa`), false)
+				Expect(err).To(BeNil())
+				Expect(resources).To(HaveLen(1))
+				Expect(resources[0].Object.(*unstructured.Unstructured).GetLabels()).To(HaveKeyWithValue("transformed", "true"))
+			})
+
+			It("should use the kube client of a custom factory", func() {
+				custom := kube.New(nil)
+				var gotNamespace string
+				acg, err := NewActionConfigGetter(cfg, rm, logr.Discard(),
+					KubeClientFactory(func(_ *rest.Config, namespace string) (kube.Interface, error) {
+						gotNamespace = namespace
+						return custom, nil
+					}),
+				)
+				Expect(err).To(BeNil())
+				ac, err := acg.ActionConfigFor(obj)
+				Expect(err).To(BeNil())
+				Expect(ac.KubeClient).To(BeIdenticalTo(custom))
+				Expect(gotNamespace).To(Equal(obj.GetNamespace()))
+			})
+
+			It("should fail if the kube client factory fails", func() {
+				acg, err := NewActionConfigGetter(cfg, rm, logr.Discard(),
+					KubeClientFactory(func(_ *rest.Config, _ string) (kube.Interface, error) {
+						return nil, errors.New("factory failed")
+					}),
+				)
+				Expect(err).To(BeNil())
+				_, err = acg.ActionConfigFor(obj)
+				Expect(err).To(MatchError(ContainSubstring("factory failed")))
+			})
+
+			It("should impersonate the service account of the object", func() {
+				acg, err := NewActionConfigGetter(cfg, rm, logr.Discard(),
+					ServiceAccountMapper(func(_ client.Object) (string, error) { return "tenant", nil }),
+				)
+				Expect(err).To(BeNil())
+				ac, err := acg.ActionConfigFor(obj)
+				Expect(err).To(BeNil())
+				kc := ac.KubeClient.(*kube.Client)
+				Expect(kc.Namespace).To(Equal(obj.GetNamespace()))
+				restConfig, err := kc.Factory.ToRawKubeConfigLoader().ClientConfig()
+				Expect(err).To(BeNil())
+				Expect(restConfig.Impersonate.UserName).To(Equal("system:serviceaccount:" + obj.GetNamespace() + ":tenant"))
+
+				ac2, err := acg.ActionConfigFor(obj)
+				Expect(err).To(BeNil())
+				Expect(ac2.KubeClient.(*kube.Client).Factory).To(BeIdenticalTo(kc.Factory))
+			})
+
+			It("should use the permissions of the operator without a service account", func() {
+				acg, err := NewActionConfigGetter(cfg, rm, logr.Discard(),
+					ServiceAccountMapper(func(_ client.Object) (string, error) { return "", nil }),
+				)
+				Expect(err).To(BeNil())
+				ac, err := acg.ActionConfigFor(obj)
+				Expect(err).To(BeNil())
+				restConfig, err := ac.KubeClient.(*kube.Client).Factory.ToRawKubeConfigLoader().ClientConfig()
+				Expect(err).To(BeNil())
+				Expect(restConfig.Impersonate.UserName).To(BeEmpty())
+			})
+
+			It("should use the cluster returned by the cluster resolver", func() {
+				remoteCfg := rest.CopyConfig(cfg)
+				remoteCfg.UserAgent = "remote"
+				acg, err := NewActionConfigGetter(cfg, rm, logr.Discard(),
+					ClusterResolver(func(o client.Object) (*rest.Config, error) {
+						if o.GetName() == "local" {
+							return nil, nil
+						}
+						return remoteCfg, nil
+					}),
+				)
+				Expect(err).To(BeNil())
+				ac, err := acg.ActionConfigFor(obj)
+				Expect(err).To(BeNil())
+				kc := ac.KubeClient.(*kube.Client)
+				Expect(kc.Namespace).To(Equal(obj.GetNamespace()))
+				Expect(kc.Factory).NotTo(BeIdenticalTo(acg.(*actionConfigGetter).local.kubeClient.Factory))
+				restConfig, err := kc.Factory.ToRawKubeConfigLoader().ClientConfig()
+				Expect(err).To(BeNil())
+				Expect(restConfig.UserAgent).To(Equal("remote"))
+
+				By("reusing the clients of the cluster")
+				ac2, err := acg.ActionConfigFor(obj)
+				Expect(err).To(BeNil())
+				Expect(ac2.KubeClient.(*kube.Client).Factory).To(BeIdenticalTo(kc.Factory))
+
+				By("using the local cluster if no cluster is resolved")
+				local := obj.DeepCopyObject().(client.Object)
+				local.SetName("local")
+				ac3, err := acg.ActionConfigFor(local)
+				Expect(err).To(BeNil())
+				Expect(ac3.KubeClient.(*kube.Client).Factory).To(BeIdenticalTo(acg.(*actionConfigGetter).local.kubeClient.Factory))
+			})
+
+			It("should fail if the cluster resolver fails", func() {
+				acg, err := NewActionConfigGetter(cfg, rm, logr.Discard(),
+					ClusterResolver(func(_ client.Object) (*rest.Config, error) {
+						return nil, errors.New("no kubeconfig")
+					}),
+				)
+				Expect(err).To(BeNil())
+				_, err = acg.ActionConfigFor(obj)
+				Expect(err).To(MatchError(ContainSubstring("no kubeconfig")))
+			})
+
 			It("should use a custom client namespace", func() {
 				clientNs := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("client-%s", rand.String(8))}}
 				clientNsMapper := func(_ client.Object) (string, error) { return clientNs.Name, nil }
@@ -167,6 +291,51 @@ metadata:
 				_, err = action.NewUninstall(ac).Run(i.ReleaseName)
 				Expect(err).To(BeNil())
 			})
+
+			It("should use a shared storage namespace", func() {
+				storageNs := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("shared-%s", rand.String(8))}}
+				acg, err := NewActionConfigGetter(cfg, rm, logr.Discard(),
+					SharedStorageNamespace(storageNs.Name),
+				)
+				Expect(err).To(BeNil())
+
+				ac, err := acg.ActionConfigFor(obj)
+				Expect(err).To(BeNil())
+
+				By("Creating the storage namespace")
+				Expect(cl.Create(context.Background(), storageNs)).To(Succeed())
+
+				By("Installing a release")
+				i := action.NewInstall(ac)
+				i.ReleaseName = fmt.Sprintf("release-name-%s", rand.String(8))
+				i.Namespace = obj.GetNamespace()
+				rel, err := i.Run(&chrt, nil)
+				Expect(err).To(BeNil())
+				Expect(rel.Namespace).To(Equal(obj.GetNamespace()))
+
+				By("Verifying the release secret is created in the shared storage namespace")
+				secretKey := types.NamespacedName{
+					Namespace: storageNs.Name,
+					Name:      fmt.Sprintf("%s.sh.helm.release.v1.%s.v1", obj.GetNamespace(), i.ReleaseName),
+				}
+				secret := &corev1.Secret{}
+				Expect(cl.Get(context.Background(), secretKey, secret)).To(Succeed())
+				Expect(secret.Labels).To(HaveKeyWithValue(SharedStorageNamespaceLabel, obj.GetNamespace()))
+				Expect(secret.OwnerReferences).To(HaveLen(0))
+
+				By("Looking up the release history")
+				hist, err := action.NewHistory(ac).Run(i.ReleaseName)
+				Expect(err).To(BeNil())
+				Expect(hist).To(HaveLen(1))
+
+				By("Uninstalling the release")
+				_, err = action.NewUninstall(ac).Run(i.ReleaseName)
+				Expect(err).To(BeNil())
+				Expect(cl.Get(context.Background(), secretKey, secret)).NotTo(Succeed())
+
+				By("Deleting the storage namespace")
+				Expect(cl.Delete(context.Background(), storageNs)).To(Succeed())
+			})
 		})
 	})
 
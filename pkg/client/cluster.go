@@ -0,0 +1,129 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"helm.sh/helm/v3/pkg/kube"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// ClusterResolverFunc returns the REST config of the cluster that the release
+// of an object is deployed to, or nil to deploy it to the cluster of the
+// action config getter.
+type ClusterResolverFunc func(client.Object) (*rest.Config, error)
+
+// ClusterResolver configures a function that resolves the cluster of the
+// release of an object, so that the release is stored in and its resources are
+// applied to that cluster, e.g. a spoke cluster of a hub operator. The clients
+// of a cluster are reused for as long as the resolver returns the same host
+// and credentials.
+//
+// Owner references cannot refer to objects in other clusters, so no owner
+// references are set on the release storage of releases in other clusters.
+// Action client getters that use the action config getter should use
+// OwnerReferencePolicyNone for the same reason.
+func ClusterResolver(f ClusterResolverFunc) ActionConfigGetterOption {
+	return func(getter *actionConfigGetter) {
+		getter.clusterResolver = f
+	}
+}
+
+// cluster holds the clients of a cluster that releases are deployed to.
+type cluster struct {
+	key              string
+	restConfig       *rest.Config
+	restMapper       meta.RESTMapper
+	kubeClient       *kube.Client
+	kubeClientSet    kubernetes.Interface
+	restClientGetter *restClientGetter
+}
+
+// newCluster creates the clients of the cluster of cfg.
+func newCluster(key string, cfg *rest.Config, rm meta.RESTMapper, debugLog func(string, ...interface{})) (*cluster, error) {
+	rcg := newRESTClientGetter(cfg, rm, "")
+	kc := kube.New(rcg)
+	kc.Log = debugLog
+
+	kcs, err := kc.Factory.KubernetesClientSet()
+	if err != nil {
+		return nil, fmt.Errorf("creating kubernetes client set: %w", err)
+	}
+	return &cluster{
+		key:              key,
+		restConfig:       cfg,
+		restMapper:       rm,
+		kubeClient:       kc,
+		kubeClientSet:    kcs,
+		restClientGetter: rcg.restClientGetter,
+	}, nil
+}
+
+// clusterFor returns the cluster of the release of obj, and whether it is a
+// cluster other than the one of the action config getter.
+func (acg *actionConfigGetter) clusterFor(obj client.Object) (*cluster, bool, error) {
+	if acg.clusterResolver == nil {
+		return acg.local, false, nil
+	}
+	cfg, err := acg.clusterResolver(obj)
+	if err != nil {
+		return nil, false, fmt.Errorf("resolve cluster: %w", err)
+	}
+	if cfg == nil {
+		return acg.local, false, nil
+	}
+
+	key := clusterKey(cfg)
+	if c, ok := acg.clusters.Load(key); ok {
+		return c.(*cluster), true, nil
+	}
+	httpClient, err := rest.HTTPClientFor(cfg)
+	if err != nil {
+		return nil, false, fmt.Errorf("create HTTP client for cluster %s: %w", cfg.Host, err)
+	}
+	rm, err := apiutil.NewDynamicRESTMapper(cfg, httpClient)
+	if err != nil {
+		return nil, false, fmt.Errorf("create REST mapper for cluster %s: %w", cfg.Host, err)
+	}
+	c, err := newCluster(key, cfg, rm, acg.debugLog)
+	if err != nil {
+		return nil, false, err
+	}
+	actual, _ := acg.clusters.LoadOrStore(key, c)
+	return actual.(*cluster), true, nil
+}
+
+// clusterKey returns a key that identifies the host and credentials of cfg.
+func clusterKey(cfg *rest.Config) string {
+	h := sha256.New()
+	for _, s := range []string{
+		cfg.Host, cfg.APIPath, cfg.Username, cfg.Password, cfg.BearerToken, cfg.BearerTokenFile,
+		cfg.Impersonate.UserName, cfg.TLSClientConfig.ServerName, cfg.TLSClientConfig.CertFile, cfg.TLSClientConfig.KeyFile, cfg.TLSClientConfig.CAFile,
+		string(cfg.TLSClientConfig.CertData), string(cfg.TLSClientConfig.KeyData), string(cfg.TLSClientConfig.CAData),
+	} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"helm.sh/helm/v3/pkg/kube"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/jsonmergepatch"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+// PatchStrategy determines how the resources of a release are updated when
+// the release is upgraded.
+type PatchStrategy string
+
+const (
+	// PatchStrategyStrategicMerge updates resources with a three-way
+	// strategic merge patch. It is only supported for kinds that are built
+	// into Kubernetes.
+	PatchStrategyStrategicMerge PatchStrategy = "StrategicMerge"
+
+	// PatchStrategyJSONMerge updates resources with a three-way JSON merge
+	// patch, which replaces lists as a whole.
+	PatchStrategyJSONMerge PatchStrategy = "JSONMerge"
+
+	// PatchStrategyReplace replaces resources with their manifest, dropping
+	// all changes made to them outside the release.
+	PatchStrategyReplace PatchStrategy = "Replace"
+)
+
+// PatchStrategies configures how the resources of the given kinds are updated
+// when a release is upgraded. The resources of other kinds are updated like by
+// Helm. Patch strategies are not used with a custom KubeClientFactory.
+func PatchStrategies(strategies map[schema.GroupVersionKind]PatchStrategy) ActionConfigGetterOption {
+	return func(getter *actionConfigGetter) {
+		if getter.patchStrategies == nil {
+			getter.patchStrategies = map[schema.GroupVersionKind]PatchStrategy{}
+		}
+		for gvk, strategy := range strategies {
+			getter.patchStrategies[gvk] = strategy
+		}
+	}
+}
+
+// patchingKubeClient is a Helm Kubernetes client that updates the resources
+// of kinds with a configured patch strategy itself and leaves all other
+// resources to Helm.
+type patchingKubeClient struct {
+	*transformingKubeClient
+	strategies map[schema.GroupVersionKind]PatchStrategy
+}
+
+func (c *patchingKubeClient) Update(original, target kube.ResourceList, force bool) (*kube.Result, error) {
+	var patched, rest kube.ResourceList
+	for _, info := range target {
+		if _, ok := c.strategies[info.Mapping.GroupVersionKind]; ok {
+			patched.Append(info)
+		} else {
+			rest.Append(info)
+		}
+	}
+	if len(patched) == 0 {
+		return c.Client.Update(original, target, force)
+	}
+
+	// Helm deletes the original resources that are not in the target, so the
+	// resources that are patched here are removed from both.
+	res, err := c.Client.Update(original.Filter(func(info *resource.Info) bool {
+		return patched.Get(info) == nil
+	}), rest, force)
+	if err != nil {
+		return res, err
+	}
+	for _, info := range patched {
+		strategy := c.strategies[info.Mapping.GroupVersionKind]
+		created, err := patchResource(original.Get(info), info, strategy)
+		if err != nil {
+			return res, fmt.Errorf("update %s %q with patch strategy %s: %w", info.Mapping.GroupVersionKind.Kind, info.Name, strategy, err)
+		}
+		if created {
+			res.Created = append(res.Created, info)
+		} else {
+			res.Updated = append(res.Updated, info)
+		}
+	}
+	return res, nil
+}
+
+// patchResource creates target, or updates it with strategy if it exists. The
+// previous manifest of the resource is original, which is nil if the resource
+// was not part of the previous release. It reports whether target was
+// created.
+func patchResource(original, target *resource.Info, strategy PatchStrategy) (bool, error) {
+	helper := resource.NewHelper(target.Client, target.Mapping)
+	current, err := helper.Get(target.Namespace, target.Name)
+	if apierrors.IsNotFound(err) {
+		obj, err := helper.Create(target.Namespace, true, target.Object)
+		if err != nil {
+			return false, err
+		}
+		return true, target.Refresh(obj, true)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var obj runtime.Object
+	if strategy == PatchStrategyReplace {
+		currentMeta, err := meta.Accessor(current)
+		if err != nil {
+			return false, err
+		}
+		targetMeta, err := meta.Accessor(target.Object)
+		if err != nil {
+			return false, err
+		}
+		targetMeta.SetResourceVersion(currentMeta.GetResourceVersion())
+		if obj, err = helper.Replace(target.Namespace, target.Name, true, target.Object); err != nil {
+			return false, err
+		}
+		return false, target.Refresh(obj, true)
+	}
+
+	patch, patchType, err := threeWayPatch(original, target, current, strategy)
+	if err != nil {
+		return false, err
+	}
+	if len(patch) == 0 || bytes.Equal(patch, []byte("{}")) {
+		return false, target.Refresh(current, true)
+	}
+	if obj, err = helper.Patch(target.Namespace, target.Name, patchType, patch, &metav1.PatchOptions{}); err != nil {
+		return false, err
+	}
+	return false, target.Refresh(obj, true)
+}
+
+// threeWayPatch returns a patch of strategy that updates current to target,
+// and removes the fields of original that are not in target.
+func threeWayPatch(original, target *resource.Info, current runtime.Object, strategy PatchStrategy) ([]byte, apitypes.PatchType, error) {
+	var originalJSON []byte
+	if original != nil {
+		var err error
+		if originalJSON, err = json.Marshal(original.Object); err != nil {
+			return nil, "", err
+		}
+	}
+	targetJSON, err := json.Marshal(target.Object)
+	if err != nil {
+		return nil, "", err
+	}
+	currentJSON, err := json.Marshal(current)
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch strategy {
+	case PatchStrategyJSONMerge:
+		patch, err := jsonmergepatch.CreateThreeWayJSONMergePatch(originalJSON, targetJSON, currentJSON)
+		return patch, apitypes.MergePatchType, err
+	case PatchStrategyStrategicMerge:
+		versioned := kube.AsVersioned(target)
+		if _, ok := versioned.(runtime.Unstructured); ok {
+			return nil, "", fmt.Errorf("strategic merge patches are not supported for %s", target.Mapping.GroupVersionKind)
+		}
+		patchMeta, err := strategicpatch.NewPatchMetaFromStruct(versioned)
+		if err != nil {
+			return nil, "", err
+		}
+		patch, err := strategicpatch.CreateThreeWayMergePatch(originalJSON, targetJSON, currentJSON, patchMeta, true)
+		return patch, apitypes.StrategicMergePatchType, err
+	default:
+		return nil, "", fmt.Errorf("unknown patch strategy %q", strategy)
+	}
+}
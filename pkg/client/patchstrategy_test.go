@@ -0,0 +1,83 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+var _ = Describe("threeWayPatch", func() {
+	deploymentGVK := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+
+	deployment := func(env ...corev1.EnvVar) *appsv1.Deployment {
+		d := &appsv1.Deployment{}
+		d.SetGroupVersionKind(deploymentGVK)
+		d.SetName("test")
+		d.Spec.Template.Spec.Containers = []corev1.Container{{Name: "app", Env: env}}
+		return d
+	}
+	info := func(d *appsv1.Deployment) *resource.Info {
+		return &resource.Info{
+			Name:    d.GetName(),
+			Object:  d,
+			Mapping: &meta.RESTMapping{GroupVersionKind: deploymentGVK},
+		}
+	}
+
+	var (
+		original, target *resource.Info
+		current          *appsv1.Deployment
+	)
+
+	BeforeEach(func() {
+		original = info(deployment(corev1.EnvVar{Name: "A", Value: "1"}))
+		target = info(deployment(corev1.EnvVar{Name: "B", Value: "2"}))
+		current = deployment(corev1.EnvVar{Name: "A", Value: "1"}, corev1.EnvVar{Name: "C", Value: "3"})
+	})
+
+	It("should replace lists with a JSON merge patch", func() {
+		patch, patchType, err := threeWayPatch(original, target, current, PatchStrategyJSONMerge)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(patchType).To(Equal(apitypes.MergePatchType))
+		Expect(string(patch)).To(ContainSubstring(`"env":[{"name":"B","value":"2"}]`))
+	})
+
+	It("should merge lists by key with a strategic merge patch", func() {
+		patch, patchType, err := threeWayPatch(original, target, current, PatchStrategyStrategicMerge)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(patchType).To(Equal(apitypes.StrategicMergePatchType))
+		Expect(string(patch)).To(ContainSubstring(`{"name":"B","value":"2"}`))
+		Expect(string(patch)).To(ContainSubstring(`{"$patch":"delete","name":"A"}`))
+	})
+
+	It("should not use strategic merge patches for custom resources", func() {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "TestApp"})
+		obj.SetName("test")
+		cr := &resource.Info{Name: "test", Object: obj, Mapping: &meta.RESTMapping{GroupVersionKind: obj.GroupVersionKind()}}
+		_, _, err := threeWayPatch(nil, cr, obj, PatchStrategyStrategicMerge)
+		Expect(err).To(MatchError(ContainSubstring("not supported")))
+	})
+})
@@ -85,7 +85,7 @@ func (f PostRendererFunc) Run(buffer *bytes.Buffer) (*bytes.Buffer, error) {
 // in a helm release manifest. This is the default post-renderer used by ActionClients created with
 // NewActionClientGetter.
 var DefaultPostRendererFunc = func(rm meta.RESTMapper, kubeClient kube.Interface, owner client.Object) postrender.PostRenderer {
-	return &ownerPostRenderer{rm, kubeClient, owner}
+	return &ownerPostRenderer{rm: rm, kubeClient: kubeClient, owner: owner}
 }
 
 type chainedPostRenderer []postrender.PostRenderer
@@ -109,6 +109,10 @@ type ownerPostRenderer struct {
 	rm         meta.RESTMapper
 	kubeClient kube.Interface
 	owner      client.Object
+
+	// annotationsOnly disables owner references and tracks all objects with
+	// owner annotations.
+	annotationsOnly bool
 }
 
 func (pr *ownerPostRenderer) Run(in *bytes.Buffer) (*bytes.Buffer, error) {
@@ -127,9 +131,12 @@ func (pr *ownerPostRenderer) Run(in *bytes.Buffer) (*bytes.Buffer, error) {
 			return err
 		}
 		u := &unstructured.Unstructured{Object: objMap}
-		useOwnerRef, err := controllerutil.SupportsOwnerReference(pr.rm, pr.owner, u)
-		if err != nil {
-			return err
+		useOwnerRef := false
+		if !pr.annotationsOnly {
+			useOwnerRef, err = controllerutil.SupportsOwnerReference(pr.rm, pr.owner, u)
+			if err != nil {
+				return err
+			}
 		}
 		if useOwnerRef && !manifestutil.HasResourcePolicyKeep(u.GetAnnotations()) {
 			ownerRef := metav1.NewControllerRef(pr.owner, pr.owner.GetObjectKind().GroupVersionKind())
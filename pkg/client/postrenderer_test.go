@@ -5,6 +5,7 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	sdkhandler "github.com/operator-framework/operator-lib/handler"
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/kube"
 	"helm.sh/helm/v3/pkg/postrender"
@@ -194,6 +195,18 @@ var _ = Describe("ownerPostRenderer", func() {
 		_, err := pr.Run(bytes.NewBufferString("test"))
 		Expect(err).NotTo(BeNil())
 	})
+
+	It("injects owner annotations instead of owner references", func() {
+		pr.annotationsOnly = true
+		buf, err := pr.Run(bytes.NewBufferString(getTestManifest()))
+		Expect(err).To(BeNil())
+		objs := manifestToObjects(buf.String())
+		for _, obj := range objs {
+			Expect(obj.GetOwnerReferences()).To(BeEmpty())
+			Expect(obj.GetAnnotations()).To(HaveKey(sdkhandler.NamespacedNameAnnotation))
+			Expect(obj.GetAnnotations()).To(HaveKey(sdkhandler.TypeAnnotation))
+		}
+	})
 })
 
 func getTestManifest() string {
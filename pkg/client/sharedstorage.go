@@ -0,0 +1,130 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	v1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// SharedStorageNamespaceLabel is the label of the release storage Secrets in a
+// shared storage namespace that holds the namespace of the release.
+const SharedStorageNamespaceLabel = "helm.sdk.operatorframework.io/owner-namespace"
+
+// SharedStorageNamespace configures the action configurations to store the
+// releases of all objects in namespace, e.g. a namespace of the operator,
+// instead of the storage namespace of each object. The resources of the
+// releases are still managed in the client namespace of each object.
+//
+// Since releases with the same name in different namespaces would collide, the
+// names of the storage Secrets are prefixed with the client namespace of the
+// object and the Secrets are labeled with SharedStorageNamespaceLabel.
+// Owner references are not added to the Secrets, because they cannot refer to
+// objects in other namespaces. Releases that are already stored elsewhere are
+// not migrated. This option takes precedence over StorageNamespaceMapper.
+func SharedStorageNamespace(namespace string) ActionConfigGetterOption {
+	return func(getter *actionConfigGetter) {
+		getter.sharedStorageNamespace = namespace
+	}
+}
+
+var _ v1.SecretInterface = &sharedStorageSecretClient{}
+
+// sharedStorageSecretClient stores the release Secrets of one release
+// namespace in a shared namespace. Only the methods used by the Helm Secrets
+// storage driver are supported.
+type sharedStorageSecretClient struct {
+	v1.SecretInterface
+	releaseNamespace string
+}
+
+func newSharedStorageSecretClient(c v1.SecretInterface, releaseNamespace string) *sharedStorageSecretClient {
+	return &sharedStorageSecretClient{SecretInterface: c, releaseNamespace: releaseNamespace}
+}
+
+// storageName returns the name of the Secret named name in the shared
+// namespace.
+func (c *sharedStorageSecretClient) storageName(name string) string {
+	if c.releaseNamespace == "" {
+		return name
+	}
+	return c.releaseNamespace + "." + name
+}
+
+// fromStorage restores the name of a Secret read from the shared namespace.
+func (c *sharedStorageSecretClient) fromStorage(s *corev1.Secret) *corev1.Secret {
+	if s != nil && c.releaseNamespace != "" {
+		s.Name = strings.TrimPrefix(s.Name, c.releaseNamespace+".")
+	}
+	return s
+}
+
+func (c *sharedStorageSecretClient) toStorage(in *corev1.Secret) *corev1.Secret {
+	out := in.DeepCopy()
+	out.Name = c.storageName(in.Name)
+	if out.Labels == nil {
+		out.Labels = map[string]string{}
+	}
+	out.Labels[SharedStorageNamespaceLabel] = c.releaseNamespace
+	return out
+}
+
+func (c *sharedStorageSecretClient) Create(ctx context.Context, in *corev1.Secret, opts metav1.CreateOptions) (*corev1.Secret, error) {
+	out, err := c.SecretInterface.Create(ctx, c.toStorage(in), opts)
+	return c.fromStorage(out), err
+}
+
+func (c *sharedStorageSecretClient) Update(ctx context.Context, in *corev1.Secret, opts metav1.UpdateOptions) (*corev1.Secret, error) {
+	out, err := c.SecretInterface.Update(ctx, c.toStorage(in), opts)
+	return c.fromStorage(out), err
+}
+
+func (c *sharedStorageSecretClient) Get(ctx context.Context, name string, opts metav1.GetOptions) (*corev1.Secret, error) {
+	out, err := c.SecretInterface.Get(ctx, c.storageName(name), opts)
+	return c.fromStorage(out), err
+}
+
+func (c *sharedStorageSecretClient) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	return c.SecretInterface.Delete(ctx, c.storageName(name), opts)
+}
+
+func (c *sharedStorageSecretClient) List(ctx context.Context, opts metav1.ListOptions) (*corev1.SecretList, error) {
+	sel, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("parse label selector: %w", err)
+	}
+	req, err := labels.NewRequirement(SharedStorageNamespaceLabel, selection.Equals, []string{c.releaseNamespace})
+	if err != nil {
+		return nil, err
+	}
+	opts.LabelSelector = sel.Add(*req).String()
+	list, err := c.SecretInterface.List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list.Items {
+		c.fromStorage(&list.Items[i])
+	}
+	return list, nil
+}
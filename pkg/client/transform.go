@@ -0,0 +1,65 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"io"
+
+	"helm.sh/helm/v3/pkg/kube"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// ResourceTransformFunc modifies an object of a release before it is created
+// or updated in the cluster.
+type ResourceTransformFunc func(obj *unstructured.Unstructured) error
+
+// ResourceTransforms configures functions that modify all objects built by the
+// Kubernetes client of the action configurations. Unlike post-renderers,
+// which Helm does not run on hooks, the transforms apply to the objects of
+// hooks as well.
+func ResourceTransforms(fs ...ResourceTransformFunc) ActionConfigGetterOption {
+	return func(getter *actionConfigGetter) {
+		getter.resourceTransforms = append(getter.resourceTransforms, fs...)
+	}
+}
+
+// transformingKubeClient is a Helm Kubernetes client that applies transforms
+// to all objects it builds from manifests.
+type transformingKubeClient struct {
+	*kube.Client
+	transforms []ResourceTransformFunc
+}
+
+func (c *transformingKubeClient) Build(reader io.Reader, validate bool) (kube.ResourceList, error) {
+	resources, err := c.Client.Build(reader, validate)
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range resources {
+		u, ok := info.Object.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		for _, transform := range c.transforms {
+			if err := transform(u); err != nil {
+				return nil, fmt.Errorf("transform %s %q: %w", u.GetKind(), u.GetName(), err)
+			}
+		}
+	}
+	return resources, nil
+}
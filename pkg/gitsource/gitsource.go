@@ -0,0 +1,168 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package gitsource loads Helm charts from Git repositories. It uses the git
+// command line client, which must be available in the PATH.
+package gitsource
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+)
+
+// Source loads a chart from a directory of a Git repository at a given ref.
+type Source struct {
+	repository string
+	ref        string
+	path       string
+
+	username string
+	password string
+	dir      string
+
+	mu sync.Mutex
+}
+
+// Option configures a Source.
+type Option func(s *Source) error
+
+// WithBasicAuth configures the credentials used to fetch from the repository
+// over HTTP(S). For most Git hosting services, password can be an access
+// token.
+func WithBasicAuth(username, password string) Option {
+	return func(s *Source) error {
+		s.username = username
+		s.password = password
+		return nil
+	}
+}
+
+// WithDir configures the directory into which the repository is fetched. By
+// default, a new temporary directory is used.
+func WithDir(dir string) Option {
+	return func(s *Source) error {
+		s.dir = dir
+		return nil
+	}
+}
+
+// New returns a Source for the chart in the directory path of the Git
+// repository at the URL repository, at ref. The ref can be a branch, a tag or
+// a commit.
+func New(repository, ref, path string, opts ...Option) (*Source, error) {
+	if repository == "" {
+		return nil, errors.New("repository must not be empty")
+	}
+	if ref == "" {
+		return nil, errors.New("ref must not be empty")
+	}
+	if err := ValidatePath(path); err != nil {
+		return nil, err
+	}
+	s := &Source{repository: repository, ref: ref, path: path}
+	for _, o := range opts {
+		if err := o(s); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// ValidatePath returns an error if path is not a relative path within a
+// repository.
+func ValidatePath(path string) error {
+	if filepath.IsAbs(path) {
+		return fmt.Errorf("path %q must be relative", path)
+	}
+	if clean := filepath.Clean(path); clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("path %q must not leave the repository", path)
+	}
+	return nil
+}
+
+// Fetch fetches the configured ref from the repository and loads the chart.
+// It returns the chart and the commit it was loaded from.
+func (s *Source) Fetch(ctx context.Context) (*chart.Chart, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.dir == "" {
+		dir, err := os.MkdirTemp("", "helm-operator-git-")
+		if err != nil {
+			return nil, "", err
+		}
+		s.dir = dir
+	}
+	if _, err := os.Stat(filepath.Join(s.dir, ".git")); os.IsNotExist(err) {
+		if _, err := s.git(ctx, "init", "--quiet"); err != nil {
+			return nil, "", err
+		}
+		if _, err := s.git(ctx, "remote", "add", "origin", s.repository); err != nil {
+			return nil, "", err
+		}
+	}
+	if _, err := s.git(ctx, "fetch", "--quiet", "--depth", "1", "origin", s.ref); err != nil {
+		return nil, "", err
+	}
+	if _, err := s.git(ctx, "checkout", "--quiet", "--force", "FETCH_HEAD"); err != nil {
+		return nil, "", err
+	}
+	commit, err := s.git(ctx, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, "", err
+	}
+
+	chrt, err := loader.Load(filepath.Join(s.dir, s.path))
+	if err != nil {
+		return nil, "", fmt.Errorf("load chart %q at %s: %w", s.path, commit, err)
+	}
+	return chrt, commit, nil
+}
+
+// git runs a git command in the directory of s. Credentials are passed in the
+// environment, so that they are neither stored in the repository
+// configuration nor visible in the process list.
+func (s *Source) git(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = s.dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if s.username != "" || s.password != "" {
+		auth := base64.StdEncoding.EncodeToString([]byte(s.username + ":" + s.password))
+		cmd.Env = append(cmd.Env,
+			"GIT_CONFIG_COUNT=1",
+			"GIT_CONFIG_KEY_0=http.extraHeader",
+			"GIT_CONFIG_VALUE_0=Authorization: Basic "+auth,
+		)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s in repository %q: %v: %s", args[0], s.repository, err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitsource_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/operator-framework/helm-operator-plugins/pkg/gitsource"
+)
+
+var _ = Describe("Source", func() {
+	var repoDir string
+
+	git := func(args ...string) string {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = repoDir
+		out, err := cmd.CombinedOutput()
+		Expect(err).NotTo(HaveOccurred(), string(out))
+		return string(out)
+	}
+	writeChart := func(version string) {
+		chartDir := filepath.Join(repoDir, "charts", "test")
+		Expect(os.MkdirAll(chartDir, 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte("apiVersion: v2\nname: test\nversion: "+version+"\n"), 0o644)).To(Succeed())
+		git("add", "-A")
+		git("commit", "--quiet", "-m", version)
+	}
+
+	BeforeEach(func() {
+		repoDir = GinkgoT().TempDir()
+		git("init", "--quiet", "--initial-branch", "main")
+		writeChart("0.1.0")
+	})
+
+	It("should load the chart at the ref", func() {
+		s, err := gitsource.New("file://"+repoDir, "main", "charts/test", gitsource.WithDir(GinkgoT().TempDir()))
+		Expect(err).NotTo(HaveOccurred())
+
+		chrt, commit, err := s.Fetch(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(chrt.Metadata.Version).To(Equal("0.1.0"))
+		Expect(commit).NotTo(BeEmpty())
+
+		writeChart("0.2.0")
+		chrt, newCommit, err := s.Fetch(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(chrt.Metadata.Version).To(Equal("0.2.0"))
+		Expect(newCommit).NotTo(Equal(commit))
+	})
+	It("should fail for a missing ref", func() {
+		s, err := gitsource.New("file://"+repoDir, "missing", "charts/test", gitsource.WithDir(GinkgoT().TempDir()))
+		Expect(err).NotTo(HaveOccurred())
+		_, _, err = s.Fetch(context.Background())
+		Expect(err).To(HaveOccurred())
+	})
+	It("should reject paths outside of the repository", func() {
+		_, err := gitsource.New("file://"+repoDir, "main", "../test")
+		Expect(err).To(HaveOccurred())
+		_, err = gitsource.New("file://"+repoDir, "main", "/charts/test")
+		Expect(err).To(HaveOccurred())
+	})
+})
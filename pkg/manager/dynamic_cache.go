@@ -0,0 +1,581 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manager
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// DynamicNamespaceCache is a cache.Cache that is scoped to a set of namespaces
+// which can be changed while the cache is running. It keeps one cache per
+// namespace, like the multi-namespace cache of controller-runtime, and starts
+// or stops these caches when namespaces are added or removed with
+// SetNamespaces. Event handlers and indexes that were registered on the cache
+// are added to the caches of new namespaces, so that controllers start to
+// receive events for them without being restarted.
+//
+// Cluster-scoped objects are served by a separate cache for the whole
+// cluster. Namespaced objects in namespaces that are not watched cannot be
+// read; Get returns a NotFound error for them.
+type DynamicNamespaceCache struct {
+	config *rest.Config
+	opts   cache.Options
+	log    logr.Logger
+
+	clusterCache cache.Cache
+
+	mu         sync.RWMutex
+	ctx        context.Context
+	caches     map[string]*namespaceCache
+	informers  map[schema.GroupVersionKind]*dynamicInformer
+	indexes    []fieldIndex
+	namespaces []string
+}
+
+type namespaceCache struct {
+	cache.Cache
+	cancel context.CancelFunc
+}
+
+type fieldIndex struct {
+	obj          client.Object
+	field        string
+	extractValue client.IndexerFunc
+}
+
+var _ cache.Cache = &DynamicNamespaceCache{}
+
+// NewDynamicNamespaceCache returns a DynamicNamespaceCache for the given
+// namespaces. An empty list of namespaces, or a list that contains
+// metav1.NamespaceAll, watches all namespaces. The Namespaces field of opts is
+// ignored.
+func NewDynamicNamespaceCache(config *rest.Config, opts cache.Options, namespaces []string, log logr.Logger) (*DynamicNamespaceCache, error) {
+	// The options are defaulted here, so that all caches share the same
+	// HTTP client and RESTMapper.
+	if opts.HTTPClient == nil {
+		httpClient, err := rest.HTTPClientFor(config)
+		if err != nil {
+			return nil, fmt.Errorf("create HTTP client: %w", err)
+		}
+		opts.HTTPClient = httpClient
+	}
+	if opts.Scheme == nil {
+		opts.Scheme = scheme.Scheme
+	}
+	if opts.Mapper == nil {
+		mapper, err := apiutil.NewDiscoveryRESTMapper(config, opts.HTTPClient)
+		if err != nil {
+			return nil, fmt.Errorf("create RESTMapper: %w", err)
+		}
+		opts.Mapper = mapper
+	}
+
+	clusterOpts := opts
+	clusterOpts.Namespaces = nil
+	clusterCache, err := cache.New(config, clusterOpts)
+	if err != nil {
+		return nil, fmt.Errorf("create cluster cache: %w", err)
+	}
+	c := &DynamicNamespaceCache{
+		config:       config,
+		opts:         opts,
+		log:          log,
+		clusterCache: clusterCache,
+		caches:       map[string]*namespaceCache{},
+		informers:    map[schema.GroupVersionKind]*dynamicInformer{},
+	}
+	if err := c.SetNamespaces(namespaces); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Namespaces returns the namespaces that are currently watched.
+func (c *DynamicNamespaceCache) Namespaces() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]string(nil), c.namespaces...)
+}
+
+// SetNamespaces changes the set of watched namespaces. Caches for namespaces
+// that are no longer watched are stopped; caches for new namespaces are
+// created, populated with the informers, event handlers and indexes that are
+// registered on the cache and, if the cache is running, started.
+func (c *DynamicNamespaceCache) SetNamespaces(namespaces []string) error {
+	namespaces = normalizeNamespaces(namespaces)
+	wanted := make(map[string]struct{}, len(namespaces))
+	for _, ns := range namespaces {
+		wanted[ns] = struct{}{}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for ns, nsCache := range c.caches {
+		if _, ok := wanted[ns]; ok {
+			continue
+		}
+		if nsCache.cancel != nil {
+			nsCache.cancel()
+		}
+		delete(c.caches, ns)
+		for _, inf := range c.informers {
+			inf.removeNamespace(ns)
+		}
+		c.log.Info("Stopped watching namespace", "namespace", ns)
+	}
+
+	for _, ns := range namespaces {
+		if _, ok := c.caches[ns]; ok {
+			continue
+		}
+		if err := c.addNamespaceLocked(ns); err != nil {
+			return fmt.Errorf("watch namespace %q: %w", ns, err)
+		}
+		c.log.Info("Started watching namespace", "namespace", ns)
+	}
+	c.namespaces = namespaces
+	return nil
+}
+
+func (c *DynamicNamespaceCache) addNamespaceLocked(ns string) error {
+	opts := c.opts
+	opts.Namespaces = []string{ns}
+	nsCache, err := cache.New(c.config, opts)
+	if err != nil {
+		return err
+	}
+	for _, idx := range c.indexes {
+		if err := nsCache.IndexField(context.Background(), idx.obj, idx.field, idx.extractValue); err != nil {
+			return err
+		}
+	}
+	entry := &namespaceCache{Cache: nsCache}
+	for _, inf := range c.informers {
+		informer, err := nsCache.GetInformer(context.Background(), inf.obj)
+		if err != nil {
+			return err
+		}
+		if err := inf.addNamespace(ns, informer); err != nil {
+			return err
+		}
+	}
+	c.caches[ns] = entry
+	if c.ctx != nil {
+		c.startLocked(ns, entry)
+	}
+	return nil
+}
+
+func (c *DynamicNamespaceCache) startLocked(ns string, nsCache *namespaceCache) {
+	ctx, cancel := context.WithCancel(c.ctx)
+	nsCache.cancel = cancel
+	go func() {
+		if err := nsCache.Start(ctx); err != nil {
+			c.log.Error(err, "Namespace cache failed to start", "namespace", ns)
+		}
+	}()
+}
+
+// cacheFor returns the cache that serves objects in namespace ns.
+func (c *DynamicNamespaceCache) cacheFor(ns string) (cache.Cache, bool) {
+	if nsCache, ok := c.caches[ns]; ok {
+		return nsCache, true
+	}
+	if nsCache, ok := c.caches[metav1.NamespaceAll]; ok {
+		return nsCache, true
+	}
+	return nil, false
+}
+
+// GetInformer implements cache.Informers.
+func (c *DynamicNamespaceCache) GetInformer(ctx context.Context, obj client.Object) (cache.Informer, error) {
+	isNamespaced, err := apiutil.IsObjectNamespaced(obj, c.opts.Scheme, c.opts.Mapper)
+	if err != nil {
+		return nil, err
+	}
+	if !isNamespaced {
+		return c.clusterCache.GetInformer(ctx, obj)
+	}
+	gvk, err := apiutil.GVKForObject(obj, c.opts.Scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if inf, ok := c.informers[gvk]; ok {
+		return inf, nil
+	}
+	inf := newDynamicInformer(obj.DeepCopyObject().(client.Object))
+	for ns, nsCache := range c.caches {
+		informer, err := nsCache.GetInformer(ctx, obj)
+		if err != nil {
+			return nil, err
+		}
+		if err := inf.addNamespace(ns, informer); err != nil {
+			return nil, err
+		}
+	}
+	c.informers[gvk] = inf
+	return inf, nil
+}
+
+// GetInformerForKind implements cache.Informers.
+func (c *DynamicNamespaceCache) GetInformerForKind(ctx context.Context, gvk schema.GroupVersionKind) (cache.Informer, error) {
+	obj, err := c.opts.Scheme.New(gvk)
+	if err != nil {
+		return nil, err
+	}
+	cObj, ok := obj.(client.Object)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a client.Object", obj)
+	}
+	return c.GetInformer(ctx, cObj)
+}
+
+// Start implements cache.Informers. It starts the caches of all watched
+// namespaces and blocks until ctx is done.
+func (c *DynamicNamespaceCache) Start(ctx context.Context) error {
+	go func() {
+		if err := c.clusterCache.Start(ctx); err != nil {
+			c.log.Error(err, "Cluster cache failed to start")
+		}
+	}()
+
+	c.mu.Lock()
+	c.ctx = ctx
+	for ns, nsCache := range c.caches {
+		c.startLocked(ns, nsCache)
+	}
+	c.mu.Unlock()
+
+	<-ctx.Done()
+	return nil
+}
+
+// WaitForCacheSync implements cache.Informers.
+func (c *DynamicNamespaceCache) WaitForCacheSync(ctx context.Context) bool {
+	c.mu.RLock()
+	caches := make([]cache.Cache, 0, len(c.caches))
+	for _, nsCache := range c.caches {
+		caches = append(caches, nsCache)
+	}
+	c.mu.RUnlock()
+
+	synced := c.clusterCache.WaitForCacheSync(ctx)
+	for _, nsCache := range caches {
+		if !nsCache.WaitForCacheSync(ctx) {
+			synced = false
+		}
+	}
+	return synced
+}
+
+// IndexField implements client.FieldIndexer. The index is also added to the
+// caches of namespaces that are watched later.
+func (c *DynamicNamespaceCache) IndexField(ctx context.Context, obj client.Object, field string, extractValue client.IndexerFunc) error {
+	isNamespaced, err := apiutil.IsObjectNamespaced(obj, c.opts.Scheme, c.opts.Mapper)
+	if err != nil {
+		return err
+	}
+	if !isNamespaced {
+		return c.clusterCache.IndexField(ctx, obj, field, extractValue)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, nsCache := range c.caches {
+		if err := nsCache.IndexField(ctx, obj, field, extractValue); err != nil {
+			return err
+		}
+	}
+	c.indexes = append(c.indexes, fieldIndex{obj: obj, field: field, extractValue: extractValue})
+	return nil
+}
+
+// Get implements client.Reader.
+func (c *DynamicNamespaceCache) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	isNamespaced, err := apiutil.IsObjectNamespaced(obj, c.opts.Scheme, c.opts.Mapper)
+	if err != nil {
+		return err
+	}
+	if !isNamespaced {
+		return c.clusterCache.Get(ctx, key, obj, opts...)
+	}
+
+	c.mu.RLock()
+	nsCache, ok := c.cacheFor(key.Namespace)
+	c.mu.RUnlock()
+	if !ok {
+		gvk, _ := apiutil.GVKForObject(obj, c.opts.Scheme)
+		return apierrors.NewNotFound(schema.GroupResource{Group: gvk.Group, Resource: gvk.Kind}, key.Name)
+	}
+	return nsCache.Get(ctx, key, obj, opts...)
+}
+
+// List implements client.Reader. Listing across all namespaces lists the
+// objects in all watched namespaces.
+func (c *DynamicNamespaceCache) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	listOpts := client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+
+	isNamespaced, err := apiutil.IsObjectNamespaced(list, c.opts.Scheme, c.opts.Mapper)
+	if err != nil {
+		return err
+	}
+	if !isNamespaced {
+		return c.clusterCache.List(ctx, list, opts...)
+	}
+
+	c.mu.RLock()
+	var caches []cache.Cache
+	if listOpts.Namespace != metav1.NamespaceAll {
+		if nsCache, ok := c.cacheFor(listOpts.Namespace); ok {
+			caches = append(caches, nsCache)
+		}
+	} else {
+		for _, nsCache := range c.caches {
+			caches = append(caches, nsCache)
+		}
+	}
+	c.mu.RUnlock()
+
+	listAccessor, err := apimeta.ListAccessor(list)
+	if err != nil {
+		return err
+	}
+	var allItems []runtime.Object
+	var resourceVersion string
+	for _, nsCache := range caches {
+		listObj := list.DeepCopyObject().(client.ObjectList)
+		if err := nsCache.List(ctx, listObj, &listOpts); err != nil {
+			return err
+		}
+		items, err := apimeta.ExtractList(listObj)
+		if err != nil {
+			return err
+		}
+		accessor, err := apimeta.ListAccessor(listObj)
+		if err != nil {
+			return err
+		}
+		allItems = append(allItems, items...)
+		resourceVersion = accessor.GetResourceVersion()
+	}
+	listAccessor.SetResourceVersion(resourceVersion)
+	return apimeta.SetList(list, allItems)
+}
+
+// dynamicInformer combines the informers of all watched namespaces for one
+// kind. It remembers the event handlers and indexers added to it, so that
+// they can be added to the informers of namespaces that are watched later.
+type dynamicInformer struct {
+	obj client.Object
+
+	mu        sync.Mutex
+	informers map[string]cache.Informer
+	handlers  []*dynamicRegistration
+	indexers  []toolscache.Indexers
+}
+
+type dynamicRegistration struct {
+	handler      toolscache.ResourceEventHandler
+	resyncPeriod time.Duration
+
+	mu            sync.Mutex
+	registrations map[string]toolscache.ResourceEventHandlerRegistration
+}
+
+var _ cache.Informer = &dynamicInformer{}
+
+func newDynamicInformer(obj client.Object) *dynamicInformer {
+	return &dynamicInformer{obj: obj, informers: map[string]cache.Informer{}}
+}
+
+func (i *dynamicInformer) addNamespace(ns string, informer cache.Informer) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for _, indexers := range i.indexers {
+		if err := informer.AddIndexers(indexers); err != nil {
+			return err
+		}
+	}
+	for _, reg := range i.handlers {
+		if err := reg.add(ns, informer); err != nil {
+			return err
+		}
+	}
+	i.informers[ns] = informer
+	return nil
+}
+
+func (i *dynamicInformer) removeNamespace(ns string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	delete(i.informers, ns)
+	for _, reg := range i.handlers {
+		reg.mu.Lock()
+		delete(reg.registrations, ns)
+		reg.mu.Unlock()
+	}
+}
+
+// AddEventHandler implements cache.Informer.
+func (i *dynamicInformer) AddEventHandler(handler toolscache.ResourceEventHandler) (toolscache.ResourceEventHandlerRegistration, error) {
+	return i.AddEventHandlerWithResyncPeriod(handler, 0)
+}
+
+// AddEventHandlerWithResyncPeriod implements cache.Informer.
+func (i *dynamicInformer) AddEventHandlerWithResyncPeriod(handler toolscache.ResourceEventHandler, resyncPeriod time.Duration) (toolscache.ResourceEventHandlerRegistration, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	reg := &dynamicRegistration{
+		handler:       handler,
+		resyncPeriod:  resyncPeriod,
+		registrations: map[string]toolscache.ResourceEventHandlerRegistration{},
+	}
+	for ns, informer := range i.informers {
+		if err := reg.add(ns, informer); err != nil {
+			return nil, err
+		}
+	}
+	i.handlers = append(i.handlers, reg)
+	return reg, nil
+}
+
+// RemoveEventHandler implements cache.Informer.
+func (i *dynamicInformer) RemoveEventHandler(handle toolscache.ResourceEventHandlerRegistration) error {
+	reg, ok := handle.(*dynamicRegistration)
+	if !ok {
+		return fmt.Errorf("registration %T was not returned by this informer", handle)
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for idx, h := range i.handlers {
+		if h == reg {
+			i.handlers = append(i.handlers[:idx], i.handlers[idx+1:]...)
+			break
+		}
+	}
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	for ns, informer := range i.informers {
+		if r, ok := reg.registrations[ns]; ok {
+			if err := informer.RemoveEventHandler(r); err != nil {
+				return err
+			}
+			delete(reg.registrations, ns)
+		}
+	}
+	return nil
+}
+
+// AddIndexers implements cache.Informer.
+func (i *dynamicInformer) AddIndexers(indexers toolscache.Indexers) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for _, informer := range i.informers {
+		if err := informer.AddIndexers(indexers); err != nil {
+			return err
+		}
+	}
+	i.indexers = append(i.indexers, indexers)
+	return nil
+}
+
+// HasSynced implements cache.Informer.
+func (i *dynamicInformer) HasSynced() bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for _, informer := range i.informers {
+		if !informer.HasSynced() {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *dynamicRegistration) add(ns string, informer cache.Informer) error {
+	var (
+		registration toolscache.ResourceEventHandlerRegistration
+		err          error
+	)
+	if r.resyncPeriod > 0 {
+		registration, err = informer.AddEventHandlerWithResyncPeriod(r.handler, r.resyncPeriod)
+	} else {
+		registration, err = informer.AddEventHandler(r.handler)
+	}
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.registrations[ns] = registration
+	return nil
+}
+
+// HasSynced implements toolscache.ResourceEventHandlerRegistration. It
+// reports whether the handler has received the initial state of the
+// informers of all watched namespaces.
+func (r *dynamicRegistration) HasSynced() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, registration := range r.registrations {
+		if s, ok := registration.(interface{ HasSynced() bool }); ok && !s.HasSynced() {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeNamespaces removes duplicates from namespaces. If all
+// namespaces are watched, the result only contains metav1.NamespaceAll.
+func normalizeNamespaces(namespaces []string) []string {
+	seen := map[string]struct{}{}
+	out := []string{}
+	for _, ns := range namespaces {
+		if ns == metav1.NamespaceAll {
+			return []string{metav1.NamespaceAll}
+		}
+		if _, ok := seen[ns]; ok {
+			continue
+		}
+		seen[ns] = struct{}{}
+		out = append(out, ns)
+	}
+	if len(out) == 0 {
+		return []string{metav1.NamespaceAll}
+	}
+	return out
+}
@@ -22,6 +22,7 @@ import (
 
 	"github.com/go-logr/logr"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
@@ -43,9 +44,37 @@ func ConfigureWatchNamespaces(options *manager.Options, log logr.Logger) {
 	}
 
 	options.NewCache = func(config *rest.Config, opts cache.Options) (cache.Cache, error) {
-		return cache.New(config, cache.Options{
-			Namespaces: watchNamespaces,
-		})
+		opts.Namespaces = watchNamespaces
+		return cache.New(config, opts)
+	}
+}
+
+// ExcludeWatchNamespaces configures the cache of options to ignore all objects
+// in the given namespaces, so that they are never reconciled. It wraps the
+// NewCache function of options and must therefore be called after
+// ConfigureWatchNamespaces. Excluded namespaces take precedence over watched
+// namespaces: a namespace that is both watched and excluded is not watched.
+func ExcludeWatchNamespaces(options *manager.Options, namespaces []string) {
+	if len(namespaces) == 0 {
+		return
+	}
+	selectors := make([]fields.Selector, 0, len(namespaces))
+	for _, ns := range namespaces {
+		selectors = append(selectors, fields.OneTermNotEqualSelector("metadata.namespace", ns))
+	}
+	excludeSelector := fields.AndSelectors(selectors...)
+
+	newCache := options.NewCache
+	if newCache == nil {
+		newCache = cache.New
+	}
+	options.NewCache = func(config *rest.Config, opts cache.Options) (cache.Cache, error) {
+		if opts.DefaultFieldSelector != nil {
+			opts.DefaultFieldSelector = fields.AndSelectors(opts.DefaultFieldSelector, excludeSelector)
+		} else {
+			opts.DefaultFieldSelector = excludeSelector
+		}
+		return newCache(config, opts)
 	}
 }
 
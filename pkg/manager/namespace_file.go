@@ -0,0 +1,154 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manager
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/go-logr/logr"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// DefaultNamespacesFileInterval is the interval at which a NamespacesFile is
+// checked for changes, if not configured otherwise.
+const DefaultNamespacesFileInterval = 10 * time.Second
+
+// NamespacesFile reads the namespaces to watch from a file, e.g. a key of a
+// mounted ConfigMap, and keeps the cache of the manager in sync with it. The
+// file contains namespaces separated by commas or whitespace. An empty file
+// watches all namespaces, like an empty WATCH_NAMESPACE.
+//
+// NamespacesFile implements manager.Runnable and must be added to the
+// manager, which then checks the file for changes every interval. When the
+// set of namespaces changes, informers for new namespaces are started and
+// informers for removed namespaces are stopped, without restarting the
+// manager. If the file cannot be read, the namespaces are left unchanged.
+type NamespacesFile struct {
+	path     string
+	interval time.Duration
+	log      logr.Logger
+
+	mu         sync.Mutex
+	namespaces []string
+	cache      *DynamicNamespaceCache
+}
+
+// NewNamespacesFile returns a NamespacesFile for the file at path that is
+// checked for changes every interval. The file must exist.
+func NewNamespacesFile(path string, interval time.Duration, log logr.Logger) (*NamespacesFile, error) {
+	namespaces, err := readNamespacesFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &NamespacesFile{
+		path:       path,
+		interval:   interval,
+		log:        log,
+		namespaces: namespaces,
+	}, nil
+}
+
+// ConfigureWatchNamespacesFile configures the cache of options to watch the
+// namespaces listed in f. It is an alternative to ConfigureWatchNamespaces;
+// the WATCH_NAMESPACE environment variable is ignored. f must be added to the
+// manager that is created from options.
+func ConfigureWatchNamespacesFile(options *manager.Options, f *NamespacesFile) {
+	f.log.Info("watching namespaces from file", "file", f.path, "namespaces", f.Namespaces())
+	options.NewCache = f.newCache
+}
+
+// Namespaces returns the namespaces that were last read from the file.
+func (f *NamespacesFile) Namespaces() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.namespaces...)
+}
+
+func (f *NamespacesFile) newCache(config *rest.Config, opts cache.Options) (cache.Cache, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, err := NewDynamicNamespaceCache(config, opts, f.namespaces, f.log)
+	if err != nil {
+		return nil, err
+	}
+	f.cache = c
+	return c, nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, so that the
+// namespaces are updated on all replicas.
+func (f *NamespacesFile) NeedLeaderElection() bool {
+	return false
+}
+
+// Start implements manager.Runnable. It checks the file every interval until
+// ctx is done.
+func (f *NamespacesFile) Start(ctx context.Context) error {
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := f.Reload(); err != nil {
+				f.log.Error(err, "Failed to reload watched namespaces", "file", f.path)
+			}
+		}
+	}
+}
+
+// Reload reads the file and, if the namespaces changed, updates the cache.
+func (f *NamespacesFile) Reload() error {
+	namespaces, err := readNamespacesFile(f.path)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if reflect.DeepEqual(namespaces, f.namespaces) {
+		return nil
+	}
+	f.log.Info("watched namespaces changed", "file", f.path, "namespaces", namespaces)
+	if f.cache != nil {
+		if err := f.cache.SetNamespaces(namespaces); err != nil {
+			return err
+		}
+	}
+	f.namespaces = namespaces
+	return nil
+}
+
+func readNamespacesFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read namespaces file: %w", err)
+	}
+	return strings.FieldsFunc(string(data), func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	}), nil
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manager_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	. "github.com/operator-framework/helm-operator-plugins/pkg/manager"
+)
+
+var _ = Describe("NamespacesFile", func() {
+	var (
+		opts manager.Options
+		log  = logr.Discard()
+		path string
+	)
+
+	BeforeEach(func() {
+		opts = manager.Options{}
+		path = filepath.Join(GinkgoT().TempDir(), "namespaces")
+	})
+
+	writeNamespaces := func(namespaces ...string) {
+		Expect(os.WriteFile(path, []byte(strings.Join(namespaces, "\n")), 0600)).To(Succeed())
+	}
+
+	It("should fail if the file does not exist", func() {
+		_, err := NewNamespacesFile(path, time.Hour, log)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should read namespaces separated by commas and whitespace", func() {
+		Expect(os.WriteFile(path, []byte("ns1, ns2\nns3\n"), 0600)).To(Succeed())
+		f, err := NewNamespacesFile(path, time.Hour, log)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f.Namespaces()).To(Equal([]string{"ns1", "ns2", "ns3"}))
+	})
+
+	It("should update the watched namespaces when the file changes", func() {
+		By("creating pods in two namespaces")
+		pods, err := createPods(context.TODO(), 2)
+		Expect(err).To(BeNil())
+		first, second := pods[0], pods[1]
+
+		By("watching the namespace of the first pod")
+		writeNamespaces(first.Namespace)
+		f, err := NewNamespacesFile(path, time.Hour, log)
+		Expect(err).NotTo(HaveOccurred())
+		ConfigureWatchNamespacesFile(&opts, f)
+		c, err := opts.NewCache(cfg, cache.Options{})
+		Expect(err).To(BeNil())
+
+		By("registering an event handler for pods")
+		var (
+			mu    sync.Mutex
+			added = map[string]bool{}
+		)
+		informer, err := c.GetInformer(context.TODO(), &v1.Pod{})
+		Expect(err).To(BeNil())
+		_, err = informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				mu.Lock()
+				defer mu.Unlock()
+				added[obj.(*v1.Pod).Namespace] = true
+			},
+		})
+		Expect(err).To(BeNil())
+		wasAdded := func(ns string) func() bool {
+			return func() bool {
+				mu.Lock()
+				defer mu.Unlock()
+				return added[ns]
+			}
+		}
+
+		By("starting the cache and waiting for it to sync")
+		ctx, cancel := context.WithCancel(context.Background())
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			Expect(c.Start(ctx)).To(Succeed())
+			wg.Done()
+		}()
+		Expect(c.WaitForCacheSync(ctx)).To(BeTrue())
+
+		By("getting only the pod in the watched namespace")
+		Expect(c.Get(context.TODO(), client.ObjectKeyFromObject(&first), &v1.Pod{})).To(Succeed())
+		Expect(apierrors.IsNotFound(c.Get(context.TODO(), client.ObjectKeyFromObject(&second), &v1.Pod{}))).To(BeTrue())
+		Eventually(wasAdded(first.Namespace)).Should(BeTrue())
+		Expect(wasAdded(second.Namespace)()).To(BeFalse())
+
+		By("switching the file to the namespace of the second pod")
+		writeNamespaces(second.Namespace)
+		Expect(f.Reload()).To(Succeed())
+		Expect(f.Namespaces()).To(Equal([]string{second.Namespace}))
+
+		By("receiving events for the newly watched namespace")
+		Eventually(wasAdded(second.Namespace)).Should(BeTrue())
+		Eventually(func() error {
+			return c.Get(context.TODO(), client.ObjectKeyFromObject(&second), &v1.Pod{})
+		}).Should(Succeed())
+
+		By("no longer getting the pod in the removed namespace")
+		Expect(apierrors.IsNotFound(c.Get(context.TODO(), client.ObjectKeyFromObject(&first), &v1.Pod{}))).To(BeTrue())
+
+		cancel()
+		wg.Wait()
+	})
+})
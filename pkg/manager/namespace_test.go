@@ -168,6 +168,63 @@ var _ = Describe("ConfigureWatchNamespaces", func() {
 	})
 })
 
+var _ = Describe("ExcludeWatchNamespaces", func() {
+	var (
+		opts manager.Options
+		log  = logr.Discard()
+	)
+
+	BeforeEach(func() {
+		opts = manager.Options{}
+		Expect(os.Unsetenv(WatchNamespaceEnvVar)).To(Succeed())
+	})
+
+	It("should not watch excluded namespaces", func() {
+		By("creating pods in watched and excluded namespaces")
+		watchedPods, err := createPods(context.TODO(), 2)
+		Expect(err).To(BeNil())
+		excludedPods, err := createPods(context.TODO(), 2)
+		Expect(err).To(BeNil())
+
+		By("excluding the namespaces of the excluded pods")
+		ConfigureWatchNamespaces(&opts, log)
+		ExcludeWatchNamespaces(&opts, getNamespaces(excludedPods))
+
+		By("using the options NewCache function to create a cache")
+		c, err := opts.NewCache(cfg, cache.Options{})
+		Expect(err).To(BeNil())
+
+		By("starting the cache and waiting for it to sync")
+		ctx, cancel := context.WithCancel(context.Background())
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			Expect(c.Start(ctx)).To(Succeed())
+			wg.Done()
+		}()
+		Expect(c.WaitForCacheSync(ctx)).To(BeTrue())
+
+		By("successfully getting the watched pods")
+		for _, p := range watchedPods {
+			key := client.ObjectKeyFromObject(&p)
+			Expect(c.Get(context.TODO(), key, &p)).To(Succeed())
+		}
+
+		By("failing to get the excluded pods")
+		for _, p := range excludedPods {
+			key := client.ObjectKeyFromObject(&p)
+			Expect(c.Get(context.TODO(), key, &p)).NotTo(Succeed())
+		}
+		cancel()
+		wg.Wait()
+	})
+
+	It("should not change options without excluded namespaces", func() {
+		ExcludeWatchNamespaces(&opts, nil)
+		Expect(opts.NewCache).To(BeNil())
+	})
+})
+
 func createPods(ctx context.Context, count int) ([]v1.Pod, error) {
 	cl, err := client.New(cfg, client.Options{})
 	if err != nil {
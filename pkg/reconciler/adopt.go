@@ -0,0 +1,137 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"helm.sh/helm/v3/pkg/action"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The ownership metadata that Helm checks before it adopts an existing
+// resource into a release on install.
+const (
+	helmManagedByLabel             = "app.kubernetes.io/managed-by"
+	helmManagedByValue             = "Helm"
+	helmReleaseNameAnnotation      = "meta.helm.sh/release-name"
+	helmReleaseNamespaceAnnotation = "meta.helm.sh/release-namespace"
+)
+
+// adoptResources prepares the existing resources of the release of obj for
+// adoption by the next install. It renders the chart without contacting the
+// API server and marks every rendered resource that exists and matches the
+// adoption selector with Helm's ownership metadata, so that Helm adopts it
+// instead of failing because it already exists. It returns the number of
+// adopted resources.
+func (r *Reconciler) adoptResources(ctx context.Context, obj *unstructured.Unstructured, vals map[string]interface{}, log logr.Logger) (int, error) {
+	selector, err := r.adoptionSelector(obj)
+	if err != nil {
+		return 0, fmt.Errorf("get adoption selector: %w", err)
+	}
+
+	rendered, err := r.renderClientOnly(obj, vals)
+	if err != nil {
+		return 0, err
+	}
+
+	adopted := 0
+	for i := range rendered {
+		existing := &unstructured.Unstructured{}
+		existing.SetGroupVersionKind(rendered[i].GroupVersionKind())
+		key := client.ObjectKeyFromObject(&rendered[i])
+		namespaced, err := r.client.IsObjectNamespaced(&rendered[i])
+		if err != nil {
+			return adopted, fmt.Errorf("get scope of %s %s: %w", existing.GetKind(), key.Name, err)
+		}
+		if namespaced && key.Namespace == "" {
+			key.Namespace = r.releaseNamespace(obj)
+		}
+		if err := r.apiReader.Get(ctx, key, existing); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return adopted, fmt.Errorf("get %s %s: %w", existing.GetKind(), key, err)
+		}
+		if !selector.Matches(labels.Set(existing.GetLabels())) {
+			continue
+		}
+		if isOwnedByRelease(existing, obj.GetName(), r.releaseNamespace(obj)) {
+			continue
+		}
+
+		patch := client.MergeFrom(existing.DeepCopy())
+		setReleaseMetadata(existing, obj.GetName(), r.releaseNamespace(obj))
+		if err := r.client.Patch(ctx, existing, patch); err != nil {
+			return adopted, fmt.Errorf("adopt %s %s: %w", existing.GetKind(), key, err)
+		}
+
+		log.Info("Adopted existing resource", "kind", existing.GetKind(), "name", key)
+		r.eventRecorder.Eventf(obj, "Normal", "ResourceAdopted",
+			"Existing %s %q was adopted into release %q", existing.GetKind(), existing.GetName(), obj.GetName())
+		adopted++
+	}
+	return adopted, nil
+}
+
+// renderClientOnly renders the release of obj without contacting the API
+// server. The chart is rendered with a separate client-only configuration,
+// since a client-only install replaces the Kubernetes client and the release
+// storage of its configuration.
+func (r *Reconciler) renderClientOnly(obj *unstructured.Unstructured, vals map[string]interface{}) ([]unstructured.Unstructured, error) {
+	install := action.NewInstall(&action.Configuration{Log: func(string, ...interface{}) {}})
+	install.DryRun = true
+	install.ClientOnly = true
+	install.ReleaseName = obj.GetName()
+	install.Namespace = r.releaseNamespace(obj)
+	rel, err := install.Run(r.chrt, vals)
+	if err != nil {
+		return nil, fmt.Errorf("render chart: %w", err)
+	}
+	return parseManifests(rel.Manifest)
+}
+
+func isOwnedByRelease(obj *unstructured.Unstructured, name, namespace string) bool {
+	annotations := obj.GetAnnotations()
+	return obj.GetLabels()[helmManagedByLabel] == helmManagedByValue &&
+		annotations[helmReleaseNameAnnotation] == name &&
+		annotations[helmReleaseNamespaceAnnotation] == namespace
+}
+
+// setReleaseMetadata labels and annotates o as belonging to the release with
+// the given name and namespace, so that Helm adopts it.
+func setReleaseMetadata(o *unstructured.Unstructured, name, namespace string) {
+	lbls := o.GetLabels()
+	if lbls == nil {
+		lbls = map[string]string{}
+	}
+	lbls[helmManagedByLabel] = helmManagedByValue
+	o.SetLabels(lbls)
+
+	annotations := o.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[helmReleaseNameAnnotation] = name
+	annotations[helmReleaseNamespaceAnnotation] = namespace
+	o.SetAnnotations(annotations)
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// DisallowedValuesPolicy determines how values of a CR spec outside of the
+// paths allowed with WithAllowedValuePaths are handled.
+type DisallowedValuesPolicy string
+
+const (
+	// DisallowedValuesDrop ignores disallowed values and records a Warning
+	// event on the CR.
+	DisallowedValuesDrop DisallowedValuesPolicy = "Drop"
+
+	// DisallowedValuesReject fails the reconciliation of a CR with
+	// disallowed values, so that its release is not installed or upgraded
+	// until the values are removed.
+	DisallowedValuesReject DisallowedValuesPolicy = "Reject"
+)
+
+// WithAllowedValuePaths is an Option that restricts the values that the spec
+// of a CR can set to paths, e.g. to let tenants edit CRs without allowing them
+// to change the image repository. A path is a dot-separated list of keys, e.g.
+// "image.tag", and allows the value at the path and all values below it. A
+// "*" key matches any key. Values at other paths are handled according to
+// WithDisallowedValuesPolicy; the values of the chart and the values set by
+// the operator, e.g. with WithOverrideValues, are not restricted. Without
+// paths, no values of the spec are allowed.
+//
+// By default, all values of the spec are allowed.
+func WithAllowedValuePaths(paths []string) Option {
+	return func(r *Reconciler) error {
+		allowed := make([][]string, 0, len(paths))
+		for _, p := range paths {
+			keys := strings.Split(p, ".")
+			for _, k := range keys {
+				if k == "" {
+					return fmt.Errorf("invalid allowed value path %q", p)
+				}
+			}
+			allowed = append(allowed, keys)
+		}
+		r.allowedValuePaths = allowed
+		return nil
+	}
+}
+
+// WithDisallowedValuesPolicy is an Option that configures how values of a CR
+// spec outside of the paths allowed with WithAllowedValuePaths are handled.
+// By default, they are dropped.
+func WithDisallowedValuesPolicy(policy DisallowedValuesPolicy) Option {
+	return func(r *Reconciler) error {
+		switch policy {
+		case DisallowedValuesDrop, DisallowedValuesReject:
+		default:
+			return fmt.Errorf("unknown disallowed values policy %q", policy)
+		}
+		r.disallowedValuesPolicy = policy
+		return nil
+	}
+}
+
+// restrictSpecValues removes the values of the spec of obj that are not
+// allowed, or returns an error if they are rejected.
+func (r *Reconciler) restrictSpecValues(obj *unstructured.Unstructured) error {
+	if r.allowedValuePaths == nil {
+		return nil
+	}
+	spec, ok := obj.Object["spec"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	disallowed := disallowedValuePaths(spec, r.allowedValuePaths, nil)
+	if len(disallowed) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(disallowed))
+	for _, p := range disallowed {
+		names = append(names, strings.Join(p, "."))
+	}
+	sort.Strings(names)
+	if r.disallowedValuesPolicy == DisallowedValuesReject {
+		return fmt.Errorf("spec sets values that are not allowed: %s", strings.Join(names, ", "))
+	}
+	for _, p := range disallowed {
+		unstructured.RemoveNestedField(spec, p...)
+	}
+	r.eventRecorder.Eventf(obj, "Warning", "ValueDisallowed",
+		"Ignoring values of the spec that are not allowed: %s", strings.Join(names, ", "))
+	return nil
+}
+
+// disallowedValuePaths returns the paths of the values in m, which is at
+// prefix, that are not allowed by any of allowed.
+func disallowedValuePaths(m map[string]interface{}, allowed [][]string, prefix []string) [][]string {
+	var disallowed [][]string
+	for k, v := range m {
+		path := append(append([]string(nil), prefix...), k)
+		switch allowance(path, allowed) {
+		case pathAllowed:
+		case pathPartiallyAllowed:
+			if child, ok := v.(map[string]interface{}); ok {
+				disallowed = append(disallowed, disallowedValuePaths(child, allowed, path)...)
+				continue
+			}
+			disallowed = append(disallowed, path)
+		default:
+			disallowed = append(disallowed, path)
+		}
+	}
+	return disallowed
+}
+
+type pathAllowance int
+
+const (
+	pathDisallowed pathAllowance = iota
+	pathPartiallyAllowed
+	pathAllowed
+)
+
+// allowance returns whether path is allowed by one of allowed, or whether
+// some paths below it are allowed.
+func allowance(path []string, allowed [][]string) pathAllowance {
+	result := pathDisallowed
+	for _, a := range allowed {
+		if !matchesPrefix(path, a) {
+			continue
+		}
+		if len(a) <= len(path) {
+			return pathAllowed
+		}
+		result = pathPartiallyAllowed
+	}
+	return result
+}
+
+// matchesPrefix reports whether the keys of path and allowed match up to the
+// length of the shorter one.
+func matchesPrefix(path, allowed []string) bool {
+	for i := 0; i < len(path) && i < len(allowed); i++ {
+		if allowed[i] != "*" && allowed[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+)
+
+var _ = Describe("WithAllowedValuePaths", func() {
+	var (
+		r        *Reconciler
+		recorder *record.FakeRecorder
+		obj      *unstructured.Unstructured
+	)
+
+	BeforeEach(func() {
+		recorder = record.NewFakeRecorder(1)
+		r = &Reconciler{eventRecorder: recorder}
+		Expect(WithAllowedValuePaths([]string{"replicaCount", "image.tag", "ingress.*.host"})(r)).To(Succeed())
+		obj = &unstructured.Unstructured{Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"replicaCount": int64(2),
+				"image": map[string]interface{}{
+					"repository": "evil.example.com/app",
+					"tag":        "1.0",
+				},
+				"ingress": map[string]interface{}{
+					"web": map[string]interface{}{"host": "web.example.com", "tls": true},
+				},
+				"debug": true,
+			},
+		}}
+	})
+
+	It("should drop disallowed values", func() {
+		Expect(r.restrictSpecValues(obj)).To(Succeed())
+		Expect(obj.Object["spec"]).To(Equal(map[string]interface{}{
+			"replicaCount": int64(2),
+			"image":        map[string]interface{}{"tag": "1.0"},
+			"ingress": map[string]interface{}{
+				"web": map[string]interface{}{"host": "web.example.com"},
+			},
+		}))
+		Expect(recorder.Events).To(Receive(Equal("Warning ValueDisallowed Ignoring values of the spec that are not allowed: " +
+			"debug, image.repository, ingress.web.tls")))
+	})
+
+	It("should reject disallowed values", func() {
+		Expect(WithDisallowedValuesPolicy(DisallowedValuesReject)(r)).To(Succeed())
+		Expect(r.restrictSpecValues(obj)).To(MatchError("spec sets values that are not allowed: debug, image.repository, ingress.web.tls"))
+		Expect(recorder.Events).NotTo(Receive())
+	})
+
+	It("should allow all values by default", func() {
+		r = &Reconciler{eventRecorder: recorder}
+		spec := obj.DeepCopy().Object["spec"]
+		Expect(r.restrictSpecValues(obj)).To(Succeed())
+		Expect(obj.Object["spec"]).To(Equal(spec))
+	})
+
+	It("should fail with invalid paths or policies", func() {
+		Expect(WithAllowedValuePaths([]string{"image..tag"})(r)).NotTo(Succeed())
+		Expect(WithDisallowedValuesPolicy("Ignore")(r)).NotTo(Succeed())
+	})
+})
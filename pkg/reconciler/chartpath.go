@@ -0,0 +1,100 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// NewChartPathSource returns a ChartSource that loads the chart directory or
+// archive at path, e.g. a chart mounted from a ConfigMap. The revision of the
+// chart is a digest of its content, so that used with WithChartSource, all
+// CRs are reconciled with the new chart when the content of the chart
+// changes. Each fetch loads the whole chart, so the refresh interval should
+// not be too short for large charts.
+func NewChartPathSource(path string) ChartSource {
+	return chartPathSource(path)
+}
+
+type chartPathSource string
+
+func (s chartPathSource) Fetch(ctx context.Context) (*chart.Chart, string, error) {
+	chrt, revision, _, err := s.fetchArchive(ctx)
+	return chrt, revision, err
+}
+
+// archiveChartSource is implemented by chart sources that may load their
+// charts from packaged chart archives, whose provenance can be verified.
+type archiveChartSource interface {
+	// fetchArchive is like Fetch, but also returns the archive from which
+	// the chart was loaded, or nil if it was not loaded from an archive.
+	fetchArchive(ctx context.Context) (*chart.Chart, string, *chartArchive, error)
+}
+
+func (s chartPathSource) fetchArchive(_ context.Context) (*chart.Chart, string, *chartArchive, error) {
+	p := filepath.Clean(string(s))
+	chrt, archive, err := loadChartFS(os.DirFS(filepath.Dir(p)), filepath.Base(p))
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("load chart %s: %w", string(s), err)
+	}
+	return chrt, chartDigest(chrt), archive, nil
+}
+
+// chartChangeSource returns a source that records the work queue of the
+// controller when it is started, so that all CRs can be enqueued when the
+// chart changes. The CRs are listed from c and filtered with the selector of
+// r.
+func (r *Reconciler) chartChangeSource(c client.Reader) source.Source {
+	list := r.requestLister(c)
+	return source.Func(func(_ context.Context, _ handler.EventHandler, queue workqueue.RateLimitingInterface, _ ...predicate.Predicate) error {
+		r.chartChangeMu.Lock()
+		defer r.chartChangeMu.Unlock()
+		r.chartChangeTarget = &resyncTarget{list: list, queue: queue}
+		return nil
+	})
+}
+
+// enqueueForChartChange enqueues all CRs of r after its chart changed. Before
+// the controller is started, nothing is enqueued, since all CRs are
+// reconciled when it starts.
+func (r *Reconciler) enqueueForChartChange(ctx context.Context) {
+	r.chartChangeMu.Lock()
+	t := r.chartChangeTarget
+	r.chartChangeMu.Unlock()
+	if t == nil {
+		return
+	}
+	reqs, err := t.list(ctx)
+	if err != nil {
+		r.log.Error(err, "Failed to enqueue custom resources after chart change")
+		return
+	}
+	r.log.Info("Chart changed, reconciling all custom resources", "count", len(reqs))
+	for _, req := range reqs {
+		t.queue.Add(req)
+	}
+}
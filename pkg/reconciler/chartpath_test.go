@@ -0,0 +1,84 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/chart"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// revisionSource is a ChartSource that returns a chart with a configurable
+// revision.
+type revisionSource struct {
+	revision string
+}
+
+func (s *revisionSource) Fetch(_ context.Context) (*chart.Chart, string, error) {
+	return &chart.Chart{Metadata: &chart.Metadata{Name: "test", Version: "1.0.0"}}, s.revision, nil
+}
+
+var _ = Describe("NewChartPathSource", func() {
+	It("should identify a chart by its content", func() {
+		_, rev1, err := NewChartPathSource("../internal/testdata/test-chart-1.2.0.tgz").Fetch(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		_, rev2, err := NewChartPathSource("../internal/testdata/test-chart-1.2.0.tgz").Fetch(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		_, rev3, err := NewChartPathSource("../internal/testdata/test-chart-1.2.3.tgz").Fetch(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rev1).To(Equal(rev2))
+		Expect(rev1).NotTo(Equal(rev3))
+	})
+
+	It("should fail if the chart cannot be loaded", func() {
+		_, _, err := NewChartPathSource("../internal/testdata/missing").Fetch(context.Background())
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Chart changes", func() {
+	It("should enqueue all custom resources when the chart revision changes", func() {
+		ctx := context.Background()
+		gvk := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "TestApp"}
+		src := &revisionSource{revision: "a"}
+		r := &Reconciler{gvk: &gvk, log: logr.Discard()}
+		Expect(WithChartSource(src, 0)(r)).To(Succeed())
+		Expect(r.refreshChart(ctx)).To(Succeed())
+
+		queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+		DeferCleanup(queue.ShutDown)
+		obj := unstructured.Unstructured{}
+		obj.SetNamespace("ns")
+		obj.SetName("a")
+		reader := &listReader{items: []unstructured.Unstructured{obj}}
+		Expect(r.chartChangeSource(reader).Start(ctx, nil, queue)).To(Succeed())
+
+		Expect(r.refreshChart(ctx)).To(Succeed())
+		Expect(queue.Len()).To(Equal(0))
+
+		src.revision = "b"
+		Expect(r.refreshChart(ctx)).To(Succeed())
+		Expect(queue.Len()).To(Equal(1))
+		Expect(r.chartRevision).To(Equal("b"))
+	})
+})
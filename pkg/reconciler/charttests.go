@@ -0,0 +1,207 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	helmclient "github.com/operator-framework/helm-operator-plugins/pkg/client"
+	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/conditions"
+	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/updater"
+)
+
+// DefaultChartTestTimeout is the timeout of chart tests if no action timeout
+// is configured.
+const DefaultChartTestTimeout = 5 * time.Minute
+
+// ChartTestFailurePolicy determines how failed chart tests affect the status
+// of a CR.
+type ChartTestFailurePolicy string
+
+const (
+	// ChartTestFailureFatal reports a release whose tests failed as not
+	// deployed until the release is upgraded.
+	ChartTestFailureFatal ChartTestFailurePolicy = "Fatal"
+
+	// ChartTestFailureAdvisory only reports failed tests in the
+	// ChartTestsPassed condition and in events.
+	ChartTestFailureAdvisory ChartTestFailurePolicy = "Advisory"
+)
+
+// ChartTestCleanupPolicy determines when the resources of the test hooks of a
+// release, e.g. test pods, are deleted after the tests ran. Independent of the
+// policy, test resources are deleted according to their
+// helm.sh/hook-delete-policy annotations.
+type ChartTestCleanupPolicy string
+
+const (
+	// ChartTestCleanupNever keeps the test resources, e.g. to inspect the
+	// logs of the test pods.
+	ChartTestCleanupNever ChartTestCleanupPolicy = "Never"
+
+	// ChartTestCleanupOnSuccess deletes the test resources if all tests
+	// passed, and keeps them if a test failed.
+	ChartTestCleanupOnSuccess ChartTestCleanupPolicy = "OnSuccess"
+
+	// ChartTestCleanupAlways deletes the test resources after the tests ran.
+	ChartTestCleanupAlways ChartTestCleanupPolicy = "Always"
+)
+
+// WithRunChartTests is an Option that configures whether the tests of the
+// chart, i.e. its test hooks, are run like by helm test once the release is
+// deployed. The tests run once per revision of the release, and their result
+// is reported in the ChartTestsPassed condition and in events. How failed
+// tests affect the status of the CR is configured with
+// WithChartTestFailurePolicy. It is disabled by default.
+func WithRunChartTests(enabled bool) Option {
+	return func(r *Reconciler) error {
+		r.chartTests = enabled
+		return nil
+	}
+}
+
+// WithChartTestFailurePolicy is an Option that configures how failed chart
+// tests affect the status of a CR. By default, failed tests are fatal, i.e.
+// the Deployed condition of the CR is false until the release is upgraded.
+func WithChartTestFailurePolicy(policy ChartTestFailurePolicy) Option {
+	return func(r *Reconciler) error {
+		switch policy {
+		case ChartTestFailureFatal, ChartTestFailureAdvisory:
+		default:
+			return fmt.Errorf("unknown chart test failure policy %q", policy)
+		}
+		r.chartTestFailurePolicy = policy
+		return nil
+	}
+}
+
+// WithChartTestCleanupPolicy is an Option that configures when the resources
+// of the test hooks of a release are deleted after the tests ran. By default,
+// they are deleted only according to their hook delete policies.
+func WithChartTestCleanupPolicy(policy ChartTestCleanupPolicy) Option {
+	return func(r *Reconciler) error {
+		switch policy {
+		case ChartTestCleanupNever, ChartTestCleanupOnSuccess, ChartTestCleanupAlways:
+		default:
+			return fmt.Errorf("unknown chart test cleanup policy %q", policy)
+		}
+		r.chartTestCleanupPolicy = policy
+		return nil
+	}
+}
+
+// testRelease runs the tests of rel, unless they already ran for its revision,
+// and reports their results. It returns rel with the results of the tests and
+// whether all tests passed.
+func (r *Reconciler) testRelease(actionClient helmclient.ActionInterface, u *updater.Updater, obj *unstructured.Unstructured, rel *release.Release, timeout time.Duration, log logr.Logger) (*release.Release, bool, error) {
+	if len(testHooks(rel)) == 0 {
+		u.UpdateStatus(updater.EnsureCondition(conditions.ChartTestsPassed(corev1.ConditionTrue, conditions.ReasonNoChartTests, "chart has no tests")))
+		return rel, true, nil
+	}
+
+	if !testsRan(rel) {
+		tester, ok := actionClient.(helmclient.ChartTester)
+		if !ok {
+			err := errors.New("action client cannot run chart tests")
+			u.UpdateStatus(updater.EnsureCondition(conditions.ChartTestsPassed(corev1.ConditionUnknown, conditions.ReasonErrorRunningChartTests, err)))
+			return rel, false, err
+		}
+		if timeout == 0 {
+			timeout = DefaultChartTestTimeout
+		}
+		log.Info("Running chart tests", "name", rel.Name, "version", rel.Version)
+		tested, err := tester.Test(rel.Name, func(t *action.ReleaseTesting) error {
+			t.Namespace = rel.Namespace
+			t.Timeout = timeout
+			return nil
+		})
+		if tested == nil {
+			u.UpdateStatus(updater.EnsureCondition(conditions.ChartTestsPassed(corev1.ConditionUnknown, conditions.ReasonErrorRunningChartTests, err)))
+			return rel, false, fmt.Errorf("run chart tests: %w", err)
+		}
+		rel = tested
+
+		failed := failedTests(rel)
+		if len(failed) == 0 {
+			r.eventRecorder.Eventf(obj, "Normal", string(conditions.ReasonChartTestsSucceeded),
+				"Chart tests of revision %d passed", rel.Version)
+		} else {
+			r.eventRecorder.Eventf(obj, "Warning", string(conditions.ReasonChartTestsFailed),
+				"Chart tests of revision %d failed: %s", rel.Version, strings.Join(failed, ", "))
+		}
+		if r.chartTestCleanupPolicy == ChartTestCleanupAlways || (r.chartTestCleanupPolicy == ChartTestCleanupOnSuccess && len(failed) == 0) {
+			if err := tester.CleanupTests(rel); err != nil {
+				log.Error(err, "Failed to clean up chart tests", "name", rel.Name, "version", rel.Version)
+			}
+		}
+	}
+
+	if failed := failedTests(rel); len(failed) > 0 {
+		u.UpdateStatus(updater.EnsureCondition(conditions.ChartTestsPassed(corev1.ConditionFalse, conditions.ReasonChartTestsFailed,
+			fmt.Sprintf("chart tests of revision %d failed: %s", rel.Version, strings.Join(failed, ", ")))))
+		return rel, false, nil
+	}
+	u.UpdateStatus(updater.EnsureCondition(conditions.ChartTestsPassed(corev1.ConditionTrue, conditions.ReasonChartTestsSucceeded,
+		fmt.Sprintf("chart tests of revision %d passed", rel.Version))))
+	return rel, true, nil
+}
+
+// testHooks returns the test hooks of rel.
+func testHooks(rel *release.Release) []*release.Hook {
+	var hooks []*release.Hook
+	for _, h := range rel.Hooks {
+		for _, e := range h.Events {
+			if e == release.HookTest {
+				hooks = append(hooks, h)
+				break
+			}
+		}
+	}
+	return hooks
+}
+
+// testsRan reports whether the tests of rel ran, which is recorded in the
+// release by Helm.
+func testsRan(rel *release.Release) bool {
+	for _, h := range testHooks(rel) {
+		if h.LastRun.Phase != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// failedTests returns the names of the test hooks of rel that did not
+// succeed.
+func failedTests(rel *release.Release) []string {
+	var failed []string
+	for _, h := range testHooks(rel) {
+		if h.LastRun.Phase != release.HookPhaseSucceeded {
+			failed = append(failed, h.Name)
+		}
+	}
+	return failed
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"errors"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/release"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+
+	helmfake "github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/fake"
+	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/updater"
+)
+
+var _ = Describe("WithRunChartTests", func() {
+	var (
+		r        *Reconciler
+		ac       helmfake.ActionClient
+		u        updater.Updater
+		recorder *record.FakeRecorder
+		obj      *unstructured.Unstructured
+	)
+
+	testRelease := func(phase release.HookPhase) *release.Release {
+		return &release.Release{
+			Name:      "test",
+			Namespace: "default",
+			Version:   2,
+			Hooks: []*release.Hook{
+				{Name: "pre-install", Events: []release.HookEvent{release.HookPreInstall}},
+				{Name: "test-connection", Events: []release.HookEvent{release.HookTest}, LastRun: release.HookExecution{Phase: phase}},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		recorder = record.NewFakeRecorder(1)
+		r = &Reconciler{eventRecorder: recorder}
+		Expect(WithRunChartTests(true)(r)).To(Succeed())
+		ac = helmfake.NewActionClient()
+		ac.HandleCleanupTests = func() error { return nil }
+		u = updater.New(nil)
+		obj = &unstructured.Unstructured{}
+	})
+
+	It("should pass without test hooks", func() {
+		rel := &release.Release{Name: "test", Version: 1}
+		_, passed, err := r.testRelease(&ac, &u, obj, rel, 0, logr.Discard())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(passed).To(BeTrue())
+		Expect(ac.Tests).To(BeEmpty())
+	})
+
+	It("should run the tests once per revision", func() {
+		ac.HandleTest = func() (*release.Release, error) { return testRelease(release.HookPhaseSucceeded), nil }
+		rel, passed, err := r.testRelease(&ac, &u, obj, testRelease(""), 0, logr.Discard())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(passed).To(BeTrue())
+		Expect(ac.Tests).To(HaveLen(1))
+		Expect(recorder.Events).To(Receive(Equal("Normal ChartTestsSucceeded Chart tests of revision 2 passed")))
+
+		_, passed, err = r.testRelease(&ac, &u, obj, rel, 0, logr.Discard())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(passed).To(BeTrue())
+		Expect(ac.Tests).To(HaveLen(1))
+	})
+
+	It("should report failed tests", func() {
+		ac.HandleTest = func() (*release.Release, error) {
+			return testRelease(release.HookPhaseFailed), errors.New("pod failed")
+		}
+		_, passed, err := r.testRelease(&ac, &u, obj, testRelease(""), 0, logr.Discard())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(passed).To(BeFalse())
+		Expect(recorder.Events).To(Receive(Equal("Warning ChartTestsFailed Chart tests of revision 2 failed: test-connection")))
+		Expect(ac.Cleanups).To(BeEmpty())
+	})
+
+	It("should fail if the tests cannot be run", func() {
+		ac.HandleTest = func() (*release.Release, error) { return nil, errors.New("release not found") }
+		_, _, err := r.testRelease(&ac, &u, obj, testRelease(""), 0, logr.Discard())
+		Expect(err).To(MatchError(ContainSubstring("release not found")))
+	})
+
+	DescribeTable("should clean up the tests according to the policy",
+		func(policy ChartTestCleanupPolicy, phase release.HookPhase, cleanup bool) {
+			Expect(WithChartTestCleanupPolicy(policy)(r)).To(Succeed())
+			ac.HandleTest = func() (*release.Release, error) { return testRelease(phase), nil }
+			_, _, err := r.testRelease(&ac, &u, obj, testRelease(""), 0, logr.Discard())
+			Expect(err).NotTo(HaveOccurred())
+			if cleanup {
+				Expect(ac.Cleanups).To(HaveLen(1))
+			} else {
+				Expect(ac.Cleanups).To(BeEmpty())
+			}
+		},
+		Entry("never", ChartTestCleanupNever, release.HookPhaseSucceeded, false),
+		Entry("on success after passed tests", ChartTestCleanupOnSuccess, release.HookPhaseSucceeded, true),
+		Entry("on success after failed tests", ChartTestCleanupOnSuccess, release.HookPhaseFailed, false),
+		Entry("always", ChartTestCleanupAlways, release.HookPhaseFailed, true),
+	)
+
+	It("should fail with unknown policies", func() {
+		Expect(WithChartTestFailurePolicy("Sometimes")(r)).NotTo(Succeed())
+		Expect(WithChartTestCleanupPolicy("Sometimes")(r)).NotTo(Succeed())
+		Expect(WithChartTestFailurePolicy(ChartTestFailureAdvisory)(r)).To(Succeed())
+		Expect(r.chartTestFailurePolicy).To(Equal(ChartTestFailureAdvisory))
+	})
+})
@@ -0,0 +1,60 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"strings"
+
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// WithContentBasedUpgradeDetection is an Option that configures whether a
+// release is only upgraded if its rendered manifest changed, ignoring chart
+// version bumps that do not affect the rendered resources. Charts commonly
+// stamp their version on all resources, e.g. in the helm.sh/chart label, so
+// the version is replaced by the chart name in the "<name>-<version>" form
+// before the manifests are compared.
+//
+// A release that is not upgraded keeps the chart version it was deployed
+// with. It is disabled by default.
+func WithContentBasedUpgradeDetection(enabled bool) Option {
+	return func(r *Reconciler) error {
+		r.contentBasedUpgradeDetection = enabled
+		return nil
+	}
+}
+
+// manifestChanged returns whether the manifest of the spec release differs
+// from the one of the current release.
+func (r *Reconciler) manifestChanged(current, spec *release.Release) bool {
+	if !r.contentBasedUpgradeDetection {
+		return spec.Manifest != current.Manifest
+	}
+	return withoutChartVersion(spec) != withoutChartVersion(current)
+}
+
+// withoutChartVersion returns the manifest of rel with the chart version
+// removed from the "<name>-<version>" references to its chart, which is how
+// charts created with helm create label their resources.
+func withoutChartVersion(rel *release.Release) string {
+	if rel.Chart == nil || rel.Chart.Metadata == nil || rel.Chart.Metadata.Version == "" {
+		return rel.Manifest
+	}
+	meta := rel.Chart.Metadata
+	chartRef := meta.Name + "-" + strings.ReplaceAll(meta.Version, "+", "_")
+	return strings.ReplaceAll(rel.Manifest, chartRef, meta.Name)
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	helmfake "github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/fake"
+)
+
+var _ = Describe("WithContentBasedUpgradeDetection", func() {
+	var (
+		r    *Reconciler
+		obj  *unstructured.Unstructured
+		ac   helmfake.ActionClient
+		spec *release.Release
+	)
+
+	newRelease := func(version, manifest string) *release.Release {
+		return &release.Release{
+			Name:     "test",
+			Version:  3,
+			Chart:    &chart.Chart{Metadata: &chart.Metadata{Name: "test", Version: version}},
+			Manifest: manifest,
+			Info:     &release.Info{Status: release.StatusDeployed},
+		}
+	}
+
+	BeforeEach(func() {
+		r = &Reconciler{chrt: &chart.Chart{Metadata: &chart.Metadata{Name: "test", Version: "1.0.1+build.2"}}}
+		obj = &unstructured.Unstructured{}
+		obj.SetName("test")
+		obj.SetNamespace("ns")
+
+		current := newRelease("1.0.0", "kind: ConfigMap\nmetadata:\n  labels:\n    helm.sh/chart: test-1.0.0\n")
+		spec = newRelease("1.0.1+build.2", "kind: ConfigMap\nmetadata:\n  labels:\n    helm.sh/chart: test-1.0.1_build.2\n")
+		ac = helmfake.NewActionClient()
+		ac.HandleGet = func() (*release.Release, error) { return current, nil }
+		ac.HandleUpgrade = func() (*release.Release, error) { return spec, nil }
+	})
+
+	It("should upgrade on a chart version bump by default", func() {
+		_, state, err := r.getReleaseState(&ac, obj, nil, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(state).To(Equal(stateNeedsUpgrade))
+	})
+
+	It("should not upgrade on a chart version bump when enabled", func() {
+		Expect(WithContentBasedUpgradeDetection(true)(r)).To(Succeed())
+		_, state, err := r.getReleaseState(&ac, obj, nil, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(state).To(Equal(stateUnchanged))
+	})
+
+	It("should upgrade when the rendered resources changed when enabled", func() {
+		Expect(WithContentBasedUpgradeDetection(true)(r)).To(Succeed())
+		spec.Manifest += "data:\n  key: value\n"
+		_, state, err := r.getReleaseState(&ac, obj, nil, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(state).To(Equal(stateNeedsUpgrade))
+	})
+})
@@ -0,0 +1,166 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	helmclient "github.com/operator-framework/helm-operator-plugins/pkg/client"
+)
+
+// actionTimeoutFor returns the timeout of the Helm actions of obj, which is
+// the duration of its TimeoutAnnotation if set, or the timeout configured
+// with WithActionTimeout.
+func (r *Reconciler) actionTimeoutFor(obj *unstructured.Unstructured) (time.Duration, error) {
+	v, ok := obj.GetAnnotations()[TimeoutAnnotation]
+	if !ok {
+		return r.actionTimeout, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q of annotation %s: %w", v, TimeoutAnnotation, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid value %q of annotation %s: timeout must not be negative", v, TimeoutAnnotation)
+	}
+	return d, nil
+}
+
+// actionTracker tracks the Helm actions of a reconciliation that were
+// abandoned after their deadline. An abandoned action keeps running in the
+// background, so the release lock and the concurrency slots of the
+// reconciliation must be held until it returns. Otherwise a requeued
+// reconciliation could start a second Helm action on the same release.
+type actionTracker struct {
+	running   sync.WaitGroup
+	abandoned atomic.Bool
+}
+
+// release calls f once all abandoned actions have returned. If no action was
+// abandoned, f is called immediately; otherwise release does not block.
+func (t *actionTracker) release(f func()) {
+	if !t.abandoned.Load() {
+		f()
+		return
+	}
+	go func() {
+		t.running.Wait()
+		f()
+	}()
+}
+
+// deadlineActionClient wraps an ActionInterface so that its Helm actions
+// return once ctx is done, even if the underlying actions do not support
+// cancellation. An abandoned action keeps running in the background until it
+// returns on its own, and is recorded in tracker.
+type deadlineActionClient struct {
+	helmclient.ActionInterface
+	ctx     context.Context
+	tracker *actionTracker
+}
+
+// withDeadline returns an ActionInterface that runs the Helm actions of c with
+// ctx. If c supports contexts, ctx is passed to the actions, so that they are
+// stopped when ctx is done. Actions that are still running when ctx is done
+// are recorded in tracker.
+func withDeadline(ctx context.Context, c helmclient.ActionInterface, tracker *actionTracker) helmclient.ActionInterface {
+	if cc, ok := c.(helmclient.ContextActionInterface); ok {
+		c = cc.WithContext(ctx)
+	}
+	return &deadlineActionClient{ActionInterface: c, ctx: ctx, tracker: tracker}
+}
+
+func (c *deadlineActionClient) Install(name, namespace string, chrt *chart.Chart, vals map[string]interface{}, opts ...helmclient.InstallOption) (*release.Release, error) {
+	return runWithDeadline(c.ctx, c.tracker, "install", func() (*release.Release, error) {
+		return c.ActionInterface.Install(name, namespace, chrt, vals, opts...)
+	})
+}
+
+func (c *deadlineActionClient) Upgrade(name, namespace string, chrt *chart.Chart, vals map[string]interface{}, opts ...helmclient.UpgradeOption) (*release.Release, error) {
+	return runWithDeadline(c.ctx, c.tracker, "upgrade", func() (*release.Release, error) {
+		return c.ActionInterface.Upgrade(name, namespace, chrt, vals, opts...)
+	})
+}
+
+func (c *deadlineActionClient) Uninstall(name string, opts ...helmclient.UninstallOption) (*release.UninstallReleaseResponse, error) {
+	return runWithDeadline(c.ctx, c.tracker, "uninstall", func() (*release.UninstallReleaseResponse, error) {
+		return c.ActionInterface.Uninstall(name, opts...)
+	})
+}
+
+func (c *deadlineActionClient) Reconcile(rel *release.Release) error {
+	_, err := runWithDeadline(c.ctx, c.tracker, "reconcile", func() (struct{}, error) {
+		return struct{}{}, c.ActionInterface.Reconcile(rel)
+	})
+	return err
+}
+
+func (c *deadlineActionClient) Test(name string, opts ...helmclient.TestOption) (*release.Release, error) {
+	tester, ok := c.ActionInterface.(helmclient.ChartTester)
+	if !ok {
+		return nil, errors.New("action client cannot run chart tests")
+	}
+	return runWithDeadline(c.ctx, c.tracker, "test", func() (*release.Release, error) {
+		return tester.Test(name, opts...)
+	})
+}
+
+func (c *deadlineActionClient) CleanupTests(rel *release.Release) error {
+	tester, ok := c.ActionInterface.(helmclient.ChartTester)
+	if !ok {
+		return errors.New("action client cannot run chart tests")
+	}
+	_, err := runWithDeadline(c.ctx, c.tracker, "test cleanup", func() (struct{}, error) {
+		return struct{}{}, tester.CleanupTests(rel)
+	})
+	return err
+}
+
+// runWithDeadline runs f and returns its result, or an error wrapping the
+// error of ctx if ctx is done before f returns. In that case, f is recorded
+// in tracker as abandoned until it returns.
+func runWithDeadline[T any](ctx context.Context, tracker *actionTracker, op string, f func() (T, error)) (T, error) {
+	type result struct {
+		v   T
+		err error
+	}
+	ch := make(chan result, 1)
+	tracker.running.Add(1)
+	go func() {
+		defer tracker.running.Done()
+		v, err := f()
+		ch <- result{v, err}
+	}()
+	select {
+	case res := <-ch:
+		return res.v, res.err
+	case <-ctx.Done():
+		tracker.abandoned.Store(true)
+		var zero T
+		return zero, fmt.Errorf("%s did not complete in time: %w", op, ctx.Err())
+	}
+}
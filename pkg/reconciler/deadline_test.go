@@ -0,0 +1,131 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/release"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	helmfake "github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/fake"
+)
+
+var _ = Describe("withDeadline", func() {
+	var (
+		ac          helmfake.ActionClient
+		tracker     *actionTracker
+		testRelease = &release.Release{Name: "test"}
+	)
+
+	BeforeEach(func() {
+		ac = helmfake.NewActionClient()
+		tracker = &actionTracker{}
+	})
+
+	It("should return the result of actions that complete in time", func() {
+		ac.HandleInstall = func() (*release.Release, error) { return testRelease, nil }
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		rel, err := withDeadline(ctx, &ac, tracker).Install("test", "default", nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rel).To(Equal(testRelease))
+
+		released := false
+		tracker.release(func() { released = true })
+		Expect(released).To(BeTrue())
+	})
+
+	It("should stop waiting for actions that do not complete in time", func() {
+		done := make(chan struct{})
+		defer close(done)
+		ac.HandleUpgrade = func() (*release.Release, error) {
+			<-done
+			return testRelease, nil
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := withDeadline(ctx, &ac, tracker).Upgrade("test", "default", nil, nil)
+		Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+		Expect(errors.Is(classifyActionError(err), ErrActionTimeout)).To(BeTrue())
+	})
+
+	It("should hold the release until abandoned actions return", func() {
+		done := make(chan struct{})
+		ac.HandleInstall = func() (*release.Release, error) {
+			<-done
+			return testRelease, nil
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := withDeadline(ctx, &ac, tracker).Install("test", "default", nil, nil)
+		Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+
+		released := make(chan struct{})
+		tracker.release(func() { close(released) })
+		Consistently(released, 50*time.Millisecond).ShouldNot(BeClosed())
+		close(done)
+		Eventually(released).Should(BeClosed())
+	})
+
+	It("should pass through action errors", func() {
+		ac.HandleReconcile = func() error { return errors.New("reconcile failed") }
+
+		err := withDeadline(context.Background(), &ac, tracker).Reconcile(testRelease)
+		Expect(err).To(MatchError("reconcile failed"))
+	})
+})
+
+var _ = Describe("actionTimeoutFor", func() {
+	var (
+		r   *Reconciler
+		obj *unstructured.Unstructured
+	)
+
+	BeforeEach(func() {
+		r = &Reconciler{}
+		Expect(WithActionTimeout(time.Minute)(r)).To(Succeed())
+		obj = &unstructured.Unstructured{}
+	})
+
+	It("should return the configured timeout without annotation", func() {
+		Expect(r.actionTimeoutFor(obj)).To(Equal(time.Minute))
+	})
+
+	It("should return the timeout of the annotation", func() {
+		obj.SetAnnotations(map[string]string{TimeoutAnnotation: "10m"})
+		Expect(r.actionTimeoutFor(obj)).To(Equal(10 * time.Minute))
+		obj.SetAnnotations(map[string]string{TimeoutAnnotation: "0"})
+		Expect(r.actionTimeoutFor(obj)).To(BeZero())
+	})
+
+	It("should fail with an invalid annotation", func() {
+		obj.SetAnnotations(map[string]string{TimeoutAnnotation: "ten minutes"})
+		_, err := r.actionTimeoutFor(obj)
+		Expect(err).To(MatchError(ContainSubstring(`invalid value "ten minutes" of annotation ` + TimeoutAnnotation)))
+		obj.SetAnnotations(map[string]string{TimeoutAnnotation: "-1m"})
+		_, err = r.actionTimeoutFor(obj)
+		Expect(err).To(MatchError(ContainSubstring("must not be negative")))
+	})
+})
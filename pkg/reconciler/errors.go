@@ -0,0 +1,263 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/operator-framework/helm-operator-plugins/pkg/internal/status"
+	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/conditions"
+)
+
+// Errors returned by Reconcile wrap one of the following sentinel errors, so
+// that callers can distinguish the causes of failed reconciliations with
+// errors.Is.
+var (
+	// ErrValuesFailed is wrapped by errors computing the values of a release,
+	// including errors of the function configured with WithPreprocessCR.
+	ErrValuesFailed = errors.New("computing values failed")
+
+	// ErrProvenanceVerificationFailed is wrapped by errors verifying the
+	// provenance of the chart.
+	ErrProvenanceVerificationFailed = errors.New("chart provenance verification failed")
+
+	// ErrRenderFailed is wrapped by errors rendering the chart of a release,
+	// e.g. template errors or invalid manifests.
+	ErrRenderFailed = errors.New("chart render failed")
+
+	// ErrApplyConflict is wrapped by errors applying a release because its
+	// resources conflict with existing resources.
+	ErrApplyConflict = errors.New("apply conflict")
+
+	// ErrActionTimeout is wrapped by errors of Helm actions that timed out,
+	// e.g. while waiting for the resources of a release to become ready.
+	ErrActionTimeout = errors.New("helm action timed out")
+
+	// ErrActionForbidden is wrapped by errors of Helm actions that were denied
+	// by the API server, e.g. because an impersonated ServiceAccount lacks
+	// permissions.
+	ErrActionForbidden = errors.New("helm action forbidden")
+
+	// ErrActionFailed is wrapped by all other errors of Helm actions.
+	ErrActionFailed = errors.New("helm action failed")
+)
+
+// ReconcileError is returned by Reconcile when a step of the reconciliation
+// fails. Its message is the message of the underlying error.
+type ReconcileError struct {
+	// Op is the step that failed, e.g. "values", "install" or "upgrade".
+	Op string
+
+	// Err is the underlying error.
+	Err error
+
+	kind error
+}
+
+func (e *ReconcileError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the sentinel error describing the cause of e and the
+// underlying error.
+func (e *ReconcileError) Unwrap() []error {
+	return []error{e.kind, e.Err}
+}
+
+// newReconcileError wraps err, the error of the reconciliation step op, with
+// the sentinel error kind.
+func newReconcileError(op string, kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ReconcileError{Op: op, Err: err, kind: kind}
+}
+
+// newActionError wraps err, the error of the Helm action op, with the
+// sentinel error that matches its cause.
+func newActionError(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return newReconcileError(op, classifyActionError(err), err)
+}
+
+// actionErrorReason returns the reason of the Irreconcilable condition for
+// err, the error of a Helm action.
+func actionErrorReason(err error) status.ConditionReason {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return conditions.ReasonActionTimeout
+	}
+	if isForbidden(err) {
+		return conditions.ReasonPermissionDenied
+	}
+	return conditions.ReasonReconcileError
+}
+
+func classifyActionError(err error) error {
+	msg := err.Error()
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) ||
+		strings.Contains(msg, "timed out waiting for the condition"):
+		return ErrActionTimeout
+	case apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err) ||
+		strings.Contains(msg, "rendered manifests contain a resource that already exists"):
+		return ErrApplyConflict
+	case isForbidden(err):
+		return ErrActionForbidden
+	case strings.Contains(msg, "parse error") ||
+		strings.Contains(msg, "template: ") ||
+		strings.Contains(msg, "unable to build kubernetes objects from"):
+		return ErrRenderFailed
+	default:
+		return ErrActionFailed
+	}
+}
+
+// isForbidden reports whether err was caused by the API server denying a
+// request. Helm does not always wrap the errors of the API server, so the
+// message is checked as well.
+func isForbidden(err error) bool {
+	return apierrors.IsForbidden(err) || strings.Contains(err.Error(), " is forbidden: ")
+}
+
+// ErrorClass is the class of a reconcile error, which determines how the CR
+// is requeued after the error.
+type ErrorClass string
+
+const (
+	// ErrorClassDefault errors are requeued with the exponential backoff of
+	// the controller.
+	ErrorClassDefault ErrorClass = ""
+
+	// ErrorClassTransient errors, e.g. conflicts or temporary errors of the
+	// API server, are likely to be resolved by retrying. The CR is requeued
+	// after the delay configured with WithTransientErrorRequeueDelay, without
+	// backoff.
+	ErrorClassTransient ErrorClass = "Transient"
+
+	// ErrorClassPermanent errors, e.g. invalid values or templates, cannot be
+	// resolved by retrying. The CR is not requeued until it changes.
+	ErrorClassPermanent ErrorClass = "Permanent"
+)
+
+// ErrorClassifier returns the class of err, an error returned by Reconcile.
+type ErrorClassifier func(err error) ErrorClass
+
+// DefaultTransientErrorRequeueDelay is the delay after which a CR is
+// reconciled again after a transient error, if WithErrorClassifier is
+// configured without WithTransientErrorRequeueDelay.
+const DefaultTransientErrorRequeueDelay = 5 * time.Second
+
+// WithErrorClassifier is an Option that configures the Reconciler to requeue
+// CRs after a failed reconciliation depending on the class of the error as
+// returned by f. DefaultErrorClassifier can be used as is, or be wrapped to
+// override the class of specific errors.
+//
+// A CR that failed with a transient error is requeued after a fixed delay
+// and Reconcile returns no error, so that the backoff of the controller is
+// reset. A permanent error is returned as a terminal error, so that the CR is
+// not requeued until it changes. Both are still logged and recorded like
+// other errors. By default, all errors are requeued with the exponential
+// backoff of the controller.
+func WithErrorClassifier(f ErrorClassifier) Option {
+	return func(r *Reconciler) error {
+		if f == nil {
+			return errors.New("error classifier must not be nil")
+		}
+		r.errorClassifier = f
+		return nil
+	}
+}
+
+// WithTransientErrorRequeueDelay is an Option that configures the delay after
+// which a CR is reconciled again after a transient error, as classified by
+// the function configured with WithErrorClassifier. The default is
+// DefaultTransientErrorRequeueDelay.
+func WithTransientErrorRequeueDelay(d time.Duration) Option {
+	return func(r *Reconciler) error {
+		if d <= 0 {
+			return errors.New("transient error requeue delay must be positive")
+		}
+		r.transientErrorRequeueDelay = d
+		return nil
+	}
+}
+
+// DefaultErrorClassifier classifies conflicts, timeouts, throttling and
+// internal errors of the API server as transient, and errors computing the
+// values, rendering the chart or verifying its provenance as permanent,
+// unless they were caused by a transient error. All other errors, including
+// errors of Helm actions that timed out, have the default class.
+func DefaultErrorClassifier(err error) ErrorClass {
+	switch {
+	case isTransientAPIError(err):
+		return ErrorClassTransient
+	case errors.Is(err, ErrValuesFailed),
+		errors.Is(err, ErrRenderFailed),
+		errors.Is(err, ErrProvenanceVerificationFailed):
+		return ErrorClassPermanent
+	default:
+		return ErrorClassDefault
+	}
+}
+
+// isTransientAPIError reports whether err was caused by an error of the API
+// server that is likely to be resolved by retrying.
+func isTransientAPIError(err error) bool {
+	if apierrors.IsConflict(err) ||
+		apierrors.IsServerTimeout(err) ||
+		apierrors.IsTimeout(err) ||
+		apierrors.IsTooManyRequests(err) ||
+		apierrors.IsServiceUnavailable(err) ||
+		apierrors.IsInternalError(err) {
+		return true
+	}
+	var status apierrors.APIStatus
+	return errors.As(err, &status) && status.Status().Code >= http.StatusInternalServerError
+}
+
+// requeueForErrorClass returns the result and error of Reconcile for err
+// according to its class as returned by the configured classifier.
+func (r *Reconciler) requeueForErrorClass(res ctrl.Result, err error) (ctrl.Result, error) {
+	if err == nil || r.errorClassifier == nil {
+		return res, err
+	}
+	switch r.errorClassifier(err) {
+	case ErrorClassTransient:
+		delay := r.transientErrorRequeueDelay
+		if delay == 0 {
+			delay = DefaultTransientErrorRequeueDelay
+		}
+		return ctrl.Result{RequeueAfter: delay}, nil
+	case ErrorClassPermanent:
+		if errors.Is(err, reconcile.TerminalError(nil)) {
+			return res, err
+		}
+		return ctrl.Result{}, reconcile.TerminalError(err)
+	default:
+		return res, err
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/conditions"
+)
+
+var _ = Describe("ReconcileError", func() {
+	It("should keep the message of the underlying error", func() {
+		err := newActionError("install", errors.New("install failed"))
+		Expect(err).To(MatchError("install failed"))
+
+		var reconcileErr *ReconcileError
+		Expect(errors.As(err, &reconcileErr)).To(BeTrue())
+		Expect(reconcileErr.Op).To(Equal("install"))
+	})
+	It("should return nil for nil errors", func() {
+		Expect(newActionError("install", nil)).To(BeNil())
+		Expect(newReconcileError("values", ErrValuesFailed, nil)).To(BeNil())
+	})
+	DescribeTable("should classify Helm action errors",
+		func(err, expected error) {
+			Expect(errors.Is(newActionError("upgrade", err), expected)).To(BeTrue())
+			Expect(errors.Is(newActionError("upgrade", err), err)).To(BeTrue())
+		},
+		Entry("timeout", fmt.Errorf("wait: %w", context.DeadlineExceeded), ErrActionTimeout),
+		Entry("wait timeout", errors.New("timed out waiting for the condition"), ErrActionTimeout),
+		Entry("conflict", apierrors.NewConflict(schema.GroupResource{Resource: "configmaps"}, "test", errors.New("modified")), ErrApplyConflict),
+		Entry("existing resource", errors.New("rendered manifests contain a resource that already exists"), ErrApplyConflict),
+		Entry("template error", errors.New("template: test/templates/cm.yaml:3:4: executing"), ErrRenderFailed),
+		Entry("forbidden", apierrors.NewForbidden(schema.GroupResource{Resource: "configmaps"}, "test", errors.New("denied")), ErrActionForbidden),
+		Entry("forbidden message", errors.New(`configmaps "test" is forbidden: User "system:serviceaccount:ns:tenant" cannot create resource "configmaps"`), ErrActionForbidden),
+		Entry("other error", errors.New("storage unavailable"), ErrActionFailed),
+	)
+	It("should use the ActionTimeout reason for timed out actions", func() {
+		Expect(actionErrorReason(fmt.Errorf("install: %w", context.DeadlineExceeded))).To(Equal(conditions.ReasonActionTimeout))
+		Expect(actionErrorReason(errors.New("install failed"))).To(Equal(conditions.ReasonReconcileError))
+	})
+	It("should use the PermissionDenied reason for forbidden actions", func() {
+		err := apierrors.NewForbidden(schema.GroupResource{Resource: "configmaps"}, "test", errors.New("denied"))
+		Expect(actionErrorReason(fmt.Errorf("install: %w", err))).To(Equal(conditions.ReasonPermissionDenied))
+	})
+})
+
+var _ = Describe("ErrorClassifier", func() {
+	gr := schema.GroupResource{Resource: "configmaps"}
+
+	DescribeTable("DefaultErrorClassifier",
+		func(err error, expected ErrorClass) {
+			Expect(DefaultErrorClassifier(err)).To(Equal(expected))
+		},
+		Entry("conflict", newActionError("upgrade", apierrors.NewConflict(gr, "test", errors.New("modified"))), ErrorClassTransient),
+		Entry("server timeout", apierrors.NewServerTimeout(gr, "get", 1), ErrorClassTransient),
+		Entry("throttled", apierrors.NewTooManyRequests("slow down", 1), ErrorClassTransient),
+		Entry("internal error", apierrors.NewInternalError(errors.New("etcd")), ErrorClassTransient),
+		Entry("values error caused by the API server", newReconcileError("values", ErrValuesFailed, apierrors.NewServiceUnavailable("unavailable")), ErrorClassTransient),
+		Entry("invalid values", newReconcileError("values", ErrValuesFailed, errors.New("invalid")), ErrorClassPermanent),
+		Entry("render error", newReconcileError("render", ErrRenderFailed, errors.New("template: bad")), ErrorClassPermanent),
+		Entry("action timeout", newActionError("install", context.DeadlineExceeded), ErrorClassDefault),
+		Entry("not found", apierrors.NewNotFound(gr, "test"), ErrorClassDefault),
+	)
+
+	It("should fail with a nil classifier or a non-positive delay", func() {
+		Expect(WithErrorClassifier(nil)(&Reconciler{})).NotTo(Succeed())
+		Expect(WithTransientErrorRequeueDelay(0)(&Reconciler{})).NotTo(Succeed())
+	})
+
+	It("should not change errors without a classifier", func() {
+		err := errors.New("failed")
+		res, resErr := (&Reconciler{}).requeueForErrorClass(ctrl.Result{}, err)
+		Expect(res).To(Equal(ctrl.Result{}))
+		Expect(resErr).To(BeIdenticalTo(err))
+	})
+
+	It("should requeue transient errors after the delay", func() {
+		r := &Reconciler{}
+		Expect(WithErrorClassifier(func(error) ErrorClass { return ErrorClassTransient })(r)).To(Succeed())
+		res, err := r.requeueForErrorClass(ctrl.Result{}, errors.New("failed"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res).To(Equal(ctrl.Result{RequeueAfter: DefaultTransientErrorRequeueDelay}))
+
+		Expect(WithTransientErrorRequeueDelay(time.Second)(r)).To(Succeed())
+		res, _ = r.requeueForErrorClass(ctrl.Result{}, errors.New("failed"))
+		Expect(res).To(Equal(ctrl.Result{RequeueAfter: time.Second}))
+	})
+
+	It("should return permanent errors as terminal errors", func() {
+		r := &Reconciler{}
+		Expect(WithErrorClassifier(DefaultErrorClassifier)(r)).To(Succeed())
+		cause := newReconcileError("render", ErrRenderFailed, errors.New("template: bad"))
+		_, err := r.requeueForErrorClass(ctrl.Result{}, cause)
+		Expect(errors.Is(err, reconcile.TerminalError(nil))).To(BeTrue())
+		Expect(errors.Is(err, ErrRenderFailed)).To(BeTrue())
+
+		_, again := r.requeueForErrorClass(ctrl.Result{}, err)
+		Expect(again).To(BeIdenticalTo(err))
+	})
+})
@@ -0,0 +1,197 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	helmclient "github.com/operator-framework/helm-operator-plugins/pkg/client"
+	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/conditions"
+	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/updater"
+)
+
+// AcknowledgeExternalModificationAnnotation is the annotation that
+// acknowledges a modification of the release of a CR that was not made by the
+// operator. Once acknowledged, the operator reconciles the release again and
+// removes the annotation.
+const AcknowledgeExternalModificationAnnotation = "helm.sdk.operatorframework.io/acknowledge-external-modification"
+
+// WithExternalModificationDetection is an Option that configures whether
+// revisions of a release that were not produced by the operator, e.g. by a
+// manual helm upgrade or helm rollback, are detected.
+//
+// The operator records a lock of every revision it produces in the
+// status.releaseLock field of the CR. If the current revision of the release
+// does not match the lock, the ExternalModificationDetected condition of the
+// CR is set and a Warning event is recorded. The condition is cleared once the
+// operator produces a new revision, or the modification is acknowledged with
+// AcknowledgeExternalModificationAnnotation.
+func WithExternalModificationDetection(detect bool) Option {
+	return func(r *Reconciler) error {
+		r.detectExternalModification = detect
+		return nil
+	}
+}
+
+// WithPauseOnExternalModification is an Option that configures whether a CR
+// whose release was modified externally is no longer reconciled until the
+// modification is acknowledged with AcknowledgeExternalModificationAnnotation.
+// Otherwise, the release is reconciled as usual, which reverts the external
+// modification. It implies WithExternalModificationDetection.
+func WithPauseOnExternalModification(pause bool) Option {
+	return func(r *Reconciler) error {
+		r.pauseOnExternalModification = pause
+		if pause {
+			r.detectExternalModification = true
+		}
+		return nil
+	}
+}
+
+// releaseLock returns the lock of rel, which identifies its revision, or an
+// empty string if rel is nil.
+func releaseLock(rel *release.Release) string {
+	if rel == nil {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s/%s/%d/%s", rel.Namespace, rel.Name, rel.Version, rel.Manifest)))
+	return hex.EncodeToString(sum[:])
+}
+
+// checkExternalModification compares rel, the current revision of the release
+// of obj, with the lock recorded in the status of obj. It returns true if rel
+// was modified externally and the reconciliation of obj is paused.
+func (r *Reconciler) checkExternalModification(u *updater.Updater, obj *unstructured.Unstructured, rel *release.Release, log logr.Logger) bool {
+	lock := updater.ReleaseLock(obj)
+	current := releaseLock(rel)
+	if rel == nil {
+		u.UpdateStatus(updater.EnsureCondition(conditions.ExternalModificationDetected(corev1.ConditionFalse, "", "")))
+		return false
+	}
+	if lock == "" || lock == current {
+		// Without a recorded lock, the current revision is adopted, e.g. when
+		// the detection was enabled for an existing release.
+		u.UpdateStatus(
+			updater.EnsureReleaseLock(current),
+			updater.EnsureCondition(conditions.ExternalModificationDetected(corev1.ConditionFalse, "", "")),
+		)
+		return false
+	}
+
+	if _, ok := obj.GetAnnotations()[AcknowledgeExternalModificationAnnotation]; ok {
+		log.Info("External modification of release acknowledged", "name", rel.Name, "version", rel.Version)
+		r.eventRecorder.Eventf(obj, "Normal", "ExternalModificationAcknowledged",
+			"Modification of release %q at version %d was acknowledged", rel.Name, rel.Version)
+		u.Update(updater.RemoveAnnotation(AcknowledgeExternalModificationAnnotation))
+		u.UpdateStatus(
+			updater.EnsureReleaseLock(current),
+			updater.EnsureCondition(conditions.ExternalModificationDetected(corev1.ConditionFalse, "", "")),
+		)
+		return false
+	}
+
+	msg := fmt.Sprintf("release %q was modified at version %d by something other than the operator", rel.Name, rel.Version)
+	if r.pauseOnExternalModification {
+		msg = fmt.Sprintf("%s; reconciliation is paused until the annotation %s is set", msg, AcknowledgeExternalModificationAnnotation)
+	}
+	log.Info("Release was modified externally", "name", rel.Name, "version", rel.Version, "paused", r.pauseOnExternalModification)
+	r.eventRecorder.Eventf(obj, "Warning", string(conditions.ReasonReleaseModifiedExternally), "Release %s", msg)
+	u.UpdateStatus(updater.EnsureCondition(conditions.ExternalModificationDetected(corev1.ConditionTrue, conditions.ReasonReleaseModifiedExternally, msg)))
+	return r.pauseOnExternalModification
+}
+
+// withReleaseLock returns an ActionInterface that records the lock of the
+// latest revision of a release in the status of the CR after each Helm action
+// of c that may produce a revision.
+func withReleaseLock(c helmclient.ActionInterface, u *updater.Updater) helmclient.ActionInterface {
+	return &lockingActionClient{ActionInterface: c, u: u}
+}
+
+// lockingActionClient is the ActionInterface returned by withReleaseLock.
+type lockingActionClient struct {
+	helmclient.ActionInterface
+	u *updater.Updater
+}
+
+// recordLock records the lock of the latest revision of the release with the
+// given name. The release is read again, since failed actions may produce
+// revisions, too.
+func (c *lockingActionClient) recordLock(name string) {
+	rel, err := c.ActionInterface.Get(name)
+	if err != nil && !errors.Is(err, driver.ErrReleaseNotFound) {
+		return
+	}
+	c.u.UpdateStatus(updater.EnsureReleaseLock(releaseLock(rel)))
+}
+
+func (c *lockingActionClient) Install(name, namespace string, chrt *chart.Chart, vals map[string]interface{}, opts ...helmclient.InstallOption) (*release.Release, error) {
+	defer c.recordLock(name)
+	return c.ActionInterface.Install(name, namespace, chrt, vals, opts...)
+}
+
+func (c *lockingActionClient) Upgrade(name, namespace string, chrt *chart.Chart, vals map[string]interface{}, opts ...helmclient.UpgradeOption) (*release.Release, error) {
+	defer c.recordLock(name)
+	return c.ActionInterface.Upgrade(name, namespace, chrt, vals, opts...)
+}
+
+func (c *lockingActionClient) Uninstall(name string, opts ...helmclient.UninstallOption) (*release.UninstallReleaseResponse, error) {
+	defer c.recordLock(name)
+	return c.ActionInterface.Uninstall(name, opts...)
+}
+
+func (c *lockingActionClient) MarkFailed(rel *release.Release, description string) error {
+	recoverer, ok := c.ActionInterface.(helmclient.PendingReleaseRecoverer)
+	if !ok {
+		return errors.New("action client cannot recover pending releases")
+	}
+	return recoverer.MarkFailed(rel, description)
+}
+
+func (c *lockingActionClient) Rollback(name string, opts ...helmclient.RollbackOption) error {
+	recoverer, ok := c.ActionInterface.(helmclient.PendingReleaseRecoverer)
+	if !ok {
+		return errors.New("action client cannot recover pending releases")
+	}
+	defer c.recordLock(name)
+	return recoverer.Rollback(name, opts...)
+}
+
+func (c *lockingActionClient) Test(name string, opts ...helmclient.TestOption) (*release.Release, error) {
+	tester, ok := c.ActionInterface.(helmclient.ChartTester)
+	if !ok {
+		return nil, errors.New("action client cannot run chart tests")
+	}
+	return tester.Test(name, opts...)
+}
+
+func (c *lockingActionClient) CleanupTests(rel *release.Release) error {
+	tester, ok := c.ActionInterface.(helmclient.ChartTester)
+	if !ok {
+		return errors.New("action client cannot run chart tests")
+	}
+	return tester.CleanupTests(rel)
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/release"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+
+	helmfake "github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/fake"
+	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/updater"
+)
+
+var _ = Describe("WithExternalModificationDetection", func() {
+	var (
+		r        *Reconciler
+		u        updater.Updater
+		recorder *record.FakeRecorder
+		obj      *unstructured.Unstructured
+		rel      *release.Release
+	)
+
+	BeforeEach(func() {
+		recorder = record.NewFakeRecorder(1)
+		r = &Reconciler{eventRecorder: recorder}
+		Expect(WithExternalModificationDetection(true)(r)).To(Succeed())
+		u = updater.New(nil)
+		rel = &release.Release{Name: "test", Namespace: "default", Version: 3, Manifest: "manifest"}
+		obj = &unstructured.Unstructured{Object: map[string]interface{}{
+			"status": map[string]interface{}{
+				"releaseLock": releaseLock(&release.Release{Name: "test", Namespace: "default", Version: 2, Manifest: "manifest"}),
+			},
+		}}
+	})
+
+	It("should enable the detection when pausing", func() {
+		r = &Reconciler{}
+		Expect(WithPauseOnExternalModification(true)(r)).To(Succeed())
+		Expect(r.detectExternalModification).To(BeTrue())
+		Expect(r.pauseOnExternalModification).To(BeTrue())
+	})
+
+	It("should compute a lock per revision", func() {
+		Expect(releaseLock(nil)).To(BeEmpty())
+		c := *rel
+		Expect(releaseLock(rel)).To(Equal(releaseLock(&c)))
+		Expect(releaseLock(rel)).NotTo(Equal(updater.ReleaseLock(obj)))
+	})
+
+	It("should adopt a release without a lock", func() {
+		obj.Object["status"] = map[string]interface{}{}
+		Expect(r.checkExternalModification(&u, obj, rel, logr.Discard())).To(BeFalse())
+		Expect(recorder.Events).NotTo(Receive())
+	})
+
+	It("should accept a release that matches the lock", func() {
+		rel.Version = 2
+		Expect(r.checkExternalModification(&u, obj, rel, logr.Discard())).To(BeFalse())
+		Expect(recorder.Events).NotTo(Receive())
+	})
+
+	It("should report a modified release without pausing", func() {
+		Expect(r.checkExternalModification(&u, obj, rel, logr.Discard())).To(BeFalse())
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning ReleaseModifiedExternally")))
+	})
+
+	It("should pause a modified release until acknowledged", func() {
+		Expect(WithPauseOnExternalModification(true)(r)).To(Succeed())
+		Expect(r.checkExternalModification(&u, obj, rel, logr.Discard())).To(BeTrue())
+		Expect(recorder.Events).To(Receive(Equal("Warning ReleaseModifiedExternally Release release \"test\" was modified at version 3 " +
+			"by something other than the operator; reconciliation is paused until the annotation " + AcknowledgeExternalModificationAnnotation + " is set")))
+
+		obj.SetAnnotations(map[string]string{AcknowledgeExternalModificationAnnotation: ""})
+		Expect(r.checkExternalModification(&u, obj, rel, logr.Discard())).To(BeFalse())
+		Expect(recorder.Events).To(Receive(Equal("Normal ExternalModificationAcknowledged Modification of release \"test\" at version 3 was acknowledged")))
+	})
+
+	It("should read the release again after actions", func() {
+		ac := helmfake.NewActionClient()
+		ac.HandleGet = func() (*release.Release, error) { return rel, nil }
+		ac.HandleUpgrade = func() (*release.Release, error) { return rel, nil }
+		c := withReleaseLock(&ac, &u)
+		_, err := c.Upgrade("test", "default", nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ac.Upgrades).To(HaveLen(1))
+		Expect(ac.Gets).To(HaveLen(1))
+	})
+})
@@ -0,0 +1,74 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"errors"
+
+	"helm.sh/helm/v3/pkg/release"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// hookJobDefaults are the defaults of the specs of hook Jobs.
+type hookJobDefaults struct {
+	activeDeadlineSeconds int64
+	backoffLimit          int32
+}
+
+// WithHookJobDefaults is an Option that bounds the execution of the Jobs of
+// chart hooks, so that a stuck hook fails the release action, and the
+// reconciliation with a ReleaseFailed condition, instead of blocking it
+// indefinitely. The activeDeadlineSeconds and backoffLimit are set on the
+// specs of hook Jobs that do not set them; Jobs that set them are left
+// untouched, as are Jobs that are not hooks.
+//
+// Helm does not run post-renderers on hooks, so the defaults are applied when
+// the objects of hooks are built. This option only has an effect on the
+// default ActionClientGetter; it is ignored if WithActionClientGetter is used.
+func WithHookJobDefaults(activeDeadlineSeconds int64, backoffLimit int32) Option {
+	return func(r *Reconciler) error {
+		if activeDeadlineSeconds <= 0 {
+			return errors.New("hook job active deadline seconds must be positive")
+		}
+		if backoffLimit < 0 {
+			return errors.New("hook job backoff limit must not be negative")
+		}
+		r.hookJobDefaults = &hookJobDefaults{activeDeadlineSeconds: activeDeadlineSeconds, backoffLimit: backoffLimit}
+		return nil
+	}
+}
+
+// apply sets the defaults on obj if it is a hook Job.
+func (d hookJobDefaults) apply(obj *unstructured.Unstructured) error {
+	if obj.GetKind() != "Job" || obj.GroupVersionKind().Group != "batch" {
+		return nil
+	}
+	if _, ok := obj.GetAnnotations()[release.HookAnnotation]; !ok {
+		return nil
+	}
+	if _, found, _ := unstructured.NestedFieldNoCopy(obj.Object, "spec", "activeDeadlineSeconds"); !found {
+		if err := unstructured.SetNestedField(obj.Object, d.activeDeadlineSeconds, "spec", "activeDeadlineSeconds"); err != nil {
+			return err
+		}
+	}
+	if _, found, _ := unstructured.NestedFieldNoCopy(obj.Object, "spec", "backoffLimit"); !found {
+		if err := unstructured.SetNestedField(obj.Object, int64(d.backoffLimit), "spec", "backoffLimit"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
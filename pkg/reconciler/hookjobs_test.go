@@ -0,0 +1,74 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var _ = Describe("WithHookJobDefaults", func() {
+	var (
+		r   *Reconciler
+		job *unstructured.Unstructured
+	)
+
+	BeforeEach(func() {
+		r = &Reconciler{}
+		job = &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "batch/v1",
+			"kind":       "Job",
+			"metadata": map[string]interface{}{
+				"name":        "migrate",
+				"annotations": map[string]interface{}{"helm.sh/hook": "pre-upgrade"},
+			},
+			"spec": map[string]interface{}{},
+		}}
+	})
+
+	It("should fail with invalid limits", func() {
+		Expect(WithHookJobDefaults(0, 1)(r)).NotTo(Succeed())
+		Expect(WithHookJobDefaults(60, -1)(r)).NotTo(Succeed())
+	})
+
+	It("should set the limits on hook jobs", func() {
+		Expect(WithHookJobDefaults(600, 2)(r)).To(Succeed())
+		Expect(r.hookJobDefaults.apply(job)).To(Succeed())
+		Expect(job.Object["spec"]).To(Equal(map[string]interface{}{
+			"activeDeadlineSeconds": int64(600),
+			"backoffLimit":          int64(2),
+		}))
+	})
+
+	It("should not override limits set by the chart", func() {
+		Expect(WithHookJobDefaults(600, 2)(r)).To(Succeed())
+		job.Object["spec"] = map[string]interface{}{"backoffLimit": int64(0)}
+		Expect(r.hookJobDefaults.apply(job)).To(Succeed())
+		Expect(job.Object["spec"]).To(Equal(map[string]interface{}{
+			"activeDeadlineSeconds": int64(600),
+			"backoffLimit":          int64(0),
+		}))
+	})
+
+	It("should not change jobs that are not hooks", func() {
+		Expect(WithHookJobDefaults(600, 2)(r)).To(Succeed())
+		job.SetAnnotations(nil)
+		Expect(r.hookJobDefaults.apply(job)).To(Succeed())
+		Expect(job.Object["spec"]).To(BeEmpty())
+	})
+})
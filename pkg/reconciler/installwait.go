@@ -0,0 +1,77 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"errors"
+	"time"
+
+	"helm.sh/helm/v3/pkg/action"
+
+	helmclient "github.com/operator-framework/helm-operator-plugins/pkg/client"
+)
+
+// WithInstallWait is an Option that configures the reconciler to install and
+// upgrade releases with Helm's wait semantics: the install or upgrade only
+// succeeds once all resources of the release are ready, i.e. pods, PVCs,
+// services and the minimum number of pods of deployments, stateful sets and
+// replica sets, or fails after timeout. The Deployed condition of a CR is
+// therefore only set once its release is ready, and a release that does not
+// become ready in time sets the ReleaseFailed and Irreconcilable conditions
+// like any other failed install or upgrade.
+//
+// The wait counts against the deadline configured with WithActionTimeout, so
+// New fails unless the action timeout is longer than timeout, and Helm's wait
+// ends first. If the TimeoutAnnotation of a CR sets a shorter deadline, the
+// deadline wins: the CR gets the ActionTimeout reason, and the release stays
+// locked until Helm's wait returns. Unlike WithReadinessCheck, the reconciler
+// blocks while it waits.
+func WithInstallWait(timeout time.Duration) Option {
+	return func(r *Reconciler) error {
+		if timeout <= 0 {
+			return errors.New("install wait timeout must be positive")
+		}
+		r.installWaitTimeout = timeout
+		return nil
+	}
+}
+
+// installWaitInstallOptions returns the install options that wait for the
+// resources of the release to become ready, if configured.
+func (r *Reconciler) installWaitInstallOptions() []helmclient.InstallOption {
+	if r.installWaitTimeout == 0 {
+		return nil
+	}
+	return []helmclient.InstallOption{func(i *action.Install) error {
+		i.Wait = true
+		i.Timeout = r.installWaitTimeout
+		return nil
+	}}
+}
+
+// installWaitUpgradeOptions returns the upgrade options that wait for the
+// resources of the release to become ready, if configured.
+func (r *Reconciler) installWaitUpgradeOptions() []helmclient.UpgradeOption {
+	if r.installWaitTimeout == 0 {
+		return nil
+	}
+	return []helmclient.UpgradeOption{func(u *action.Upgrade) error {
+		u.Wait = true
+		u.Timeout = r.installWaitTimeout
+		return nil
+	}}
+}
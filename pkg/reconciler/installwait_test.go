@@ -0,0 +1,186 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/operator-framework/helm-operator-plugins/pkg/internal/testutil"
+	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/conditions"
+	helmfake "github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/fake"
+	internalvalues "github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/values"
+)
+
+var _ = Describe("WithInstallWait", func() {
+	It("should fail without a positive timeout", func() {
+		Expect(WithInstallWait(0)(&Reconciler{})).NotTo(Succeed())
+		Expect(WithInstallWait(-time.Second)(&Reconciler{})).NotTo(Succeed())
+	})
+
+	It("should not wait by default", func() {
+		r := &Reconciler{}
+		Expect(r.installWaitInstallOptions()).To(BeEmpty())
+		Expect(r.installWaitUpgradeOptions()).To(BeEmpty())
+	})
+
+	It("should wait for installs and upgrades", func() {
+		r := &Reconciler{}
+		Expect(WithInstallWait(2 * time.Minute)(r)).To(Succeed())
+
+		i := &action.Install{}
+		for _, opt := range r.installWaitInstallOptions() {
+			Expect(opt(i)).To(Succeed())
+		}
+		Expect(i.Wait).To(BeTrue())
+		Expect(i.Timeout).To(Equal(2 * time.Minute))
+
+		u := &action.Upgrade{}
+		for _, opt := range r.installWaitUpgradeOptions() {
+			Expect(opt(u)).To(Succeed())
+		}
+		Expect(u.Wait).To(BeTrue())
+		Expect(u.Timeout).To(Equal(2 * time.Minute))
+	})
+})
+
+var _ = Describe("Reconcile with WithInstallWait", func() {
+	// Helm reports resources that do not become ready in time with this error.
+	const waitError = "timed out waiting for the condition"
+
+	var (
+		r        *Reconciler
+		ac       helmfake.ActionClient
+		cl       client.Client
+		recorder *record.FakeRecorder
+		obj      *unstructured.Unstructured
+		objKey   types.NamespacedName
+	)
+
+	BeforeEach(func() {
+		obj = testutil.BuildTestCR(gvk)
+		// The fake client can only copy JSON-compatible values.
+		obj.Object["spec"] = map[string]interface{}{"replicas": int64(2)}
+		objKey = types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+		cl = fake.NewClientBuilder().WithObjects(obj).WithStatusSubresource(obj).Build()
+		recorder = record.NewFakeRecorder(10)
+		ac = helmfake.NewActionClient()
+
+		var err error
+		r, err = New(WithGroupVersionKind(gvk), WithChart(chrt), WithInstallWait(time.Minute), WithLog(logr.Discard()))
+		Expect(err).NotTo(HaveOccurred())
+		r.client = cl
+		r.apiReader = cl
+		r.eventRecorder = recorder
+		r.actionClientGetter = helmfake.NewActionClientGetter(&ac, nil)
+		r.valueTranslator = internalvalues.DefaultTranslator
+		r.valueMapper = internalvalues.DefaultMapper
+	})
+
+	reconcileAndGetStatus := func() (*objStatus, error) {
+		_, reconcileErr := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: objKey})
+		Expect(cl.Get(context.Background(), objKey, obj)).To(Succeed())
+		st := &objStatus{}
+		Expect(runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, st)).To(Succeed())
+		return st, reconcileErr
+	}
+
+	It("should not mark the CR deployed when the install wait fails", func() {
+		ac.HandleGet = func() (*release.Release, error) { return nil, driver.ErrReleaseNotFound }
+		ac.HandleInstall = func() (*release.Release, error) { return nil, errors.New(waitError) }
+
+		st, err := reconcileAndGetStatus()
+		Expect(err).To(MatchError(ContainSubstring(waitError)))
+
+		Expect(ac.Installs).To(HaveLen(1))
+		install := &action.Install{}
+		for _, opt := range ac.Installs[0].Opts {
+			Expect(opt(install)).To(Succeed())
+		}
+		Expect(install.Wait).To(BeTrue())
+		Expect(install.Timeout).To(Equal(time.Minute))
+
+		Expect(st.Status.Conditions.IsTrueFor(conditions.TypeDeployed)).To(BeFalse())
+		Expect(st.Status.Conditions.IsTrueFor(conditions.TypeReleaseFailed)).To(BeTrue())
+		c := st.Status.Conditions.GetCondition(conditions.TypeReleaseFailed)
+		Expect(c.Reason).To(Equal(conditions.ReasonInstallError))
+		Expect(c.Message).To(ContainSubstring(waitError))
+		Expect(st.Status.Conditions.IsTrueFor(conditions.TypeIrreconcilable)).To(BeTrue())
+	})
+
+	It("should keep reporting the previous release when the upgrade wait fails", func() {
+		const (
+			deployedManifest = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: v1\n"
+			upgradedManifest = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: v2\n"
+		)
+		deployed := &release.Release{
+			Name:     obj.GetName(),
+			Version:  1,
+			Info:     &release.Info{Status: release.StatusDeployed},
+			Chart:    &chrt,
+			Manifest: deployedManifest,
+		}
+		ac.HandleGet = func() (*release.Release, error) { return deployed, nil }
+		ac.HandleUpgrade = func() (*release.Release, error) {
+			upgrade := &action.Upgrade{}
+			for _, opt := range ac.Upgrades[len(ac.Upgrades)-1].Opts {
+				if err := opt(upgrade); err != nil {
+					return nil, err
+				}
+			}
+			if upgrade.DryRun {
+				return &release.Release{Name: obj.GetName(), Version: 2, Info: &release.Info{}, Manifest: upgradedManifest}, nil
+			}
+			Expect(upgrade.Wait).To(BeTrue())
+			Expect(upgrade.Timeout).To(Equal(time.Minute))
+			return nil, errors.New(waitError)
+		}
+
+		st, err := reconcileAndGetStatus()
+		Expect(err).To(MatchError(ContainSubstring(waitError)))
+		// The previous revision stays deployed, so the CR does not report
+		// the upgraded release.
+		Expect(st.Status.DeployedRelease).NotTo(BeNil())
+		Expect(st.Status.DeployedRelease.Manifest).To(Equal(deployedManifest))
+		c := st.Status.Conditions.GetCondition(conditions.TypeReleaseFailed)
+		Expect(c).NotTo(BeNil())
+		Expect(c.Reason).To(Equal(conditions.ReasonUpgradeError))
+		Expect(c.Message).To(ContainSubstring(waitError))
+	})
+
+	It("should require an action timeout longer than the wait", func() {
+		_, err := New(WithGroupVersionKind(gvk), WithChart(chrt), WithInstallWait(time.Minute), WithActionTimeout(time.Minute))
+		Expect(err).To(MatchError(ContainSubstring("must be longer than the install wait timeout")))
+		_, err = New(WithGroupVersionKind(gvk), WithChart(chrt), WithInstallWait(time.Minute), WithActionTimeout(2*time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
@@ -0,0 +1,94 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package breaker
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Breaker counts consecutive reconcile failures per object. Once the number
+// of consecutive failures of an object reaches the threshold, its circuit is
+// open until the generation of the object changes or the failures are reset.
+type Breaker struct {
+	threshold int
+
+	mu     sync.Mutex
+	states map[types.NamespacedName]state
+}
+
+type state struct {
+	failures   int
+	generation int64
+}
+
+func New(threshold int) *Breaker {
+	return &Breaker{
+		threshold: threshold,
+		states:    map[types.NamespacedName]state{},
+	}
+}
+
+// IsOpen reports whether the circuit of the object identified by key is open
+// at the given generation. If the generation differs from the generation of
+// the last recorded failure, the failures are reset.
+func (b *Breaker) IsOpen(key types.NamespacedName, generation int64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s, ok := b.states[key]
+	if !ok {
+		return false
+	}
+	if s.generation != generation {
+		delete(b.states, key)
+		return false
+	}
+	return s.failures >= b.threshold
+}
+
+// RecordFailure records a failed reconciliation of the object identified by
+// key at the given generation and reports whether its circuit is open
+// afterwards.
+func (b *Breaker) RecordFailure(key types.NamespacedName, generation int64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := b.states[key]
+	if s.generation != generation {
+		s = state{generation: generation}
+	}
+	s.failures++
+	b.states[key] = s
+	return s.failures >= b.threshold
+}
+
+// Failures returns the number of consecutive failures recorded for the object
+// identified by key.
+func (b *Breaker) Failures(key types.NamespacedName) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.states[key].failures
+}
+
+// Reset forgets all failures of the object identified by key.
+func (b *Breaker) Reset(key types.NamespacedName) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.states, key)
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package breaker
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ = Describe("Breaker", func() {
+	var (
+		b   *Breaker
+		key = types.NamespacedName{Namespace: "ns", Name: "test"}
+	)
+
+	BeforeEach(func() {
+		b = New(2)
+	})
+
+	It("should be closed without failures", func() {
+		Expect(b.IsOpen(key, 1)).To(BeFalse())
+		Expect(b.Failures(key)).To(Equal(0))
+	})
+
+	It("should open once the threshold is reached", func() {
+		Expect(b.RecordFailure(key, 1)).To(BeFalse())
+		Expect(b.IsOpen(key, 1)).To(BeFalse())
+		Expect(b.RecordFailure(key, 1)).To(BeTrue())
+		Expect(b.IsOpen(key, 1)).To(BeTrue())
+		Expect(b.Failures(key)).To(Equal(2))
+	})
+
+	It("should track objects independently", func() {
+		other := types.NamespacedName{Namespace: "ns", Name: "other"}
+		b.RecordFailure(key, 1)
+		b.RecordFailure(key, 1)
+		Expect(b.IsOpen(key, 1)).To(BeTrue())
+		Expect(b.IsOpen(other, 1)).To(BeFalse())
+	})
+
+	It("should close when the generation changes", func() {
+		b.RecordFailure(key, 1)
+		b.RecordFailure(key, 1)
+		Expect(b.IsOpen(key, 2)).To(BeFalse())
+		Expect(b.Failures(key)).To(Equal(0))
+	})
+
+	It("should restart counting when a failure is recorded at a new generation", func() {
+		b.RecordFailure(key, 1)
+		Expect(b.RecordFailure(key, 2)).To(BeFalse())
+		Expect(b.Failures(key)).To(Equal(1))
+	})
+
+	It("should close when reset", func() {
+		b.RecordFailure(key, 1)
+		b.RecordFailure(key, 1)
+		b.Reset(key)
+		Expect(b.IsOpen(key, 1)).To(BeFalse())
+		Expect(b.Failures(key)).To(Equal(0))
+	})
+})
@@ -25,10 +25,24 @@ import (
 )
 
 const (
-	TypeInitialized    = "Initialized"
-	TypeDeployed       = "Deployed"
-	TypeReleaseFailed  = "ReleaseFailed"
-	TypeIrreconcilable = "Irreconcilable"
+	TypeInitialized          = "Initialized"
+	TypeDeployed             = "Deployed"
+	TypeReleaseFailed        = "ReleaseFailed"
+	TypeIrreconcilable       = "Irreconcilable"
+	TypeUpgradeAvailable     = "UpgradeAvailable"
+	TypeCircuitOpen          = "CircuitOpen"
+	TypeWaitingForDependency = "WaitingForDependency"
+	TypeWaitingForReadiness  = "WaitingForReadiness"
+	TypePolicyViolation      = "PolicyViolation"
+	TypeDisallowedResource   = "DisallowedResource"
+	TypeManifestTooLarge     = "ManifestTooLarge"
+	TypeOutOfSync            = "OutOfSync"
+	TypeChartTestsPassed     = "ChartTestsPassed"
+
+	TypeExternalModificationDetected = "ExternalModificationDetected"
+	TypeValuesTypeMismatch           = "ValuesTypeMismatch"
+	TypeNamespaceTerminating         = "NamespaceTerminating"
+	TypeProvenanceVerificationFailed = "ProvenanceVerificationFailed"
 
 	ReasonInstallSuccessful   = status.ConditionReason("InstallSuccessful")
 	ReasonUpgradeSuccessful   = status.ConditionReason("UpgradeSuccessful")
@@ -36,11 +50,57 @@ const (
 
 	ReasonErrorGettingClient       = status.ConditionReason("ErrorGettingClient")
 	ReasonErrorGettingValues       = status.ConditionReason("ErrorGettingValues")
+	ReasonUnknownEnvironment       = status.ConditionReason("UnknownEnvironment")
+	ReasonInvalidValuesPatch       = status.ConditionReason("InvalidValuesPatch")
+	ReasonErrorPreprocessingCR     = status.ConditionReason("ErrorPreprocessingCR")
 	ReasonErrorGettingReleaseState = status.ConditionReason("ErrorGettingReleaseState")
 	ReasonInstallError             = status.ConditionReason("InstallError")
 	ReasonUpgradeError             = status.ConditionReason("UpgradeError")
 	ReasonReconcileError           = status.ConditionReason("ReconcileError")
+	ReasonActionTimeout            = status.ConditionReason("ActionTimeout")
+	ReasonInvalidTimeout           = status.ConditionReason("InvalidTimeout")
+	ReasonPermissionDenied         = status.ConditionReason("PermissionDenied")
 	ReasonUninstallError           = status.ConditionReason("UninstallError")
+	ReasonErrorExportingManifests  = status.ConditionReason("ErrorExportingManifests")
+	ReasonErrorApplyingCRDs        = status.ConditionReason("ErrorApplyingCRDs")
+	ReasonErrorRenderingManifests  = status.ConditionReason("ErrorRenderingManifests")
+	ReasonErrorApplyingWaves       = status.ConditionReason("ErrorApplyingWaves")
+	ReasonErrorAdoptingResources   = status.ConditionReason("ErrorAdoptingResources")
+
+	ReasonNewerChartVersion    = status.ConditionReason("NewerChartVersion")
+	ReasonChartUpToDate        = status.ConditionReason("ChartUpToDate")
+	ReasonErrorCheckingUpgrade = status.ConditionReason("ErrorCheckingUpgrade")
+
+	ReasonFailureThresholdReached = status.ConditionReason("FailureThresholdReached")
+
+	ReasonDependencyNotDeployed    = status.ConditionReason("DependencyNotDeployed")
+	ReasonErrorGettingDependencies = status.ConditionReason("ErrorGettingDependencies")
+
+	ReasonReleaseNotReady        = status.ConditionReason("ReleaseNotReady")
+	ReasonErrorCheckingReadiness = status.ConditionReason("ErrorCheckingReadiness")
+	ReasonApplyWaveNotReady      = status.ConditionReason("ApplyWaveNotReady")
+
+	ReasonValidationFailed = status.ConditionReason("ValidationFailed")
+	ReasonKindNotAllowed   = status.ConditionReason("KindNotAllowed")
+
+	ReasonManifestSizeExceeded = status.ConditionReason("ManifestSizeExceeded")
+
+	ReasonProvenanceVerificationFailed = status.ConditionReason("ProvenanceVerificationFailed")
+
+	ReasonReleaseNotInstalled = status.ConditionReason("ReleaseNotInstalled")
+	ReasonReleaseOutdated     = status.ConditionReason("ReleaseOutdated")
+	ReasonReleasePending      = status.ConditionReason("ReleasePending")
+
+	ReasonChartTestsSucceeded    = status.ConditionReason("ChartTestsSucceeded")
+	ReasonChartTestsFailed       = status.ConditionReason("ChartTestsFailed")
+	ReasonNoChartTests           = status.ConditionReason("NoChartTests")
+	ReasonErrorRunningChartTests = status.ConditionReason("ErrorRunningChartTests")
+
+	ReasonReleaseModifiedExternally = status.ConditionReason("ReleaseModifiedExternally")
+
+	ReasonInvalidValueType = status.ConditionReason("InvalidValueType")
+
+	ReasonNamespaceTerminating = status.ConditionReason("NamespaceTerminating")
 )
 
 func Initialized(stat corev1.ConditionStatus, reason status.ConditionReason, message interface{}) status.Condition {
@@ -59,6 +119,58 @@ func Irreconcilable(stat corev1.ConditionStatus, reason status.ConditionReason,
 	return newCondition(TypeIrreconcilable, stat, reason, message)
 }
 
+func UpgradeAvailable(stat corev1.ConditionStatus, reason status.ConditionReason, message interface{}) status.Condition {
+	return newCondition(TypeUpgradeAvailable, stat, reason, message)
+}
+
+func CircuitOpen(stat corev1.ConditionStatus, reason status.ConditionReason, message interface{}) status.Condition {
+	return newCondition(TypeCircuitOpen, stat, reason, message)
+}
+
+func WaitingForDependency(stat corev1.ConditionStatus, reason status.ConditionReason, message interface{}) status.Condition {
+	return newCondition(TypeWaitingForDependency, stat, reason, message)
+}
+
+func WaitingForReadiness(stat corev1.ConditionStatus, reason status.ConditionReason, message interface{}) status.Condition {
+	return newCondition(TypeWaitingForReadiness, stat, reason, message)
+}
+
+func PolicyViolation(stat corev1.ConditionStatus, reason status.ConditionReason, message interface{}) status.Condition {
+	return newCondition(TypePolicyViolation, stat, reason, message)
+}
+
+func DisallowedResource(stat corev1.ConditionStatus, reason status.ConditionReason, message interface{}) status.Condition {
+	return newCondition(TypeDisallowedResource, stat, reason, message)
+}
+
+func ManifestTooLarge(stat corev1.ConditionStatus, reason status.ConditionReason, message interface{}) status.Condition {
+	return newCondition(TypeManifestTooLarge, stat, reason, message)
+}
+
+func OutOfSync(stat corev1.ConditionStatus, reason status.ConditionReason, message interface{}) status.Condition {
+	return newCondition(TypeOutOfSync, stat, reason, message)
+}
+
+func ChartTestsPassed(stat corev1.ConditionStatus, reason status.ConditionReason, message interface{}) status.Condition {
+	return newCondition(TypeChartTestsPassed, stat, reason, message)
+}
+
+func ExternalModificationDetected(stat corev1.ConditionStatus, reason status.ConditionReason, message interface{}) status.Condition {
+	return newCondition(TypeExternalModificationDetected, stat, reason, message)
+}
+
+func ValuesTypeMismatch(stat corev1.ConditionStatus, reason status.ConditionReason, message interface{}) status.Condition {
+	return newCondition(TypeValuesTypeMismatch, stat, reason, message)
+}
+
+func NamespaceTerminating(stat corev1.ConditionStatus, reason status.ConditionReason, message interface{}) status.Condition {
+	return newCondition(TypeNamespaceTerminating, stat, reason, message)
+}
+
+func ProvenanceVerificationFailed(stat corev1.ConditionStatus, reason status.ConditionReason, message interface{}) status.Condition {
+	return newCondition(TypeProvenanceVerificationFailed, stat, reason, message)
+}
+
 func newCondition(t status.ConditionType, s corev1.ConditionStatus, r status.ConditionReason, m interface{}) status.Condition {
 	message := fmt.Sprintf("%s", m)
 	return status.Condition{
@@ -77,4 +77,148 @@ var _ = Describe("Conditions", func() {
 			Expect(Irreconcilable(e.Status, e.Reason, err)).To(Equal(e))
 		})
 	})
+
+	var _ = Describe("UpgradeAvailable", func() {
+		It("should return an UpgradeAvailable condition with the correct status, reason, and message", func() {
+			e := status.Condition{
+				Type:    TypeUpgradeAvailable,
+				Status:  corev1.ConditionTrue,
+				Reason:  ReasonNewerChartVersion,
+				Message: "message",
+			}
+			Expect(UpgradeAvailable(e.Status, e.Reason, e.Message)).To(Equal(e))
+		})
+	})
+	var _ = Describe("CircuitOpen", func() {
+		It("should return a CircuitOpen condition with the correct status, reason, and message", func() {
+			e := status.Condition{
+				Type:    TypeCircuitOpen,
+				Status:  corev1.ConditionTrue,
+				Reason:  ReasonFailureThresholdReached,
+				Message: "message",
+			}
+			Expect(CircuitOpen(e.Status, e.Reason, e.Message)).To(Equal(e))
+		})
+	})
+	var _ = Describe("WaitingForDependency", func() {
+		It("should return a WaitingForDependency condition with the correct status, reason, and message", func() {
+			e := status.Condition{
+				Type:    TypeWaitingForDependency,
+				Status:  corev1.ConditionTrue,
+				Reason:  ReasonDependencyNotDeployed,
+				Message: "message",
+			}
+			Expect(WaitingForDependency(e.Status, e.Reason, e.Message)).To(Equal(e))
+		})
+	})
+	var _ = Describe("WaitingForReadiness", func() {
+		It("should return a WaitingForReadiness condition with the correct status, reason, and message", func() {
+			e := status.Condition{
+				Type:    TypeWaitingForReadiness,
+				Status:  corev1.ConditionTrue,
+				Reason:  ReasonReleaseNotReady,
+				Message: "message",
+			}
+			Expect(WaitingForReadiness(e.Status, e.Reason, e.Message)).To(Equal(e))
+		})
+	})
+	var _ = Describe("PolicyViolation", func() {
+		It("should return a PolicyViolation condition with the correct status, reason, and message", func() {
+			e := status.Condition{
+				Type:    TypePolicyViolation,
+				Status:  corev1.ConditionTrue,
+				Reason:  ReasonValidationFailed,
+				Message: "message",
+			}
+			Expect(PolicyViolation(e.Status, e.Reason, e.Message)).To(Equal(e))
+		})
+	})
+	var _ = Describe("DisallowedResource", func() {
+		It("should return a DisallowedResource condition with the correct status, reason, and message", func() {
+			e := status.Condition{
+				Type:    TypeDisallowedResource,
+				Status:  corev1.ConditionTrue,
+				Reason:  ReasonKindNotAllowed,
+				Message: "message",
+			}
+			Expect(DisallowedResource(e.Status, e.Reason, e.Message)).To(Equal(e))
+		})
+	})
+	var _ = Describe("ManifestTooLarge", func() {
+		It("should return a ManifestTooLarge condition with the correct status, reason, and message", func() {
+			e := status.Condition{
+				Type:    TypeManifestTooLarge,
+				Status:  corev1.ConditionTrue,
+				Reason:  ReasonManifestSizeExceeded,
+				Message: "message",
+			}
+			Expect(ManifestTooLarge(e.Status, e.Reason, e.Message)).To(Equal(e))
+		})
+	})
+	var _ = Describe("OutOfSync", func() {
+		It("should return an OutOfSync condition with the correct status, reason, and message", func() {
+			e := status.Condition{
+				Type:    TypeOutOfSync,
+				Status:  corev1.ConditionTrue,
+				Reason:  ReasonReleaseOutdated,
+				Message: "message",
+			}
+			Expect(OutOfSync(e.Status, e.Reason, e.Message)).To(Equal(e))
+		})
+	})
+	var _ = Describe("ChartTestsPassed", func() {
+		It("should return a ChartTestsPassed condition with the correct status, reason, and message", func() {
+			e := status.Condition{
+				Type:    TypeChartTestsPassed,
+				Status:  corev1.ConditionFalse,
+				Reason:  ReasonChartTestsFailed,
+				Message: "message",
+			}
+			Expect(ChartTestsPassed(e.Status, e.Reason, e.Message)).To(Equal(e))
+		})
+	})
+	var _ = Describe("ExternalModificationDetected", func() {
+		It("should return an ExternalModificationDetected condition with the correct status, reason, and message", func() {
+			e := status.Condition{
+				Type:    TypeExternalModificationDetected,
+				Status:  corev1.ConditionTrue,
+				Reason:  ReasonReleaseModifiedExternally,
+				Message: "message",
+			}
+			Expect(ExternalModificationDetected(e.Status, e.Reason, e.Message)).To(Equal(e))
+		})
+	})
+	var _ = Describe("ValuesTypeMismatch", func() {
+		It("should return a ValuesTypeMismatch condition with the correct status, reason, and message", func() {
+			e := status.Condition{
+				Type:    TypeValuesTypeMismatch,
+				Status:  corev1.ConditionTrue,
+				Reason:  ReasonInvalidValueType,
+				Message: "message",
+			}
+			Expect(ValuesTypeMismatch(e.Status, e.Reason, e.Message)).To(Equal(e))
+		})
+	})
+	var _ = Describe("NamespaceTerminating", func() {
+		It("should return a NamespaceTerminating condition with the correct status, reason, and message", func() {
+			e := status.Condition{
+				Type:    TypeNamespaceTerminating,
+				Status:  corev1.ConditionTrue,
+				Reason:  ReasonNamespaceTerminating,
+				Message: "message",
+			}
+			Expect(NamespaceTerminating(e.Status, e.Reason, e.Message)).To(Equal(e))
+		})
+	})
+	var _ = Describe("ProvenanceVerificationFailed", func() {
+		It("should return a ProvenanceVerificationFailed condition with the correct status, reason, and message", func() {
+			e := status.Condition{
+				Type:    TypeProvenanceVerificationFailed,
+				Status:  corev1.ConditionTrue,
+				Reason:  ReasonProvenanceVerificationFailed,
+				Message: "message",
+			}
+			Expect(ProvenanceVerificationFailed(e.Status, e.Reason, e.Message)).To(Equal(e))
+		})
+	})
 })
@@ -0,0 +1,78 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package debounce provides an event handler that collapses bursts of events
+// into a single reconcile request per object.
+package debounce
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+)
+
+// Handler wraps h so that the requests it enqueues are added to the queue
+// only after window has passed. Requests for the same object that are
+// enqueued while an earlier request is still waiting are dropped by the
+// queue, so that all events within the window result in a single reconcile.
+//
+// If window is not positive, h is returned unchanged.
+func Handler(h handler.EventHandler, window time.Duration) handler.EventHandler {
+	if window <= 0 {
+		return h
+	}
+	return &debounceHandler{handler: h, window: window}
+}
+
+type debounceHandler struct {
+	handler handler.EventHandler
+	window  time.Duration
+}
+
+func (d *debounceHandler) Create(ctx context.Context, evt event.CreateEvent, q workqueue.RateLimitingInterface) {
+	d.handler.Create(ctx, evt, d.queue(q))
+}
+
+func (d *debounceHandler) Update(ctx context.Context, evt event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	d.handler.Update(ctx, evt, d.queue(q))
+}
+
+func (d *debounceHandler) Delete(ctx context.Context, evt event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	d.handler.Delete(ctx, evt, d.queue(q))
+}
+
+func (d *debounceHandler) Generic(ctx context.Context, evt event.GenericEvent, q workqueue.RateLimitingInterface) {
+	d.handler.Generic(ctx, evt, d.queue(q))
+}
+
+func (d *debounceHandler) queue(q workqueue.RateLimitingInterface) workqueue.RateLimitingInterface {
+	return &delayingQueue{RateLimitingInterface: q, window: d.window}
+}
+
+// delayingQueue turns every Add into an AddAfter with the debounce window.
+// The delaying queue keeps at most one waiting entry per item, which is what
+// collapses repeated requests.
+type delayingQueue struct {
+	workqueue.RateLimitingInterface
+	window time.Duration
+}
+
+func (q *delayingQueue) Add(item interface{}) {
+	q.RateLimitingInterface.AddAfter(item, q.window)
+}
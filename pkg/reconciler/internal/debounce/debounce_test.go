@@ -0,0 +1,61 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debounce
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+)
+
+var _ = Describe("Handler", func() {
+	var (
+		q   workqueue.RateLimitingInterface
+		obj *corev1.ConfigMap
+	)
+
+	BeforeEach(func() {
+		q = workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+		obj = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "values"}}
+	})
+
+	AfterEach(func() {
+		q.ShutDown()
+	})
+
+	It("should return the handler unchanged without a window", func() {
+		h := &handler.EnqueueRequestForObject{}
+		Expect(Handler(h, 0)).To(BeIdenticalTo(h))
+	})
+
+	It("should collapse events within the window into a single request", func() {
+		h := Handler(&handler.EnqueueRequestForObject{}, 100*time.Millisecond)
+		for i := 0; i < 5; i++ {
+			h.Update(context.Background(), event.UpdateEvent{ObjectOld: obj, ObjectNew: obj}, q)
+		}
+		Expect(q.Len()).To(Equal(0))
+		Eventually(q.Len).Should(Equal(1))
+		Consistently(q.Len, 200*time.Millisecond).Should(Equal(1))
+	})
+})
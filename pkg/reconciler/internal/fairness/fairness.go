@@ -0,0 +1,119 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fairness shares a limited number of workers between several
+// controllers.
+package fairness
+
+import (
+	"context"
+	"sync"
+)
+
+// Scheduler limits the number of reconciliations that run at the same time
+// across several controllers. When all slots are in use, a freed slot is
+// handed to the waiting controllers round-robin, so that a burst of
+// reconciliations of one controller does not starve the others.
+type Scheduler struct {
+	mu      sync.Mutex
+	free    int
+	order   []string
+	next    int
+	waiters map[string][]chan struct{}
+}
+
+// New returns a Scheduler with the given number of slots.
+func New(slots int) *Scheduler {
+	return &Scheduler{free: slots, waiters: map[string][]chan struct{}{}}
+}
+
+// Acquire waits for a free slot for the controller identified by key and
+// returns a function that releases it. It returns an error if ctx is done
+// before a slot is free.
+func (s *Scheduler) Acquire(ctx context.Context, key string) (func(), error) {
+	s.mu.Lock()
+	if s.free > 0 && s.waiting() == 0 {
+		s.free--
+		s.mu.Unlock()
+		return s.releaseFunc(), nil
+	}
+	granted := make(chan struct{}, 1)
+	if _, ok := s.waiters[key]; !ok {
+		s.order = append(s.order, key)
+	}
+	s.waiters[key] = append(s.waiters[key], granted)
+	s.mu.Unlock()
+
+	select {
+	case <-granted:
+		return s.releaseFunc(), nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if !s.remove(key, granted) {
+			// The slot was granted while ctx was done, pass it on.
+			s.grant()
+		}
+		return nil, ctx.Err()
+	}
+}
+
+func (s *Scheduler) releaseFunc() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.grant()
+		})
+	}
+}
+
+// grant hands a freed slot to the next waiting controller in round-robin
+// order, or returns it to the pool if no controller is waiting.
+func (s *Scheduler) grant() {
+	for i := 0; i < len(s.order); i++ {
+		idx := (s.next + i) % len(s.order)
+		key := s.order[idx]
+		if len(s.waiters[key]) == 0 {
+			continue
+		}
+		w := s.waiters[key][0]
+		s.waiters[key] = s.waiters[key][1:]
+		s.next = (idx + 1) % len(s.order)
+		w <- struct{}{}
+		return
+	}
+	s.free++
+}
+
+func (s *Scheduler) remove(key string, w chan struct{}) bool {
+	for i, c := range s.waiters[key] {
+		if c == w {
+			s.waiters[key] = append(s.waiters[key][:i], s.waiters[key][i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Scheduler) waiting() int {
+	n := 0
+	for _, ws := range s.waiters {
+		n += len(ws)
+	}
+	return n
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fairness
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Scheduler", func() {
+	It("should limit the number of acquired slots", func() {
+		s := New(1)
+		release, err := s.Acquire(context.Background(), "a")
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err = s.Acquire(ctx, "a")
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+
+		release()
+		release, err = s.Acquire(context.Background(), "a")
+		Expect(err).NotTo(HaveOccurred())
+		release()
+	})
+
+	It("should hand out freed slots round-robin", func() {
+		s := New(1)
+		release, err := s.Acquire(context.Background(), "a")
+		Expect(err).NotTo(HaveOccurred())
+
+		order := make(chan string, 4)
+		acquire := func(key string) {
+			defer GinkgoRecover()
+			r, err := s.Acquire(context.Background(), key)
+			Expect(err).NotTo(HaveOccurred())
+			order <- key
+			r()
+		}
+		waiting := func() int {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			return s.waiting()
+		}
+		for i, key := range []string{"a", "a", "b"} {
+			go acquire(key)
+			Eventually(waiting).Should(Equal(i + 1))
+		}
+
+		release()
+		Eventually(order).Should(Receive(Equal("a")))
+		Eventually(order).Should(Receive(Equal("b")))
+		Eventually(order).Should(Receive(Equal("a")))
+	})
+})
@@ -53,12 +53,20 @@ type ActionClient struct {
 	Upgrades   []UpgradeCall
 	Uninstalls []UninstallCall
 	Reconciles []ReconcileCall
-
-	HandleGet       func() (*release.Release, error)
-	HandleInstall   func() (*release.Release, error)
-	HandleUpgrade   func() (*release.Release, error)
-	HandleUninstall func() (*release.UninstallReleaseResponse, error)
-	HandleReconcile func() error
+	MarkFails  []MarkFailedCall
+	Rollbacks  []RollbackCall
+	Tests      []TestCall
+	Cleanups   []CleanupTestsCall
+
+	HandleGet          func() (*release.Release, error)
+	HandleInstall      func() (*release.Release, error)
+	HandleUpgrade      func() (*release.Release, error)
+	HandleUninstall    func() (*release.UninstallReleaseResponse, error)
+	HandleReconcile    func() error
+	HandleMarkFailed   func() error
+	HandleRollback     func() error
+	HandleTest         func() (*release.Release, error)
+	HandleCleanupTests func() error
 }
 
 func NewActionClient() ActionClient {
@@ -77,16 +85,26 @@ func NewActionClient() ActionClient {
 		Upgrades:   make([]UpgradeCall, 0),
 		Uninstalls: make([]UninstallCall, 0),
 		Reconciles: make([]ReconcileCall, 0),
-
-		HandleGet:       relFunc(errors.New("get not implemented")),
-		HandleInstall:   relFunc(errors.New("install not implemented")),
-		HandleUpgrade:   relFunc(errors.New("upgrade not implemented")),
-		HandleUninstall: uninstFunc(errors.New("uninstall not implemented")),
-		HandleReconcile: recFunc(errors.New("reconcile not implemented")),
+		MarkFails:  make([]MarkFailedCall, 0),
+		Rollbacks:  make([]RollbackCall, 0),
+		Tests:      make([]TestCall, 0),
+		Cleanups:   make([]CleanupTestsCall, 0),
+
+		HandleGet:          relFunc(errors.New("get not implemented")),
+		HandleInstall:      relFunc(errors.New("install not implemented")),
+		HandleUpgrade:      relFunc(errors.New("upgrade not implemented")),
+		HandleUninstall:    uninstFunc(errors.New("uninstall not implemented")),
+		HandleReconcile:    recFunc(errors.New("reconcile not implemented")),
+		HandleMarkFailed:   recFunc(errors.New("mark failed not implemented")),
+		HandleRollback:     recFunc(errors.New("rollback not implemented")),
+		HandleTest:         relFunc(errors.New("test not implemented")),
+		HandleCleanupTests: recFunc(errors.New("cleanup tests not implemented")),
 	}
 }
 
 var _ client.ActionInterface = &ActionClient{}
+var _ client.PendingReleaseRecoverer = &ActionClient{}
+var _ client.ChartTester = &ActionClient{}
 
 type GetCall struct {
 	Name string
@@ -118,6 +136,25 @@ type ReconcileCall struct {
 	Release *release.Release
 }
 
+type MarkFailedCall struct {
+	Release     *release.Release
+	Description string
+}
+
+type RollbackCall struct {
+	Name string
+	Opts []client.RollbackOption
+}
+
+type TestCall struct {
+	Name string
+	Opts []client.TestOption
+}
+
+type CleanupTestsCall struct {
+	Release *release.Release
+}
+
 func (c *ActionClient) Get(name string, opts ...client.GetOption) (*release.Release, error) {
 	c.Gets = append(c.Gets, GetCall{name, opts})
 	return c.HandleGet()
@@ -142,3 +179,23 @@ func (c *ActionClient) Reconcile(rel *release.Release) error {
 	c.Reconciles = append(c.Reconciles, ReconcileCall{rel})
 	return c.HandleReconcile()
 }
+
+func (c *ActionClient) MarkFailed(rel *release.Release, description string) error {
+	c.MarkFails = append(c.MarkFails, MarkFailedCall{rel, description})
+	return c.HandleMarkFailed()
+}
+
+func (c *ActionClient) Rollback(name string, opts ...client.RollbackOption) error {
+	c.Rollbacks = append(c.Rollbacks, RollbackCall{name, opts})
+	return c.HandleRollback()
+}
+
+func (c *ActionClient) Test(name string, opts ...client.TestOption) (*release.Release, error) {
+	c.Tests = append(c.Tests, TestCall{name, opts})
+	return c.HandleTest()
+}
+
+func (c *ActionClient) CleanupTests(rel *release.Release) error {
+	c.Cleanups = append(c.Cleanups, CleanupTestsCall{rel})
+	return c.HandleCleanupTests()
+}
@@ -18,6 +18,7 @@ package hook
 
 import (
 	"sync"
+	"time"
 
 	"github.com/go-logr/logr"
 	sdkhandler "github.com/operator-framework/operator-lib/handler"
@@ -37,23 +38,53 @@ import (
 	"github.com/operator-framework/helm-operator-plugins/pkg/hook"
 	"github.com/operator-framework/helm-operator-plugins/pkg/internal/predicate"
 	"github.com/operator-framework/helm-operator-plugins/pkg/manifestutil"
+	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/debounce"
 )
 
-func NewDependentResourceWatcher(c controller.Controller, rm meta.RESTMapper, cache cache.Cache, scheme *runtime.Scheme) hook.PostHook {
-	return &dependentResourceWatcher{
-		controller: c,
-		restMapper: rm,
-		m:          sync.Mutex{},
-		watches:    make(map[schema.GroupVersionKind]struct{}),
+type DependentResourceWatcherOption func(*dependentResourceWatcher)
+
+// WithOwnerReferences configures whether events for dependent resources are
+// mapped to their owner using owner references, where they are supported. If
+// disabled, owner annotations are used for all dependent resources. By
+// default, owner references are used.
+func WithOwnerReferences(enabled bool) DependentResourceWatcherOption {
+	return func(d *dependentResourceWatcher) {
+		d.useOwnerRefs = enabled
+	}
+}
+
+// WithDebounce configures the window within which events for dependent
+// resources of the same owner are collapsed into a single reconcile request.
+// By default, events are not debounced.
+func WithDebounce(window time.Duration) DependentResourceWatcherOption {
+	return func(d *dependentResourceWatcher) {
+		d.debounce = window
 	}
 }
 
+func NewDependentResourceWatcher(c controller.Controller, rm meta.RESTMapper, cache cache.Cache, scheme *runtime.Scheme, opts ...DependentResourceWatcherOption) hook.PostHook {
+	d := &dependentResourceWatcher{
+		controller:   c,
+		restMapper:   rm,
+		useOwnerRefs: true,
+		m:            sync.Mutex{},
+		watches:      make(map[schema.GroupVersionKind]struct{}),
+	}
+	for _, o := range opts {
+		o(d)
+	}
+	return d
+}
+
 type dependentResourceWatcher struct {
 	controller controller.Controller
 	restMapper meta.RESTMapper
 	cache      cache.Cache
 	scheme     runtime.Scheme
 
+	useOwnerRefs bool
+	debounce     time.Duration
+
 	m       sync.Mutex
 	watches map[schema.GroupVersionKind]struct{}
 }
@@ -90,25 +121,31 @@ func (d *dependentResourceWatcher) Exec(owner *unstructured.Unstructured, rel re
 				return nil
 			}
 
-			useOwnerRef, err := controllerutil.SupportsOwnerReference(d.restMapper, owner, unstructuredObj)
-			if err != nil {
-				return err
+			useOwnerRef := false
+			if d.useOwnerRefs {
+				var err error
+				useOwnerRef, err = controllerutil.SupportsOwnerReference(d.restMapper, owner, unstructuredObj)
+				if err != nil {
+					return err
+				}
 			}
 
 			if useOwnerRef && !manifestutil.HasResourcePolicyKeep(unstructuredObj.GetAnnotations()) { // Setup watch using owner references.
-				if err := d.controller.Watch(source.Kind(d.cache, unstructuredObj), handler.EnqueueRequestForOwner(&d.scheme, d.restMapper, owner, handler.OnlyControllerOwner()), dependentPredicate); err != nil {
+				if err := d.controller.Watch(source.Kind(d.cache, unstructuredObj), debounce.Handler(handler.EnqueueRequestForOwner(&d.scheme, d.restMapper, owner, handler.OnlyControllerOwner()), d.debounce), dependentPredicate); err != nil {
 					return err
 				}
 			} else { // Setup watch using annotations.
-				if err := d.controller.Watch(source.Kind(d.cache, unstructuredObj), &sdkhandler.EnqueueRequestForAnnotation{
+				if err := d.controller.Watch(source.Kind(d.cache, unstructuredObj), debounce.Handler(&sdkhandler.EnqueueRequestForAnnotation{
 					Type: owner.GetObjectKind().GroupVersionKind().GroupKind(),
-				}, dependentPredicate); err != nil {
+				}, d.debounce), dependentPredicate); err != nil {
 					return err
 				}
 			}
 
-			d.watches[depGVK] = struct{}{}
-			log.V(1).Info("Watching dependent resource", "dependentAPIVersion", depGVK.GroupVersion(), "dependentKind", depGVK.Kind)
+			// The kind of the dependent is recorded, not the kind of the
+			// manifest, which differs for the items of a List.
+			d.watches[gvkDependent] = struct{}{}
+			log.V(1).Info("Watching dependent resource", "dependentAPIVersion", gvkDependent.GroupVersion(), "dependentKind", gvkDependent.Kind)
 			return nil
 		}
 
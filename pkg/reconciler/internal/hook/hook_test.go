@@ -228,15 +228,18 @@ var _ = Describe("Hook", func() {
 					Expect(c.WatchCalls[1].Handler).To(BeAssignableToTypeOf(&sdkhandler.EnqueueRequestForAnnotation{}))
 					Expect(c.WatchCalls[2].Handler).To(BeAssignableToTypeOf(&sdkhandler.EnqueueRequestForAnnotation{}))
 				})
-				It("should iterate the kind list and be able to set watches on each item", func() {
+				It("should iterate the kind list and watch the kinds of its items once", func() {
 					rel = &release.Release{
 						Manifest: strings.Join([]string{replicaSetList}, "---\n"),
 					}
 					drw = internalhook.NewDependentResourceWatcher(c, rm, cache, sch)
 					Expect(drw.Exec(owner, *rel, log)).To(Succeed())
-					Expect(c.WatchCalls).To(HaveLen(2))
+					Expect(c.WatchCalls).To(HaveLen(1))
 					Expect(c.WatchCalls[0].Handler).To(BeAssignableToTypeOf(handler.EnqueueRequestForOwner(sch, rm, owner, handler.OnlyControllerOwner())))
-					Expect(c.WatchCalls[1].Handler).To(BeAssignableToTypeOf(handler.EnqueueRequestForOwner(sch, rm, owner, handler.OnlyControllerOwner())))
+
+					By("not watching the kinds again for the next revision")
+					Expect(drw.Exec(owner, *rel, log)).To(Succeed())
+					Expect(c.WatchCalls).To(HaveLen(1))
 				})
 				It("should error when unable to list objects", func() {
 					rel = &release.Release{
@@ -0,0 +1,78 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package keylock provides mutual exclusion keyed by arbitrary strings.
+package keylock
+
+import (
+	"context"
+	"sync"
+)
+
+// KeyLock is a set of mutexes, one per key. Locks for keys that are not held
+// or waited for are not retained. The zero value is ready to use.
+type KeyLock struct {
+	mu    sync.Mutex
+	locks map[string]*lock
+}
+
+type lock struct {
+	ch   chan struct{}
+	refs int
+}
+
+// Lock acquires the lock for key, waiting until it is released by its current
+// holder or until ctx is done. On success, the returned function releases the
+// lock; it must be called exactly once. If ctx is done first, ctx.Err() is
+// returned.
+func (k *KeyLock) Lock(ctx context.Context, key string) (func(), error) {
+	l := k.acquire(key)
+	select {
+	case l.ch <- struct{}{}:
+		return func() {
+			<-l.ch
+			k.release(key)
+		}, nil
+	case <-ctx.Done():
+		k.release(key)
+		return nil, ctx.Err()
+	}
+}
+
+func (k *KeyLock) acquire(key string) *lock {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.locks == nil {
+		k.locks = map[string]*lock{}
+	}
+	l, ok := k.locks[key]
+	if !ok {
+		l = &lock{ch: make(chan struct{}, 1)}
+		k.locks[key] = l
+	}
+	l.refs++
+	return l
+}
+
+func (k *KeyLock) release(key string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	l := k.locks[key]
+	l.refs--
+	if l.refs == 0 {
+		delete(k.locks, key)
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keylock
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("KeyLock", func() {
+	var k *KeyLock
+
+	BeforeEach(func() {
+		k = &KeyLock{}
+	})
+
+	It("should serialize holders of the same key", func() {
+		unlock, err := k.Lock(context.Background(), "a")
+		Expect(err).NotTo(HaveOccurred())
+
+		acquired := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			unlock, err := k.Lock(context.Background(), "a")
+			Expect(err).NotTo(HaveOccurred())
+			close(acquired)
+			unlock()
+		}()
+		Consistently(acquired, 100*time.Millisecond).ShouldNot(BeClosed())
+
+		unlock()
+		Eventually(acquired).Should(BeClosed())
+	})
+
+	It("should not block holders of other keys", func() {
+		unlock, err := k.Lock(context.Background(), "a")
+		Expect(err).NotTo(HaveOccurred())
+		defer unlock()
+
+		unlockB, err := k.Lock(context.Background(), "b")
+		Expect(err).NotTo(HaveOccurred())
+		unlockB()
+	})
+
+	It("should give up when the context is done", func() {
+		unlock, err := k.Lock(context.Background(), "a")
+		Expect(err).NotTo(HaveOccurred())
+		defer unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err = k.Lock(ctx, "a")
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+	})
+
+	It("should not retain unused locks", func() {
+		unlock, err := k.Lock(context.Background(), "a")
+		Expect(err).NotTo(HaveOccurred())
+		unlock()
+		Expect(k.locks).To(BeEmpty())
+	})
+})
@@ -0,0 +1,78 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redact
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"sigs.k8s.io/yaml"
+)
+
+// Redacted replaces the values of redacted Secret keys.
+const Redacted = "REDACTED"
+
+// Secrets returns manifest with the values of the data and stringData fields
+// of all Secrets replaced by Redacted. All other documents are returned
+// unchanged.
+func Secrets(manifest string) (string, error) {
+	docs := releaseutil.SplitManifests(manifest)
+	keys := make([]string, 0, len(docs))
+	for k := range docs {
+		keys = append(keys, k)
+	}
+	sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+
+	out := make([]string, 0, len(keys))
+	for _, k := range keys {
+		doc, err := redactSecret(docs[k])
+		if err != nil {
+			return "", err
+		}
+		out = append(out, strings.TrimSpace(doc))
+	}
+	if len(out) == 0 {
+		return "", nil
+	}
+	return "---\n" + strings.Join(out, "\n---\n") + "\n", nil
+}
+
+func redactSecret(doc string) (string, error) {
+	var obj map[string]interface{}
+	if err := yaml.Unmarshal([]byte(doc), &obj); err != nil {
+		return "", fmt.Errorf("parse manifest: %w", err)
+	}
+	if obj["apiVersion"] != "v1" || obj["kind"] != "Secret" {
+		return doc, nil
+	}
+	for _, field := range []string{"data", "stringData"} {
+		values, ok := obj[field].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for k := range values {
+			values[k] = Redacted
+		}
+	}
+	out, err := yaml.Marshal(obj)
+	if err != nil {
+		return "", fmt.Errorf("serialize manifest: %w", err)
+	}
+	return string(out), nil
+}
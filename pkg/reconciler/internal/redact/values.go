@@ -0,0 +1,228 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redact
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+)
+
+// Values is a set of sensitive values that are replaced by Redacted wherever
+// they occur in a string. The values are grouped by key, e.g. the custom
+// resource they were resolved for, so that the values of a key can be
+// replaced as a whole. The values of all keys are redacted in all strings.
+// It is safe for concurrent use.
+type Values struct {
+	mu       sync.RWMutex
+	byKey    map[string][]string
+	replacer *strings.Replacer
+}
+
+// Set replaces the sensitive values of key with values. Each value is also
+// redacted in its base64 encoded form, as used in the data of Secrets, and in
+// its escaped form in JSON strings. Empty values are ignored.
+func (v *Values) Set(key string, values []string) {
+	var expanded []string
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		expanded = append(expanded, value, base64.StdEncoding.EncodeToString([]byte(value)))
+		if quoted, err := json.Marshal(value); err == nil {
+			if escaped := string(quoted[1 : len(quoted)-1]); escaped != value {
+				expanded = append(expanded, escaped)
+			}
+		}
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if len(expanded) == 0 && len(v.byKey[key]) == 0 {
+		return
+	}
+	if v.byKey == nil {
+		v.byKey = map[string][]string{}
+	}
+	if len(expanded) == 0 {
+		delete(v.byKey, key)
+	} else {
+		v.byKey[key] = expanded
+	}
+	v.replacer = v.newReplacer()
+}
+
+// newReplacer returns a replacer of all values, which tries longer values
+// first, so that a value that contains another value is redacted as a whole.
+func (v *Values) newReplacer() *strings.Replacer {
+	seen := map[string]struct{}{}
+	var all []string
+	for _, values := range v.byKey {
+		for _, value := range values {
+			if _, ok := seen[value]; !ok {
+				seen[value] = struct{}{}
+				all = append(all, value)
+			}
+		}
+	}
+	if len(all) == 0 {
+		return nil
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if len(all[i]) != len(all[j]) {
+			return len(all[i]) > len(all[j])
+		}
+		return all[i] < all[j]
+	})
+	oldnew := make([]string, 0, 2*len(all))
+	for _, value := range all {
+		oldnew = append(oldnew, value, Redacted)
+	}
+	return strings.NewReplacer(oldnew...)
+}
+
+// String returns s with all sensitive values replaced by Redacted.
+func (v *Values) String(s string) string {
+	if v == nil {
+		return s
+	}
+	v.mu.RLock()
+	replacer := v.replacer
+	v.mu.RUnlock()
+	if replacer == nil {
+		return s
+	}
+	return replacer.Replace(s)
+}
+
+// Error returns err if its message does not contain sensitive values.
+// Otherwise, it returns an error with the redacted message of err, which
+// still wraps err, so that it can be inspected with errors.Is and errors.As.
+func (v *Values) Error(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	if redacted := v.String(msg); redacted != msg {
+		return &redactedError{msg: redacted, err: err}
+	}
+	return err
+}
+
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
+
+// LogSink returns a logr.LogSink that redacts the sensitive values of v in
+// the messages, names, errors and key/value pairs that are logged with sink.
+// Values of key/value pairs that contain sensitive values when formatted are
+// replaced by their redacted string form. The sink should be used with
+// logr.Logger.WithSink, since sink is expected to be initialized already.
+func (v *Values) LogSink(sink logr.LogSink) logr.LogSink {
+	// Account for the additional frame of the returned sink.
+	if cd, ok := sink.(logr.CallDepthLogSink); ok {
+		sink = cd.WithCallDepth(1)
+	}
+	return &logSink{sink: sink, values: v}
+}
+
+type logSink struct {
+	sink   logr.LogSink
+	values *Values
+}
+
+var _ logr.CallDepthLogSink = &logSink{}
+
+func (s *logSink) Init(info logr.RuntimeInfo) {
+	s.sink.Init(info)
+}
+
+func (s *logSink) Enabled(level int) bool {
+	return s.sink.Enabled(level)
+}
+
+func (s *logSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	s.sink.Info(level, s.values.String(msg), s.keysAndValues(keysAndValues)...)
+}
+
+func (s *logSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	if err != nil {
+		if redacted := s.values.String(err.Error()); redacted != err.Error() {
+			err = errors.New(redacted)
+		}
+	}
+	s.sink.Error(err, s.values.String(msg), s.keysAndValues(keysAndValues)...)
+}
+
+func (s *logSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	return &logSink{sink: s.sink.WithValues(s.keysAndValues(keysAndValues)...), values: s.values}
+}
+
+func (s *logSink) WithName(name string) logr.LogSink {
+	return &logSink{sink: s.sink.WithName(s.values.String(name)), values: s.values}
+}
+
+func (s *logSink) WithCallDepth(depth int) logr.LogSink {
+	if sink, ok := s.sink.(logr.CallDepthLogSink); ok {
+		return &logSink{sink: sink.WithCallDepth(depth), values: s.values}
+	}
+	return s
+}
+
+func (s *logSink) keysAndValues(keysAndValues []interface{}) []interface{} {
+	out := make([]interface{}, len(keysAndValues))
+	for i, kv := range keysAndValues {
+		out[i] = s.value(kv)
+	}
+	return out
+}
+
+// value returns kv, or its redacted string form if kv contains sensitive
+// values when formatted either with fmt or as JSON, as done by most sinks.
+func (s *logSink) value(kv interface{}) interface{} {
+	if m, ok := kv.(logr.Marshaler); ok {
+		kv = m.MarshalLog()
+	}
+	var forms []string
+	switch kv := kv.(type) {
+	case string:
+		forms = []string{kv}
+	case error:
+		forms = []string{kv.Error()}
+	default:
+		forms = []string{fmt.Sprintf("%+v", kv)}
+		if data, err := json.Marshal(kv); err == nil {
+			forms = append(forms, string(data))
+		}
+	}
+	for _, form := range forms {
+		if redacted := s.values.String(form); redacted != form {
+			return redacted
+		}
+	}
+	return kv
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redact
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var errTest = errors.New("test")
+
+var _ = Describe("Values", func() {
+	var v *Values
+
+	BeforeEach(func() {
+		v = &Values{}
+		v.Set("ns/a", []string{"s3cr3t", "", `p"w`})
+	})
+
+	It("should not redact without values", func() {
+		Expect((&Values{}).String("s3cr3t")).To(Equal("s3cr3t"))
+		Expect((*Values)(nil).String("s3cr3t")).To(Equal("s3cr3t"))
+	})
+
+	It("should redact the values and their encoded forms", func() {
+		Expect(v.String("password: s3cr3t")).To(Equal("password: REDACTED"))
+		Expect(v.String("password: czNjcjN0")).To(Equal("password: REDACTED"))
+		Expect(v.String(`{"password":"p\"w"}`)).To(Equal(`{"password":"REDACTED"}`))
+	})
+
+	It("should redact the values of all keys", func() {
+		v.Set("ns/b", []string{"other"})
+		Expect(v.String("s3cr3t other")).To(Equal("REDACTED REDACTED"))
+	})
+
+	It("should replace the values of a key", func() {
+		v.Set("ns/a", []string{"rotated"})
+		Expect(v.String("s3cr3t rotated")).To(Equal("s3cr3t REDACTED"))
+		v.Set("ns/a", nil)
+		Expect(v.String("s3cr3t rotated")).To(Equal("s3cr3t rotated"))
+	})
+
+	It("should redact a value that contains another value as a whole", func() {
+		v.Set("ns/b", []string{"s3cr3t-long"})
+		Expect(v.String("s3cr3t-long")).To(Equal("REDACTED"))
+	})
+
+	It("should redact errors and keep them wrapped", func() {
+		err := v.Error(fmt.Errorf("failed with s3cr3t: %w", errTest))
+		Expect(err).To(MatchError("failed with REDACTED: test"))
+		Expect(errors.Is(err, errTest)).To(BeTrue())
+
+		plain := errors.New("failed")
+		Expect(v.Error(plain)).To(BeIdenticalTo(plain))
+		Expect(v.Error(nil)).To(BeNil())
+	})
+
+	It("should redact logs", func() {
+		var lines []string
+		sink := funcr.New(func(prefix, args string) {
+			lines = append(lines, prefix+" "+args)
+		}, funcr.Options{}).GetSink()
+		log := logr.New(sink)
+		log = log.WithSink(v.LogSink(log.GetSink()))
+
+		log.WithValues("password", "s3cr3t").Info("using s3cr3t", "values", map[string]interface{}{"password": "s3cr3t"}, "count", 1)
+		log.Error(errors.New("failed with s3cr3t"), "failed")
+		Expect(lines).To(HaveLen(2))
+		for _, line := range lines {
+			Expect(line).NotTo(ContainSubstring("s3cr3t"))
+		}
+		Expect(lines[0]).To(ContainSubstring(`"count"=1`))
+		Expect(lines[1]).To(ContainSubstring("failed with REDACTED"))
+	})
+})
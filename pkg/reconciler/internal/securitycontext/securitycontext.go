@@ -0,0 +1,96 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package securitycontext
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// podSpecPaths maps the kinds of the built-in workload resources to the path
+// of their pod spec.
+var podSpecPaths = map[string][]string{
+	"Pod":                   {"spec"},
+	"Deployment":            {"spec", "template", "spec"},
+	"StatefulSet":           {"spec", "template", "spec"},
+	"DaemonSet":             {"spec", "template", "spec"},
+	"ReplicaSet":            {"spec", "template", "spec"},
+	"ReplicationController": {"spec", "template", "spec"},
+	"Job":                   {"spec", "template", "spec"},
+	"CronJob":               {"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+// PodSpecPath returns the path of the pod spec of the built-in workload
+// resources of the given kind.
+func PodSpecPath(kind string) ([]string, bool) {
+	path, ok := podSpecPaths[kind]
+	return path, ok
+}
+
+// Apply sets pod as the securityContext of the pod spec of obj and container
+// as the securityContext of its containers and init containers, if obj is a
+// built-in workload resource. Existing security contexts are never modified,
+// and nil defaults are ignored.
+func Apply(obj *unstructured.Unstructured, pod *corev1.PodSecurityContext, container *corev1.SecurityContext) error {
+	path, ok := podSpecPaths[obj.GetKind()]
+	if !ok {
+		return nil
+	}
+	spec, found, err := unstructured.NestedMap(obj.Object, path...)
+	if err != nil || !found {
+		return err
+	}
+
+	changed := false
+	if pod != nil {
+		if _, ok := spec["securityContext"]; !ok {
+			sc, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pod)
+			if err != nil {
+				return err
+			}
+			spec["securityContext"] = sc
+			changed = true
+		}
+	}
+	if container != nil {
+		sc, err := runtime.DefaultUnstructuredConverter.ToUnstructured(container)
+		if err != nil {
+			return err
+		}
+		for _, field := range []string{"initContainers", "containers"} {
+			containers, ok := spec[field].([]interface{})
+			if !ok {
+				continue
+			}
+			for _, c := range containers {
+				c, ok := c.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if _, ok := c["securityContext"]; !ok {
+					c["securityContext"] = runtime.DeepCopyJSONValue(sc)
+					changed = true
+				}
+			}
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return unstructured.SetNestedMap(obj.Object, spec, path...)
+}
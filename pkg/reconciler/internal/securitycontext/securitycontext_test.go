@@ -0,0 +1,101 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package securitycontext_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/yaml"
+
+	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/securitycontext"
+)
+
+var _ = Describe("Apply", func() {
+	var (
+		podSC       = &corev1.PodSecurityContext{RunAsNonRoot: pointer.Bool(true)}
+		containerSC = &corev1.SecurityContext{AllowPrivilegeEscalation: pointer.Bool(false)}
+	)
+
+	parse := func(manifest string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		Expect(yaml.Unmarshal([]byte(manifest), &obj.Object)).To(Succeed())
+		return obj
+	}
+
+	It("should set missing security contexts of a job", func() {
+		obj := parse(`
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: hook
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+      containers:
+      - name: main
+`)
+		Expect(securitycontext.Apply(obj, podSC, containerSC)).To(Succeed())
+
+		podSpec, _, _ := unstructured.NestedMap(obj.Object, "spec", "template", "spec")
+		Expect(podSpec["securityContext"]).To(Equal(map[string]interface{}{"runAsNonRoot": true}))
+		for _, field := range []string{"initContainers", "containers"} {
+			containers := podSpec[field].([]interface{})
+			Expect(containers[0].(map[string]interface{})["securityContext"]).To(Equal(map[string]interface{}{"allowPrivilegeEscalation": false}))
+		}
+	})
+
+	It("should not override existing security contexts", func() {
+		obj := parse(`
+apiVersion: v1
+kind: Pod
+metadata:
+  name: test
+spec:
+  securityContext:
+    runAsUser: 1000
+  containers:
+  - name: main
+    securityContext:
+      privileged: true
+  - name: sidecar
+`)
+		Expect(securitycontext.Apply(obj, podSC, containerSC)).To(Succeed())
+
+		podSpec, _, _ := unstructured.NestedMap(obj.Object, "spec")
+		Expect(podSpec["securityContext"]).To(Equal(map[string]interface{}{"runAsUser": float64(1000)}))
+		containers := podSpec["containers"].([]interface{})
+		Expect(containers[0].(map[string]interface{})["securityContext"]).To(Equal(map[string]interface{}{"privileged": true}))
+		Expect(containers[1].(map[string]interface{})["securityContext"]).To(Equal(map[string]interface{}{"allowPrivilegeEscalation": false}))
+	})
+
+	It("should ignore other kinds", func() {
+		obj := parse(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+`)
+		expected := obj.DeepCopy()
+		Expect(securitycontext.Apply(obj, podSC, containerSC)).To(Succeed())
+		Expect(obj).To(Equal(expected))
+	})
+})
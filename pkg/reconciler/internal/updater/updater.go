@@ -18,9 +18,12 @@ package updater
 
 import (
 	"context"
+	"strings"
 
 	"helm.sh/helm/v3/pkg/release"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/util/retry"
@@ -37,9 +40,11 @@ func New(client client.Client) Updater {
 }
 
 type Updater struct {
-	client            client.Client
-	updateFuncs       []UpdateFunc
-	updateStatusFuncs []UpdateStatusFunc
+	client              client.Client
+	updateFuncs         []UpdateFunc
+	updateStatusFuncs   []UpdateStatusFunc
+	skipUnchangedStatus bool
+	noStatusSubresource bool
 }
 
 type UpdateFunc func(*unstructured.Unstructured) bool
@@ -53,6 +58,21 @@ func (u *Updater) UpdateStatus(fs ...UpdateStatusFunc) {
 	u.updateStatusFuncs = append(u.updateStatusFuncs, fs...)
 }
 
+// SkipUnchangedStatus configures the Updater to compare the resulting status
+// with the current status of the object and to skip the status update if they
+// are equal, even if an UpdateStatusFunc reported a change.
+func (u *Updater) SkipUnchangedStatus(skip bool) {
+	u.skipUnchangedStatus = skip
+}
+
+// UseStatusSubresource configures whether the Updater updates the status of
+// the object through the status subresource. If not, the status is updated
+// together with the rest of the object, which is required for resources whose
+// CRD does not enable the status subresource. It is enabled by default.
+func (u *Updater) UseStatusSubresource(use bool) {
+	u.noStatusSubresource = !use
+}
+
 func (u *Updater) Apply(ctx context.Context, obj *unstructured.Unstructured) error {
 	backoff := retry.DefaultRetry
 
@@ -61,6 +81,10 @@ func (u *Updater) Apply(ctx context.Context, obj *unstructured.Unstructured) err
 	// because the object and its status will be garbage-collected
 	if err := retry.RetryOnConflict(backoff, func() error {
 		st := statusFor(obj)
+		curSt, err := runtime.DefaultUnstructuredConverter.ToUnstructured(st)
+		if err != nil {
+			return err
+		}
 		needsStatusUpdate := false
 		for _, f := range u.updateStatusFuncs {
 			needsStatusUpdate = f(st) || needsStatusUpdate
@@ -70,7 +94,13 @@ func (u *Updater) Apply(ctx context.Context, obj *unstructured.Unstructured) err
 			if err != nil {
 				return err
 			}
+			if u.skipUnchangedStatus && equality.Semantic.DeepEqual(curSt, uSt) {
+				return nil
+			}
 			obj.Object["status"] = uSt
+			if u.noStatusSubresource {
+				return u.client.Update(ctx, obj)
+			}
 			return u.client.Status().Update(ctx, obj)
 		}
 		return nil
@@ -114,6 +144,18 @@ func RemoveFinalizer(finalizer string) UpdateFunc {
 	}
 }
 
+func RemoveAnnotation(name string) UpdateFunc {
+	return func(obj *unstructured.Unstructured) bool {
+		annotations := obj.GetAnnotations()
+		if _, ok := annotations[name]; !ok {
+			return false
+		}
+		delete(annotations, name)
+		obj.SetAnnotations(annotations)
+		return true
+	}
+}
+
 func EnsureCondition(condition status.Condition) UpdateStatusFunc {
 	return func(status *helmAppStatus) bool {
 		return status.Conditions.SetCondition(condition)
@@ -129,9 +171,23 @@ func EnsureConditionUnknown(t status.ConditionType) UpdateStatusFunc {
 	}
 }
 
+// RemoveCondition removes the condition of type t, if present.
+func RemoveCondition(t status.ConditionType) UpdateStatusFunc {
+	return func(s *helmAppStatus) bool {
+		return s.Conditions.RemoveCondition(t)
+	}
+}
+
 func EnsureDeployedRelease(rel *release.Release) UpdateStatusFunc {
+	return ensureDeployedRelease(rel, false)
+}
+
+func ensureDeployedRelease(rel *release.Release, withNotes bool) UpdateStatusFunc {
 	return func(status *helmAppStatus) bool {
 		newRel := helmAppReleaseFor(rel)
+		if newRel != nil && withNotes && rel.Info != nil {
+			newRel.Notes = strings.TrimSpace(rel.Info.Notes)
+		}
 		if status.DeployedRelease == nil && newRel == nil {
 			return false
 		}
@@ -144,18 +200,154 @@ func EnsureDeployedRelease(rel *release.Release) UpdateStatusFunc {
 	}
 }
 
+// EnsureDeployedReleaseWithNotes is like EnsureDeployedRelease, but also
+// records the rendered notes of rel.
+func EnsureDeployedReleaseWithNotes(rel *release.Release) UpdateStatusFunc {
+	return ensureDeployedRelease(rel, true)
+}
+
 func RemoveDeployedRelease() UpdateStatusFunc {
 	return EnsureDeployedRelease(nil)
 }
 
+// EnsureLastReconcileError records message as the last reconcile error. The
+// time of the error is only updated when the message changes, so that
+// repeated failures with the same error do not change the status.
+func EnsureLastReconcileError(message string) UpdateStatusFunc {
+	return func(status *helmAppStatus) bool {
+		if status.LastReconcileError != nil && status.LastReconcileError.Message == message {
+			return false
+		}
+		status.LastReconcileError = &helmAppError{
+			Message: message,
+			Time:    metav1.Now(),
+		}
+		return true
+	}
+}
+
+func RemoveLastReconcileError() UpdateStatusFunc {
+	return func(status *helmAppStatus) bool {
+		if status.LastReconcileError == nil {
+			return false
+		}
+		status.LastReconcileError = nil
+		return true
+	}
+}
+
+// RedactStatus replaces every string in the status with its redacted form as
+// returned by redact. All fields of the status are redacted, including the
+// manifest of the deployed release and the messages of conditions and
+// errors. It should be the last status update function.
+func RedactStatus(redact func(string) string) UpdateStatusFunc {
+	return func(status *helmAppStatus) bool {
+		u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(status)
+		if err != nil {
+			return false
+		}
+		if !redactStrings(u, redact) {
+			return false
+		}
+		redacted := &helmAppStatus{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u, redacted); err != nil {
+			return false
+		}
+		*status = *redacted
+		return true
+	}
+}
+
+// redactStrings redacts the strings in the maps and slices of v in place and
+// returns whether any string was changed.
+func redactStrings(v interface{}, redact func(string) string) bool {
+	changed := false
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			if s, ok := e.(string); ok {
+				if r := redact(s); r != s {
+					v[k], changed = r, true
+				}
+				continue
+			}
+			changed = redactStrings(e, redact) || changed
+		}
+	case []interface{}:
+		for i, e := range v {
+			if s, ok := e.(string); ok {
+				if r := redact(s); r != s {
+					v[i], changed = r, true
+				}
+				continue
+			}
+			changed = redactStrings(e, redact) || changed
+		}
+	}
+	return changed
+}
+
 type helmAppStatus struct {
-	Conditions      status.Conditions `json:"conditions"`
-	DeployedRelease *helmAppRelease   `json:"deployedRelease,omitempty"`
+	Conditions         status.Conditions `json:"conditions"`
+	DeployedRelease    *helmAppRelease   `json:"deployedRelease,omitempty"`
+	LastReconcileError *helmAppError     `json:"lastReconcileError,omitempty"`
+	ReleaseInputsHash  string            `json:"releaseInputsHash,omitempty"`
+	ReleaseLock        string            `json:"releaseLock,omitempty"`
+}
+
+// EnsureReleaseInputsHash records the hash of the inputs of the deployed
+// release. An empty hash removes it.
+func EnsureReleaseInputsHash(hash string) UpdateStatusFunc {
+	return func(status *helmAppStatus) bool {
+		if status.ReleaseInputsHash == hash {
+			return false
+		}
+		status.ReleaseInputsHash = hash
+		return true
+	}
+}
+
+// ReleaseInputsHash returns the hash of the inputs of the deployed release
+// that is recorded in the status of obj.
+func ReleaseInputsHash(obj *unstructured.Unstructured) string {
+	status := statusFor(obj)
+	if status == nil {
+		return ""
+	}
+	return status.ReleaseInputsHash
+}
+
+// EnsureReleaseLock records the lock of the latest revision of the release
+// that was produced by the operator. An empty lock removes it.
+func EnsureReleaseLock(lock string) UpdateStatusFunc {
+	return func(status *helmAppStatus) bool {
+		if status.ReleaseLock == lock {
+			return false
+		}
+		status.ReleaseLock = lock
+		return true
+	}
+}
+
+// ReleaseLock returns the lock of the latest revision of the release produced
+// by the operator that is recorded in the status of obj.
+func ReleaseLock(obj *unstructured.Unstructured) string {
+	status := statusFor(obj)
+	if status == nil {
+		return ""
+	}
+	return status.ReleaseLock
+}
+
+type helmAppError struct {
+	Message string      `json:"message"`
+	Time    metav1.Time `json:"time"`
 }
 
 type helmAppRelease struct {
 	Name     string `json:"name,omitempty"`
 	Manifest string `json:"manifest,omitempty"`
+	Notes    string `json:"notes,omitempty"`
 }
 
 func statusFor(obj *unstructured.Unstructured) *helmAppStatus {
@@ -18,6 +18,7 @@ package updater
 
 import (
 	"context"
+	"strings"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -29,6 +30,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
+	"github.com/operator-framework/helm-operator-plugins/pkg/internal/status"
 	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/conditions"
 )
 
@@ -87,6 +89,67 @@ var _ = Describe("Updater", func() {
 			Expect(obj.GetResourceVersion()).NotTo(Equal(resourceVersion))
 		})
 	})
+
+	When("the status subresource is not used", func() {
+		BeforeEach(func() {
+			// The fake client treats the status of built-in types as a
+			// subresource, so use a custom resource without one.
+			client = fake.NewClientBuilder().Build()
+			u = New(client)
+			obj = &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "example.com/v1",
+				"kind":       "TestApp",
+				"metadata": map[string]interface{}{
+					"name":      "testApp",
+					"namespace": "testNamespace",
+				},
+				"spec": map[string]interface{}{},
+			}}
+			Expect(client.Create(context.TODO(), obj)).To(Succeed())
+		})
+
+		It("should update the status with the object", func() {
+			u.UseStatusSubresource(false)
+			u.UpdateStatus(EnsureCondition(conditions.Deployed(corev1.ConditionTrue, "", "")))
+
+			Expect(u.Apply(context.TODO(), obj)).To(Succeed())
+			obj = &unstructured.Unstructured{}
+			obj.SetAPIVersion("example.com/v1")
+			obj.SetKind("TestApp")
+			Expect(client.Get(context.TODO(), types.NamespacedName{Namespace: "testNamespace", Name: "testApp"}, obj)).To(Succeed())
+			Expect(obj.Object["status"]).To(HaveKeyWithValue("conditions", HaveLen(1)))
+		})
+	})
+
+	When("the resulting status is unchanged", func() {
+		BeforeEach(func() {
+			u.UpdateStatus(EnsureCondition(conditions.Deployed(corev1.ConditionTrue, "", "")))
+			Expect(u.Apply(context.TODO(), obj)).To(Succeed())
+			u = New(client)
+			// A condition that is changed and then changed back reports a change.
+			u.UpdateStatus(
+				EnsureCondition(conditions.Deployed(corev1.ConditionTrue, "", "changed")),
+				EnsureCondition(conditions.Deployed(corev1.ConditionTrue, "", "")),
+			)
+		})
+
+		It("should skip the status update if configured", func() {
+			u.SkipUnchangedStatus(true)
+			resourceVersion := obj.GetResourceVersion()
+
+			Expect(u.Apply(context.TODO(), obj)).To(Succeed())
+			Expect(client.Get(context.TODO(), types.NamespacedName{Namespace: "testNamespace", Name: "testDeployment"}, obj)).To(Succeed())
+			Expect(obj.GetResourceVersion()).To(Equal(resourceVersion))
+		})
+
+		It("should update the status otherwise", func() {
+			resourceVersion := obj.GetResourceVersion()
+
+			Expect(u.Apply(context.TODO(), obj)).To(Succeed())
+			Expect(client.Get(context.TODO(), types.NamespacedName{Namespace: "testNamespace", Name: "testDeployment"}, obj)).To(Succeed())
+			Expect(obj.GetResourceVersion()).NotTo(Equal(resourceVersion))
+		})
+	})
 })
 
 var _ = Describe("EnsureFinalizer", func() {
@@ -127,6 +190,25 @@ var _ = Describe("RemoveFinalizer", func() {
 	})
 })
 
+var _ = Describe("RemoveAnnotation", func() {
+	var obj *unstructured.Unstructured
+
+	BeforeEach(func() {
+		obj = &unstructured.Unstructured{}
+	})
+
+	It("should remove annotation if present", func() {
+		obj.SetAnnotations(map[string]string{"foo": "bar", "baz": "qux"})
+		Expect(RemoveAnnotation("foo")(obj)).To(BeTrue())
+		Expect(obj.GetAnnotations()).To(Equal(map[string]string{"baz": "qux"}))
+	})
+
+	It("should return false if annotation is not present", func() {
+		Expect(RemoveAnnotation("foo")(obj)).To(BeFalse())
+		Expect(obj.GetAnnotations()).To(BeEmpty())
+	})
+})
+
 var _ = Describe("EnsureCondition", func() {
 	var obj *helmAppStatus
 
@@ -146,6 +228,16 @@ var _ = Describe("EnsureCondition", func() {
 	})
 })
 
+var _ = Describe("RemoveCondition", func() {
+	It("should remove the condition if present", func() {
+		obj := &helmAppStatus{}
+		obj.Conditions.SetCondition(conditions.Deployed(corev1.ConditionTrue, "", ""))
+		Expect(RemoveCondition(conditions.TypeDeployed)(obj)).To(BeTrue())
+		Expect(obj.Conditions.GetCondition(conditions.TypeDeployed)).To(BeNil())
+		Expect(RemoveCondition(conditions.TypeDeployed)(obj)).To(BeFalse())
+	})
+})
+
 var _ = Describe("EnsureDeployedRelease", func() {
 	var obj *helmAppStatus
 	var rel *release.Release
@@ -187,6 +279,23 @@ var _ = Describe("EnsureDeployedRelease", func() {
 	})
 })
 
+var _ = Describe("EnsureDeployedReleaseWithNotes", func() {
+	It("should record the notes of the release", func() {
+		obj := &helmAppStatus{}
+		rel := &release.Release{Name: "name", Manifest: "manifest", Info: &release.Info{Notes: "Visit http://example.com\n"}}
+		Expect(EnsureDeployedReleaseWithNotes(rel)(obj)).To(BeTrue())
+		Expect(obj.DeployedRelease).To(Equal(&helmAppRelease{Name: "name", Manifest: "manifest", Notes: "Visit http://example.com"}))
+		Expect(EnsureDeployedReleaseWithNotes(rel)(obj)).To(BeFalse())
+	})
+
+	It("should remove the notes when disabled", func() {
+		obj := &helmAppStatus{DeployedRelease: &helmAppRelease{Name: "name", Manifest: "manifest", Notes: "notes"}}
+		rel := &release.Release{Name: "name", Manifest: "manifest", Info: &release.Info{Notes: "notes"}}
+		Expect(EnsureDeployedRelease(rel)(obj)).To(BeTrue())
+		Expect(obj.DeployedRelease.Notes).To(BeEmpty())
+	})
+})
+
 var _ = Describe("RemoveDeployedRelease", func() {
 	var obj *helmAppStatus
 	var statusRelease *helmAppRelease
@@ -211,6 +320,109 @@ var _ = Describe("RemoveDeployedRelease", func() {
 	})
 })
 
+var _ = Describe("EnsureLastReconcileError", func() {
+	var obj *helmAppStatus
+
+	BeforeEach(func() {
+		obj = &helmAppStatus{}
+	})
+
+	It("should record the error if none is present", func() {
+		Expect(EnsureLastReconcileError("failed")(obj)).To(BeTrue())
+		Expect(obj.LastReconcileError).NotTo(BeNil())
+		Expect(obj.LastReconcileError.Message).To(Equal("failed"))
+		Expect(obj.LastReconcileError.Time.IsZero()).To(BeFalse())
+	})
+
+	It("should not update if the message is unchanged", func() {
+		Expect(EnsureLastReconcileError("failed")(obj)).To(BeTrue())
+		Expect(EnsureLastReconcileError("failed")(obj)).To(BeFalse())
+	})
+
+	It("should update if the message changed", func() {
+		Expect(EnsureLastReconcileError("failed")(obj)).To(BeTrue())
+		Expect(EnsureLastReconcileError("failed again")(obj)).To(BeTrue())
+		Expect(obj.LastReconcileError.Message).To(Equal("failed again"))
+	})
+})
+
+var _ = Describe("RemoveLastReconcileError", func() {
+	It("should remove the error if present", func() {
+		obj := &helmAppStatus{LastReconcileError: &helmAppError{Message: "failed"}}
+		Expect(RemoveLastReconcileError()(obj)).To(BeTrue())
+		Expect(obj.LastReconcileError).To(BeNil())
+	})
+
+	It("should not update if no error is present", func() {
+		Expect(RemoveLastReconcileError()(&helmAppStatus{})).To(BeFalse())
+	})
+})
+
+var _ = Describe("EnsureReleaseInputsHash", func() {
+	It("should record the hash", func() {
+		obj := &helmAppStatus{}
+		Expect(EnsureReleaseInputsHash("abc")(obj)).To(BeTrue())
+		Expect(obj.ReleaseInputsHash).To(Equal("abc"))
+		Expect(EnsureReleaseInputsHash("abc")(obj)).To(BeFalse())
+	})
+
+	It("should remove the hash if empty", func() {
+		obj := &helmAppStatus{ReleaseInputsHash: "abc"}
+		Expect(EnsureReleaseInputsHash("")(obj)).To(BeTrue())
+		Expect(obj.ReleaseInputsHash).To(BeEmpty())
+	})
+})
+
+var _ = Describe("RedactStatus", func() {
+	redactSecret := func(s string) string { return strings.ReplaceAll(s, "s3cr3t", "REDACTED") }
+
+	It("should redact all strings of the status", func() {
+		obj := &helmAppStatus{
+			Conditions:         status.NewConditions(status.Condition{Type: "Deployed", Status: corev1.ConditionTrue, Message: "password is s3cr3t"}),
+			DeployedRelease:    &helmAppRelease{Name: "test", Manifest: "password: s3cr3t"},
+			LastReconcileError: &helmAppError{Message: "failed with s3cr3t"},
+		}
+		Expect(RedactStatus(redactSecret)(obj)).To(BeTrue())
+		Expect(obj.Conditions.GetCondition("Deployed").Message).To(Equal("password is REDACTED"))
+		Expect(obj.DeployedRelease).To(Equal(&helmAppRelease{Name: "test", Manifest: "password: REDACTED"}))
+		Expect(obj.LastReconcileError.Message).To(Equal("failed with REDACTED"))
+	})
+
+	It("should not update a status without sensitive values", func() {
+		obj := &helmAppStatus{DeployedRelease: &helmAppRelease{Name: "test", Manifest: "password: REDACTED"}}
+		Expect(RedactStatus(redactSecret)(obj)).To(BeFalse())
+	})
+})
+
+var _ = Describe("ReleaseInputsHash", func() {
+	It("should return the recorded hash", func() {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{
+			"status": map[string]interface{}{"releaseInputsHash": "abc"},
+		}}
+		Expect(ReleaseInputsHash(obj)).To(Equal("abc"))
+	})
+
+	It("should return an empty hash without a status", func() {
+		Expect(ReleaseInputsHash(&unstructured.Unstructured{Object: map[string]interface{}{}})).To(BeEmpty())
+		Expect(ReleaseInputsHash(nil)).To(BeEmpty())
+	})
+})
+
+var _ = Describe("ReleaseLock", func() {
+	It("should record and return the lock", func() {
+		obj := &helmAppStatus{}
+		Expect(EnsureReleaseLock("abc")(obj)).To(BeTrue())
+		Expect(EnsureReleaseLock("abc")(obj)).To(BeFalse())
+		Expect(ReleaseLock(&unstructured.Unstructured{Object: map[string]interface{}{
+			"status": map[string]interface{}{"releaseLock": obj.ReleaseLock},
+		}})).To(Equal("abc"))
+	})
+
+	It("should return an empty lock without a status", func() {
+		Expect(ReleaseLock(nil)).To(BeEmpty())
+	})
+})
+
 var _ = Describe("statusFor", func() {
 	var obj *unstructured.Unstructured
 
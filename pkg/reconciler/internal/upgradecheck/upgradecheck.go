@@ -0,0 +1,125 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package upgradecheck
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/blang/semver/v4"
+)
+
+// LatestVersionFunc returns the latest published version of a chart.
+type LatestVersionFunc func(ctx context.Context, chartName string) (string, error)
+
+// Checker looks up the latest published version of a chart at most once
+// per interval and caches the result in between. Lookups of the same chart
+// are shared, and lookups of different charts run concurrently, so that a
+// slow repository only delays the checks of its own charts.
+type Checker struct {
+	latestVersion LatestVersionFunc
+	interval      time.Duration
+	now           func() time.Time
+
+	mu       sync.Mutex
+	results  map[string]result
+	inflight map[string]*lookup
+}
+
+// lookup is a lookup of the latest version of a chart that is in progress.
+// res is set before done is closed.
+type lookup struct {
+	done chan struct{}
+	res  result
+}
+
+type result struct {
+	version   string
+	err       error
+	checkedAt time.Time
+}
+
+func New(latestVersion LatestVersionFunc, interval time.Duration) *Checker {
+	return &Checker{
+		latestVersion: latestVersion,
+		interval:      interval,
+		now:           time.Now,
+		results:       map[string]result{},
+		inflight:      map[string]*lookup{},
+	}
+}
+
+// Interval returns the interval between two lookups of the same chart.
+func (c *Checker) Interval() time.Duration {
+	return c.interval
+}
+
+// LatestVersion returns the latest published version of the named chart.
+// While the chart is looked up again, the previous result is returned, if
+// any. Otherwise LatestVersion waits for the lookup in progress or until ctx
+// is done.
+func (c *Checker) LatestVersion(ctx context.Context, chartName string) (string, error) {
+	c.mu.Lock()
+	res, cached := c.results[chartName]
+	if cached && c.now().Sub(res.checkedAt) < c.interval {
+		c.mu.Unlock()
+		return res.version, res.err
+	}
+	l, running := c.inflight[chartName]
+	if running && cached {
+		c.mu.Unlock()
+		return res.version, res.err
+	}
+	if !running {
+		l = &lookup{done: make(chan struct{})}
+		c.inflight[chartName] = l
+	}
+	c.mu.Unlock()
+
+	if running {
+		select {
+		case <-l.done:
+			return l.res.version, l.res.err
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	v, err := c.latestVersion(ctx, chartName)
+	l.res = result{version: v, err: err, checkedAt: c.now()}
+	c.mu.Lock()
+	c.results[chartName] = l.res
+	delete(c.inflight, chartName)
+	c.mu.Unlock()
+	close(l.done)
+	return v, err
+}
+
+// IsNewer returns whether latest is a newer semantic version than current.
+func IsNewer(current, latest string) (bool, error) {
+	cv, err := semver.ParseTolerant(current)
+	if err != nil {
+		return false, fmt.Errorf("parse current chart version %q: %w", current, err)
+	}
+	lv, err := semver.ParseTolerant(latest)
+	if err != nil {
+		return false, fmt.Errorf("parse latest chart version %q: %w", latest, err)
+	}
+	return lv.GT(cv), nil
+}
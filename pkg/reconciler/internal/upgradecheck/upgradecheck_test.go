@@ -0,0 +1,156 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package upgradecheck
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Checker", func() {
+	var (
+		c     *Checker
+		calls int
+		now   time.Time
+		err   error
+	)
+	BeforeEach(func() {
+		calls = 0
+		err = nil
+		now = time.Now()
+		c = New(func(context.Context, string) (string, error) {
+			calls++
+			return "1.2.3", err
+		}, time.Hour)
+		c.now = func() time.Time { return now }
+	})
+
+	It("should cache the latest version for the interval", func() {
+		Expect(c.LatestVersion(context.Background(), "test-chart")).To(Equal("1.2.3"))
+		now = now.Add(59 * time.Minute)
+		Expect(c.LatestVersion(context.Background(), "test-chart")).To(Equal("1.2.3"))
+		Expect(calls).To(Equal(1))
+	})
+	It("should look up the latest version again after the interval", func() {
+		Expect(c.LatestVersion(context.Background(), "test-chart")).To(Equal("1.2.3"))
+		now = now.Add(time.Hour)
+		Expect(c.LatestVersion(context.Background(), "test-chart")).To(Equal("1.2.3"))
+		Expect(calls).To(Equal(2))
+	})
+	It("should cache lookup errors for the interval", func() {
+		err = errors.New("repository unavailable")
+		_, lookupErr := c.LatestVersion(context.Background(), "test-chart")
+		Expect(lookupErr).To(MatchError("repository unavailable"))
+		_, lookupErr = c.LatestVersion(context.Background(), "test-chart")
+		Expect(lookupErr).To(MatchError("repository unavailable"))
+		Expect(calls).To(Equal(1))
+	})
+})
+
+var _ = Describe("Checker with a slow repository", func() {
+	var (
+		c       *Checker
+		release chan struct{}
+		started chan string
+	)
+	BeforeEach(func() {
+		rel, st := make(chan struct{}), make(chan string, 10)
+		release, started = rel, st
+		c = New(func(_ context.Context, chartName string) (string, error) {
+			st <- chartName
+			if chartName == "slow-chart" {
+				<-rel
+			}
+			return "1.2.3", nil
+		}, time.Hour)
+		DeferCleanup(func() {
+			select {
+			case <-rel:
+			default:
+				close(rel)
+			}
+		})
+	})
+
+	lookUp := func(chartName string) <-chan string {
+		ch := make(chan string, 1)
+		go func() {
+			v, _ := c.LatestVersion(context.Background(), chartName)
+			ch <- v
+		}()
+		return ch
+	}
+
+	It("should not block lookups of other charts", func() {
+		slow := lookUp("slow-chart")
+		Eventually(started).Should(Receive(Equal("slow-chart")))
+		Eventually(lookUp("fast-chart")).Should(Receive(Equal("1.2.3")))
+		Consistently(slow, 50*time.Millisecond).ShouldNot(Receive())
+		close(release)
+		Eventually(slow).Should(Receive(Equal("1.2.3")))
+	})
+
+	It("should share lookups of the same chart", func() {
+		first := lookUp("slow-chart")
+		Eventually(started).Should(Receive(Equal("slow-chart")))
+		second := lookUp("slow-chart")
+		Consistently(second, 50*time.Millisecond).ShouldNot(Receive())
+		close(release)
+		Eventually(first).Should(Receive(Equal("1.2.3")))
+		Eventually(second).Should(Receive(Equal("1.2.3")))
+		Expect(started).NotTo(Receive())
+	})
+
+	It("should stop waiting for a shared lookup when the context is done", func() {
+		lookUp("slow-chart")
+		Eventually(started).Should(Receive(Equal("slow-chart")))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := c.LatestVersion(ctx, "slow-chart")
+		Expect(err).To(MatchError(context.Canceled))
+	})
+
+	It("should return the previous result while the chart is looked up again", func() {
+		now := time.Now()
+		c.now = func() time.Time { return now }
+		c.results["slow-chart"] = result{version: "1.0.0", checkedAt: now.Add(-2 * time.Hour)}
+
+		lookUp("slow-chart")
+		Eventually(started).Should(Receive(Equal("slow-chart")))
+		Expect(c.LatestVersion(context.Background(), "slow-chart")).To(Equal("1.0.0"))
+	})
+})
+
+var _ = Describe("IsNewer", func() {
+	It("should return true if latest is newer", func() {
+		Expect(IsNewer("1.2.0", "1.2.3")).To(BeTrue())
+	})
+	It("should return false if latest is the same or older", func() {
+		Expect(IsNewer("1.2.3", "1.2.3")).To(BeFalse())
+		Expect(IsNewer("1.3.0", "1.2.3")).To(BeFalse())
+	})
+	It("should fail on invalid versions", func() {
+		_, err := IsNewer("foo", "1.2.3")
+		Expect(err).To(HaveOccurred())
+		_, err = IsNewer("1.2.3", "bar")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,133 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package values
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gomodules.xyz/jsonpatch/v2"
+)
+
+// ValidatePatch checks that ops only contains add, remove and replace
+// operations with valid paths.
+func ValidatePatch(ops []jsonpatch.Operation) error {
+	for i, op := range ops {
+		switch op.Operation {
+		case "add", "remove", "replace":
+		default:
+			return fmt.Errorf("values patch operation %d: unsupported operation %q", i, op.Operation)
+		}
+		if _, err := parsePointer(op.Path); err != nil {
+			return fmt.Errorf("values patch operation %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// ApplyPatch returns a copy of vals with the JSON Patch operations ops
+// applied in order. Only add, remove and replace operations are supported.
+// As defined by RFC 6902, all elements of the path of an operation must
+// exist, except for the last element of the path of an add operation.
+//
+// Helm restores the chart defaults of keys that are missing from the values,
+// so keys removed from maps are set to null, which makes Helm remove them.
+func ApplyPatch(vals map[string]interface{}, ops []jsonpatch.Operation) (map[string]interface{}, error) {
+	out, _ := deepCopyValue(vals).(map[string]interface{})
+	for i, op := range ops {
+		tokens, err := parsePointer(op.Path)
+		if err != nil {
+			return nil, fmt.Errorf("values patch operation %d: %w", i, err)
+		}
+		if _, err := patchValue(out, tokens, op); err != nil {
+			return nil, fmt.Errorf("values patch operation %d (%s %s): %w", i, op.Operation, op.Path, err)
+		}
+	}
+	return out, nil
+}
+
+// parsePointer returns the reference tokens of the JSON pointer path.
+func parsePointer(path string) ([]string, error) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("invalid path %q: must start with /", path)
+	}
+	tokens := strings.Split(path[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+var errPathNotFound = errors.New("path does not exist")
+
+// patchValue applies op to the value at tokens in doc and returns doc, which
+// differs from the given doc if a list was grown or shrunk.
+func patchValue(doc interface{}, tokens []string, op jsonpatch.Operation) (interface{}, error) {
+	key, last := tokens[0], len(tokens) == 1
+	switch d := doc.(type) {
+	case map[string]interface{}:
+		child, ok := d[key]
+		if !last {
+			if !ok {
+				return d, errPathNotFound
+			}
+			v, err := patchValue(child, tokens[1:], op)
+			d[key] = v
+			return d, err
+		}
+		if !ok && op.Operation != "add" {
+			return d, errPathNotFound
+		}
+		if op.Operation == "remove" {
+			d[key] = nil
+		} else {
+			d[key] = deepCopyValue(op.Value)
+		}
+		return d, nil
+	case []interface{}:
+		if last && key == "-" && op.Operation == "add" {
+			return append(d, deepCopyValue(op.Value)), nil
+		}
+		idx, err := strconv.Atoi(key)
+		if err != nil || idx < 0 {
+			return d, fmt.Errorf("invalid list index %q", key)
+		}
+		if idx > len(d) || (idx == len(d) && !(last && op.Operation == "add")) {
+			return d, errPathNotFound
+		}
+		if !last {
+			v, err := patchValue(d[idx], tokens[1:], op)
+			d[idx] = v
+			return d, err
+		}
+		switch op.Operation {
+		case "add":
+			d = append(d, nil)
+			copy(d[idx+1:], d[idx:])
+			d[idx] = deepCopyValue(op.Value)
+		case "replace":
+			d[idx] = deepCopyValue(op.Value)
+		case "remove":
+			d = append(d[:idx], d[idx+1:]...)
+		}
+		return d, nil
+	default:
+		return doc, errPathNotFound
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package values_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gomodules.xyz/jsonpatch/v2"
+
+	. "github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/values"
+)
+
+var _ = Describe("ApplyPatch", func() {
+	var vals map[string]interface{}
+
+	BeforeEach(func() {
+		vals = map[string]interface{}{
+			"image": map[string]interface{}{"repository": "nginx", "tag": "1.25"},
+			"args":  []interface{}{"--a", "--b"},
+			"a/b":   "slash",
+		}
+	})
+
+	It("should add, replace and remove values", func() {
+		out, err := ApplyPatch(vals, []jsonpatch.Operation{
+			{Operation: "add", Path: "/image/pullPolicy", Value: "Always"},
+			{Operation: "replace", Path: "/args/1", Value: "--c"},
+			{Operation: "add", Path: "/args/0", Value: "--first"},
+			{Operation: "add", Path: "/args/-", Value: "--last"},
+			{Operation: "remove", Path: "/image/tag"},
+			{Operation: "replace", Path: "/a~1b", Value: map[string]interface{}{"x": int64(1)}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(Equal(map[string]interface{}{
+			"image": map[string]interface{}{"repository": "nginx", "tag": nil, "pullPolicy": "Always"},
+			"args":  []interface{}{"--first", "--a", "--c", "--last"},
+			"a/b":   map[string]interface{}{"x": int64(1)},
+		}))
+	})
+
+	It("should remove list elements", func() {
+		out, err := ApplyPatch(vals, []jsonpatch.Operation{{Operation: "remove", Path: "/args/0"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out["args"]).To(Equal([]interface{}{"--b"}))
+	})
+
+	It("should not modify the given values", func() {
+		_, err := ApplyPatch(vals, []jsonpatch.Operation{
+			{Operation: "replace", Path: "/image/tag", Value: "1.26"},
+			{Operation: "remove", Path: "/args/0"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(vals["image"]).To(HaveKeyWithValue("tag", "1.25"))
+		Expect(vals["args"]).To(Equal([]interface{}{"--a", "--b"}))
+	})
+
+	It("should fail for paths that do not exist", func() {
+		for _, op := range []jsonpatch.Operation{
+			{Operation: "remove", Path: "/image/digest"},
+			{Operation: "replace", Path: "/missing", Value: 1},
+			{Operation: "add", Path: "/missing/key", Value: 1},
+			{Operation: "replace", Path: "/args/2", Value: "--c"},
+			{Operation: "add", Path: "/args/x", Value: "--c"},
+			{Operation: "add", Path: "/image/tag/x", Value: "--c"},
+		} {
+			_, err := ApplyPatch(vals, []jsonpatch.Operation{op})
+			Expect(err).To(HaveOccurred(), op.Path)
+			Expect(err.Error()).To(ContainSubstring(op.Path))
+		}
+	})
+})
+
+var _ = Describe("ValidatePatch", func() {
+	It("should only accept add, remove and replace operations", func() {
+		Expect(ValidatePatch([]jsonpatch.Operation{{Operation: "add", Path: "/a", Value: 1}, {Operation: "remove", Path: "/b"}})).To(Succeed())
+		Expect(ValidatePatch([]jsonpatch.Operation{{Operation: "move", Path: "/a"}})).NotTo(Succeed())
+		Expect(ValidatePatch([]jsonpatch.Operation{{Operation: "add", Path: "a", Value: 1}})).NotTo(Succeed())
+	})
+})
@@ -23,6 +23,7 @@ import (
 	"helm.sh/helm/v3/pkg/strvals"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"os"
+	"sort"
 
 	"github.com/operator-framework/helm-operator-plugins/pkg/values"
 )
@@ -33,13 +34,21 @@ var DefaultTranslator = values.TranslatorFunc(func(ctx context.Context, u *unstr
 	return getSpecMap(u)
 })
 
+// ApplyOverrides sets each of overrideValues in the spec of obj. The keys
+// are applied in sorted order, so that overrides whose keys collide, such as
+// "image" and "image.tag", always produce the same spec.
 func ApplyOverrides(overrideValues map[string]string, obj *unstructured.Unstructured) error {
 	specMap, err := getSpecMap(obj)
 	if err != nil {
 		return err
 	}
-	for inK, inV := range overrideValues {
-		val := fmt.Sprintf("%s=%s", inK, os.ExpandEnv(inV))
+	keys := make([]string, 0, len(overrideValues))
+	for k := range overrideValues {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, inK := range keys {
+		val := fmt.Sprintf("%s=%s", inK, os.ExpandEnv(overrideValues[inK]))
 		if err := strvals.ParseInto(val, specMap); err != nil {
 			return err
 		}
@@ -47,6 +56,92 @@ func ApplyOverrides(overrideValues map[string]string, obj *unstructured.Unstruct
 	return nil
 }
 
+// ApplyOverrideLayers deep-merges each of layers, in order, into the spec of
+// obj. Nested maps are merged key by key, while all other values, including
+// lists, replace the existing value. Later layers therefore take precedence
+// over earlier layers and over the spec itself.
+func ApplyOverrideLayers(layers []map[string]interface{}, obj *unstructured.Unstructured) error {
+	specMap, err := getSpecMap(obj)
+	if err != nil {
+		return err
+	}
+	for _, layer := range layers {
+		mergeInto(specMap, layer)
+	}
+	return nil
+}
+
+func mergeInto(dst, src map[string]interface{}) {
+	for k, srcV := range src {
+		srcMap, srcIsMap := srcV.(map[string]interface{})
+		dstMap, dstIsMap := dst[k].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			mergeInto(dstMap, srcMap)
+			continue
+		}
+		dst[k] = deepCopyValue(srcV)
+	}
+}
+
+// ApplyMergeStrategy adjusts merged, the result of coalescing values with
+// the chart defaults, so that it reflects the given merge semantics. With
+// replaceMaps, keys of a default map that are missing from the corresponding
+// map in values are set to nil, which makes Helm drop them while rendering.
+// With appendLists, lists in values are appended to the corresponding default
+// lists instead of replacing them.
+func ApplyMergeStrategy(defaults, values, merged map[string]interface{}, replaceMaps, appendLists bool) {
+	for k, v := range values {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			defaultMap, ok := defaults[k].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			mergedMap, ok := merged[k].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if replaceMaps {
+				for dk := range defaultMap {
+					if _, ok := v[dk]; !ok {
+						mergedMap[dk] = nil
+					}
+				}
+			}
+			ApplyMergeStrategy(defaultMap, v, mergedMap, replaceMaps, appendLists)
+		case []interface{}:
+			defaultList, ok := defaults[k].([]interface{})
+			if !ok || !appendLists {
+				continue
+			}
+			list := make([]interface{}, 0, len(defaultList)+len(v))
+			list = append(list, deepCopyValue(defaultList).([]interface{})...)
+			merged[k] = append(list, deepCopyValue(v).([]interface{})...)
+		}
+	}
+}
+
+// deepCopyValue copies maps and lists so that merging never aliases the
+// configured override layers into an object.
+func deepCopyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			out[k] = deepCopyValue(e)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			out[i] = deepCopyValue(e)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
 func getSpecMap(obj *unstructured.Unstructured) (map[string]interface{}, error) {
 	if obj == nil || obj.Object == nil {
 		return nil, fmt.Errorf("nil object")
@@ -18,6 +18,7 @@ package values_test
 
 import (
 	"context"
+	"encoding/json"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"helm.sh/helm/v3/pkg/chartutil"
@@ -71,6 +72,95 @@ var _ = Describe("ApplyOverrides", func() {
 		It("should fail with invalid overrides", func() {
 			Expect(ApplyOverrides(map[string]string{"foo[": "test"}, u)).ToNot(BeNil())
 		})
+
+		It("should apply colliding overrides deterministically", func() {
+			overrides := map[string]string{
+				"list":       "{a,b}",
+				"list[2]":    "c",
+				"image.tag":  "1.0",
+				"image.name": "nginx",
+				"replicas":   "3",
+			}
+			var expected []byte
+			for i := 0; i < 100; i++ {
+				u = &unstructured.Unstructured{Object: map[string]interface{}{"spec": map[string]interface{}{}}}
+				Expect(ApplyOverrides(overrides, u)).To(Succeed())
+				out, err := json.Marshal(u.Object)
+				Expect(err).NotTo(HaveOccurred())
+				if expected == nil {
+					expected = out
+				}
+				Expect(out).To(Equal(expected))
+			}
+			Expect(u.Object["spec"]).To(HaveKeyWithValue("list", []interface{}{"a", "b", "c"}))
+		})
+	})
+})
+
+var _ = Describe("ApplyOverrideLayers", func() {
+	var u *unstructured.Unstructured
+
+	BeforeEach(func() {
+		u = &unstructured.Unstructured{Object: map[string]interface{}{"spec": map[string]interface{}{
+			"image": map[string]interface{}{"repository": "nginx", "tag": "1.0"},
+			"ports": []interface{}{int64(80)},
+		}}}
+	})
+
+	It("should error with missing spec", func() {
+		u = &unstructured.Unstructured{Object: map[string]interface{}{}}
+		Expect(ApplyOverrideLayers(nil, u)).NotTo(Succeed())
+	})
+
+	It("should merge nested maps in order", func() {
+		Expect(ApplyOverrideLayers([]map[string]interface{}{
+			{"image": map[string]interface{}{"tag": "2.0", "pullPolicy": "Always"}},
+			{"image": map[string]interface{}{"tag": "3.0"}},
+		}, u)).To(Succeed())
+		Expect(u.Object["spec"]).To(Equal(map[string]interface{}{
+			"image": map[string]interface{}{"repository": "nginx", "tag": "3.0", "pullPolicy": "Always"},
+			"ports": []interface{}{int64(80)},
+		}))
+	})
+
+	It("should merge the same layers into identical specs", func() {
+		layers := []map[string]interface{}{
+			{"image": map[string]interface{}{"tag": "2.0", "pullPolicy": "Always"}, "ports": []interface{}{int64(8080)}},
+			{"image": map[string]interface{}{"tag": "3.0", "registry": map[string]interface{}{"host": "quay.io"}}},
+		}
+		var expected []byte
+		for i := 0; i < 100; i++ {
+			u = &unstructured.Unstructured{Object: map[string]interface{}{"spec": map[string]interface{}{
+				"image": map[string]interface{}{"repository": "nginx", "tag": "1.0"},
+			}}}
+			Expect(ApplyOverrideLayers(layers, u)).To(Succeed())
+			out, err := json.Marshal(u.Object)
+			Expect(err).NotTo(HaveOccurred())
+			if expected == nil {
+				expected = out
+			}
+			Expect(out).To(Equal(expected))
+		}
+	})
+
+	It("should replace lists instead of appending to them", func() {
+		Expect(ApplyOverrideLayers([]map[string]interface{}{
+			{"ports": []interface{}{int64(8080), int64(8443)}},
+			{"ports": []interface{}{int64(9090)}},
+		}, u)).To(Succeed())
+		Expect(u.Object["spec"].(map[string]interface{})["ports"]).To(Equal([]interface{}{int64(9090)}))
+	})
+
+	It("should replace a map with a non-map value", func() {
+		Expect(ApplyOverrideLayers([]map[string]interface{}{{"image": "nginx:3.0"}}, u)).To(Succeed())
+		Expect(u.Object["spec"].(map[string]interface{})["image"]).To(Equal("nginx:3.0"))
+	})
+
+	It("should not alias the override layers", func() {
+		layer := map[string]interface{}{"extra": map[string]interface{}{"foo": "bar"}}
+		Expect(ApplyOverrideLayers([]map[string]interface{}{layer}, u)).To(Succeed())
+		u.Object["spec"].(map[string]interface{})["extra"].(map[string]interface{})["foo"] = "baz"
+		Expect(layer).To(Equal(map[string]interface{}{"extra": map[string]interface{}{"foo": "bar"}}))
 	})
 })
 
@@ -98,3 +188,33 @@ var _ = Describe("DefaultTranslator", func() {
 		Expect(DefaultTranslator.Translate(context.Background(), u)).To(Equal(chartutil.Values(m)))
 	})
 })
+
+var _ = Describe("ApplyMergeStrategy", func() {
+	var defaults, vals, merged map[string]interface{}
+
+	BeforeEach(func() {
+		defaults = map[string]interface{}{
+			"resources": map[string]interface{}{"cpu": "100m", "memory": "128Mi"},
+			"args":      []interface{}{"--default"},
+		}
+		vals = map[string]interface{}{
+			"resources": map[string]interface{}{"cpu": "1"},
+			"args":      []interface{}{"--custom"},
+		}
+		merged = map[string]interface{}{
+			"resources": map[string]interface{}{"cpu": "1", "memory": "128Mi"},
+			"args":      []interface{}{"--custom"},
+		}
+	})
+
+	It("should drop default map keys when replacing maps", func() {
+		ApplyMergeStrategy(defaults, vals, merged, true, false)
+		Expect(merged["resources"]).To(Equal(map[string]interface{}{"cpu": "1", "memory": nil}))
+		Expect(merged["args"]).To(Equal([]interface{}{"--custom"}))
+	})
+	It("should append lists to default lists", func() {
+		ApplyMergeStrategy(defaults, vals, merged, false, true)
+		Expect(merged["resources"]).To(Equal(map[string]interface{}{"cpu": "1", "memory": "128Mi"}))
+		Expect(merged["args"]).To(Equal([]interface{}{"--default", "--custom"}))
+	})
+})
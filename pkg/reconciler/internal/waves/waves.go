@@ -0,0 +1,107 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package waves
+
+import (
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Group groups objs by the wave returned by waveOf, in ascending wave order.
+// Objects of the same wave keep their relative order.
+func Group(objs []unstructured.Unstructured, waveOf func(unstructured.Unstructured) int) [][]unstructured.Unstructured {
+	byWave := map[int][]unstructured.Unstructured{}
+	for _, obj := range objs {
+		w := waveOf(obj)
+		byWave[w] = append(byWave[w], obj)
+	}
+	waves := make([]int, 0, len(byWave))
+	for w := range byWave {
+		waves = append(waves, w)
+	}
+	sort.Ints(waves)
+
+	groups := make([][]unstructured.Unstructured, 0, len(waves))
+	for _, w := range waves {
+		groups = append(groups, byWave[w])
+	}
+	return groups
+}
+
+// Ready reports whether obj is ready. Workloads are ready once their current
+// generation is rolled out and available, Jobs once they succeeded, Pods once
+// they are ready and PersistentVolumeClaims once they are bound. All other
+// objects are ready as soon as they exist.
+func Ready(obj *unstructured.Unstructured) bool {
+	gvk := obj.GroupVersionKind()
+	switch {
+	case gvk.Group == "apps" && gvk.Kind == "Deployment":
+		replicas := specReplicas(obj)
+		return observedGeneration(obj) &&
+			statusInt(obj, "updatedReplicas") >= replicas &&
+			statusInt(obj, "availableReplicas") >= replicas
+	case gvk.Group == "apps" && gvk.Kind == "StatefulSet":
+		replicas := specReplicas(obj)
+		return observedGeneration(obj) &&
+			statusInt(obj, "updatedReplicas") >= replicas &&
+			statusInt(obj, "readyReplicas") >= replicas
+	case gvk.Group == "apps" && gvk.Kind == "DaemonSet":
+		desired := statusInt(obj, "desiredNumberScheduled")
+		return observedGeneration(obj) &&
+			statusInt(obj, "updatedNumberScheduled") >= desired &&
+			statusInt(obj, "numberReady") >= desired
+	case gvk.Group == "batch" && gvk.Kind == "Job":
+		return statusInt(obj, "succeeded") > 0
+	case gvk.Group == "" && gvk.Kind == "Pod":
+		phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+		return phase == "Succeeded" || hasTrueCondition(obj, "Ready")
+	case gvk.Group == "" && gvk.Kind == "PersistentVolumeClaim":
+		phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+		return phase == "Bound"
+	default:
+		return true
+	}
+}
+
+func specReplicas(obj *unstructured.Unstructured) int64 {
+	replicas, found, err := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	if !found || err != nil {
+		return 1
+	}
+	return replicas
+}
+
+func statusInt(obj *unstructured.Unstructured, field string) int64 {
+	v, _, _ := unstructured.NestedInt64(obj.Object, "status", field)
+	return v
+}
+
+func observedGeneration(obj *unstructured.Unstructured) bool {
+	return statusInt(obj, "observedGeneration") >= obj.GetGeneration()
+}
+
+func hasTrueCondition(obj *unstructured.Unstructured, conditionType string) bool {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if ok && cond["type"] == conditionType && cond["status"] == "True" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package waves
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newObj(apiVersion, kind, name string, status map[string]interface{}) unstructured.Unstructured {
+	obj := unstructured.Unstructured{Object: map[string]interface{}{}}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetName(name)
+	if status != nil {
+		obj.Object["status"] = status
+	}
+	return obj
+}
+
+var _ = Describe("Group", func() {
+	It("should group objects in ascending wave order", func() {
+		objs := []unstructured.Unstructured{
+			newObj("apps/v1", "Deployment", "app", nil),
+			newObj("v1", "Secret", "a", nil),
+			newObj("v1", "ConfigMap", "b", nil),
+		}
+		groups := Group(objs, func(obj unstructured.Unstructured) int {
+			if obj.GetKind() == "Deployment" {
+				return 1
+			}
+			return -1
+		})
+		Expect(groups).To(HaveLen(2))
+		Expect(groups[0]).To(HaveLen(2))
+		Expect(groups[0][0].GetName()).To(Equal("a"))
+		Expect(groups[0][1].GetName()).To(Equal("b"))
+		Expect(groups[1][0].GetName()).To(Equal("app"))
+	})
+})
+
+var _ = Describe("Ready", func() {
+	It("should wait for deployments to be available", func() {
+		obj := newObj("apps/v1", "Deployment", "app", map[string]interface{}{"observedGeneration": int64(1), "updatedReplicas": int64(1)})
+		obj.SetGeneration(1)
+		Expect(Ready(&obj)).To(BeFalse())
+		obj.Object["status"].(map[string]interface{})["availableReplicas"] = int64(1)
+		Expect(Ready(&obj)).To(BeTrue())
+	})
+	It("should wait for jobs to succeed", func() {
+		obj := newObj("batch/v1", "Job", "job", map[string]interface{}{"active": int64(1)})
+		Expect(Ready(&obj)).To(BeFalse())
+		obj.Object["status"].(map[string]interface{})["succeeded"] = int64(1)
+		Expect(Ready(&obj)).To(BeTrue())
+	})
+	It("should consider other objects ready", func() {
+		obj := newObj("v1", "Secret", "a", nil)
+		Expect(Ready(&obj)).To(BeTrue())
+	})
+})
@@ -0,0 +1,136 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"helm.sh/helm/v3/pkg/release"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// LifecycleObserver is notified when the release of a CR changes state, e.g.
+// to send notifications to a chat or paging system. The methods are called
+// after the status of the CR has been updated, with a context that is
+// cancelled after the observer timeout.
+type LifecycleObserver interface {
+	// OnInstalled is called after rel was installed for obj.
+	OnInstalled(ctx context.Context, obj *unstructured.Unstructured, rel *release.Release)
+
+	// OnUpgraded is called after the release of obj was upgraded to rel.
+	OnUpgraded(ctx context.Context, obj *unstructured.Unstructured, rel *release.Release)
+
+	// OnFailed is called after an install, upgrade or uninstall of the
+	// release of obj failed with err. rel is the current release, or nil if
+	// there is none.
+	OnFailed(ctx context.Context, obj *unstructured.Unstructured, rel *release.Release, err error)
+
+	// OnUninstalled is called after rel was uninstalled because obj was
+	// deleted. rel is nil if the release did not exist.
+	OnUninstalled(ctx context.Context, obj *unstructured.Unstructured, rel *release.Release)
+}
+
+// DefaultLifecycleObserverTimeout is the time a reconciliation waits for a
+// LifecycleObserver, if not configured with WithLifecycleObserverTimeout.
+const DefaultLifecycleObserverTimeout = 10 * time.Second
+
+// WithLifecycleObserver is an Option that registers an observer that is
+// notified when the release of a CR is installed, upgraded or uninstalled,
+// or when one of these actions fails. Observers are called one after another
+// and each call is abandoned after the observer timeout, so that a slow
+// observer cannot block reconciliations indefinitely. The option may be
+// used multiple times to register several observers.
+func WithLifecycleObserver(o LifecycleObserver) Option {
+	return func(r *Reconciler) error {
+		if o == nil {
+			return errors.New("lifecycle observer must not be nil")
+		}
+		r.lifecycleObservers = append(r.lifecycleObservers, o)
+		return nil
+	}
+}
+
+// WithLifecycleObserverTimeout is an Option that configures how long a
+// reconciliation waits for each call of a LifecycleObserver.
+//
+// By default, the timeout is 10 seconds.
+func WithLifecycleObserverTimeout(timeout time.Duration) Option {
+	return func(r *Reconciler) error {
+		if timeout <= 0 {
+			return errors.New("lifecycle observer timeout must be positive")
+		}
+		r.lifecycleObserverTimeout = timeout
+		return nil
+	}
+}
+
+// lifecycleNotifier collects the lifecycle notifications of a reconciliation,
+// so that they can be sent after the status of the CR is updated.
+type lifecycleNotifier struct {
+	obj     *unstructured.Unstructured
+	pending []func(ctx context.Context, o LifecycleObserver)
+}
+
+func (n *lifecycleNotifier) installed(rel *release.Release) {
+	n.pending = append(n.pending, func(ctx context.Context, o LifecycleObserver) { o.OnInstalled(ctx, n.obj, rel) })
+}
+
+func (n *lifecycleNotifier) upgraded(rel *release.Release) {
+	n.pending = append(n.pending, func(ctx context.Context, o LifecycleObserver) { o.OnUpgraded(ctx, n.obj, rel) })
+}
+
+func (n *lifecycleNotifier) failed(rel *release.Release, err error) {
+	n.pending = append(n.pending, func(ctx context.Context, o LifecycleObserver) { o.OnFailed(ctx, n.obj, rel, err) })
+}
+
+func (n *lifecycleNotifier) uninstalled(rel *release.Release) {
+	n.pending = append(n.pending, func(ctx context.Context, o LifecycleObserver) { o.OnUninstalled(ctx, n.obj, rel) })
+}
+
+// notifyLifecycleObservers sends the notifications collected by n to all
+// lifecycle observers.
+func (r *Reconciler) notifyLifecycleObservers(ctx context.Context, n *lifecycleNotifier, log logr.Logger) {
+	timeout := r.lifecycleObserverTimeout
+	if timeout == 0 {
+		timeout = DefaultLifecycleObserverTimeout
+	}
+	for _, notify := range n.pending {
+		for _, o := range r.lifecycleObservers {
+			callObserver(ctx, timeout, o, notify, log)
+		}
+	}
+}
+
+func callObserver(ctx context.Context, timeout time.Duration, o LifecycleObserver, notify func(context.Context, LifecycleObserver), log logr.Logger) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		notify(ctx, o)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Info("Lifecycle observer did not return in time", "observer", fmt.Sprintf("%T", o), "timeout", timeout)
+	}
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/release"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+type recordingObserver struct {
+	calls []string
+	block bool
+}
+
+func (o *recordingObserver) record(ctx context.Context, call string) {
+	o.calls = append(o.calls, call)
+	if o.block {
+		<-ctx.Done()
+	}
+}
+
+func (o *recordingObserver) OnInstalled(ctx context.Context, _ *unstructured.Unstructured, rel *release.Release) {
+	o.record(ctx, "installed "+rel.Name)
+}
+
+func (o *recordingObserver) OnUpgraded(ctx context.Context, _ *unstructured.Unstructured, rel *release.Release) {
+	o.record(ctx, "upgraded "+rel.Name)
+}
+
+func (o *recordingObserver) OnFailed(ctx context.Context, _ *unstructured.Unstructured, _ *release.Release, err error) {
+	o.record(ctx, "failed "+err.Error())
+}
+
+func (o *recordingObserver) OnUninstalled(ctx context.Context, _ *unstructured.Unstructured, _ *release.Release) {
+	o.record(ctx, "uninstalled")
+}
+
+var _ = Describe("LifecycleObserver", func() {
+	var (
+		r *Reconciler
+		o *recordingObserver
+		n *lifecycleNotifier
+	)
+
+	BeforeEach(func() {
+		r = &Reconciler{}
+		o = &recordingObserver{}
+		n = &lifecycleNotifier{obj: &unstructured.Unstructured{}}
+		Expect(WithLifecycleObserver(o)(r)).To(Succeed())
+	})
+
+	It("should fail with a nil observer or an invalid timeout", func() {
+		Expect(WithLifecycleObserver(nil)(r)).NotTo(Succeed())
+		Expect(WithLifecycleObserverTimeout(0)(r)).NotTo(Succeed())
+	})
+
+	It("should notify observers in order", func() {
+		n.installed(&release.Release{Name: "a"})
+		n.upgraded(&release.Release{Name: "b"})
+		n.failed(nil, errors.New("boom"))
+		n.uninstalled(nil)
+		r.notifyLifecycleObservers(context.Background(), n, logr.Discard())
+		Expect(o.calls).To(Equal([]string{"installed a", "upgraded b", "failed boom", "uninstalled"}))
+	})
+
+	It("should not wait for observers longer than the timeout", func() {
+		o.block = true
+		Expect(WithLifecycleObserverTimeout(10 * time.Millisecond)(r)).To(Succeed())
+		n.installed(&release.Release{Name: "a"})
+		done := make(chan struct{})
+		go func() {
+			r.notifyLifecycleObservers(context.Background(), n, logr.Discard())
+			close(done)
+		}()
+		Eventually(done).Should(BeClosed())
+	})
+})
@@ -0,0 +1,99 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-logr/logr"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	helmclient "github.com/operator-framework/helm-operator-plugins/pkg/client"
+	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/conditions"
+	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/updater"
+)
+
+// WithNamespaceTerminationHandling is an Option that configures whether CRs
+// in terminating namespaces are handled specially, which is enabled by
+// default. Resources cannot be created in a terminating namespace, so instead
+// of failing to install or upgrade the release of such a CR, the reconciler
+// sets its NamespaceTerminating condition and waits for the CR to be deleted.
+// When it is deleted, the release is uninstalled without running its hooks,
+// and the finalizer is removed even if the uninstall fails, so that the CR
+// does not block the deletion of the namespace.
+//
+// The operator needs permission to get namespaces; if it cannot get the
+// namespace of a CR, the namespace is assumed not to be terminating.
+func WithNamespaceTerminationHandling(enabled bool) Option {
+	return func(r *Reconciler) error {
+		r.ignoreNamespaceTermination = !enabled
+		return nil
+	}
+}
+
+// namespaceTerminating returns whether the namespace of obj is terminating.
+func (r *Reconciler) namespaceTerminating(ctx context.Context, obj *unstructured.Unstructured, log logr.Logger) bool {
+	if r.ignoreNamespaceTermination || obj.GetNamespace() == "" {
+		return false
+	}
+	ns := &corev1.Namespace{}
+	if err := r.apiReader.Get(ctx, client.ObjectKey{Name: obj.GetNamespace()}, ns); err != nil {
+		log.V(1).Info("Unable to get the namespace of the resource", "namespace", obj.GetNamespace(), "error", err.Error())
+		return false
+	}
+	return ns.Status.Phase == corev1.NamespaceTerminating || ns.GetDeletionTimestamp() != nil
+}
+
+// uninstallFromTerminatingNamespace uninstalls the release of obj, which is
+// being deleted with its namespace, and removes the finalizer of obj even if
+// the uninstall fails, since the resources in the namespace are deleted
+// anyway. Hooks are not run, since their resources cannot be created.
+func (r *Reconciler) uninstallFromTerminatingNamespace(actionClient helmclient.ActionInterface, u *updater.Updater, obj *unstructured.Unstructured, log logr.Logger) *release.Release {
+	opts := append(r.uninstallOptions(obj), func(u *action.Uninstall) error {
+		u.DisableHooks = true
+		u.Wait = false
+		return nil
+	})
+	var rel *release.Release
+	reason := conditions.ReasonUninstallSuccessful
+	resp, err := actionClient.Uninstall(obj.GetName(), opts...)
+	switch {
+	case err == nil:
+		rel = resp.Release
+		log.Info("Release uninstalled from terminating namespace", "name", rel.Name, "version", rel.Version)
+	case errors.Is(err, driver.ErrReleaseNotFound):
+		log.Info("Release not found, removing finalizer")
+	default:
+		reason = conditions.ReasonUninstallError
+		log.Info("Failed to uninstall release from terminating namespace, removing finalizer", "error", err.Error())
+		r.eventRecorder.Eventf(obj, "Warning", "UninstallFailed",
+			"Release %q could not be uninstalled from the terminating namespace: %v", obj.GetName(), err)
+	}
+	u.Update(updater.RemoveFinalizer(r.finalizerName()))
+	u.UpdateStatus(
+		updater.EnsureCondition(conditions.NamespaceTerminating(corev1.ConditionTrue, conditions.ReasonNamespaceTerminating, "namespace is terminating")),
+		updater.EnsureCondition(conditions.Deployed(corev1.ConditionFalse, reason, "")),
+		updater.RemoveDeployedRelease(),
+	)
+	return rel
+}
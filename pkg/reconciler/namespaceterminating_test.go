@@ -0,0 +1,105 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	helmfake "github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/fake"
+	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/updater"
+)
+
+var _ = Describe("WithNamespaceTerminationHandling", func() {
+	var (
+		r        *Reconciler
+		recorder *record.FakeRecorder
+		obj      *unstructured.Unstructured
+	)
+
+	BeforeEach(func() {
+		recorder = record.NewFakeRecorder(1)
+		obj = &unstructured.Unstructured{}
+		obj.SetName("test")
+		obj.SetNamespace("tenant")
+		r = &Reconciler{
+			eventRecorder: recorder,
+			apiReader: fake.NewClientBuilder().WithObjects(&corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{Name: "tenant"},
+				Status:     corev1.NamespaceStatus{Phase: corev1.NamespaceTerminating},
+			}, &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{Name: "active"},
+				Status:     corev1.NamespaceStatus{Phase: corev1.NamespaceActive},
+			}).Build(),
+		}
+	})
+
+	It("should detect a terminating namespace by default", func() {
+		Expect(r.namespaceTerminating(context.Background(), obj, logr.Discard())).To(BeTrue())
+	})
+
+	It("should not detect a terminating namespace when disabled", func() {
+		Expect(WithNamespaceTerminationHandling(false)(r)).To(Succeed())
+		Expect(r.namespaceTerminating(context.Background(), obj, logr.Discard())).To(BeFalse())
+	})
+
+	It("should not report active or missing namespaces as terminating", func() {
+		obj.SetNamespace("active")
+		Expect(r.namespaceTerminating(context.Background(), obj, logr.Discard())).To(BeFalse())
+		obj.SetNamespace("missing")
+		Expect(r.namespaceTerminating(context.Background(), obj, logr.Discard())).To(BeFalse())
+	})
+
+	It("should uninstall without hooks", func() {
+		ac := helmfake.NewActionClient()
+		ac.HandleUninstall = func() (*release.UninstallReleaseResponse, error) {
+			return &release.UninstallReleaseResponse{Release: &release.Release{Name: "test", Version: 2}}, nil
+		}
+		u := updater.New(nil)
+		rel := r.uninstallFromTerminatingNamespace(&ac, &u, obj, logr.Discard())
+		Expect(rel).NotTo(BeNil())
+		Expect(ac.Uninstalls).To(HaveLen(1))
+		uninstall := &action.Uninstall{Wait: true}
+		for _, opt := range ac.Uninstalls[0].Opts {
+			Expect(opt(uninstall)).To(Succeed())
+		}
+		Expect(uninstall.DisableHooks).To(BeTrue())
+		Expect(uninstall.Wait).To(BeFalse())
+		Expect(recorder.Events).NotTo(Receive())
+	})
+
+	It("should not fail if the uninstall fails", func() {
+		ac := helmfake.NewActionClient()
+		ac.HandleUninstall = func() (*release.UninstallReleaseResponse, error) {
+			return nil, errors.New("namespace is terminating")
+		}
+		u := updater.New(nil)
+		Expect(r.uninstallFromTerminatingNamespace(&ac, &u, obj, logr.Discard())).To(BeNil())
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning UninstallFailed")))
+	})
+})
@@ -0,0 +1,76 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"fmt"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/release"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// maxNotesEventLength bounds the length of the messages of release notes
+// events.
+const maxNotesEventLength = 1024
+
+// WithRenderNotes is an Option that configures whether the notes of the chart,
+// rendered from its NOTES.txt with the values of the release like by helm
+// install, are surfaced to users. When enabled, the notes of the deployed
+// release are recorded in status.deployedRelease.notes, and a Normal
+// ReleaseNotes event with the notes is recorded on the CR after every install
+// and upgrade. Long notes are truncated in the event, but not in the status.
+//
+// Independent of this option, the notes are used as the message of the
+// Deployed condition. It is disabled by default.
+func WithRenderNotes(enabled bool) Option {
+	return func(r *Reconciler) error {
+		r.renderNotes = enabled
+		return nil
+	}
+}
+
+// reportNotes records an event with the notes of rel on obj, if enabled.
+func (r *Reconciler) reportNotes(obj *unstructured.Unstructured, rel *release.Release) {
+	if !r.renderNotes {
+		return
+	}
+	notes := releaseNotes(rel)
+	if notes == "" {
+		return
+	}
+	r.eventRecorder.Event(obj, "Normal", "ReleaseNotes", notesMessage(rel.Version, notes))
+}
+
+// releaseNotes returns the rendered notes of rel without surrounding
+// whitespace.
+func releaseNotes(rel *release.Release) string {
+	if rel == nil || rel.Info == nil {
+		return ""
+	}
+	return strings.TrimSpace(rel.Info.Notes)
+}
+
+// notesMessage returns the message of the notes event of a revision, with the
+// notes truncated to maxNotesEventLength.
+func notesMessage(version int, notes string) string {
+	msg := fmt.Sprintf("Notes of revision %d:\n%s", version, notes)
+	if runes := []rune(msg); len(runes) > maxNotesEventLength {
+		msg = string(runes[:maxNotesEventLength-3]) + "..."
+	}
+	return msg
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/release"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+)
+
+var _ = Describe("WithRenderNotes", func() {
+	var (
+		r        *Reconciler
+		recorder *record.FakeRecorder
+		obj      *unstructured.Unstructured
+		rel      *release.Release
+	)
+
+	BeforeEach(func() {
+		recorder = record.NewFakeRecorder(1)
+		r = &Reconciler{eventRecorder: recorder}
+		obj = &unstructured.Unstructured{}
+		rel = &release.Release{Version: 2, Info: &release.Info{Notes: "\nVisit http://example.com\n"}}
+	})
+
+	It("should record an event with the notes", func() {
+		Expect(WithRenderNotes(true)(r)).To(Succeed())
+		r.reportNotes(obj, rel)
+		Expect(recorder.Events).To(Receive(Equal("Normal ReleaseNotes Notes of revision 2:\nVisit http://example.com")))
+	})
+
+	It("should not record an event when disabled", func() {
+		r.reportNotes(obj, rel)
+		Expect(recorder.Events).NotTo(Receive())
+	})
+
+	It("should not record an event without notes", func() {
+		Expect(WithRenderNotes(true)(r)).To(Succeed())
+		r.reportNotes(obj, &release.Release{Version: 1, Info: &release.Info{}})
+		Expect(recorder.Events).NotTo(Receive())
+	})
+
+	It("should truncate long notes", func() {
+		msg := notesMessage(1, strings.Repeat("a", 2*maxNotesEventLength))
+		Expect([]rune(msg)).To(HaveLen(maxNotesEventLength))
+		Expect(msg).To(HaveSuffix("..."))
+	})
+})
@@ -0,0 +1,124 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"helm.sh/helm/v3/pkg/release"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/operator-framework/helm-operator-plugins/internal/metrics"
+	helmclient "github.com/operator-framework/helm-operator-plugins/pkg/client"
+	"github.com/operator-framework/helm-operator-plugins/pkg/internal/status"
+	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/conditions"
+	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/updater"
+)
+
+// WithObserveOnly is an Option that configures the reconciler to only observe
+// the releases of CRs, e.g. to audit pre-existing releases before enabling
+// active management. In observe-only mode, the reconciler looks up the release
+// of each CR and reports in the OutOfSync condition and the
+// helm_operator_release_out_of_sync metric whether the release is missing or
+// differs from the CR spec, but it never installs, upgrades, reconciles or
+// uninstalls releases, and it does not add the uninstall finalizer to CRs.
+// Readiness checks and chart upgrade checks are still performed, if
+// configured. It is disabled by default.
+func WithObserveOnly(observeOnly bool) Option {
+	return func(r *Reconciler) error {
+		r.observeOnly = observeOnly
+		return nil
+	}
+}
+
+// observe reports the state of the release of obj without changing it.
+func (r *Reconciler) observe(ctx context.Context, actionClient helmclient.ActionInterface, u *updater.Updater, obj *unstructured.Unstructured, log logr.Logger) (ctrl.Result, error) {
+	if obj.GetDeletionTimestamp() != nil {
+		log.V(1).Info("Not uninstalling the release of the deleted resource in observe-only mode")
+		return ctrl.Result{}, nil
+	}
+
+	vals, err := r.getValues(ctx, obj)
+	if err != nil {
+		u.UpdateStatus(
+			updater.EnsureCondition(conditions.Irreconcilable(corev1.ConditionTrue, conditions.ReasonErrorGettingValues, err)),
+			updater.EnsureConditionUnknown(conditions.TypeOutOfSync),
+		)
+		return ctrl.Result{}, newReconcileError("values", ErrValuesFailed, err)
+	}
+
+	rel, state, err := r.getReleaseState(actionClient, obj, vals.AsMap(), "")
+	if err != nil {
+		u.UpdateStatus(
+			updater.EnsureCondition(conditions.Irreconcilable(corev1.ConditionTrue, conditions.ReasonErrorGettingReleaseState, err)),
+			updater.EnsureConditionUnknown(conditions.TypeOutOfSync),
+		)
+		return ctrl.Result{}, newActionError("get release state", err)
+	}
+	log.V(1).Info("Observed release state", "state", state)
+	u.UpdateStatus(
+		updater.EnsureCondition(conditions.Irreconcilable(corev1.ConditionFalse, "", "")),
+		updater.EnsureCondition(outOfSyncCondition(state, rel)),
+	)
+	metrics.SetReleaseOutOfSync(*r.gvk, obj.GetNamespace(), obj.GetName(), state == stateNeedsInstall || state == stateNeedsUpgrade)
+
+	res := ctrl.Result{RequeueAfter: r.reconcilePeriod}
+	if rel == nil {
+		return res, nil
+	}
+	r.recordManagedResources(obj, rel, log)
+
+	if r.readinessCheck != nil {
+		ready, err := r.readinessCheck(ctx, rel)
+		switch {
+		case err != nil:
+			u.UpdateStatus(updater.EnsureCondition(conditions.WaitingForReadiness(corev1.ConditionUnknown, conditions.ReasonErrorCheckingReadiness, err)))
+		case !ready:
+			u.UpdateStatus(updater.EnsureCondition(conditions.WaitingForReadiness(corev1.ConditionTrue, conditions.ReasonReleaseNotReady, "release is not ready yet")))
+		default:
+			u.UpdateStatus(updater.EnsureCondition(conditions.WaitingForReadiness(corev1.ConditionFalse, "", "")))
+		}
+	}
+
+	if r.upgradeChecker != nil {
+		r.checkChartUpgrade(ctx, u, obj, rel, log)
+		if res.RequeueAfter == 0 || r.upgradeChecker.Interval() < res.RequeueAfter {
+			res.RequeueAfter = r.upgradeChecker.Interval()
+		}
+	}
+	return res, nil
+}
+
+// outOfSyncCondition returns the OutOfSync condition of a release in state.
+func outOfSyncCondition(state helmReleaseState, rel *release.Release) status.Condition {
+	switch state {
+	case stateNeedsInstall:
+		return conditions.OutOfSync(corev1.ConditionTrue, conditions.ReasonReleaseNotInstalled, "release is not installed")
+	case stateNeedsUpgrade:
+		return conditions.OutOfSync(corev1.ConditionTrue, conditions.ReasonReleaseOutdated,
+			fmt.Sprintf("release revision %d differs from the spec", rel.Version))
+	case statePending:
+		return conditions.OutOfSync(corev1.ConditionUnknown, conditions.ReasonReleasePending,
+			fmt.Sprintf("release revision %d has a pending operation", rel.Version))
+	default:
+		return conditions.OutOfSync(corev1.ConditionFalse, "", "")
+	}
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/release"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/conditions"
+	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/updater"
+)
+
+var _ = Describe("WithObserveOnly", func() {
+	It("should set the reconciler to observe only", func() {
+		r := &Reconciler{}
+		Expect(WithObserveOnly(true)(r)).To(Succeed())
+		Expect(r.observeOnly).To(BeTrue())
+	})
+
+	It("should not uninstall the release of a deleted resource", func() {
+		r := &Reconciler{observeOnly: true}
+		obj := &unstructured.Unstructured{}
+		now := metav1.Now()
+		obj.SetDeletionTimestamp(&now)
+		u := updater.New(nil)
+		res, err := r.observe(context.Background(), nil, &u, obj, logr.Discard())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res).To(Equal(ctrl.Result{}))
+	})
+
+	DescribeTable("should report whether the release is out of sync",
+		func(state helmReleaseState, stat corev1.ConditionStatus, reason string) {
+			cond := outOfSyncCondition(state, &release.Release{Version: 3})
+			Expect(cond.Type).To(BeEquivalentTo(conditions.TypeOutOfSync))
+			Expect(cond.Status).To(Equal(stat))
+			Expect(cond.Reason).To(BeEquivalentTo(reason))
+		},
+		Entry("when the release is missing", stateNeedsInstall, corev1.ConditionTrue, string(conditions.ReasonReleaseNotInstalled)),
+		Entry("when the release differs from the spec", stateNeedsUpgrade, corev1.ConditionTrue, string(conditions.ReasonReleaseOutdated)),
+		Entry("when the release is pending", statePending, corev1.ConditionUnknown, string(conditions.ReasonReleasePending)),
+		Entry("when the release is unchanged", stateUnchanged, corev1.ConditionFalse, ""),
+	)
+})
@@ -0,0 +1,64 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import "errors"
+
+// OperationLimiter limits the number of Helm operations that run at the same
+// time across all Reconcilers that share it, independent of the number of
+// workers of their controllers. Unlike a FairScheduler, an OperationLimiter
+// never blocks a worker: a reconcile that finds all operation slots in use is
+// requeued with backoff.
+type OperationLimiter struct {
+	slots chan struct{}
+}
+
+// NewOperationLimiter returns an OperationLimiter that allows at most max
+// Helm operations at the same time.
+func NewOperationLimiter(max int) (*OperationLimiter, error) {
+	if max < 1 {
+		return nil, errors.New("maximum number of concurrent Helm operations must be at least 1")
+	}
+	return &OperationLimiter{slots: make(chan struct{}, max)}, nil
+}
+
+// tryAcquire acquires an operation slot without waiting. If a slot was
+// acquired, it returns a function that releases the slot.
+func (l *OperationLimiter) tryAcquire() (func(), bool) {
+	select {
+	case l.slots <- struct{}{}:
+		return func() { <-l.slots }, true
+	default:
+		return nil, false
+	}
+}
+
+// WithOperationLimiter is an Option that makes the Reconciler acquire a slot
+// of l before it runs the Helm operations of a reconcile, starting with the
+// lookup of the release, and release the slot when the reconcile returns. If
+// no slot is free, the CR is requeued. The same OperationLimiter should be
+// passed to the Reconcilers of all GroupVersionKinds to bound the aggregate
+// load of Helm operations on the API server and the release storage.
+func WithOperationLimiter(l *OperationLimiter) Option {
+	return func(r *Reconciler) error {
+		if l == nil {
+			return errors.New("operation limiter must not be nil")
+		}
+		r.operationLimiter = l
+		return nil
+	}
+}
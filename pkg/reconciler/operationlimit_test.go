@@ -0,0 +1,53 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OperationLimiter", func() {
+	It("should fail without slots", func() {
+		_, err := NewOperationLimiter(0)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should not wait for a free slot", func() {
+		l, err := NewOperationLimiter(2)
+		Expect(err).NotTo(HaveOccurred())
+		release1, ok := l.tryAcquire()
+		Expect(ok).To(BeTrue())
+		_, ok = l.tryAcquire()
+		Expect(ok).To(BeTrue())
+		_, ok = l.tryAcquire()
+		Expect(ok).To(BeFalse())
+
+		release1()
+		_, ok = l.tryAcquire()
+		Expect(ok).To(BeTrue())
+	})
+
+	It("should be set on the reconciler", func() {
+		l, err := NewOperationLimiter(1)
+		Expect(err).NotTo(HaveOccurred())
+		r := &Reconciler{}
+		Expect(WithOperationLimiter(l)(r)).To(Succeed())
+		Expect(r.operationLimiter).To(BeIdenticalTo(l))
+		Expect(WithOperationLimiter(nil)(r)).NotTo(Succeed())
+	})
+})
@@ -0,0 +1,189 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"helm.sh/helm/v3/pkg/postrender"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	helmclient "github.com/operator-framework/helm-operator-plugins/pkg/client"
+)
+
+// WithOwnershipLabel is an Option that stamps the label key on all resources
+// of a release. Its value is derived from the kind of the CR and the name and
+// namespace of the release, so that it is the same for every revision of the
+// release and can be recomputed without the release.
+//
+// Enabling the label changes the manifests of existing releases, which are
+// therefore upgraded once.
+func WithOwnershipLabel(key string) Option {
+	return func(r *Reconciler) error {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("invalid ownership label %q: %s", key, strings.Join(errs, "; "))
+		}
+		r.ownershipLabel = key
+		return nil
+	}
+}
+
+// WithUninstallByOwnershipLabel is an Option that configures whether the
+// resources of a release are deleted by their ownership label when a CR is
+// deleted and its release cannot be found, e.g. because the release storage
+// was lost or corrupted. The chart is rendered to find the kinds of the
+// resources, and all resources of those kinds that carry the ownership label
+// of the release are deleted. It requires WithOwnershipLabel.
+//
+// This is riskier than a Helm uninstall, since any resource that carries the
+// label is deleted, so it is disabled by default.
+func WithUninstallByOwnershipLabel(enabled bool) Option {
+	return func(r *Reconciler) error {
+		r.uninstallByOwnershipLabel = enabled
+		return nil
+	}
+}
+
+// ownershipLabelValue returns the value of the ownership label of the
+// resources of the release of obj. Label values are limited to 63
+// characters, so a hash is used.
+func (r *Reconciler) ownershipLabelValue(obj client.Object) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{r.gvk.Group, r.gvk.Kind, r.releaseNamespace(obj), obj.GetName()}, "/")))
+	return hex.EncodeToString(sum[:20])
+}
+
+func (r *Reconciler) ownershipPostRenderer(obj client.Object) postrender.PostRenderer {
+	return ownershipLabelPostRenderer{key: r.ownershipLabel, value: r.ownershipLabelValue(obj)}
+}
+
+// ownershipInstallOptions returns the install options that stamp the
+// ownership label on the resources of the release of obj.
+func (r *Reconciler) ownershipInstallOptions(obj client.Object) []helmclient.InstallOption {
+	if r.ownershipLabel == "" {
+		return nil
+	}
+	return []helmclient.InstallOption{helmclient.AppendInstallPostRenderer(r.ownershipPostRenderer(obj))}
+}
+
+// ownershipUpgradeOptions returns the upgrade options that stamp the
+// ownership label on the resources of the release of obj.
+func (r *Reconciler) ownershipUpgradeOptions(obj client.Object) []helmclient.UpgradeOption {
+	if r.ownershipLabel == "" {
+		return nil
+	}
+	return []helmclient.UpgradeOption{helmclient.AppendUpgradePostRenderer(r.ownershipPostRenderer(obj))}
+}
+
+// ownershipLabelPostRenderer sets a label on all rendered resources.
+type ownershipLabelPostRenderer struct {
+	key   string
+	value string
+}
+
+func (pr ownershipLabelPostRenderer) Run(in *bytes.Buffer) (*bytes.Buffer, error) {
+	objs, err := parseManifests(in.String())
+	if err != nil {
+		return nil, err
+	}
+	out := &bytes.Buffer{}
+	for i := range objs {
+		lbls := objs[i].GetLabels()
+		if lbls == nil {
+			lbls = map[string]string{}
+		}
+		lbls[pr.key] = pr.value
+		objs[i].SetLabels(lbls)
+
+		data, err := yaml.Marshal(objs[i].Object)
+		if err != nil {
+			return nil, err
+		}
+		out.WriteString("---\n")
+		out.Write(data)
+	}
+	return out, nil
+}
+
+// deleteByOwnershipLabel deletes the resources of the release of obj that
+// carry its ownership label. The kinds and namespaces of the resources are
+// taken from the chart, rendered with the current values of obj. It returns
+// the number of deleted resources.
+func (r *Reconciler) deleteByOwnershipLabel(ctx context.Context, obj *unstructured.Unstructured, log logr.Logger) (int, error) {
+	if r.ownershipLabel == "" {
+		return 0, errors.New("no ownership label configured")
+	}
+	vals, err := r.getValues(ctx, obj.DeepCopy())
+	if err != nil {
+		return 0, fmt.Errorf("get values: %w", err)
+	}
+	rendered, err := r.renderClientOnly(obj, vals.AsMap())
+	if err != nil {
+		return 0, err
+	}
+
+	type listKey struct {
+		gvk       schema.GroupVersionKind
+		namespace string
+	}
+	selector := client.MatchingLabels{r.ownershipLabel: r.ownershipLabelValue(obj)}
+	listed := map[listKey]struct{}{}
+	deleted := 0
+	for i := range rendered {
+		key := listKey{gvk: rendered[i].GroupVersionKind()}
+		namespaced, err := r.client.IsObjectNamespaced(&rendered[i])
+		if err != nil {
+			return deleted, fmt.Errorf("get scope of %s: %w", key.gvk.Kind, err)
+		}
+		if namespaced {
+			key.namespace = rendered[i].GetNamespace()
+			if key.namespace == "" {
+				key.namespace = r.releaseNamespace(obj)
+			}
+		}
+		if _, ok := listed[key]; ok {
+			continue
+		}
+		listed[key] = struct{}{}
+
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(key.gvk.GroupVersion().WithKind(key.gvk.Kind + "List"))
+		if err := r.apiReader.List(ctx, list, client.InNamespace(key.namespace), selector); err != nil {
+			return deleted, fmt.Errorf("list %s: %w", key.gvk.Kind, err)
+		}
+		for j := range list.Items {
+			item := &list.Items[j]
+			if err := r.client.Delete(ctx, item, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+				return deleted, fmt.Errorf("delete %s %s: %w", item.GetKind(), client.ObjectKeyFromObject(item), err)
+			}
+			log.Info("Deleted resource by ownership label", "kind", item.GetKind(), "name", client.ObjectKeyFromObject(item))
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	helmclient "github.com/operator-framework/helm-operator-plugins/pkg/client"
+)
+
+// WithPropagatedAnnotations is an Option that copies the given annotations of
+// a CR onto all resources of its release and onto the release itself, e.g.
+// cost-center or owner-team annotations. Keys that are not present on the CR
+// are skipped. Since a release has no annotations of its own, they are stored
+// as annotations of the chart metadata of the release.
+//
+// A change of a propagated annotation of a CR upgrades its release.
+func WithPropagatedAnnotations(keys []string) Option {
+	return func(r *Reconciler) error {
+		for _, key := range keys {
+			if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+				return fmt.Errorf("invalid propagated annotation %q: %s", key, strings.Join(errs, "; "))
+			}
+		}
+		r.propagatedAnnotations = append(r.propagatedAnnotations, keys...)
+		return nil
+	}
+}
+
+// propagatedAnnotationValues returns the propagated annotations of obj, or nil
+// if it has none.
+func (r *Reconciler) propagatedAnnotationValues(obj client.Object) map[string]string {
+	var out map[string]string
+	annotations := obj.GetAnnotations()
+	for _, key := range r.propagatedAnnotations {
+		v, ok := annotations[key]
+		if !ok {
+			continue
+		}
+		if out == nil {
+			out = map[string]string{}
+		}
+		out[key] = v
+	}
+	return out
+}
+
+// releaseChart returns the chart of the release of obj, which carries the
+// propagated annotations of obj in its metadata.
+func (r *Reconciler) releaseChart(obj client.Object) *chart.Chart {
+	annotations := r.propagatedAnnotationValues(obj)
+	if len(annotations) == 0 || r.chrt.Metadata == nil {
+		return r.chrt
+	}
+	chrt := *r.chrt
+	meta := *r.chrt.Metadata
+	meta.Annotations = make(map[string]string, len(r.chrt.Metadata.Annotations)+len(annotations))
+	for k, v := range r.chrt.Metadata.Annotations {
+		meta.Annotations[k] = v
+	}
+	for k, v := range annotations {
+		meta.Annotations[k] = v
+	}
+	chrt.Metadata = &meta
+	return &chrt
+}
+
+// propagatedAnnotationInstallOptions returns the install options that copy the
+// propagated annotations of obj onto the resources of its release.
+func (r *Reconciler) propagatedAnnotationInstallOptions(obj client.Object) []helmclient.InstallOption {
+	annotations := r.propagatedAnnotationValues(obj)
+	if len(annotations) == 0 {
+		return nil
+	}
+	return []helmclient.InstallOption{helmclient.AppendInstallPostRenderer(annotationsPostRenderer{annotations: annotations})}
+}
+
+// propagatedAnnotationUpgradeOptions returns the upgrade options that copy the
+// propagated annotations of obj onto the resources of its release.
+func (r *Reconciler) propagatedAnnotationUpgradeOptions(obj client.Object) []helmclient.UpgradeOption {
+	annotations := r.propagatedAnnotationValues(obj)
+	if len(annotations) == 0 {
+		return nil
+	}
+	return []helmclient.UpgradeOption{helmclient.AppendUpgradePostRenderer(annotationsPostRenderer{annotations: annotations})}
+}
+
+// annotationsPostRenderer sets annotations on all rendered resources.
+type annotationsPostRenderer struct {
+	annotations map[string]string
+}
+
+func (pr annotationsPostRenderer) Run(in *bytes.Buffer) (*bytes.Buffer, error) {
+	objs, err := parseManifests(in.String())
+	if err != nil {
+		return nil, err
+	}
+	out := &bytes.Buffer{}
+	for i := range objs {
+		annotations := objs[i].GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		for k, v := range pr.annotations {
+			annotations[k] = v
+		}
+		objs[i].SetAnnotations(annotations)
+
+		data, err := yaml.Marshal(objs[i].Object)
+		if err != nil {
+			return nil, err
+		}
+		out.WriteString("---\n")
+		out.Write(data)
+	}
+	return out, nil
+}
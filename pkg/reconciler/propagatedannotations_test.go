@@ -0,0 +1,95 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"bytes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/chart"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var _ = Describe("WithPropagatedAnnotations", func() {
+	var (
+		r   *Reconciler
+		obj *unstructured.Unstructured
+	)
+
+	BeforeEach(func() {
+		r = &Reconciler{chrt: &chart.Chart{Metadata: &chart.Metadata{
+			Name:        "test",
+			Version:     "0.1.0",
+			Annotations: map[string]string{"category": "web"},
+		}}}
+		obj = &unstructured.Unstructured{}
+		obj.SetName("test")
+		obj.SetAnnotations(map[string]string{"example.com/cost-center": "1234", "unrelated": "x"})
+	})
+
+	It("should fail with an invalid key", func() {
+		Expect(WithPropagatedAnnotations([]string{"invalid key"})(r)).NotTo(Succeed())
+	})
+
+	It("should skip keys that are not present on the CR", func() {
+		Expect(WithPropagatedAnnotations([]string{"example.com/cost-center", "example.com/owner-team"})(r)).To(Succeed())
+		Expect(r.propagatedAnnotationValues(obj)).To(Equal(map[string]string{"example.com/cost-center": "1234"}))
+	})
+
+	It("should set the annotations on the chart of the release", func() {
+		Expect(WithPropagatedAnnotations([]string{"example.com/cost-center"})(r)).To(Succeed())
+		chrt := r.releaseChart(obj)
+		Expect(chrt.Metadata.Annotations).To(Equal(map[string]string{"category": "web", "example.com/cost-center": "1234"}))
+		Expect(r.chrt.Metadata.Annotations).To(Equal(map[string]string{"category": "web"}))
+	})
+
+	It("should use the chart when the CR has no propagated annotations", func() {
+		Expect(WithPropagatedAnnotations([]string{"example.com/owner-team"})(r)).To(Succeed())
+		Expect(r.releaseChart(obj)).To(BeIdenticalTo(r.chrt))
+		Expect(r.propagatedAnnotationInstallOptions(obj)).To(BeEmpty())
+	})
+
+	It("should set the annotations on all rendered resources", func() {
+		pr := annotationsPostRenderer{annotations: map[string]string{"example.com/cost-center": "1234"}}
+		out, err := pr.Run(bytes.NewBufferString(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a
+  annotations:
+    existing: "true"
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: b
+`))
+		Expect(err).NotTo(HaveOccurred())
+		objs, err := parseManifests(out.String())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(objs).To(HaveLen(2))
+		Expect(objs[0].GetAnnotations()).To(Equal(map[string]string{"existing": "true", "example.com/cost-center": "1234"}))
+		Expect(objs[1].GetAnnotations()).To(Equal(map[string]string{"example.com/cost-center": "1234"}))
+	})
+
+	It("should change the release inputs when an annotation changes", func() {
+		Expect(WithPropagatedAnnotations([]string{"example.com/cost-center"})(r)).To(Succeed())
+		before := r.releaseInputsDigest(obj, nil)
+		obj.SetAnnotations(map[string]string{"example.com/cost-center": "5678"})
+		Expect(r.releaseInputsDigest(obj, nil)).NotTo(Equal(before))
+	})
+})
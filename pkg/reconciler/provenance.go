@@ -0,0 +1,126 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/downloader"
+
+	"github.com/operator-framework/helm-operator-plugins/pkg/chartfs"
+)
+
+// chartArchive is the packaged chart archive from which the chart of a
+// reconciler was loaded, together with its provenance file, if any.
+type chartArchive struct {
+	// name is the file name of the archive, which the provenance file
+	// refers to.
+	name string
+	data []byte
+	prov []byte
+	// digest identifies the name and content of the archive and its
+	// provenance file, so that the result of their verification can be
+	// cached.
+	digest string
+}
+
+// loadChartFS loads the chart directory or archive at name from fsys. If name
+// is an archive, it is returned with the provenance file next to it.
+func loadChartFS(fsys fs.FS, name string) (*chart.Chart, *chartArchive, error) {
+	fi, err := fs.Stat(fsys, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if fi.IsDir() {
+		chrt, err := chartfs.Load(fsys, name)
+		return chrt, nil, err
+	}
+
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	prov, err := fs.ReadFile(fsys, name+".prov")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, err
+	}
+	chrt, err := loader.LoadArchive(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	base := path.Base(name)
+	h := sha256.New()
+	h.Write([]byte(base))
+	h.Write(data)
+	h.Write(prov)
+	return chrt, &chartArchive{
+		name:   base,
+		data:   data,
+		prov:   prov,
+		digest: hex.EncodeToString(h.Sum(nil)),
+	}, nil
+}
+
+// verifyProvenance verifies the provenance of the archive from which the
+// current chart was loaded, if configured. The result is cached until the
+// archive changes. It must be called while holding chrtMu.
+func (r *Reconciler) verifyProvenance() error {
+	if r.provenanceKeyring == "" {
+		return nil
+	}
+	a := r.chrtArchive
+	if a == nil {
+		return errors.New("chart was not loaded from a packaged chart archive")
+	}
+	if a.prov == nil {
+		return fmt.Errorf("chart archive %s has no provenance file", a.name)
+	}
+
+	r.provenanceMu.Lock()
+	defer r.provenanceMu.Unlock()
+	if a.digest == r.provenanceDigest {
+		return r.provenanceErr
+	}
+
+	// The archive and its provenance file are verified by Helm, which reads
+	// them from disk.
+	dir, err := os.MkdirTemp("", "chart-provenance-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	archivePath := filepath.Join(dir, a.name)
+	if err := os.WriteFile(archivePath, a.data, 0o600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(archivePath+".prov", a.prov, 0o600); err != nil {
+		return err
+	}
+	_, r.provenanceErr = downloader.VerifyChart(archivePath, r.provenanceKeyring)
+	r.provenanceDigest = a.digest
+	return r.provenanceErr
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"helm.sh/helm/v3/pkg/release"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/updater"
+)
+
+// WithResultAnnotation is an Option that configures the Reconciler to write a
+// compact JSON summary of the last reconcile to the annotation key of the CR,
+// for tools that do not understand Helm or the status of the CR, e.g. GitOps
+// dashboards. For example:
+//
+//	{"action":"upgrade","revision":3,"succeeded":true,"time":"2023-06-01T12:00:00Z"}
+//
+// action is one of install, upgrade, uninstall, noop or none. On failure, the
+// summary includes the error, truncated to keep the annotation small. To
+// avoid triggering further reconciles, the annotation is only updated when
+// the summary changes, so time is the time of the last change.
+func WithResultAnnotation(key string) Option {
+	return func(r *Reconciler) error {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("invalid result annotation %q: %s", key, strings.Join(errs, "; "))
+		}
+		r.resultAnnotation = key
+		return nil
+	}
+}
+
+// WithTimingLogs is an Option that configures whether the end of every
+// reconcile is logged with a breakdown of its duration in milliseconds:
+// renderMs is the time spent rendering the chart, including dry-runs, applyMs
+// the time spent applying the release, and waitMs the time spent waiting for
+// the release to become ready and for its chart tests. It is disabled by
+// default to avoid the log volume.
+func WithTimingLogs(enabled bool) Option {
+	return func(r *Reconciler) error {
+		r.timingLogs = enabled
+		return nil
+	}
+}
+
+// WithTimingLogVerbosity is an Option that configures the verbosity level of
+// the timing logs enabled with WithTimingLogs. It defaults to 0.
+func WithTimingLogVerbosity(level int) Option {
+	return func(r *Reconciler) error {
+		if level < 0 {
+			return errors.New("timing log verbosity must not be negative")
+		}
+		r.timingLogVerbosity = level
+		return nil
+	}
+}
+
+// maxResultErrorLength bounds the length of the error of a result
+// annotation.
+const maxResultErrorLength = 256
+
+// reconcileResult is the summary written to the annotation configured with
+// WithResultAnnotation.
+type reconcileResult struct {
+	Action    reconcileAction `json:"action"`
+	Revision  int             `json:"revision,omitempty"`
+	Succeeded bool            `json:"succeeded"`
+	Error     string          `json:"error,omitempty"`
+	Time      string          `json:"time,omitempty"`
+}
+
+// reconcileAction is the Helm action taken by a reconcile.
+type reconcileAction string
+
+const (
+	reconcileActionNone      reconcileAction = "none"
+	reconcileActionInstall   reconcileAction = "install"
+	reconcileActionUpgrade   reconcileAction = "upgrade"
+	reconcileActionNoop      reconcileAction = "noop"
+	reconcileActionUninstall reconcileAction = "uninstall"
+)
+
+// reconcileSummary collects the action taken by a reconcile and the
+// resulting release revision, which are logged when the reconcile ends.
+type reconcileSummary struct {
+	start    time.Time
+	action   reconcileAction
+	revision int
+
+	// render, apply and wait are the durations of the phases of the
+	// reconcile, which are logged with WithTimingLogs.
+	render     time.Duration
+	apply      time.Duration
+	wait       time.Duration
+	phase      *time.Duration
+	phaseStart time.Time
+}
+
+func newReconcileSummary() *reconcileSummary {
+	return &reconcileSummary{start: time.Now(), action: reconcileActionNone}
+}
+
+// set records action and, if rel is not nil, its revision. It is called
+// before the action is attempted, so that failures are logged with the
+// action, and again with the resulting release.
+func (s *reconcileSummary) set(action reconcileAction, rel *release.Release) {
+	s.action = action
+	if rel != nil {
+		s.revision = rel.Version
+	}
+}
+
+// log logs the end of the reconcile. log is expected to carry the
+// correlation fields of the reconcile.
+func (s *reconcileSummary) log(log logr.Logger, err error) {
+	kv := []interface{}{"action", s.action, "duration", time.Since(s.start)}
+	if s.revision > 0 {
+		kv = append(kv, "revision", s.revision)
+	}
+	if err != nil {
+		log.Error(err, "Reconciliation failed", kv...)
+		return
+	}
+	log.Info("Reconciliation finished", kv...)
+}
+
+// enter ends the current phase of the reconcile, if any, and starts phase,
+// which may be nil. The phases are not nested.
+func (s *reconcileSummary) enter(phase *time.Duration) {
+	now := time.Now()
+	if s.phase != nil {
+		*s.phase += now.Sub(s.phaseStart)
+	}
+	s.phase, s.phaseStart = phase, now
+}
+
+// logTimings logs the durations of the phases of the reconcile.
+func (s *reconcileSummary) logTimings(log logr.Logger) {
+	s.enter(nil)
+	log.Info("Reconciliation timings",
+		"renderMs", s.render.Milliseconds(),
+		"applyMs", s.apply.Milliseconds(),
+		"waitMs", s.wait.Milliseconds(),
+		"totalMs", time.Since(s.start).Milliseconds(),
+	)
+}
+
+// ensureResultAnnotation returns an UpdateFunc that writes the summary and
+// err to the annotation key, unless only the time of the summary changed.
+func (s *reconcileSummary) ensureResultAnnotation(key string, err error) updater.UpdateFunc {
+	return func(obj *unstructured.Unstructured) bool {
+		result := reconcileResult{Action: s.action, Revision: s.revision, Succeeded: err == nil}
+		if err != nil {
+			result.Error = err.Error()
+			// Truncate runes rather than bytes, so that the error round-trips
+			// through JSON unchanged.
+			if msg := []rune(result.Error); len(msg) > maxResultErrorLength {
+				result.Error = string(msg[:maxResultErrorLength-3]) + "..."
+			}
+		}
+		annotations := obj.GetAnnotations()
+		var cur reconcileResult
+		if v, ok := annotations[key]; ok && json.Unmarshal([]byte(v), &cur) == nil {
+			cur.Time = ""
+			if cur == result {
+				return false
+			}
+		}
+		result.Time = time.Now().UTC().Format(time.RFC3339)
+		data, _ := json.Marshal(result)
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[key] = string(data)
+		obj.SetAnnotations(annotations)
+		return true
+	}
+}
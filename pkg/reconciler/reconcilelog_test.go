@@ -0,0 +1,139 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr/funcr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/release"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var _ = Describe("reconcileSummary", func() {
+	var lines []string
+
+	log := funcr.New(func(prefix, args string) {
+		lines = append(lines, args)
+	}, funcr.Options{})
+
+	BeforeEach(func() {
+		lines = nil
+	})
+
+	It("should log the action and revision", func() {
+		s := newReconcileSummary()
+		s.set(reconcileActionUpgrade, &release.Release{Version: 2})
+		s.set(reconcileActionUpgrade, &release.Release{Version: 3})
+		s.log(log, nil)
+		Expect(lines).To(HaveLen(1))
+		Expect(lines[0]).To(ContainSubstring(`"msg"="Reconciliation finished"`))
+		Expect(lines[0]).To(ContainSubstring(`"action"="upgrade"`))
+		Expect(lines[0]).To(ContainSubstring(`"revision"=3`))
+		Expect(lines[0]).To(ContainSubstring(`"duration"=`))
+	})
+
+	It("should log failures with the error", func() {
+		s := newReconcileSummary()
+		s.set(reconcileActionInstall, nil)
+		s.log(log, errors.New("install failed"))
+		Expect(lines).To(HaveLen(1))
+		Expect(lines[0]).To(ContainSubstring(`"msg"="Reconciliation failed"`))
+		Expect(lines[0]).To(ContainSubstring(`"error"="install failed"`))
+		Expect(lines[0]).To(ContainSubstring(`"action"="install"`))
+		Expect(lines[0]).NotTo(ContainSubstring(`"revision"`))
+	})
+
+	It("should default to no action", func() {
+		newReconcileSummary().log(log, nil)
+		Expect(lines[0]).To(ContainSubstring(`"action"="none"`))
+	})
+
+	It("should log the timings of the phases", func() {
+		s := newReconcileSummary()
+		s.enter(&s.render)
+		time.Sleep(10 * time.Millisecond)
+		s.enter(&s.apply)
+		time.Sleep(10 * time.Millisecond)
+		s.logTimings(log)
+		Expect(s.render).To(BeNumerically(">=", 10*time.Millisecond))
+		Expect(s.apply).To(BeNumerically(">=", 10*time.Millisecond))
+		Expect(s.wait).To(BeZero())
+		Expect(lines).To(HaveLen(1))
+		Expect(lines[0]).To(ContainSubstring(`"msg"="Reconciliation timings"`))
+		Expect(lines[0]).To(ContainSubstring(`"waitMs"=0`))
+		Expect(lines[0]).To(MatchRegexp(`"renderMs"=\d+ "applyMs"=\d+ "waitMs"=0 "totalMs"=\d+`))
+	})
+})
+
+var _ = Describe("WithTimingLogVerbosity", func() {
+	It("should fail with a negative level", func() {
+		Expect(WithTimingLogVerbosity(-1)(&Reconciler{})).NotTo(Succeed())
+		Expect(WithTimingLogVerbosity(2)(&Reconciler{})).To(Succeed())
+	})
+})
+
+var _ = Describe("WithResultAnnotation", func() {
+	const key = "example.com/last-reconcile"
+
+	It("should fail with an invalid key", func() {
+		Expect(WithResultAnnotation("")(&Reconciler{})).NotTo(Succeed())
+		Expect(WithResultAnnotation("a/b/c")(&Reconciler{})).NotTo(Succeed())
+	})
+
+	It("should write the summary", func() {
+		r := &Reconciler{}
+		Expect(WithResultAnnotation(key)(r)).To(Succeed())
+		s := newReconcileSummary()
+		s.set(reconcileActionUpgrade, &release.Release{Version: 3})
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+		Expect(s.ensureResultAnnotation(r.resultAnnotation, nil)(obj)).To(BeTrue())
+
+		var result map[string]interface{}
+		Expect(json.Unmarshal([]byte(obj.GetAnnotations()[key]), &result)).To(Succeed())
+		Expect(result).To(HaveKeyWithValue("action", "upgrade"))
+		Expect(result).To(HaveKeyWithValue("revision", BeNumerically("==", 3)))
+		Expect(result).To(HaveKeyWithValue("succeeded", true))
+		Expect(result).To(HaveKey("time"))
+		Expect(result).NotTo(HaveKey("error"))
+	})
+
+	It("should only update the annotation when the summary changes", func() {
+		s := newReconcileSummary()
+		s.set(reconcileActionNoop, &release.Release{Version: 2})
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+		Expect(s.ensureResultAnnotation(key, nil)(obj)).To(BeTrue())
+		Expect(s.ensureResultAnnotation(key, nil)(obj)).To(BeFalse())
+		Expect(s.ensureResultAnnotation(key, errors.New("upgrade failed"))(obj)).To(BeTrue())
+		Expect(obj.GetAnnotations()[key]).To(ContainSubstring(`"error":"upgrade failed"`))
+		Expect(obj.GetAnnotations()[key]).To(ContainSubstring(`"succeeded":false`))
+	})
+
+	It("should truncate long errors", func() {
+		s := newReconcileSummary()
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+		err := errors.New(strings.Repeat("ä", 1000))
+		Expect(s.ensureResultAnnotation(key, err)(obj)).To(BeTrue())
+		Expect(len(obj.GetAnnotations()[key])).To(BeNumerically("<", 1024))
+		Expect(s.ensureResultAnnotation(key, err)(obj)).To(BeFalse())
+	})
+})
@@ -20,72 +20,230 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/fs"
+	"os"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
 	sdkhandler "github.com/operator-framework/operator-lib/handler"
+	"gomodules.xyz/jsonpatch/v2"
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
 	"helm.sh/helm/v3/pkg/storage/driver"
 	corev1 "k8s.io/api/core/v1"
+	structuralschema "k8s.io/apiextensions-apiserver/pkg/apiserver/schema"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	ctrlpredicate "sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
+	"sigs.k8s.io/yaml"
 
+	"github.com/operator-framework/helm-operator-plugins/internal/metrics"
 	"github.com/operator-framework/helm-operator-plugins/internal/sdk/controllerutil"
 	"github.com/operator-framework/helm-operator-plugins/pkg/annotation"
+	"github.com/operator-framework/helm-operator-plugins/pkg/chartrepo"
 	helmclient "github.com/operator-framework/helm-operator-plugins/pkg/client"
 	"github.com/operator-framework/helm-operator-plugins/pkg/hook"
+	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/breaker"
 	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/conditions"
+	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/debounce"
 	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/diff"
+	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/fairness"
 	internalhook "github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/hook"
+	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/keylock"
+	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/redact"
+	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/securitycontext"
 	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/updater"
+	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/upgradecheck"
 	internalvalues "github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/values"
+	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/waves"
 	"github.com/operator-framework/helm-operator-plugins/pkg/values"
 )
 
+// uninstallFinalizer is the default finalizer that blocks the deletion of a
+// CR until its release is uninstalled.
 const uninstallFinalizer = "uninstall-helm-release"
 
+// CancelUninstallAnnotation is the annotation that cancels the uninstall of
+// a release during the grace period configured with WithUninstallGracePeriod.
+const CancelUninstallAnnotation = "helm.sdk.operatorframework.io/cancel-uninstall"
+
+// TimeoutAnnotation is the annotation that overrides the timeout configured
+// with WithActionTimeout for the Helm actions of a CR, e.g. "10m". A value of
+// "0" disables the timeout for the CR.
+const TimeoutAnnotation = "helm.sdk.operatorframework.io/timeout"
+
+// DefaultChartUpgradeCheckInterval is the interval at which the chart
+// repository is queried for newer chart versions when WithChartUpgradeCheck
+// is configured without an explicit interval.
+const DefaultChartUpgradeCheckInterval = 6 * time.Hour
+
+// DefaultForegroundUninstallTimeout is the maximum duration of an uninstall
+// with the foreground delete propagation policy, if no action timeout is
+// configured.
+const DefaultForegroundUninstallTimeout = 5 * time.Minute
+
 // Reconciler reconciles a Helm object
 type Reconciler struct {
-	client             client.Client
-	actionClientGetter helmclient.ActionClientGetter
-	valueTranslator    values.Translator
-	valueMapper        values.Mapper // nolint:staticcheck
-	eventRecorder      record.EventRecorder
-	preHooks           []hook.PreHook
-	postHooks          []hook.PostHook
+	client               client.Client
+	apiReader            client.Reader
+	chrtMu               sync.RWMutex
+	chartSource          ChartSource
+	chartRevision        string
+	chartRefreshInterval time.Duration
+	chartChangeMu        sync.Mutex
+	chartChangeTarget    *resyncTarget
+	releaseNS            string
+	releaseStorageNS     string
+
+	updateUnchangedStatus bool
+	statusSubresource     *bool
+	applySchemaDefaults   bool
+	schemaDefaults        *structuralschema.Structural
+
+	actionTimeout time.Duration
+
+	debounceWindow time.Duration
+
+	releaseLocks       keylock.KeyLock
+	releaseLockTimeout time.Duration
+
+	pendingReleaseRequeueDelay   time.Duration
+	pendingReleaseTimeout        time.Duration
+	pendingReleaseRecoveryPolicy PendingReleaseRecoveryPolicy
+
+	provenanceKeyring string
+	provenanceMu      sync.Mutex
+	provenanceDigest  string
+	provenanceErr     error
+
+	podSecurityContext       *corev1.PodSecurityContext
+	containerSecurityContext *corev1.SecurityContext
+
+	uninstallGracePeriod time.Duration
+	uninstallGraceMu     sync.Mutex
+	uninstallGraceActive map[types.NamespacedName]struct{}
+	actionClientGetter   helmclient.ActionClientGetter
+	kubeClientFactory    helmclient.KubeClientFactoryFunc
+	clusterResolver      helmclient.ClusterResolverFunc
+	impersonate          ImpersonationFunc
+	valueTranslator      values.Translator
+	valueMapper          values.Mapper // nolint:staticcheck
+	eventRecorder        record.EventRecorder
+	preHooks             []hook.PreHook
+	postHooks            []hook.PostHook
 
 	log                              logr.Logger
 	gvk                              *schema.GroupVersionKind
+	additionalGVKs                   []schema.GroupVersionKind
+	dependencies                     []dependency
+	readinessCheck                   ReadinessCheckFunc
+	manifestValidator                ManifestValidatorFunc
+	allowedKinds                     []schema.GroupVersionKind
+	deniedKinds                      []schema.GroupVersionKind
+	applyWaves                       ApplyWaveFunc
+	crdUpgradePolicy                 CRDUpgradePolicy
+	upgradeValuesPolicy              UpgradeValuesPolicy
 	chrt                             *chart.Chart
+	chrtArchive                      *chartArchive
 	selectorPredicate                predicate.Predicate
+	generationChangedPredicate       predicate.Predicate
+	predicates                       []predicate.Predicate
+	featureGates                     map[FeatureGate]bool
+	unknownFeatureGates              []string
 	overrideValues                   map[string]string
+	overrideValuesLayers             []map[string]interface{}
+	environmentOverrides             map[string]map[string]interface{}
+	environmentAnnotation            string
+	valuesFromEnv                    map[string]string
+	missingEnvPolicy                 MissingEnvPolicy
+	valuesFiles                      []string
+	valuesFilePrecedence             ValuesFilePrecedence
+	mergeStrategy                    MergeStrategy
 	skipDependentWatches             bool
 	maxConcurrentReconciles          int
+	fairScheduler                    *FairScheduler
+	operationLimiter                 *OperationLimiter
+	resync                           *Resync
+	secretKeyRefs                    bool
+	deletePropagationPolicy          metav1.DeletionPropagation
+	renderNotes                      bool
+	observeOnly                      bool
+	chartTests                       bool
+	chartTestFailurePolicy           ChartTestFailurePolicy
+	chartTestCleanupPolicy           ChartTestCleanupPolicy
+	errorClassifier                  ErrorClassifier
+	transientErrorRequeueDelay       time.Duration
+	secretValues                     *redact.Values
+	lifecycleObservers               []LifecycleObserver
+	lifecycleObserverTimeout         time.Duration
 	reconcilePeriod                  time.Duration
 	maxHistory                       int
+	skipUnchangedDryRun              bool
+	forceUpgrade                     bool
+	preprocessCR                     PreprocessCRFunc
+	lastErrorStatus                  bool
+	adoptionSelector                 AdoptionSelectorFunc
+	ownershipLabel                   string
+	finalizer                        string
+	resultAnnotation                 string
+	uninstallByOwnershipLabel        bool
+	releaseDescription               ReleaseDescriptionFunc
+	breaker                          *breaker.Breaker
+	manifestSink                     ManifestSinkFunc
+	manifestSinkFatal                bool
+	manifestSinkRedactSecrets        bool
+	upgradeDiffEvents                bool
+	maxManifestSize                  int
+	exportedRevisionsMu              sync.Mutex
+	exportedRevisions                map[types.NamespacedName]int
 	skipPrimaryGVKSchemeRegistration bool
+	upgradeChecker                   *upgradecheck.Checker
+	patchStrategies                  map[schema.GroupVersionKind]helmclient.PatchStrategy
+	allowedValuePaths                [][]string
+	disallowedValuesPolicy           DisallowedValuesPolicy
+	detectExternalModification       bool
+	pauseOnExternalModification      bool
+	resourceOverrides                ResourceOverridesFunc
+	propagatedAnnotations            []string
+	contentBasedUpgradeDetection     bool
+	hookJobDefaults                  *hookJobDefaults
+	timingLogs                       bool
+	timingLogVerbosity               int
+	watchTypes                       []watchType
+	valuesPatch                      []jsonpatch.Operation
+	ignoreNamespaceTermination       bool
+	installWaitTimeout               time.Duration
+	ownerReferencePolicy             helmclient.OwnerReferencePolicy
 
 	annotSetupOnce       sync.Once
 	annotations          map[string]struct{}
 	installAnnotations   map[string]annotation.Install
 	upgradeAnnotations   map[string]annotation.Upgrade
 	uninstallAnnotations map[string]annotation.Uninstall
+	reinstallAnnotations map[string]annotation.Reinstall
 }
 
 // New creates a new Reconciler that reconciles custom resources that define a
@@ -94,7 +252,7 @@ type Reconciler struct {
 //
 // Required options are:
 //   - WithGroupVersionKind
-//   - WithChart
+//   - WithChart or WithChartSource
 //
 // Other options are defaulted to sane defaults when SetupWithManager is called.
 //
@@ -120,6 +278,7 @@ func (r *Reconciler) setupAnnotationMaps() {
 	r.installAnnotations = make(map[string]annotation.Install)
 	r.upgradeAnnotations = make(map[string]annotation.Upgrade)
 	r.uninstallAnnotations = make(map[string]annotation.Uninstall)
+	r.reinstallAnnotations = make(map[string]annotation.Reinstall)
 }
 
 // SetupWithManager configures a controller for the Reconciler and registers
@@ -135,10 +294,27 @@ func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 	if err := r.addDefaults(mgr, controllerName); err != nil {
 		return err
 	}
+	if err := r.validateScope(mgr.GetRESTMapper()); err != nil {
+		return err
+	}
+	r.detectStatusSubresource(context.TODO(), mgr.GetRESTMapper())
+	if err := r.loadSchemaDefaults(context.TODO(), mgr.GetRESTMapper()); err != nil {
+		return err
+	}
+
+	if r.chartSource != nil {
+		if err := r.refreshChart(context.TODO()); err != nil {
+			return err
+		}
+		if err := mgr.Add(manager.RunnableFunc(r.runChartRefresh)); err != nil {
+			return err
+		}
+	}
 
 	if !r.skipPrimaryGVKSchemeRegistration {
 		r.setupScheme(mgr)
 	}
+	metrics.RegisterReconcilerMetrics(crmetrics.Registry)
 
 	c, err := controller.New(controllerName, mgr, controller.Options{Reconciler: r, MaxConcurrentReconciles: r.maxConcurrentReconciles})
 	if err != nil {
@@ -149,6 +325,10 @@ func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 		return err
 	}
 
+	for _, name := range r.unknownFeatureGates {
+		r.log.Info("Warning: ignoring unknown feature gate", "featureGate", name)
+	}
+
 	r.log.Info("Watching resource",
 		"group", r.gvk.Group,
 		"version", r.gvk.Version,
@@ -182,6 +362,73 @@ func WithActionClientGetter(actionClientGetter helmclient.ActionClientGetter) Op
 	}
 }
 
+// WithKubeClientFactory is an Option that configures the function that
+// creates the Helm Kubernetes clients used to manage the resources of
+// releases, e.g. to customize how resources are applied or to test the
+// Reconciler without a cluster. The default Helm client is used if this
+// option is not configured.
+//
+// The option has no effect with WithActionClientGetter. The security context
+// options only apply to clients of type *kube.Client.
+func WithKubeClientFactory(f helmclient.KubeClientFactoryFunc) Option {
+	return func(r *Reconciler) error {
+		if f == nil {
+			return errors.New("kube client factory must not be nil")
+		}
+		r.kubeClientFactory = f
+		return nil
+	}
+}
+
+// WithClusterResolver is an Option that configures a function that returns
+// the REST config of the cluster that the release of a CR is deployed to, e.g.
+// from a kubeconfig Secret referenced by the CR. The release is stored in and
+// its resources are applied to that cluster, while the CRs are watched in the
+// cluster of the manager. If the function returns nil, the release is
+// deployed to the cluster of the manager.
+//
+// Owner references cannot refer to CRs in other clusters, so the owner
+// reference policy defaults to OwnerReferencePolicyNone and cannot be
+// configured otherwise, and dependent resources are not watched. Options that
+// read or write the resources of a release directly, like WithApplyWaves,
+// WithReleaseAdoption or WithCRDUpgradePolicy, use the cluster of the manager.
+//
+// The option has no effect with WithActionClientGetter.
+func WithClusterResolver(f helmclient.ClusterResolverFunc) Option {
+	return func(r *Reconciler) error {
+		if f == nil {
+			return errors.New("cluster resolver must not be nil")
+		}
+		r.clusterResolver = f
+		return nil
+	}
+}
+
+// ImpersonationFunc returns the name of the ServiceAccount whose permissions
+// are used to install, upgrade and uninstall the release of obj, or an empty
+// string to use the permissions of the operator. The ServiceAccount must be
+// in the namespace of the release.
+type ImpersonationFunc func(obj client.Object) string
+
+// WithImpersonation is an Option that configures the Reconciler to manage the
+// resources of releases with the permissions of ServiceAccounts, e.g. to
+// enforce the boundaries of tenants in shared clusters. The operator needs
+// permission to impersonate the ServiceAccounts. Releases are still stored
+// with the permissions of the operator.
+//
+// Actions that fail because the ServiceAccount lacks permissions set the
+// Irreconcilable condition of the CR with reason PermissionDenied. The option
+// has no effect with WithActionClientGetter.
+func WithImpersonation(f ImpersonationFunc) Option {
+	return func(r *Reconciler) error {
+		if f == nil {
+			return errors.New("impersonation function must not be nil")
+		}
+		r.impersonate = f
+		return nil
+	}
+}
+
 // WithEventRecorder is an Option that configures a Reconciler's EventRecorder.
 //
 // By default, manager.GetEventRecorderFor() is used if this option is not
@@ -193,16 +440,27 @@ func WithEventRecorder(er record.EventRecorder) Option {
 	}
 }
 
-// WithLog is an Option that configures a Reconciler's logger.
+// WithLogger is an Option that configures the logger used for all logging of
+// a Reconciler. It is also used for the debug logs of Helm actions, if the
+// default ActionClientGetter is used, and it is added to the context passed
+// to value translators, so that it can be retrieved with logr.FromContext.
 //
 // A default logger is used if this option is not configured.
-func WithLog(log logr.Logger) Option {
+func WithLogger(log logr.Logger) Option {
 	return func(r *Reconciler) error {
 		r.log = log
 		return nil
 	}
 }
 
+// WithLog is an Option that configures a Reconciler's logger.
+//
+// Deprecated: Use WithLogger instead.
+// WithLog will be removed in a future release.
+func WithLog(log logr.Logger) Option {
+	return WithLogger(log)
+}
+
 // WithGroupVersionKind is an Option that configures a Reconciler's
 // GroupVersionKind.
 //
@@ -214,16 +472,379 @@ func WithGroupVersionKind(gvk schema.GroupVersionKind) Option {
 	}
 }
 
+// WithGroupVersionKinds is an Option that configures a Reconciler to handle
+// several versions of the same kind, e.g. v1alpha1 and v1beta1 of a CRD that
+// serves multiple versions. All GVKs must have the same group and kind.
+//
+// The first GVK is the primary GVK: CRs are read, watched and updated using
+// this version, and the API server converts CRs created in any other version.
+// It should therefore be the newest served version. The other versions are
+// registered with the manager's scheme. Releases are identified by the name
+// and namespace of the CR only, so changing the version of a CR or of the
+// primary GVK never recreates its release.
+//
+// Either this option or WithGroupVersionKind is required.
+func WithGroupVersionKinds(gvks []schema.GroupVersionKind) Option {
+	return func(r *Reconciler) error {
+		if len(gvks) == 0 {
+			return errors.New("at least one GroupVersionKind must be specified")
+		}
+		primary := gvks[0]
+		for _, gvk := range gvks[1:] {
+			if gvk.GroupKind() != primary.GroupKind() {
+				return fmt.Errorf("GroupVersionKind %s does not have the same group and kind as %s", gvk, primary)
+			}
+		}
+		r.gvk = &primary
+		r.additionalGVKs = append([]schema.GroupVersionKind(nil), gvks[1:]...)
+		return nil
+	}
+}
+
 // WithChart is an Option that configures a Reconciler's helm chart.
 //
-// This option is required.
+// Either this option or WithChartSource is required.
 func WithChart(chrt chart.Chart) Option {
 	return func(r *Reconciler) error {
 		r.chrt = &chrt
+		r.chrtArchive = nil
+		return nil
+	}
+}
+
+// WithChartFS is an Option that configures a Reconciler's helm chart by
+// loading the chart directory or archive at name from fsys, e.g. an embed.FS
+// that is compiled into the operator binary. See chartfs.Load for how the
+// chart is loaded. If name is a chart archive, the provenance file next to it
+// is used by WithProvenanceVerification.
+//
+// It is an alternative to WithChart and WithChartSource.
+func WithChartFS(fsys fs.FS, name string) Option {
+	return func(r *Reconciler) error {
+		chrt, archive, err := loadChartFS(fsys, name)
+		if err != nil {
+			return fmt.Errorf("load chart %q: %w", name, err)
+		}
+		r.chrt = chrt
+		r.chrtArchive = archive
+		return nil
+	}
+}
+
+// ChartSource provides a chart that may change while the operator is running,
+// e.g. because it is read from a Git repository.
+type ChartSource interface {
+	// Fetch returns the current chart and a revision that identifies it.
+	Fetch(ctx context.Context) (*chart.Chart, string, error)
+}
+
+// DefaultChartRefreshInterval is the interval at which a chart source is
+// fetched again when WithChartSource is configured without an explicit
+// interval.
+const DefaultChartRefreshInterval = 5 * time.Minute
+
+// WithChartSource is an Option that configures the reconciler to load its
+// chart from src instead of using a fixed chart. The chart is fetched when
+// the reconciler is set up with a manager, and fetched again every interval.
+// When the revision of the fetched chart changes, the new chart is used
+// starting with the next reconciliation of each CR, and all CRs are enqueued
+// for reconciliation, so that the change is rolled out without waiting for
+// the reconcile period.
+//
+// A non-positive interval uses DefaultChartRefreshInterval. This option
+// replaces WithChart.
+func WithChartSource(src ChartSource, interval time.Duration) Option {
+	return func(r *Reconciler) error {
+		if src == nil {
+			return errors.New("chart source must not be nil")
+		}
+		if interval <= 0 {
+			interval = DefaultChartRefreshInterval
+		}
+		r.chartSource = src
+		r.chartRefreshInterval = interval
+		return nil
+	}
+}
+
+// WithProvenanceVerification is an Option that configures the Reconciler to
+// verify the provenance of its chart before installing or upgrading a
+// release. The chart must be loaded from a packaged chart archive with
+// WithChartFS or NewChartPathSource, and the provenance file next to the
+// archive, with the ".prov" suffix, must be signed by a key of keyring.
+//
+// The provenance is verified again when the chart changes. If the
+// verification fails, no release is installed or upgraded, and the
+// ProvenanceVerificationFailed condition of all CRs is set.
+func WithProvenanceVerification(keyring string) Option {
+	return func(r *Reconciler) error {
+		if keyring == "" {
+			return errors.New("provenance keyring must not be empty")
+		}
+		r.provenanceKeyring = keyring
+		return nil
+	}
+}
+
+// WithDefaultSecurityContext is an Option that configures default security
+// contexts for the pods of releases, e.g. to satisfy restricted PodSecurity
+// namespaces. The pod security context is set on the pod spec, and the
+// container security context on all containers and init containers, of the
+// built-in workload resources that do not specify one. Security contexts set
+// by the chart are never overridden. Either argument may be nil.
+//
+// The defaults are applied to the objects of hooks as well. This option only
+// has an effect on the default ActionClientGetter; it is ignored if
+// WithActionClientGetter is used.
+func WithDefaultSecurityContext(pod *corev1.PodSecurityContext, container *corev1.SecurityContext) Option {
+	return func(r *Reconciler) error {
+		if pod == nil && container == nil {
+			return errors.New("at least one of the pod and container security contexts must be set")
+		}
+		r.podSecurityContext = pod
+		r.containerSecurityContext = container
+		return nil
+	}
+}
+
+// WithActionTimeout is an Option that configures the maximum duration of the
+// Helm actions of a single reconciliation. The deadline is passed to all Helm
+// actions that accept a context, so that they are stopped once it is
+// exceeded. The Reconciler stops waiting for the other actions at the
+// deadline, in which case they continue in the background until they return.
+// Until then, the release stays locked and the action keeps its slots of
+// WithOperationLimiter and WithFairScheduler, so that no other Helm action is
+// started for the release in the meantime. Actions that time out set the
+// Irreconcilable condition with the ActionTimeout reason, and Reconcile
+// returns an error that wraps ErrActionTimeout.
+//
+// The timeout of a CR can be overridden with the TimeoutAnnotation. A CR with
+// an invalid timeout annotation is not reconciled and the Irreconcilable
+// condition is set with the InvalidTimeout reason, except when it is deleted.
+//
+// By default, or if d is 0, Helm actions have no deadline.
+func WithActionTimeout(d time.Duration) Option {
+	return func(r *Reconciler) error {
+		if d < 0 {
+			return errors.New("action timeout must not be negative")
+		}
+		r.actionTimeout = d
+		return nil
+	}
+}
+
+// WithFinalizer is an Option that configures the finalizer that blocks the
+// deletion of a CR until its release is uninstalled, e.g. to avoid collisions
+// between operators that manage the same CRs. The default is
+// "uninstall-helm-release". name must be a qualified name, e.g.
+// "example.com/uninstall-helm-release".
+//
+// CRs that carry a different finalizer, e.g. the default one before the
+// option was configured, are not uninstalled by the Reconciler and their
+// finalizer must be migrated.
+func WithFinalizer(name string) Option {
+	return func(r *Reconciler) error {
+		if errs := validation.IsQualifiedName(name); len(errs) > 0 {
+			return fmt.Errorf("invalid finalizer %q: %s", name, strings.Join(errs, "; "))
+		}
+		r.finalizer = name
+		return nil
+	}
+}
+
+// finalizerName returns the finalizer configured with WithFinalizer.
+func (r *Reconciler) finalizerName() string {
+	if r.finalizer != "" {
+		return r.finalizer
+	}
+	return uninstallFinalizer
+}
+
+// WithUninstallGracePeriod is an Option that configures the Reconciler to wait
+// for the given grace period after a CR is marked for deletion before its
+// release is uninstalled. During the grace period, the uninstall finalizer is
+// kept and the CR is requeued. Events are emitted at the start and at the end
+// of the grace period.
+//
+// Since a CR that is marked for deletion cannot be restored, the uninstall is
+// cancelled by setting the CancelUninstallAnnotation to "true" on the CR
+// during the grace period. The finalizer is then removed without uninstalling
+// the release, so that a recreated CR with the same name takes over the
+// existing release. Use OwnerReferencePolicyAnnotations to prevent the
+// resources of the release from being garbage collected together with the
+// deleted CR.
+func WithUninstallGracePeriod(d time.Duration) Option {
+	return func(r *Reconciler) error {
+		if d < 0 {
+			return errors.New("uninstall grace period must not be negative")
+		}
+		r.uninstallGracePeriod = d
+		return nil
+	}
+}
+
+// WithDeletePropagationPolicy is an Option that configures the propagation
+// policy of the deletion of the resources of a release when it is
+// uninstalled, i.e. when its CR is deleted or it is reinstalled.
+//
+// With metav1.DeletePropagationForeground, the uninstall waits until the
+// resources of the release and their dependents are deleted, so that the
+// uninstall finalizer is only removed from the CR once they are gone. The wait
+// is limited by the timeout configured with WithActionTimeout, or by
+// DefaultForegroundUninstallTimeout if none is configured. With
+// metav1.DeletePropagationOrphan, the dependents of the resources are kept.
+//
+// By default, the resources are deleted with the background propagation
+// policy, like by Helm.
+func WithDeletePropagationPolicy(policy metav1.DeletionPropagation) Option {
+	return func(r *Reconciler) error {
+		switch policy {
+		case metav1.DeletePropagationForeground, metav1.DeletePropagationBackground, metav1.DeletePropagationOrphan:
+		default:
+			return fmt.Errorf("invalid delete propagation policy %q", policy)
+		}
+		r.deletePropagationPolicy = policy
+		return nil
+	}
+}
+
+// uninstallOptions returns the options of the uninstall of the release of obj.
+func (r *Reconciler) uninstallOptions(obj *unstructured.Unstructured) []helmclient.UninstallOption {
+	var opts []helmclient.UninstallOption
+	if policy := r.deletePropagationPolicy; policy != "" {
+		opts = append(opts, func(u *action.Uninstall) error {
+			// Helm expects the policy in lower case.
+			u.DeletionPropagation = strings.ToLower(string(policy))
+			if policy == metav1.DeletePropagationForeground {
+				u.Wait = true
+				u.Timeout = DefaultForegroundUninstallTimeout
+				if d, err := r.actionTimeoutFor(obj); err == nil && d > 0 {
+					u.Timeout = d
+				} else if r.actionTimeout > 0 {
+					u.Timeout = r.actionTimeout
+				}
+			}
+			return nil
+		})
+	}
+	for name, annot := range r.uninstallAnnotations {
+		if _, v, ok := lookupAnnotation(obj, name, annot); ok {
+			opts = append(opts, annot.UninstallOption(v))
+		}
+	}
+	return opts
+}
+
+// WithStatusSubresourceEnabled is an Option that configures whether the status
+// of CRs is updated through the status subresource. If the status subresource
+// is disabled, the status is updated together with the rest of the CR. By
+// default, this is detected from the CRD of the reconciled GVK when the
+// Reconciler is set up with a manager, and the status subresource is assumed
+// to be enabled if the CRD cannot be read.
+func WithStatusSubresourceEnabled(enabled bool) Option {
+	return func(r *Reconciler) error {
+		r.statusSubresource = &enabled
+		return nil
+	}
+}
+
+func (r *Reconciler) useStatusSubresource() bool {
+	return r.statusSubresource == nil || *r.statusSubresource
+}
+
+// detectStatusSubresource detects whether the CRD of the reconciled GVK
+// enables the status subresource, unless configured explicitly.
+func (r *Reconciler) detectStatusSubresource(ctx context.Context, mapper meta.RESTMapper) {
+	if r.statusSubresource != nil {
+		return
+	}
+	enabled, err := r.crdHasStatusSubresource(ctx, mapper)
+	if err != nil {
+		r.log.V(1).Info("Unable to detect the status subresource of the CRD, assuming it is enabled", "gvk", r.gvk, "error", err.Error())
+		return
+	}
+	if !enabled {
+		r.log.Info("The CRD does not enable the status subresource, status is updated with the object", "gvk", r.gvk)
+	}
+	r.statusSubresource = &enabled
+}
+
+func (r *Reconciler) crdHasStatusSubresource(ctx context.Context, mapper meta.RESTMapper) (bool, error) {
+	mapping, err := mapper.RESTMapping(r.gvk.GroupKind(), r.gvk.Version)
+	if err != nil {
+		return false, err
+	}
+	crd := &unstructured.Unstructured{}
+	crd.SetGroupVersionKind(schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"})
+	name := fmt.Sprintf("%s.%s", mapping.Resource.Resource, r.gvk.Group)
+	if err := r.apiReader.Get(ctx, client.ObjectKey{Name: name}, crd); err != nil {
+		return false, err
+	}
+	versions, _, err := unstructured.NestedSlice(crd.Object, "spec", "versions")
+	if err != nil {
+		return false, err
+	}
+	for _, v := range versions {
+		v, ok := v.(map[string]interface{})
+		if !ok || v["name"] != r.gvk.Version {
+			continue
+		}
+		_, found, err := unstructured.NestedFieldNoCopy(v, "subresources", "status")
+		return found, err
+	}
+	return false, fmt.Errorf("version %s not found in CRD %s", r.gvk.Version, name)
+}
+
+// WithSkipUnchangedStatusUpdate is an Option that configures whether the
+// Reconciler skips the status update of a CR when the computed status is equal
+// to its current status. This avoids needless API writes and resourceVersion
+// churn. It is enabled by default.
+func WithSkipUnchangedStatusUpdate(skip bool) Option {
+	return func(r *Reconciler) error {
+		r.updateUnchangedStatus = !skip
 		return nil
 	}
 }
 
+// WithReleaseNamespace is an Option that configures the namespace in which
+// the releases of all CRs are installed and stored, instead of the namespace
+// of each CR. This option is required for cluster-scoped CRs. Since releases
+// are named after their CR, namespaced CRs must have unique names across all
+// namespaces when this option is used.
+func WithReleaseNamespace(namespace string) Option {
+	return func(r *Reconciler) error {
+		if namespace == "" {
+			return errors.New("release namespace must not be empty")
+		}
+		r.releaseNS = namespace
+		return nil
+	}
+}
+
+// WithReleaseStorageNamespace is an Option that configures the namespace in
+// which the release storage Secrets of all CRs are stored, e.g. a namespace
+// owned by the operator, while the release resources are still installed in
+// the release namespace of each CR. The storage Secrets are not owned by the
+// CRs, so they are only removed when the releases are uninstalled. Releases
+// that are already stored in the namespaces of the CRs are not migrated.
+func WithReleaseStorageNamespace(namespace string) Option {
+	return func(r *Reconciler) error {
+		if namespace == "" {
+			return errors.New("release storage namespace must not be empty")
+		}
+		r.releaseStorageNS = namespace
+		return nil
+	}
+}
+
+// releaseNamespace returns the namespace of the release of obj.
+func (r *Reconciler) releaseNamespace(obj client.Object) string {
+	if r.releaseNS != "" {
+		return r.releaseNS
+	}
+	return obj.GetNamespace()
+}
+
 // WithOverrideValues is an Option that configures a Reconciler's override
 // values.
 //
@@ -252,6 +873,133 @@ func WithOverrideValues(overrides map[string]string) Option {
 	}
 }
 
+// WithOverrideValuesLayers is an Option that configures layers of override
+// values, for example common defaults followed by environment-specific
+// tweaks. The layers are deep-merged in order on top of the CR spec: nested
+// maps are merged key by key, while lists and scalar values of later layers
+// replace those of earlier layers. Lists are never appended to.
+//
+// Override values configured with WithOverrideValues are applied after all
+// layers and therefore take precedence over them.
+func WithOverrideValuesLayers(layers ...map[string]interface{}) Option {
+	return func(r *Reconciler) error {
+		r.overrideValuesLayers = append(r.overrideValuesLayers, layers...)
+		return nil
+	}
+}
+
+// WithEnvironmentOverrides is an Option that configures override values that
+// are selected by the value of the annotationKey annotation on a CR, for
+// example a "dev", "stage" or "prod" environment. The matching set of
+// overrides is deep-merged into the CR spec after the layers configured with
+// WithOverrideValuesLayers and before the values configured with
+// WithOverrideValues.
+//
+// CRs without the annotation do not receive environment overrides. CRs whose
+// annotation value has no entry in overrides are not reconciled; instead the
+// Irreconcilable condition is set with reason UnknownEnvironment.
+func WithEnvironmentOverrides(overrides map[string]map[string]interface{}, annotationKey string) Option {
+	return func(r *Reconciler) error {
+		if annotationKey == "" {
+			return errors.New("environment annotation key must not be empty")
+		}
+		r.environmentOverrides = overrides
+		r.environmentAnnotation = annotationKey
+		return nil
+	}
+}
+
+// unknownEnvironmentError is returned by getValues when a CR selects an
+// environment for which no overrides are configured.
+type unknownEnvironmentError struct {
+	annotation string
+	value      string
+}
+
+func (e *unknownEnvironmentError) Error() string {
+	return fmt.Sprintf("unknown environment %q in annotation %q", e.value, e.annotation)
+}
+
+// MissingEnvPolicy determines how values mapped from environment variables
+// with WithValuesFromEnv are handled when a variable is not set.
+type MissingEnvPolicy string
+
+const (
+	// MissingEnvSkip omits the values of unset variables.
+	MissingEnvSkip MissingEnvPolicy = "Skip"
+
+	// MissingEnvFail fails the reconciliation if a variable is not set.
+	MissingEnvFail MissingEnvPolicy = "Fail"
+)
+
+// WithValuesFromEnv is an Option that sets values from environment variables
+// of the operator, e.g. to provide the name of the cluster to every release.
+// mapping maps the names of variables to dot-separated value paths, e.g.
+// "CLUSTER_NAME" to "global.clusterName". The values are strings and are
+// merged under the values of the CR, including its overrides and values
+// files, so that a CR can still set them explicitly.
+//
+// By default, variables that are not set are skipped; see
+// WithMissingEnvPolicy.
+func WithValuesFromEnv(mapping map[string]string) Option {
+	return func(r *Reconciler) error {
+		for env, path := range mapping {
+			if env == "" {
+				return errors.New("environment variable name must not be empty")
+			}
+			for _, key := range strings.Split(path, ".") {
+				if key == "" {
+					return fmt.Errorf("invalid value path %q for environment variable %q", path, env)
+				}
+			}
+		}
+		r.valuesFromEnv = mapping
+		return nil
+	}
+}
+
+// WithMissingEnvPolicy is an Option that configures how variables of
+// WithValuesFromEnv that are not set are handled. The default is
+// MissingEnvSkip.
+func WithMissingEnvPolicy(p MissingEnvPolicy) Option {
+	return func(r *Reconciler) error {
+		switch p {
+		case MissingEnvSkip, MissingEnvFail:
+		default:
+			return fmt.Errorf("unknown missing environment variable policy %q", p)
+		}
+		r.missingEnvPolicy = p
+		return nil
+	}
+}
+
+// envValues returns the values mapped from environment variables with
+// WithValuesFromEnv.
+func (r *Reconciler) envValues() (map[string]interface{}, error) {
+	vals := map[string]interface{}{}
+	for env, path := range r.valuesFromEnv {
+		v, ok := os.LookupEnv(env)
+		if !ok {
+			if r.missingEnvPolicy == MissingEnvFail {
+				return nil, fmt.Errorf("environment variable %q for value %q is not set", env, path)
+			}
+			continue
+		}
+		keys := strings.Split(path, ".")
+		m := vals
+		for _, key := range keys[:len(keys)-1] {
+			next, ok := m[key].(map[string]interface{})
+			if !ok {
+				next = map[string]interface{}{}
+				m[key] = next
+			}
+			m = next
+		}
+		m[keys[len(keys)-1]] = v
+	}
+	return vals, nil
+}
+
 // WithDependentWatchesEnabled is an Option that configures whether the
 // Reconciler will register watches for dependent objects in releases and
 // trigger reconciliations when they change.
@@ -313,6 +1061,12 @@ func SkipPrimaryGVKSchemeRegistration(skip bool) Option {
 // WithMaxConcurrentReconciles is an Option that configures the number of
 // concurrent reconciles that the controller will run.
 //
+// Each Reconciler has its own controller with its own work queue and its own
+// max workers, so the CRs of different GroupVersionKinds never wait in the
+// same queue. However, the workers of all controllers compete for the same
+// CPU and API server capacity. Use WithFairScheduler to cap the total number
+// of concurrent reconciles across controllers and share them fairly.
+//
 // The default is 1.
 func WithMaxConcurrentReconciles(max int) Option {
 	return func(r *Reconciler) error {
@@ -324,6 +1078,40 @@ func WithMaxConcurrentReconciles(max int) Option {
 	}
 }
 
+// FairScheduler limits the number of reconciles that run at the same time
+// across all Reconcilers that share it. When all of its workers are busy, a
+// worker that becomes free goes to the Reconcilers with waiting reconciles in
+// round-robin order, so that a burst of CRs of one GroupVersionKind does not
+// delay the CRs of other GroupVersionKinds.
+type FairScheduler struct {
+	scheduler *fairness.Scheduler
+}
+
+// NewFairScheduler returns a FairScheduler that runs at most workers
+// reconciles at the same time.
+func NewFairScheduler(workers int) (*FairScheduler, error) {
+	if workers < 1 {
+		return nil, errors.New("fair scheduler workers must be at least 1")
+	}
+	return &FairScheduler{scheduler: fairness.New(workers)}, nil
+}
+
+// WithFairScheduler is an Option that makes the Reconciler acquire a worker
+// of s before each reconcile. The same FairScheduler should be passed to the
+// Reconcilers of all GroupVersionKinds that share the workers. The workers of
+// s are shared in addition to the per-controller limit configured with
+// WithMaxConcurrentReconciles, which should be at least as high as the number
+// of workers of s to make full use of them.
+func WithFairScheduler(s *FairScheduler) Option {
+	return func(r *Reconciler) error {
+		if s == nil {
+			return errors.New("fair scheduler must not be nil")
+		}
+		r.fairScheduler = s
+		return nil
+	}
+}
+
 // WithReconcilePeriod is an Option that configures the reconcile period of the
 // controller. This will cause the controller to reconcile CRs at least once
 // every period. By default, the reconcile period is set to 0, which means no
@@ -350,43 +1138,764 @@ func WithMaxReleaseHistory(maxHistory int) Option {
 	}
 }
 
-// WithInstallAnnotations is an Option that configures Install annotations
-// to enable custom action.Install fields to be set based on the value of
-// annotations found in the custom resource watched by this reconciler.
-// Duplicate annotation names will result in an error.
-func WithInstallAnnotations(as ...annotation.Install) Option {
+// WithForceUpgrade is an Option that configures whether upgrades are forced,
+// like helm upgrade --force. A forced upgrade replaces the resources of the
+// release with their rendered manifests instead of patching them, which
+// discards changes made by other controllers or users and, for resources
+// that are recreated by the API server on replacement, causes downtime. This
+// affects all resources of the release, but is typically needed for fields
+// that cannot be patched, such as the template of a Job or the selector of a
+// Deployment.
+//
+// Because this is destructive, it is disabled by default. A Warning event is
+// emitted for every resource that is replaced by a forced upgrade. The
+// upgrade-force annotation of a CR, if configured with
+// WithUpgradeAnnotations, takes precedence over this option.
+func WithForceUpgrade(force bool) Option {
 	return func(r *Reconciler) error {
-		r.annotSetupOnce.Do(r.setupAnnotationMaps)
-
-		for _, a := range as {
-			name := a.Name()
-			if _, ok := r.annotations[name]; ok {
-				return fmt.Errorf("annotation %q already exists", name)
-			}
-
-			r.annotations[name] = struct{}{}
-			r.installAnnotations[name] = a
-		}
+		r.forceUpgrade = force
 		return nil
 	}
 }
 
-// WithUpgradeAnnotations is an Option that configures Upgrade annotations
-// to enable custom action.Upgrade fields to be set based on the value of
-// annotations found in the custom resource watched by this reconciler.
-// Duplicate annotation names will result in an error.
-func WithUpgradeAnnotations(as ...annotation.Upgrade) Option {
+// WithOwnerReferencePolicy is an Option that configures how the resources of
+// a release are associated with the custom resource that owns the release.
+// This determines how those resources and the release storage secrets are
+// garbage collected when the custom resource is deleted, and how changes to
+// them trigger reconciliations.
+//
+// With OwnerReferencePolicyController (the default), a controller owner
+// reference is set wherever possible. With OwnerReferencePolicyAnnotations,
+// no owner references are set and resources are tracked with owner
+// annotations instead, so they are only removed when the release is
+// uninstalled. With OwnerReferencePolicyNone, resources are not associated
+// with the custom resource at all and dependent resources are not watched.
+//
+// This option only has an effect on the default ActionClientGetter; it is
+// ignored if WithActionClientGetter is used.
+func WithOwnerReferencePolicy(p helmclient.OwnerReferencePolicy) Option {
 	return func(r *Reconciler) error {
-		r.annotSetupOnce.Do(r.setupAnnotationMaps)
-
-		for _, a := range as {
-			name := a.Name()
-			if _, ok := r.annotations[name]; ok {
-				return fmt.Errorf("annotation %q already exists", name)
-			}
-
-			r.annotations[name] = struct{}{}
-			r.upgradeAnnotations[name] = a
+		switch p {
+		case helmclient.OwnerReferencePolicyController, helmclient.OwnerReferencePolicyAnnotations, helmclient.OwnerReferencePolicyNone:
+		default:
+			return fmt.Errorf("unknown owner reference policy %q", p)
+		}
+		r.ownerReferencePolicy = p
+		return nil
+	}
+}
+
+// WithPatchStrategy is an Option that configures how the resources of the
+// release of kind gvk are updated when the release is upgraded, e.g. to use a
+// JSON merge patch for a kind whose lists are merged incorrectly by Helm. The
+// resources of kinds without a patch strategy are updated like by Helm. It can
+// be used multiple times to configure several kinds.
+//
+// This option only has an effect on the default ActionClientGetter; it is
+// ignored if WithActionClientGetter or WithKubeClientFactory is used.
+func WithPatchStrategy(gvk schema.GroupVersionKind, strategy helmclient.PatchStrategy) Option {
+	return func(r *Reconciler) error {
+		switch strategy {
+		case helmclient.PatchStrategyStrategicMerge, helmclient.PatchStrategyJSONMerge, helmclient.PatchStrategyReplace:
+		default:
+			return fmt.Errorf("unknown patch strategy %q", strategy)
+		}
+		if gvk.Kind == "" || gvk.Version == "" {
+			return errors.New("patch strategy kind and version must not be empty")
+		}
+		if r.patchStrategies == nil {
+			r.patchStrategies = map[schema.GroupVersionKind]helmclient.PatchStrategy{}
+		}
+		r.patchStrategies[gvk] = strategy
+		return nil
+	}
+}
+
+// WithFailureThreshold is an Option that configures the reconciler to stop
+// reconciling a CR after n consecutive failed reconciliations. Once the
+// threshold is reached, the CircuitOpen condition of the CR is set and the CR
+// is not requeued anymore until its generation changes, e.g. because its spec
+// was updated. The failure counter is reset on any successful reconciliation
+// and whenever the generation of the CR changes. Deletion of a CR is always
+// handled, regardless of the failure threshold.
+//
+// By default, or if n is 0, failing CRs are requeued indefinitely.
+func WithFailureThreshold(n int) Option {
+	return func(r *Reconciler) error {
+		if n < 0 {
+			return errors.New("failure threshold must not be negative")
+		}
+		r.breaker = nil
+		if n > 0 {
+			r.breaker = breaker.New(n)
+		}
+		return nil
+	}
+}
+
+// ManifestSinkFunc receives the rendered manifests of a release revision, e.g.
+// to store them in an object store or git repository for auditing.
+type ManifestSinkFunc func(ctx context.Context, obj *unstructured.Unstructured, revision int, manifests []byte) error
+
+// WithManifestSink is an Option that configures a sink that is invoked with
+// the rendered manifests of every release revision after it was successfully
+// installed or upgraded. Each revision is passed to the sink once per
+// operator process; after a restart, the current revision of each release is
+// exported again, so sinks should be idempotent.
+//
+// By default, sink errors are logged and ignored; use WithManifestSinkFatal
+// to fail the reconciliation instead. Secrets are passed to the sink as-is
+// unless WithManifestSinkRedactSecrets is configured.
+func WithManifestSink(f ManifestSinkFunc) Option {
+	return func(r *Reconciler) error {
+		if f == nil {
+			return errors.New("manifest sink must not be nil")
+		}
+		r.manifestSink = f
+		return nil
+	}
+}
+
+// WithManifestSinkFatal is an Option that configures whether errors of the
+// sink configured with WithManifestSink fail the reconciliation. If fatal,
+// the export is retried on the next reconciliation.
+func WithManifestSinkFatal(fatal bool) Option {
+	return func(r *Reconciler) error {
+		r.manifestSinkFatal = fatal
+		return nil
+	}
+}
+
+// WithManifestSinkRedactSecrets is an Option that configures whether the
+// values of Secrets are redacted from the manifests passed to the sink
+// configured with WithManifestSink.
+func WithManifestSinkRedactSecrets(redactSecrets bool) Option {
+	return func(r *Reconciler) error {
+		r.manifestSinkRedactSecrets = redactSecrets
+		return nil
+	}
+}
+
+// CRDUpgradePolicy determines how the CRDs in the crds/ directory of a chart
+// are handled when a release is installed or upgraded.
+type CRDUpgradePolicy string
+
+const (
+	// CRDUpgradePolicySkip leaves CRDs to Helm, which creates missing CRDs on
+	// install and never touches CRDs on upgrade.
+	CRDUpgradePolicySkip CRDUpgradePolicy = "Skip"
+
+	// CRDUpgradePolicyCreateOnly creates missing CRDs before every install
+	// and upgrade, but never changes existing CRDs.
+	CRDUpgradePolicyCreateOnly CRDUpgradePolicy = "CreateOnly"
+
+	// CRDUpgradePolicyUpgrade server-side applies all CRDs before every
+	// install and upgrade, so that CRD changes of a chart are rolled out.
+	CRDUpgradePolicyUpgrade CRDUpgradePolicy = "Upgrade"
+)
+
+// UpgradeValuesPolicy determines which values are used when a release is
+// upgraded.
+type UpgradeValuesPolicy string
+
+const (
+	// UpgradeValuesPolicyReset upgrades a release with the values computed
+	// from the CR, merged over the default values of the chart, like helm
+	// upgrade --reset-values. Values of earlier revisions are discarded, so
+	// that the release always reflects the CR spec.
+	UpgradeValuesPolicyReset UpgradeValuesPolicy = "Reset"
+
+	// UpgradeValuesPolicyReuse merges the values computed from the CR over
+	// the values of the current revision of the release, like helm upgrade
+	// --reuse-values. Values that were removed from the CR spec are kept,
+	// and new default values of the chart are not applied.
+	UpgradeValuesPolicyReuse UpgradeValuesPolicy = "Reuse"
+)
+
+// WithUpgradeValuesPolicy is an Option that configures which values are used
+// when a release is upgraded. The policy also applies to the dry-run upgrade
+// that decides whether a release needs to be upgraded.
+//
+// Since the CR spec is the source of truth for a release, the default is
+// UpgradeValuesPolicyReset. UpgradeValuesPolicyReuse is meant for migration
+// scenarios, e.g. to adopt a release that was installed with values that
+// are not part of the CR spec. Note that with UpgradeValuesPolicyReuse,
+// removing a value from the CR spec does not remove it from the release.
+func WithUpgradeValuesPolicy(p UpgradeValuesPolicy) Option {
+	return func(r *Reconciler) error {
+		switch p {
+		case UpgradeValuesPolicyReset, UpgradeValuesPolicyReuse:
+		default:
+			return fmt.Errorf("unknown upgrade values policy %q", p)
+		}
+		r.upgradeValuesPolicy = p
+		return nil
+	}
+}
+
+// upgradeValuesOption returns the upgrade option that implements the upgrade
+// values policy.
+func (r *Reconciler) upgradeValuesOption() helmclient.UpgradeOption {
+	reuse := r.upgradeValuesPolicy == UpgradeValuesPolicyReuse
+	return func(u *action.Upgrade) error {
+		u.ReuseValues = reuse
+		u.ResetValues = !reuse
+		return nil
+	}
+}
+
+// fieldOwner is the field manager used to server-side apply chart CRDs and
+// the objects of apply waves.
+const fieldOwner = "helm-operator"
+
+// WithCRDUpgradePolicy is an Option that configures how the CRDs in the crds/
+// directory of the chart are handled on install and upgrade. An event is
+// recorded on the CR for every CRD that is created or changed.
+//
+// Changing CRDs may break or remove existing custom resources of other
+// users of the CRD, so CRDUpgradePolicyUpgrade must only be used with charts
+// whose CRD changes are known to be compatible.
+//
+// By default, CRDUpgradePolicySkip is used.
+func WithCRDUpgradePolicy(p CRDUpgradePolicy) Option {
+	return func(r *Reconciler) error {
+		switch p {
+		case CRDUpgradePolicySkip, CRDUpgradePolicyCreateOnly, CRDUpgradePolicyUpgrade:
+		default:
+			return fmt.Errorf("unknown CRD upgrade policy %q", p)
+		}
+		r.crdUpgradePolicy = p
+		return nil
+	}
+}
+
+// DependsOnFunc returns the names of the CRs that obj depends on.
+type DependsOnFunc func(obj *unstructured.Unstructured) []types.NamespacedName
+
+type dependency struct {
+	gvk       schema.GroupVersionKind
+	dependsOn DependsOnFunc
+}
+
+// dependencyRequeueDelay is the delay after which a CR that waits for its
+// dependencies is reconciled again, in case a change of a dependency is
+// missed.
+const dependencyRequeueDelay = 30 * time.Second
+
+// WithDependsOn is an Option that configures the reconciler to wait with the
+// reconciliation of a CR until the CRs of the given GroupVersionKind returned
+// by dependsOn have been deployed, i.e. until their Deployed condition is
+// true. While a CR is waiting, its WaitingForDependency condition is set and
+// no release is installed or upgraded. The CR is reconciled again when any
+// of its dependencies change. Deletion of a CR is never delayed.
+//
+// This option can be used multiple times to configure dependencies on CRs of
+// several GroupVersionKinds.
+func WithDependsOn(gvk schema.GroupVersionKind, dependsOn DependsOnFunc) Option {
+	return func(r *Reconciler) error {
+		if dependsOn == nil {
+			return errors.New("dependency function must not be nil")
+		}
+		r.dependencies = append(r.dependencies, dependency{gvk: gvk, dependsOn: dependsOn})
+		return nil
+	}
+}
+
+// WithDebounce is an Option that configures the window within which events
+// from secondary watches, i.e. watches of owned Secrets, dependent resources
+// and the dependencies configured with WithDependsOn, are collapsed into a
+// single reconcile per affected CR. This prevents a burst of changes to a
+// resource shared by many CRs from triggering a reconcile of every CR for
+// every change. Events for the CR itself are never delayed.
+//
+// By default, or if window is 0, events are not debounced.
+func WithDebounce(window time.Duration) Option {
+	return func(r *Reconciler) error {
+		if window < 0 {
+			return errors.New("debounce window must not be negative")
+		}
+		r.debounceWindow = window
+		return nil
+	}
+}
+
+// defaultReleaseLockTimeout is the time Reconcile waits for the lock of a
+// release that is held by another reconciliation, if not configured with
+// WithReleaseLockTimeout.
+const defaultReleaseLockTimeout = 30 * time.Second
+
+// WithReleaseLockTimeout is an Option that configures how long a
+// reconciliation waits for the lock of its release. The reconciler holds an
+// in-process lock per release, keyed by the namespace and name of the
+// release, so that a release is never touched by two reconciliations at the
+// same time, e.g. because two CRs map to the same release or because of a
+// high number of concurrent reconciles. If the lock is not acquired within
+// timeout, the CR is requeued.
+//
+// By default, the timeout is 30 seconds.
+func WithReleaseLockTimeout(timeout time.Duration) Option {
+	return func(r *Reconciler) error {
+		if timeout <= 0 {
+			return errors.New("release lock timeout must be positive")
+		}
+		r.releaseLockTimeout = timeout
+		return nil
+	}
+}
+
+// lockRelease acquires the lock of the release of obj. If the lock is not
+// acquired within the release lock timeout, it returns false.
+func (r *Reconciler) lockRelease(ctx context.Context, obj *unstructured.Unstructured) (func(), bool) {
+	timeout := r.releaseLockTimeout
+	if timeout == 0 {
+		timeout = defaultReleaseLockTimeout
+	}
+	lockCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	unlock, err := r.releaseLocks.Lock(lockCtx, r.releaseNamespace(obj)+"/"+obj.GetName())
+	return unlock, err == nil
+}
+
+// ManifestValidatorFunc validates the objects rendered for a release before
+// they are applied. A returned error describes the violated policy.
+type ManifestValidatorFunc func(manifests []unstructured.Unstructured) error
+
+// WithRenderedManifestValidator is an Option that configures a validator
+// for the objects rendered for a release. Before a release is installed or
+// upgraded, it is rendered with a dry run and the validator is called with
+// the resulting objects. If the validator returns an error, the release is
+// not applied and the PolicyViolation condition of the CR is set to true with
+// the error as message. The CR is reconciled again when it changes.
+//
+// This allows enforcing policies such as required resource limits on the
+// actual objects, without an admission webhook.
+func WithRenderedManifestValidator(f ManifestValidatorFunc) Option {
+	return func(r *Reconciler) error {
+		if f == nil {
+			return errors.New("manifest validator function must not be nil")
+		}
+		r.manifestValidator = f
+		return nil
+	}
+}
+
+// WithAllowedKinds is an Option that restricts the kinds of objects a chart
+// may create to kinds. Before a release is installed or upgraded, it is
+// rendered with a dry run, and if any rendered object has a kind that is not
+// in kinds, the release is not applied and the DisallowedResource condition
+// of the CR is set to true. A GroupVersionKind with an empty Version matches
+// all versions of its group and kind.
+func WithAllowedKinds(kinds []schema.GroupVersionKind) Option {
+	return func(r *Reconciler) error {
+		if len(kinds) == 0 {
+			return errors.New("at least one allowed kind must be specified")
+		}
+		r.allowedKinds = kinds
+		return nil
+	}
+}
+
+// WithDeniedKinds is an Option that prevents a chart from creating objects
+// of any of kinds, e.g. ClusterRoleBindings. It is checked like
+// WithAllowedKinds, and both options can be combined. A GroupVersionKind with
+// an empty Version matches all versions of its group and kind, which is
+// usually what a denylist should use.
+func WithDeniedKinds(kinds []schema.GroupVersionKind) Option {
+	return func(r *Reconciler) error {
+		r.deniedKinds = append(r.deniedKinds, kinds...)
+		return nil
+	}
+}
+
+// WithMaxManifestSize is an Option that limits the total size in bytes of the
+// rendered manifests of a release, including its hooks, as a guard against
+// runaway templating. Before a release is installed or upgraded, it is
+// rendered with a dry run, and if its manifests exceed the limit, the release
+// is not applied and the ManifestTooLarge condition of the CR is set to true
+// with the actual size.
+func WithMaxManifestSize(bytes int) Option {
+	return func(r *Reconciler) error {
+		if bytes <= 0 {
+			return errors.New("maximum manifest size must be positive")
+		}
+		r.maxManifestSize = bytes
+		return nil
+	}
+}
+
+// manifestSize returns the total size of the manifests of rel and its hooks.
+func manifestSize(rel *release.Release) int {
+	size := len(rel.Manifest)
+	for _, h := range rel.Hooks {
+		size += len(h.Manifest)
+	}
+	return size
+}
+
+// restrictsKinds reports whether the kinds of rendered objects are checked.
+func (r *Reconciler) restrictsKinds() bool {
+	return r.allowedKinds != nil || len(r.deniedKinds) > 0
+}
+
+// checkKinds returns an error that lists the rendered objects whose kinds are
+// not allowed.
+func (r *Reconciler) checkKinds(rendered []unstructured.Unstructured) error {
+	var disallowed []string
+	for i := range rendered {
+		gvk := rendered[i].GroupVersionKind()
+		if (r.allowedKinds != nil && !matchesKind(r.allowedKinds, gvk)) || matchesKind(r.deniedKinds, gvk) {
+			disallowed = append(disallowed, fmt.Sprintf("%s %q", gvk.Kind, rendered[i].GetName()))
+		}
+	}
+	if len(disallowed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("chart renders resources of disallowed kinds: %s", strings.Join(disallowed, ", "))
+}
+
+func matchesKind(kinds []schema.GroupVersionKind, gvk schema.GroupVersionKind) bool {
+	for _, k := range kinds {
+		if k.Group == gvk.Group && k.Kind == gvk.Kind && (k.Version == "" || k.Version == gvk.Version) {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyWaveFunc returns the apply wave of an object rendered for a release.
+type ApplyWaveFunc func(obj unstructured.Unstructured) int
+
+// applyWaveRequeueDelay is the delay after which a CR is reconciled again
+// while an apply wave of its release is not ready.
+const applyWaveRequeueDelay = 5 * time.Second
+
+// WithApplyWaves is an Option that configures the reconciler to apply the
+// objects of a release in waves, similar to the sync waves of GitOps tools.
+// Before a release is installed or upgraded, it is rendered with a dry run and
+// waveOf assigns a wave to each rendered object. All waves but the last one
+// are server-side applied in ascending order, and each wave must be ready
+// before the next one is applied. While a wave is not ready, the
+// WaitingForReadiness condition of the CR is set and the CR is reconciled
+// again shortly. Once all earlier waves are ready, the release is installed
+// or upgraded, which applies the last wave and adopts the earlier ones.
+//
+// Workloads are ready once they are rolled out and available, Jobs once they
+// succeeded, Pods once they are ready and PersistentVolumeClaims once they are
+// bound. All other objects are ready as soon as they are applied.
+//
+// By default, all objects are applied by Helm in a single wave.
+func WithApplyWaves(waveOf ApplyWaveFunc) Option {
+	return func(r *Reconciler) error {
+		if waveOf == nil {
+			return errors.New("apply wave function must not be nil")
+		}
+		r.applyWaves = waveOf
+		return nil
+	}
+}
+
+// defaultPendingReleaseRequeueDelay is the delay after which a CR whose
+// release is in a pending state is reconciled again, if not configured with
+// WithPendingReleaseRequeueDelay.
+const defaultPendingReleaseRequeueDelay = 10 * time.Second
+
+// PendingReleaseRecoveryPolicy determines how a release that is stuck in a
+// pending state is recovered.
+type PendingReleaseRecoveryPolicy string
+
+const (
+	// PendingReleaseRetry marks the pending revision as failed and retries
+	// the install or upgrade.
+	PendingReleaseRetry PendingReleaseRecoveryPolicy = "Retry"
+
+	// PendingReleaseRollback marks the pending revision as failed and rolls
+	// the release back to the previous revision before the upgrade is
+	// retried. Releases without a previous revision are retried.
+	PendingReleaseRollback PendingReleaseRecoveryPolicy = "Rollback"
+)
+
+// WithPendingReleaseRequeueDelay is an Option that configures the delay after
+// which a CR is reconciled again when its release is in a pending-install,
+// pending-upgrade or pending-rollback state, i.e. while another Helm
+// operation on the release is in progress.
+//
+// By default, the delay is 10 seconds.
+func WithPendingReleaseRequeueDelay(d time.Duration) Option {
+	return func(r *Reconciler) error {
+		if d <= 0 {
+			return errors.New("pending release requeue delay must be positive")
+		}
+		r.pendingReleaseRequeueDelay = d
+		return nil
+	}
+}
+
+// WithPendingReleaseRecovery is an Option that configures the recovery of
+// releases that stay in a pending state for longer than timeout. This
+// happens when the operator, or another Helm client, is stopped in the
+// middle of an install or upgrade, in which case Helm refuses any further
+// operation on the release. After timeout, the pending revision is marked
+// as failed and the release is recovered according to policy.
+//
+// The timeout should be longer than the longest install or upgrade of the
+// chart, so that operations that are still running are not interrupted. By
+// default, pending releases are not recovered and their CRs are requeued
+// until the release leaves the pending state.
+func WithPendingReleaseRecovery(timeout time.Duration, policy PendingReleaseRecoveryPolicy) Option {
+	return func(r *Reconciler) error {
+		if timeout <= 0 {
+			return errors.New("pending release timeout must be positive")
+		}
+		switch policy {
+		case PendingReleaseRetry, PendingReleaseRollback:
+		default:
+			return fmt.Errorf("unknown pending release recovery policy %q", policy)
+		}
+		r.pendingReleaseTimeout = timeout
+		r.pendingReleaseRecoveryPolicy = policy
+		return nil
+	}
+}
+
+// recoverPendingRelease recovers rel, which is in a pending state, if it has
+// been pending for longer than the pending release timeout. It reports
+// whether the release was recovered.
+func (r *Reconciler) recoverPendingRelease(actionClient helmclient.ActionInterface, obj *unstructured.Unstructured, rel *release.Release, log logr.Logger) (bool, error) {
+	if r.pendingReleaseTimeout == 0 {
+		return false, nil
+	}
+	pending := time.Since(rel.Info.LastDeployed.Time)
+	if pending < r.pendingReleaseTimeout {
+		return false, nil
+	}
+	recoverer, ok := actionClient.(helmclient.PendingReleaseRecoverer)
+	if !ok {
+		return false, errors.New("action client cannot recover pending releases")
+	}
+
+	status := rel.Info.Status
+	log.Info("Recovering pending release", "name", rel.Name, "version", rel.Version, "status", status, "pending", pending.Round(time.Second))
+	if err := recoverer.MarkFailed(rel, fmt.Sprintf("Release was %s for more than %s", status, r.pendingReleaseTimeout)); err != nil {
+		return false, fmt.Errorf("mark pending release as failed: %w", err)
+	}
+	if r.pendingReleaseRecoveryPolicy == PendingReleaseRollback && rel.Version > 1 {
+		if err := recoverer.Rollback(rel.Name, func(rb *action.Rollback) error {
+			rb.MaxHistory = r.maxHistory
+			return nil
+		}); err != nil {
+			return false, fmt.Errorf("roll back pending release: %w", err)
+		}
+	}
+	r.eventRecorder.Eventf(obj, "Warning", "PendingReleaseRecovered",
+		"Release %q was %s for more than %s and was recovered with policy %s", rel.Name, status, r.pendingReleaseTimeout, r.pendingReleaseRecoveryPolicy)
+	return true, nil
+}
+
+// ReadinessCheckFunc reports whether the resources of a deployed release are
+// ready.
+type ReadinessCheckFunc func(ctx context.Context, rel *release.Release) (bool, error)
+
+// readinessRequeueDelay is the delay after which a CR whose release is not
+// ready yet is reconciled again.
+const readinessRequeueDelay = 10 * time.Second
+
+// WithReadinessCheck is an Option that configures a check that decides when
+// a deployed release is ready. Until the check reports the release as ready,
+// the Deployed condition of the CR is false, its WaitingForReadiness
+// condition is true, and the CR is reconciled again periodically.
+//
+// This is useful for charts with readiness semantics that Helm's wait does
+// not understand, such as a ConfigMap that is populated by a job.
+func WithReadinessCheck(f ReadinessCheckFunc) Option {
+	return func(r *Reconciler) error {
+		if f == nil {
+			return errors.New("readiness check function must not be nil")
+		}
+		r.readinessCheck = f
+		return nil
+	}
+}
+
+// maxLastReconcileErrorLength is the maximum length of the message recorded
+// in status.lastReconcileError.
+const maxLastReconcileErrorLength = 1024
+
+// WithLastReconcileErrorStatus is an Option that configures whether the
+// message of the error of the last failed reconciliation and the time at
+// which it first occurred are recorded in the status.lastReconcileError field
+// of a CR. The field is removed when a reconciliation succeeds. Unlike the
+// conditions, the field always shows the most recent error, regardless of
+// the step that failed.
+//
+// Messages are truncated to 1024 characters, and sensitive data that can be
+// detected, such as passwords in URLs, is redacted. By default, the last
+// reconcile error is not recorded.
+func WithLastReconcileErrorStatus(enabled bool) Option {
+	return func(r *Reconciler) error {
+		r.lastErrorStatus = enabled
+		return nil
+	}
+}
+
+// lastReconcileErrorMessage returns the message of err as recorded in
+// status.lastReconcileError.
+func lastReconcileErrorMessage(err error) string {
+	msg := redact.Message(err.Error())
+	if runes := []rune(msg); len(runes) > maxLastReconcileErrorLength {
+		msg = string(runes[:maxLastReconcileErrorLength-3]) + "..."
+	}
+	return msg
+}
+
+// AdoptionSelectorFunc returns the label selector that matches the existing
+// resources that may be adopted into the release of obj.
+type AdoptionSelectorFunc func(obj *unstructured.Unstructured) (labels.Selector, error)
+
+// WithReleaseAdoption is an Option that enables the adoption of existing
+// resources into new releases, e.g. to migrate releases that were managed by
+// other tools such as Flux or Argo CD to the operator.
+//
+// When a release of a CR does not exist yet, the reconciler renders the chart
+// and looks for the rendered resources in the cluster. Every existing
+// resource that matches the selector returned by f is labeled and annotated
+// as belonging to the release, and an event is emitted for it. The release
+// is then installed, and Helm adopts the marked resources instead of failing
+// because they already exist. From then on, the release is upgraded like any
+// other release. Existing resources that do not match the selector still
+// cause the install to fail.
+//
+// By default, existing resources are never adopted.
+func WithReleaseAdoption(f AdoptionSelectorFunc) Option {
+	return func(r *Reconciler) error {
+		if f == nil {
+			return errors.New("adoption selector function must not be nil")
+		}
+		r.adoptionSelector = f
+		return nil
+	}
+}
+
+// PreprocessCRFunc mutates a copy of a CR before the reconciler derives the
+// values and the release from it.
+type PreprocessCRFunc func(obj *unstructured.Unstructured) error
+
+// WithPreprocessCR is an Option that configures a function that is invoked at
+// the start of every reconciliation to normalize the CR, e.g. to default or
+// rename fields of CRs created by a translation layer. The function receives
+// a copy of the CR, which is then used for everything the reconciler derives
+// from the CR, including the values and the name of the release. Changes to
+// the copy are not persisted; the status and finalizers are still updated on
+// the CR as read from the API server.
+//
+// This is lighter than a values translator when only the CR needs to be
+// normalized. If the function returns an error, the Irreconcilable condition
+// is set and the error is returned from Reconcile.
+func WithPreprocessCR(f PreprocessCRFunc) Option {
+	return func(r *Reconciler) error {
+		if f == nil {
+			return errors.New("preprocess function must not be nil")
+		}
+		r.preprocessCR = f
+		return nil
+	}
+}
+
+// ReleaseDescriptionFunc returns the description of the Helm release revision
+// that is installed or upgraded for obj.
+type ReleaseDescriptionFunc func(obj *unstructured.Unstructured) string
+
+// DefaultReleaseDescription is the ReleaseDescriptionFunc used if
+// WithReleaseDescription is not configured. It describes the CR and the
+// generation of the CR that produced the release revision.
+func DefaultReleaseDescription(obj *unstructured.Unstructured) string {
+	return fmt.Sprintf("Reconciled %s %s/%s at generation %d",
+		obj.GetKind(), obj.GetNamespace(), obj.GetName(), obj.GetGeneration())
+}
+
+// WithReleaseDescription is an Option that configures the function used to
+// compute the description of release revisions on install and upgrade. The
+// description is shown by helm history. Install and upgrade description
+// annotations on a CR take precedence over the computed description.
+//
+// By default, DefaultReleaseDescription is used.
+func WithReleaseDescription(f ReleaseDescriptionFunc) Option {
+	return func(r *Reconciler) error {
+		if f == nil {
+			return errors.New("release description function must not be nil")
+		}
+		r.releaseDescription = f
+		return nil
+	}
+}
+
+// WithChartUpgradeCheck is an Option that configures the reconciler to
+// periodically look up the latest version of the release's chart in the index
+// of the chart repository at repoURL. When the chart version of the deployed
+// release is behind, the UpgradeAvailable condition is set to true and the
+// helm_operator_chart_upgrade_available metric is set to 1.
+//
+// The check is purely informational, it never triggers an upgrade of the
+// release. The repository is queried at most once per interval per chart. If
+// interval is 0, DefaultChartUpgradeCheckInterval is used.
+//
+// The repository client can be customized with repoOpts, e.g. to configure a
+// custom CA bundle or client certificate with chartrepo.WithTLSConfig.
+func WithChartUpgradeCheck(repoURL string, interval time.Duration, repoOpts ...chartrepo.Option) Option {
+	return func(r *Reconciler) error {
+		if repoURL == "" {
+			return errors.New("chart repository URL must not be empty")
+		}
+		if interval < 0 {
+			return errors.New("chart upgrade check interval must not be negative")
+		}
+		if interval == 0 {
+			interval = DefaultChartUpgradeCheckInterval
+		}
+		repoClient, err := chartrepo.New(repoOpts...)
+		if err != nil {
+			return err
+		}
+		r.upgradeChecker = upgradecheck.New(func(ctx context.Context, chartName string) (string, error) {
+			return repoClient.LatestVersion(ctx, repoURL, chartName)
+		}, interval)
+		return nil
+	}
+}
+
+// WithInstallAnnotations is an Option that configures Install annotations
+// to enable custom action.Install fields to be set based on the value of
+// annotations found in the custom resource watched by this reconciler.
+// Duplicate annotation names will result in an error.
+//
+// Annotations that implement annotation.Aliased are also recognized under
+// their aliases, see annotation.InstallWithDomains.
+func WithInstallAnnotations(as ...annotation.Install) Option {
+	return func(r *Reconciler) error {
+		r.annotSetupOnce.Do(r.setupAnnotationMaps)
+
+		for _, a := range as {
+			if err := r.registerAnnotation(a.Name(), a); err != nil {
+				return err
+			}
+			r.installAnnotations[a.Name()] = a
+		}
+		return nil
+	}
+}
+
+// WithUpgradeAnnotations is an Option that configures Upgrade annotations
+// to enable custom action.Upgrade fields to be set based on the value of
+// annotations found in the custom resource watched by this reconciler.
+// Duplicate annotation names will result in an error.
+func WithUpgradeAnnotations(as ...annotation.Upgrade) Option {
+	return func(r *Reconciler) error {
+		r.annotSetupOnce.Do(r.setupAnnotationMaps)
+
+		for _, a := range as {
+			if err := r.registerAnnotation(a.Name(), a); err != nil {
+				return err
+			}
+			r.upgradeAnnotations[a.Name()] = a
 		}
 		return nil
 	}
@@ -401,18 +1910,75 @@ func WithUninstallAnnotations(as ...annotation.Uninstall) Option {
 		r.annotSetupOnce.Do(r.setupAnnotationMaps)
 
 		for _, a := range as {
-			name := a.Name()
-			if _, ok := r.annotations[name]; ok {
-				return fmt.Errorf("annotation %q already exists", name)
+			if err := r.registerAnnotation(a.Name(), a); err != nil {
+				return err
 			}
+			r.uninstallAnnotations[a.Name()] = a
+		}
+		return nil
+	}
+}
+
+// WithReinstallAnnotations is an Option that configures Reinstall annotations
+// to enable a full reinstall of a release, i.e. an uninstall followed by a
+// fresh install, based on the value of annotations found in the custom
+// resource watched by this reconciler. Once the release was uninstalled, the
+// annotation is removed from the custom resource. Warning and Normal events
+// are recorded for each phase of the reinstall.
+//
+// A reinstall deletes and recreates all resources of the release, so any data
+// stored in them is lost. Only enable this option if that is acceptable.
+// Duplicate annotation names will result in an error.
+func WithReinstallAnnotations(as ...annotation.Reinstall) Option {
+	return func(r *Reconciler) error {
+		r.annotSetupOnce.Do(r.setupAnnotationMaps)
 
-			r.annotations[name] = struct{}{}
-			r.uninstallAnnotations[name] = a
+		for _, a := range as {
+			if err := r.registerAnnotation(a.Name(), a); err != nil {
+				return err
+			}
+			r.reinstallAnnotations[a.Name()] = a
 		}
 		return nil
 	}
 }
 
+// registerAnnotation registers name and the aliases of a, failing if any of
+// them is already registered.
+func (r *Reconciler) registerAnnotation(name string, a interface{}) error {
+	names := []string{name}
+	if aliased, ok := a.(annotation.Aliased); ok {
+		names = append(names, aliased.Aliases()...)
+	}
+	for _, n := range names {
+		if _, ok := r.annotations[n]; ok {
+			return fmt.Errorf("annotation %q already exists", n)
+		}
+	}
+	for _, n := range names {
+		r.annotations[n] = struct{}{}
+	}
+	return nil
+}
+
+// lookupAnnotation returns the key and value of the annotation of obj that
+// a, which is registered as name, is set with. name takes precedence over the
+// aliases of a.
+func lookupAnnotation(obj metav1.Object, name string, a interface{}) (string, string, bool) {
+	annotations := obj.GetAnnotations()
+	if v, ok := annotations[name]; ok {
+		return name, v, true
+	}
+	if aliased, ok := a.(annotation.Aliased); ok {
+		for _, alias := range aliased.Aliases() {
+			if v, ok := annotations[alias]; ok {
+				return alias, v, true
+			}
+		}
+	}
+	return "", "", false
+}
+
 // WithPreHook is an Option that configures the reconciler to run the given
 // PreHook just before performing any actions (e.g. install, upgrade, uninstall,
 // or reconciliation).
@@ -455,6 +2021,25 @@ func WithValueTranslator(t values.Translator) Option {
 	}
 }
 
+// WithValuesExec is an Option that configures the Reconciler to compute the
+// values passed to Helm by running the executable at path with args, instead
+// of using a Translator. The custom resource, with any override values
+// applied, is passed as JSON on stdin, and the executable must write the
+// values as a JSON object to stdout.
+//
+// If the executable exits with values.ExitCodeRetryable, the reconciliation
+// is retried with the usual backoff. Any other non-zero exit code is treated
+// as a fatal error that is not retried until the custom resource changes.
+func WithValuesExec(path string, args ...string) Option {
+	return func(r *Reconciler) error {
+		if path == "" {
+			return errors.New("values executable path must not be empty")
+		}
+		r.valueTranslator = values.ExecTranslator(path, args...)
+		return nil
+	}
+}
+
 // WithValueMapper is an Option that configures a function that maps values
 // from a custom resource spec to the values passed to Helm.
 // Use this if you want to apply a transformation on the values obtained from your custom resource, before
@@ -482,6 +2067,178 @@ func WithSelector(s metav1.LabelSelector) Option {
 	}
 }
 
+// WithPredicates is an Option that configures additional predicates that
+// filter the events of the CRs watched by the reconciler, e.g. to reconcile
+// only CRs with a specific annotation. An event must pass all predicates,
+// including the predicate configured with WithSelector, to trigger a
+// reconciliation. WithPredicates can be used multiple times; the predicates
+// are combined.
+func WithPredicates(predicates ...predicate.Predicate) Option {
+	return func(r *Reconciler) error {
+		for _, p := range predicates {
+			if p == nil {
+				return errors.New("predicate must not be nil")
+			}
+		}
+		r.predicates = append(r.predicates, predicates...)
+		return nil
+	}
+}
+
+// MapMergeStrategy determines how a map in the values is combined with the
+// corresponding map of the chart defaults.
+type MapMergeStrategy string
+
+// ListMergeStrategy determines how a list in the values is combined with the
+// corresponding list of the chart defaults.
+type ListMergeStrategy string
+
+const (
+	// MapMergeDeep merges maps key by key, so that chart defaults are used
+	// for all keys that are not set in the values. This is the Helm default.
+	MapMergeDeep MapMergeStrategy = "Deep"
+
+	// MapMergeReplace replaces a default map with the map of the values, so
+	// that no chart defaults are used for keys that are not set.
+	MapMergeReplace MapMergeStrategy = "Replace"
+
+	// ListMergeReplace replaces a default list with the list of the values.
+	// This is the Helm default.
+	ListMergeReplace ListMergeStrategy = "Replace"
+
+	// ListMergeAppend appends the list of the values to the default list.
+	ListMergeAppend ListMergeStrategy = "Append"
+)
+
+// MergeStrategy determines how the values computed from a CR are combined
+// with the default values of the chart.
+type MergeStrategy struct {
+	Maps  MapMergeStrategy
+	Lists ListMergeStrategy
+}
+
+// WithMergeStrategy is an Option that configures how the values computed
+// from a CR are combined with the default values of the chart. Empty fields
+// of s keep the Helm behavior, which deep-merges maps and replaces lists.
+//
+// Only the default values of the chart itself are considered; defaults of
+// subcharts are always deep-merged.
+func WithMergeStrategy(s MergeStrategy) Option {
+	return func(r *Reconciler) error {
+		switch s.Maps {
+		case "", MapMergeDeep, MapMergeReplace:
+		default:
+			return fmt.Errorf("unknown map merge strategy %q", s.Maps)
+		}
+		switch s.Lists {
+		case "", ListMergeReplace, ListMergeAppend:
+		default:
+			return fmt.Errorf("unknown list merge strategy %q", s.Lists)
+		}
+		r.mergeStrategy = s
+		return nil
+	}
+}
+
+// WithValuesPatch is an Option that applies the JSON Patch operations ops to
+// the values of every release, after they have been merged with the default
+// values of the chart. It can express changes that deep-merged overrides
+// cannot, like removing a key or replacing a single list element. Only the
+// add, remove and replace operations are supported.
+//
+// An operation whose path does not exist in the values fails the
+// reconciliation with an Irreconcilable condition with the reason
+// InvalidValuesPatch.
+func WithValuesPatch(ops []jsonpatch.Operation) Option {
+	return func(r *Reconciler) error {
+		if err := internalvalues.ValidatePatch(ops); err != nil {
+			return err
+		}
+		r.valuesPatch = append(r.valuesPatch, ops...)
+		return nil
+	}
+}
+
+// valuesPatchError is returned by getValues when the patch configured with
+// WithValuesPatch cannot be applied to the values of a CR.
+type valuesPatchError struct {
+	err error
+}
+
+func (e *valuesPatchError) Error() string {
+	return e.err.Error()
+}
+
+func (e *valuesPatchError) Unwrap() error {
+	return e.err
+}
+
+// ValuesFilePrecedence determines whether values read from values files take
+// precedence over the values of a CR.
+type ValuesFilePrecedence string
+
+const (
+	// ValuesFilePrecedenceBelowCR merges values files under the CR values, so
+	// that values set by a CR take precedence.
+	ValuesFilePrecedenceBelowCR ValuesFilePrecedence = "BelowCR"
+
+	// ValuesFilePrecedenceAboveCR merges values files over the CR values, so
+	// that values set in values files take precedence, including over values
+	// configured with WithOverrideValues.
+	ValuesFilePrecedenceAboveCR ValuesFilePrecedence = "AboveCR"
+)
+
+// WithValuesFile is an Option that configures the reconciler to read chart
+// values from a file on the operator's filesystem, e.g. a mounted ConfigMap
+// or Secret, similar to helm install --values. The file is read on every
+// reconciliation, so changes to it are picked up without a restart. If the
+// option is used multiple times, later files take precedence over earlier
+// files.
+//
+// The file must exist and contain valid YAML when the reconciler is created.
+// By default, values from the file are merged under the CR values; use
+// WithValuesFilePrecedence to change this.
+func WithValuesFile(path string) Option {
+	return func(r *Reconciler) error {
+		if _, err := chartutil.ReadValuesFile(path); err != nil {
+			return fmt.Errorf("read values file %q: %w", path, err)
+		}
+		r.valuesFiles = append(r.valuesFiles, path)
+		return nil
+	}
+}
+
+// WithValuesFilePrecedence is an Option that configures whether values read
+// from the files configured with WithValuesFile take precedence over CR
+// values. By default, ValuesFilePrecedenceBelowCR is used.
+func WithValuesFilePrecedence(p ValuesFilePrecedence) Option {
+	return func(r *Reconciler) error {
+		switch p {
+		case ValuesFilePrecedenceBelowCR, ValuesFilePrecedenceAboveCR:
+		default:
+			return fmt.Errorf("unknown values file precedence %q", p)
+		}
+		r.valuesFilePrecedence = p
+		return nil
+	}
+}
+
+// WithGenerationChangedPredicate is an Option that configures the reconciler
+// to skip update events for CRs whose generation and annotations have not
+// changed, e.g. when only the status or managed fields of a CR were updated.
+// Periodic reconciliations configured with WithReconcilePeriod still occur.
+//
+// By default, every update of a CR triggers a reconciliation.
+func WithGenerationChangedPredicate(enabled bool) Option {
+	return func(r *Reconciler) error {
+		r.generationChangedPredicate = nil
+		if enabled {
+			r.generationChangedPredicate = ctrlpredicate.Or(ctrlpredicate.GenerationChangedPredicate{}, ctrlpredicate.AnnotationChangedPredicate{})
+		}
+		return nil
+	}
+}
+
 // Reconcile reconciles a CR that defines a Helm v3 release.
 //
 //   - If a release does not exist for this CR, a new release is installed.
@@ -506,28 +2263,162 @@ func WithSelector(s metav1.LabelSelector) Option {
 //   - Deployed - a release for this CR is deployed (but not necessarily ready).
 //   - ReleaseFailed - an installation or upgrade failed.
 //   - Irreconcilable - an error occurred during reconciliation
+//   - UpgradeAvailable - a newer chart version is available in the chart
+//     repository (only if WithChartUpgradeCheck is configured)
+//   - WaitingForDependency - the CR waits for its dependencies to be deployed
+//     (only if WithDependsOn is configured)
+//   - WaitingForReadiness - the release is deployed but not ready yet (only
+//     if WithReadinessCheck is configured)
+//   - CircuitOpen - reconciliation is suspended after too many consecutive
+//     failures (only if WithFailureThreshold is configured)
+//   - ChartTestsPassed - the chart tests of the deployed revision passed (only
+//     if WithRunChartTests is configured)
+//   - ExternalModificationDetected - the release was modified by something
+//     other than the operator (only if WithExternalModificationDetection is
+//     configured)
+//   - ValuesTypeMismatch - the chart could not be rendered, because values of
+//     the CR do not have the types that the chart expects (only while the
+//     mismatch persists)
+//   - NamespaceTerminating - the namespace of the CR is terminating, so the
+//     release is not installed or upgraded (unless disabled with
+//     WithNamespaceTerminationHandling)
+//   - ProvenanceVerificationFailed - the provenance of the chart could not be
+//     verified, so the release is not installed or upgraded (only if
+//     WithProvenanceVerification is configured)
+//
+// When preprocessing the CR or computing the values, verifying the chart
+// provenance, rendering the chart or a Helm action fails, Reconcile returns a
+// ReconcileError that wraps one of ErrValuesFailed,
+// ErrProvenanceVerificationFailed, ErrRenderFailed, ErrApplyConflict,
+// ErrActionTimeout, ErrActionForbidden or ErrActionFailed, which can be tested
+// with errors.Is.
+//
+// If WithObserveOnly is configured, Reconcile only reports the state of the
+// release in the OutOfSync condition and never changes the release.
+//
+// If WithErrorClassifier is configured, transient errors are not returned, but
+// requeue the CR after a fixed delay, and permanent errors are returned as
+// terminal errors.
 func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (res ctrl.Result, err error) {
-	log := r.log.WithValues(strings.ToLower(r.gvk.Kind), req.NamespacedName)
-	log.V(1).Info("Reconciliation triggered")
+	// Registered first, so that the error is classified after it was logged
+	// and recorded.
+	defer func() { res, err = r.requeueForErrorClass(res, err) }()
+
+	// Helm actions that exceed the action timeout are abandoned but keep
+	// running, so the fairness slot, the release lock and the operation slot
+	// are only released once they return.
+	var actions actionTracker
+
+	if r.fairScheduler != nil {
+		release, err := r.fairScheduler.scheduler.Acquire(ctx, r.gvk.String())
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		defer actions.release(release)
+	}
+
+	r.chrtMu.RLock()
+	defer r.chrtMu.RUnlock()
+
+	log := r.log.WithValues(strings.ToLower(r.gvk.Kind), req.NamespacedName,
+		"namespace", req.Namespace, "name", req.Name, "gvk", r.gvk.String())
+	ctx = logr.NewContext(ctx, log)
+	log.Info("Reconciliation started")
+	summary := newReconcileSummary()
+	defer func() {
+		summary.log(log, err)
+		if r.timingLogs {
+			summary.logTimings(log.V(r.timingLogVerbosity))
+		}
+	}()
 
 	obj := &unstructured.Unstructured{}
 	obj.SetGroupVersionKind(*r.gvk)
 	err = r.client.Get(ctx, req.NamespacedName, obj)
 	if apierrors.IsNotFound(err) {
 		log.V(1).Info("Resource %s/%s not found, nothing to do", req.NamespacedName.Namespace, req.NamespacedName.Name)
+		if r.breaker != nil {
+			r.breaker.Reset(req.NamespacedName)
+		}
+		r.exportedRevisionsMu.Lock()
+		delete(r.exportedRevisions, req.NamespacedName)
+		r.exportedRevisionsMu.Unlock()
 		return ctrl.Result{}, nil
 	}
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
+	// The updater works on the CR as read from the API server, so that the
+	// changes made to obj by the schema defaults, the preprocess function and
+	// the allowed value paths are not persisted.
+	apiObj := obj
+	if r.preprocessCR != nil || r.schemaDefaults != nil || r.allowedValuePaths != nil {
+		obj = obj.DeepCopy()
+	}
+	r.applySchemaDefaultsTo(obj)
+
+	// Lifecycle observers are notified after the status is updated, so the
+	// notification is deferred before the update.
+	notifier := &lifecycleNotifier{obj: apiObj}
+	if len(r.lifecycleObservers) > 0 {
+		defer r.notifyLifecycleObservers(ctx, notifier, log)
+	}
+
 	u := updater.New(r.client)
+	u.SkipUnchangedStatus(!r.updateUnchangedStatus)
+	u.UseStatusSubresource(r.useStatusSubresource())
 	defer func() {
-		applyErr := u.Apply(ctx, obj)
+		applyErr := u.Apply(ctx, apiObj)
 		if err == nil && !apierrors.IsNotFound(applyErr) {
 			err = applyErr
 		}
 	}()
+	if r.secretKeyRefs {
+		defer u.UpdateStatus(updater.RedactStatus(r.secretValues.String))
+	}
+	if r.resultAnnotation != "" {
+		defer func() { u.Update(summary.ensureResultAnnotation(r.resultAnnotation, err)) }()
+	}
+	if r.lastErrorStatus {
+		defer func() {
+			if err != nil {
+				u.UpdateStatus(updater.EnsureLastReconcileError(lastReconcileErrorMessage(err)))
+				return
+			}
+			u.UpdateStatus(updater.RemoveLastReconcileError())
+		}()
+	}
+	if r.secretKeyRefs {
+		// Registered last, so that the error is redacted before it is
+		// recorded in the status and the annotation and returned.
+		defer func() { err = r.secretValues.Error(err) }()
+	}
+
+	if r.preprocessCR != nil {
+		if err := r.preprocessCR(obj); err != nil {
+			u.UpdateStatus(
+				updater.EnsureCondition(conditions.Irreconcilable(corev1.ConditionTrue, conditions.ReasonErrorPreprocessingCR, err)),
+			)
+			return ctrl.Result{}, newReconcileError("preprocess", ErrValuesFailed, err)
+		}
+	}
+
+	unlock, locked := r.lockRelease(ctx, obj)
+	if !locked {
+		log.V(1).Info("Release is locked by another reconciliation, requeueing")
+		return ctrl.Result{Requeue: true}, nil
+	}
+	defer actions.release(unlock)
+
+	if r.operationLimiter != nil {
+		release, ok := r.operationLimiter.tryAcquire()
+		if !ok {
+			log.V(1).Info("Maximum number of concurrent Helm operations reached, requeueing")
+			return ctrl.Result{Requeue: true}, nil
+		}
+		defer actions.release(release)
+	}
 
 	actionClient, err := r.actionClientGetter.ActionClientFor(obj)
 	if err != nil {
@@ -551,6 +2442,21 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (res ctrl.
 		// CR is deleted.
 		return ctrl.Result{}, err
 	}
+	actionTimeout, err := r.actionTimeoutFor(obj)
+	if err != nil {
+		if obj.GetDeletionTimestamp() == nil {
+			u.UpdateStatus(updater.EnsureCondition(conditions.Irreconcilable(corev1.ConditionTrue, conditions.ReasonInvalidTimeout, err)))
+			return ctrl.Result{}, newReconcileError("timeout", ErrActionFailed, err)
+		}
+		// An invalid timeout must not block the uninstall of the release.
+		log.Info("Ignoring invalid timeout annotation of deleted resource", "error", err.Error())
+		actionTimeout = r.actionTimeout
+	}
+	if actionTimeout > 0 {
+		actionCtx, cancel := context.WithTimeout(ctx, actionTimeout)
+		defer cancel()
+		actionClient = withDeadline(actionCtx, actionClient, &actions)
+	}
 
 	// As soon as we get the actionClient, lookup the release and
 	// update the status with this info. We need to do this as
@@ -562,25 +2468,139 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (res ctrl.
 	if errors.Is(err, driver.ErrReleaseNotFound) {
 		u.UpdateStatus(updater.EnsureCondition(conditions.Deployed(corev1.ConditionFalse, "", "")))
 	} else if err == nil {
-		ensureDeployedRelease(&u, rel)
+		r.ensureDeployedRelease(&u, rel)
 	}
 	u.UpdateStatus(updater.EnsureCondition(conditions.Initialized(corev1.ConditionTrue, "", "")))
 
+	if r.observeOnly {
+		return r.observe(ctx, actionClient, &u, obj, log)
+	}
+
 	if obj.GetDeletionTimestamp() != nil {
-		err := r.handleDeletion(ctx, actionClient, obj, log)
+		// The grace period would block the deletion of a terminating
+		// namespace, whose resources are deleted anyway.
+		terminating := controllerutil.ContainsFinalizer(obj, r.finalizerName()) && r.namespaceTerminating(ctx, obj, log)
+		if !terminating {
+			if requeueAfter, wait := r.handleUninstallGracePeriod(&u, obj, log); wait {
+				return ctrl.Result{RequeueAfter: requeueAfter}, nil
+			}
+		}
+		summary.set(reconcileActionUninstall, rel)
+		err := r.handleDeletion(ctx, actionClient, obj, notifier, terminating, log)
 		return ctrl.Result{}, err
 	}
 
+	if r.breaker != nil {
+		if r.breaker.IsOpen(req.NamespacedName, obj.GetGeneration()) {
+			log.V(1).Info("Skipping reconciliation, because the failure threshold is reached", "generation", obj.GetGeneration())
+			return ctrl.Result{}, nil
+		}
+		defer func() {
+			if err == nil {
+				r.breaker.Reset(req.NamespacedName)
+				u.UpdateStatus(updater.EnsureCondition(conditions.CircuitOpen(corev1.ConditionFalse, "", "")))
+				return
+			}
+			if r.breaker.RecordFailure(req.NamespacedName, obj.GetGeneration()) {
+				log.Error(err, "Failure threshold reached, not requeueing until the resource changes",
+					"failures", r.breaker.Failures(req.NamespacedName))
+				u.UpdateStatus(updater.EnsureCondition(conditions.CircuitOpen(corev1.ConditionTrue, conditions.ReasonFailureThresholdReached,
+					fmt.Sprintf("reconciliation failed %d consecutive times: %v", r.breaker.Failures(req.NamespacedName), err))))
+				res, err = ctrl.Result{}, nil
+			}
+		}()
+	}
+
+	if len(r.dependencies) > 0 {
+		waiting, err := r.waitingDependencies(ctx, obj)
+		if err != nil {
+			u.UpdateStatus(updater.EnsureCondition(conditions.WaitingForDependency(corev1.ConditionUnknown, conditions.ReasonErrorGettingDependencies, err)))
+			return ctrl.Result{}, err
+		}
+		if len(waiting) > 0 {
+			log.V(1).Info("Waiting for dependencies", "dependencies", waiting)
+			u.UpdateStatus(updater.EnsureCondition(conditions.WaitingForDependency(corev1.ConditionTrue, conditions.ReasonDependencyNotDeployed,
+				fmt.Sprintf("waiting for dependencies to be deployed: %s", strings.Join(waiting, ", ")))))
+			return ctrl.Result{RequeueAfter: dependencyRequeueDelay}, nil
+		}
+		u.UpdateStatus(updater.EnsureCondition(conditions.WaitingForDependency(corev1.ConditionFalse, "", "")))
+	}
+
+	if r.detectExternalModification {
+		if r.checkExternalModification(&u, obj, rel, log) {
+			return ctrl.Result{}, nil
+		}
+		actionClient = withReleaseLock(actionClient, &u)
+	}
+
 	vals, err := r.getValues(ctx, obj)
 	if err != nil {
+		reason := conditions.ReasonErrorGettingValues
+		var envErr *unknownEnvironmentError
+		var patchErr *valuesPatchError
+		if errors.As(err, &envErr) {
+			reason = conditions.ReasonUnknownEnvironment
+		} else if errors.As(err, &patchErr) {
+			reason = conditions.ReasonInvalidValuesPatch
+		}
+		u.UpdateStatus(
+			updater.EnsureCondition(conditions.Irreconcilable(corev1.ConditionTrue, reason, err)),
+			updater.EnsureConditionUnknown(conditions.TypeReleaseFailed),
+		)
+		err = newReconcileError("values", ErrValuesFailed, err)
+		var execErr *values.ExecError
+		if errors.As(err, &execErr) && !execErr.Retryable() {
+			err = reconcile.TerminalError(err)
+		}
+		return ctrl.Result{}, err
+	}
+
+	if err := r.verifyProvenance(); err != nil {
 		u.UpdateStatus(
-			updater.EnsureCondition(conditions.Irreconcilable(corev1.ConditionTrue, conditions.ReasonErrorGettingValues, err)),
+			updater.EnsureCondition(conditions.ProvenanceVerificationFailed(corev1.ConditionTrue, conditions.ReasonProvenanceVerificationFailed, err)),
+			updater.EnsureCondition(conditions.Irreconcilable(corev1.ConditionTrue, conditions.ReasonProvenanceVerificationFailed, err)),
 			updater.EnsureConditionUnknown(conditions.TypeReleaseFailed),
 		)
+		r.eventRecorder.Eventf(obj, "Warning", string(conditions.ReasonProvenanceVerificationFailed),
+			"Chart provenance verification failed: %v", err)
+		return ctrl.Result{}, reconcile.TerminalError(newReconcileError("verify provenance", ErrProvenanceVerificationFailed, err))
+	}
+	if r.provenanceKeyring != "" {
+		u.UpdateStatus(updater.EnsureCondition(conditions.ProvenanceVerificationFailed(corev1.ConditionFalse, "", "")))
+	}
+
+	reinstalled, err := r.handleReinstall(actionClient, &u, obj, log)
+	if err != nil {
 		return ctrl.Result{}, err
 	}
 
-	rel, state, err := r.getReleaseState(actionClient, obj, vals.AsMap())
+	// Values of the wrong type surface when the chart is rendered, which
+	// happens from here on.
+	defer func() { r.updateValuesTypeMismatch(&u, err) }()
+
+	var inputsDigest string
+	if r.skipUnchangedDryRun {
+		inputsDigest = r.releaseInputsDigest(obj, vals.AsMap())
+	}
+	summary.enter(&summary.render)
+	rel, state, err := r.getReleaseState(actionClient, obj, vals.AsMap(), inputsDigest)
+	summary.enter(nil)
+	if err == nil && state == statePending {
+		var recovered bool
+		if recovered, err = r.recoverPendingRelease(actionClient, obj, rel, log); err == nil && !recovered {
+			log.Info("Release has a pending operation", "name", rel.Name, "version", rel.Version, "status", rel.Info.Status)
+			delay := r.pendingReleaseRequeueDelay
+			if delay == 0 {
+				delay = defaultPendingReleaseRequeueDelay
+			}
+			return ctrl.Result{RequeueAfter: delay}, nil
+		}
+		if err == nil {
+			summary.enter(&summary.render)
+			rel, state, err = r.getReleaseState(actionClient, obj, vals.AsMap(), inputsDigest)
+			summary.enter(nil)
+		}
+	}
 	if err != nil {
 		u.UpdateStatus(
 			updater.EnsureCondition(conditions.Irreconcilable(corev1.ConditionTrue, conditions.ReasonErrorGettingReleaseState, err)),
@@ -588,36 +2608,163 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (res ctrl.
 			updater.EnsureConditionUnknown(conditions.TypeDeployed),
 			updater.EnsureDeployedRelease(nil),
 		)
-		return ctrl.Result{}, err
+		return ctrl.Result{}, newActionError("get release state", err)
 	}
 	u.UpdateStatus(updater.EnsureCondition(conditions.Irreconcilable(corev1.ConditionFalse, "", "")))
 
+	if (state == stateNeedsInstall || state == stateNeedsUpgrade) && r.namespaceTerminating(ctx, obj, log) {
+		log.Info("Namespace is terminating, not installing or upgrading the release")
+		u.UpdateStatus(updater.EnsureCondition(conditions.NamespaceTerminating(corev1.ConditionTrue, conditions.ReasonNamespaceTerminating, "namespace is terminating")))
+		return ctrl.Result{}, nil
+	}
+
+	if r.adoptionSelector != nil && state == stateNeedsInstall {
+		if _, err := r.adoptResources(ctx, obj, vals.AsMap(), log); err != nil {
+			u.UpdateStatus(
+				updater.EnsureCondition(conditions.Irreconcilable(corev1.ConditionTrue, conditions.ReasonErrorAdoptingResources, err)),
+			)
+			return ctrl.Result{}, err
+		}
+	}
+
 	for _, h := range r.preHooks {
 		if err := h.Exec(obj, vals, log); err != nil {
 			log.Error(err, "pre-release hook failed")
 		}
 	}
 
+	var (
+		renderedRel *release.Release
+		rendered    []unstructured.Unstructured
+	)
+	if (r.manifestValidator != nil || r.applyWaves != nil || r.restrictsKinds() || r.maxManifestSize > 0) && (state == stateNeedsInstall || state == stateNeedsUpgrade) {
+		summary.enter(&summary.render)
+		renderedRel, err = r.renderRelease(actionClient, obj, vals.AsMap(), state)
+		summary.enter(nil)
+		if err == nil {
+			rendered, err = parseManifests(renderedRel.Manifest)
+		}
+		if err != nil {
+			u.UpdateStatus(
+				updater.EnsureCondition(conditions.Irreconcilable(corev1.ConditionTrue, conditions.ReasonErrorRenderingManifests, err)),
+			)
+			return ctrl.Result{}, newReconcileError("render", ErrRenderFailed, err)
+		}
+	}
+
+	if r.maxManifestSize > 0 && renderedRel != nil {
+		if size := manifestSize(renderedRel); size > r.maxManifestSize {
+			err := fmt.Errorf("rendered manifests are %d bytes, which exceeds the limit of %d bytes", size, r.maxManifestSize)
+			log.Info("Rendered manifests are too large", "size", size, "limit", r.maxManifestSize)
+			r.eventRecorder.Eventf(obj, "Warning", "ManifestTooLarge", "Release was not applied: %v", err)
+			u.UpdateStatus(
+				updater.EnsureCondition(conditions.ManifestTooLarge(corev1.ConditionTrue, conditions.ReasonManifestSizeExceeded, err)),
+				updater.EnsureCondition(conditions.Irreconcilable(corev1.ConditionFalse, "", "")),
+			)
+			return ctrl.Result{}, nil
+		}
+		u.UpdateStatus(updater.EnsureCondition(conditions.ManifestTooLarge(corev1.ConditionFalse, "", "")))
+	}
+
+	if r.restrictsKinds() && (state == stateNeedsInstall || state == stateNeedsUpgrade) {
+		if err := r.checkKinds(rendered); err != nil {
+			log.Info("Rendered manifests contain disallowed resources", "error", err.Error())
+			r.eventRecorder.Eventf(obj, "Warning", "DisallowedResource", "Release was not applied: %v", err)
+			u.UpdateStatus(
+				updater.EnsureCondition(conditions.DisallowedResource(corev1.ConditionTrue, conditions.ReasonKindNotAllowed, err)),
+				updater.EnsureCondition(conditions.Irreconcilable(corev1.ConditionFalse, "", "")),
+			)
+			return ctrl.Result{}, nil
+		}
+		u.UpdateStatus(updater.EnsureCondition(conditions.DisallowedResource(corev1.ConditionFalse, "", "")))
+	}
+
+	if r.manifestValidator != nil && (state == stateNeedsInstall || state == stateNeedsUpgrade) {
+		if violation := r.manifestValidator(rendered); violation != nil {
+			log.Info("Rendered manifests violate policy", "violation", violation.Error())
+			r.eventRecorder.Eventf(obj, "Warning", "PolicyViolation", "Release was not applied: %v", violation)
+			u.UpdateStatus(
+				updater.EnsureCondition(conditions.PolicyViolation(corev1.ConditionTrue, conditions.ReasonValidationFailed, violation)),
+				updater.EnsureCondition(conditions.Irreconcilable(corev1.ConditionFalse, "", "")),
+			)
+			return ctrl.Result{}, nil
+		}
+		u.UpdateStatus(updater.EnsureCondition(conditions.PolicyViolation(corev1.ConditionFalse, "", "")))
+	}
+
+	summary.enter(&summary.apply)
+	if state == stateNeedsInstall || state == stateNeedsUpgrade {
+		if err := r.ensureCRDs(ctx, obj, log); err != nil {
+			u.UpdateStatus(
+				updater.EnsureCondition(conditions.Irreconcilable(corev1.ConditionTrue, conditions.ReasonErrorApplyingCRDs, err)),
+			)
+			return ctrl.Result{}, err
+		}
+	}
+
+	if r.applyWaves != nil && (state == stateNeedsInstall || state == stateNeedsUpgrade) {
+		ready, err := r.applyEarlyWaves(ctx, obj, rendered, log)
+		if err != nil {
+			u.UpdateStatus(
+				updater.EnsureCondition(conditions.Irreconcilable(corev1.ConditionTrue, conditions.ReasonErrorApplyingWaves, err)),
+			)
+			return ctrl.Result{}, err
+		}
+		if !ready {
+			u.UpdateStatus(
+				updater.EnsureCondition(conditions.WaitingForReadiness(corev1.ConditionTrue, conditions.ReasonApplyWaveNotReady, "waiting for an apply wave to become ready")),
+				updater.EnsureCondition(conditions.Irreconcilable(corev1.ConditionFalse, "", "")),
+			)
+			return ctrl.Result{RequeueAfter: applyWaveRequeueDelay}, nil
+		}
+	}
+
 	switch state {
 	case stateNeedsInstall:
+		summary.set(reconcileActionInstall, nil)
 		rel, err = r.doInstall(actionClient, &u, obj, vals.AsMap(), log)
 		if err != nil {
+			notifier.failed(nil, err)
 			return ctrl.Result{}, err
 		}
+		notifier.installed(rel)
+		summary.set(reconcileActionInstall, rel)
+		if reinstalled {
+			r.eventRecorder.Eventf(obj, "Normal", "ReleaseReinstalled",
+				"Release %q was reinstalled at version %d", rel.Name, rel.Version)
+		}
 
 	case stateNeedsUpgrade:
+		curRel := rel
+		summary.set(reconcileActionUpgrade, curRel)
 		rel, err = r.doUpgrade(actionClient, &u, obj, vals.AsMap(), log)
 		if err != nil {
+			notifier.failed(curRel, err)
 			return ctrl.Result{}, err
 		}
+		notifier.upgraded(rel)
+		summary.set(reconcileActionUpgrade, rel)
 
 	case stateUnchanged:
+		summary.set(reconcileActionNoop, rel)
 		if err := r.doReconcile(actionClient, &u, rel, log); err != nil {
 			return ctrl.Result{}, err
 		}
 	default:
 		return ctrl.Result{}, fmt.Errorf("unexpected release state: %s", state)
 	}
+	summary.enter(nil)
+
+	r.recordManagedResources(obj, rel, log)
+
+	if err := r.exportManifests(ctx, obj, rel); err != nil {
+		if r.manifestSinkFatal {
+			r.ensureDeployedRelease(&u, rel)
+			u.UpdateStatus(updater.EnsureCondition(conditions.Irreconcilable(corev1.ConditionTrue, conditions.ReasonErrorExportingManifests, err)))
+			return ctrl.Result{}, err
+		}
+		log.Error(err, "failed to export release manifests", "name", rel.Name, "version", rel.Version)
+	}
 
 	for _, h := range r.postHooks {
 		if err := h.Exec(obj, *rel, log); err != nil {
@@ -625,16 +2772,80 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (res ctrl.
 		}
 	}
 
-	ensureDeployedRelease(&u, rel)
+	summary.enter(&summary.wait)
+	if r.chartTests {
+		var passed bool
+		rel, passed, err = r.testRelease(actionClient, &u, obj, rel, actionTimeout, log)
+		if err != nil {
+			u.Update(updater.EnsureFinalizer(r.finalizerName()))
+			u.UpdateStatus(updater.EnsureDeployedRelease(rel))
+			return ctrl.Result{}, err
+		}
+		if !passed && r.chartTestFailurePolicy != ChartTestFailureAdvisory {
+			u.Update(updater.EnsureFinalizer(r.finalizerName()))
+			u.UpdateStatus(
+				updater.EnsureDeployedRelease(rel),
+				updater.EnsureCondition(conditions.Deployed(corev1.ConditionFalse, conditions.ReasonChartTestsFailed, "chart tests failed")),
+				updater.EnsureCondition(conditions.ReleaseFailed(corev1.ConditionFalse, "", "")),
+				updater.EnsureCondition(conditions.Irreconcilable(corev1.ConditionFalse, "", "")),
+			)
+			return ctrl.Result{RequeueAfter: r.reconcilePeriod}, nil
+		}
+	}
+
+	if r.readinessCheck != nil {
+		ready, err := r.readinessCheck(ctx, rel)
+		if err != nil {
+			u.Update(updater.EnsureFinalizer(r.finalizerName()))
+			u.UpdateStatus(
+				updater.EnsureDeployedRelease(rel),
+				updater.EnsureCondition(conditions.WaitingForReadiness(corev1.ConditionUnknown, conditions.ReasonErrorCheckingReadiness, err)),
+			)
+			return ctrl.Result{}, err
+		}
+		if !ready {
+			log.Info("Release is not ready yet", "name", rel.Name, "version", rel.Version)
+			u.Update(updater.EnsureFinalizer(r.finalizerName()))
+			u.UpdateStatus(
+				updater.EnsureDeployedRelease(rel),
+				updater.EnsureCondition(conditions.Deployed(corev1.ConditionFalse, conditions.ReasonReleaseNotReady, "release is not ready yet")),
+				updater.EnsureCondition(conditions.WaitingForReadiness(corev1.ConditionTrue, conditions.ReasonReleaseNotReady, "release is not ready yet")),
+				updater.EnsureCondition(conditions.ReleaseFailed(corev1.ConditionFalse, "", "")),
+				updater.EnsureCondition(conditions.Irreconcilable(corev1.ConditionFalse, "", "")),
+			)
+			return ctrl.Result{RequeueAfter: readinessRequeueDelay}, nil
+		}
+		u.UpdateStatus(updater.EnsureCondition(conditions.WaitingForReadiness(corev1.ConditionFalse, "", "")))
+	}
+	summary.enter(nil)
+
+	r.ensureDeployedRelease(&u, rel)
 	u.UpdateStatus(
 		updater.EnsureCondition(conditions.ReleaseFailed(corev1.ConditionFalse, "", "")),
 		updater.EnsureCondition(conditions.Irreconcilable(corev1.ConditionFalse, "", "")),
+		updater.EnsureReleaseInputsHash(releaseInputsHash(inputsDigest, rel.Version)),
 	)
 
-	return ctrl.Result{RequeueAfter: r.reconcilePeriod}, nil
+	res = ctrl.Result{RequeueAfter: r.reconcilePeriod}
+	if r.upgradeChecker != nil {
+		r.checkChartUpgrade(ctx, &u, obj, rel, log)
+		if res.RequeueAfter == 0 || r.upgradeChecker.Interval() < res.RequeueAfter {
+			res.RequeueAfter = r.upgradeChecker.Interval()
+		}
+	}
+	return res, nil
 }
 
 func (r *Reconciler) getValues(ctx context.Context, obj *unstructured.Unstructured) (chartutil.Values, error) {
+	if err := r.restrictSpecValues(obj); err != nil {
+		return chartutil.Values{}, err
+	}
+	if err := internalvalues.ApplyOverrideLayers(r.overrideValuesLayers, obj); err != nil {
+		return chartutil.Values{}, err
+	}
+	if err := r.applyEnvironmentOverrides(obj); err != nil {
+		return chartutil.Values{}, err
+	}
 	if err := internalvalues.ApplyOverrides(r.overrideValues, obj); err != nil {
 		return chartutil.Values{}, err
 	}
@@ -643,11 +2854,72 @@ func (r *Reconciler) getValues(ctx context.Context, obj *unstructured.Unstructur
 		return chartutil.Values{}, err
 	}
 	vals = r.valueMapper.Map(vals)
-	vals, err = chartutil.CoalesceValues(r.chrt, vals)
+	if r.secretKeyRefs {
+		if err := r.resolveSecretKeyRefs(ctx, obj, vals); err != nil {
+			return chartutil.Values{}, err
+		}
+	}
+	vals, err = r.mergeValuesFiles(vals)
 	if err != nil {
 		return chartutil.Values{}, err
 	}
-	return vals, nil
+	if len(r.valuesFromEnv) > 0 {
+		envVals, err := r.envValues()
+		if err != nil {
+			return chartutil.Values{}, err
+		}
+		vals = chartutil.CoalesceTables(vals, envVals)
+	}
+	merged, err := chartutil.CoalesceValues(r.chrt, vals)
+	if err != nil {
+		return chartutil.Values{}, err
+	}
+	replaceMaps := r.mergeStrategy.Maps == MapMergeReplace
+	appendLists := r.mergeStrategy.Lists == ListMergeAppend
+	if replaceMaps || appendLists {
+		internalvalues.ApplyMergeStrategy(r.chrt.Values, vals, merged, replaceMaps, appendLists)
+	}
+	if len(r.valuesPatch) > 0 {
+		patched, err := internalvalues.ApplyPatch(merged, r.valuesPatch)
+		if err != nil {
+			return chartutil.Values{}, &valuesPatchError{err: err}
+		}
+		merged = patched
+	}
+	return merged, nil
+}
+
+func (r *Reconciler) applyEnvironmentOverrides(obj *unstructured.Unstructured) error {
+	if r.environmentAnnotation == "" {
+		return nil
+	}
+	env, ok := obj.GetAnnotations()[r.environmentAnnotation]
+	if !ok {
+		return nil
+	}
+	overrides, ok := r.environmentOverrides[env]
+	if !ok {
+		return &unknownEnvironmentError{annotation: r.environmentAnnotation, value: env}
+	}
+	return internalvalues.ApplyOverrideLayers([]map[string]interface{}{overrides}, obj)
+}
+
+func (r *Reconciler) mergeValuesFiles(vals chartutil.Values) (chartutil.Values, error) {
+	if len(r.valuesFiles) == 0 {
+		return vals, nil
+	}
+	fileVals := map[string]interface{}{}
+	for _, path := range r.valuesFiles {
+		v, err := chartutil.ReadValuesFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read values file %q: %w", path, err)
+		}
+		fileVals = chartutil.CoalesceTables(v, fileVals)
+	}
+	if r.valuesFilePrecedence == ValuesFilePrecedenceAboveCR {
+		return chartutil.CoalesceTables(fileVals, vals), nil
+	}
+	return chartutil.CoalesceTables(vals, fileVals), nil
 }
 
 type helmReleaseState string
@@ -656,11 +2928,57 @@ const (
 	stateNeedsInstall helmReleaseState = "needs install"
 	stateNeedsUpgrade helmReleaseState = "needs upgrade"
 	stateUnchanged    helmReleaseState = "unchanged"
+	statePending      helmReleaseState = "pending"
 	stateError        helmReleaseState = "error"
 )
 
-func (r *Reconciler) handleDeletion(ctx context.Context, actionClient helmclient.ActionInterface, obj *unstructured.Unstructured, log logr.Logger) error {
-	if !controllerutil.ContainsFinalizer(obj, uninstallFinalizer) {
+// handleUninstallGracePeriod reports whether the uninstall of the release of
+// obj, which is marked for deletion, must wait for the uninstall grace period,
+// and the remaining time. If the uninstall is cancelled, the uninstall
+// finalizer is removed from obj and wait is true.
+func (r *Reconciler) handleUninstallGracePeriod(u *updater.Updater, obj *unstructured.Unstructured, log logr.Logger) (time.Duration, bool) {
+	if r.uninstallGracePeriod == 0 || !controllerutil.ContainsFinalizer(obj, r.finalizerName()) {
+		return 0, false
+	}
+	key := types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+	r.uninstallGraceMu.Lock()
+	defer r.uninstallGraceMu.Unlock()
+	if r.uninstallGraceActive == nil {
+		r.uninstallGraceActive = map[types.NamespacedName]struct{}{}
+	}
+	_, active := r.uninstallGraceActive[key]
+
+	if obj.GetAnnotations()[CancelUninstallAnnotation] == "true" {
+		log.Info("Uninstall cancelled, keeping release")
+		r.eventRecorder.Eventf(obj, "Normal", "UninstallCancelled",
+			"Uninstall of release %q cancelled, the release is kept", obj.GetName())
+		u.Update(updater.RemoveFinalizer(r.finalizerName()))
+		delete(r.uninstallGraceActive, key)
+		return 0, true
+	}
+
+	deadline := obj.GetDeletionTimestamp().Add(r.uninstallGracePeriod)
+	if remaining := time.Until(deadline); remaining > 0 {
+		if !active {
+			r.eventRecorder.Eventf(obj, "Normal", "UninstallGracePeriodStarted",
+				"Release %q will be uninstalled at %s, set annotation %s=true to cancel",
+				obj.GetName(), deadline.UTC().Format(time.RFC3339), CancelUninstallAnnotation)
+			r.uninstallGraceActive[key] = struct{}{}
+		}
+		log.V(1).Info("Waiting for uninstall grace period", "remaining", remaining)
+		return remaining, true
+	}
+
+	if active {
+		r.eventRecorder.Eventf(obj, "Normal", "UninstallGracePeriodEnded",
+			"Uninstall grace period of release %q ended, uninstalling", obj.GetName())
+		delete(r.uninstallGraceActive, key)
+	}
+	return 0, false
+}
+
+func (r *Reconciler) handleDeletion(ctx context.Context, actionClient helmclient.ActionInterface, obj *unstructured.Unstructured, notifier *lifecycleNotifier, namespaceTerminating bool, log logr.Logger) error {
+	if !controllerutil.ContainsFinalizer(obj, r.finalizerName()) {
 		log.Info("Resource is terminated, skipping reconciliation")
 		return nil
 	}
@@ -671,18 +2989,29 @@ func (r *Reconciler) handleDeletion(ctx context.Context, actionClient helmclient
 	// However, if uninstall fails, the finalizer will not be removed
 	// and we need to be able to update the conditions on the CR to
 	// indicate that the uninstall failed.
+	var rel *release.Release
 	if err := func() (err error) {
 		uninstallUpdater := updater.New(r.client)
+		uninstallUpdater.SkipUnchangedStatus(!r.updateUnchangedStatus)
+		uninstallUpdater.UseStatusSubresource(r.useStatusSubresource())
 		defer func() {
 			applyErr := uninstallUpdater.Apply(ctx, obj)
 			if err == nil {
 				err = applyErr
 			}
 		}()
-		return r.doUninstall(actionClient, &uninstallUpdater, obj, log)
+		if namespaceTerminating {
+			rel = r.uninstallFromTerminatingNamespace(actionClient, &uninstallUpdater, obj, log)
+			return nil
+		}
+		rel, err = r.doUninstall(ctx, actionClient, &uninstallUpdater, obj, log)
+		return err
 	}(); err != nil {
+		notifier.failed(nil, err)
 		return err
 	}
+	notifier.uninstalled(rel)
+	metrics.DeleteReleaseMetrics(*r.gvk, obj.GetNamespace(), obj.GetName())
 
 	// Since the client is hitting a cache, waiting for the
 	// deletion here will guarantee that the next reconciliation
@@ -694,7 +3023,7 @@ func (r *Reconciler) handleDeletion(ctx context.Context, actionClient helmclient
 	return nil
 }
 
-func (r *Reconciler) getReleaseState(client helmclient.ActionInterface, obj metav1.Object, vals map[string]interface{}) (*release.Release, helmReleaseState, error) {
+func (r *Reconciler) getReleaseState(client helmclient.ActionInterface, obj *unstructured.Unstructured, vals map[string]interface{}, inputsDigest string) (*release.Release, helmReleaseState, error) {
 	currentRelease, err := client.Get(obj.GetName())
 	if err != nil && !errors.Is(err, driver.ErrReleaseNotFound) {
 		return nil, stateError, err
@@ -703,8 +3032,14 @@ func (r *Reconciler) getReleaseState(client helmclient.ActionInterface, obj meta
 	if errors.Is(err, driver.ErrReleaseNotFound) {
 		return nil, stateNeedsInstall, nil
 	}
+	if currentRelease.Info != nil && currentRelease.Info.Status.IsPending() {
+		return currentRelease, statePending, nil
+	}
+	if isUnchangedRelease(obj, currentRelease, inputsDigest) {
+		return currentRelease, stateUnchanged, nil
+	}
 
-	var opts []helmclient.UpgradeOption
+	opts := []helmclient.UpgradeOption{r.upgradeValuesOption()}
 	if r.maxHistory > 0 {
 		opts = append(opts, func(u *action.Upgrade) error {
 			u.MaxHistory = r.maxHistory
@@ -712,19 +3047,22 @@ func (r *Reconciler) getReleaseState(client helmclient.ActionInterface, obj meta
 		})
 	}
 	for name, annot := range r.upgradeAnnotations {
-		if v, ok := obj.GetAnnotations()[name]; ok {
+		if _, v, ok := lookupAnnotation(obj, name, annot); ok {
 			opts = append(opts, annot.UpgradeOption(v))
 		}
 	}
+	opts = append(opts, r.ownershipUpgradeOptions(obj)...)
+	opts = append(opts, r.resourceOverrideUpgradeOptions()...)
+	opts = append(opts, r.propagatedAnnotationUpgradeOptions(obj)...)
 	opts = append(opts, func(u *action.Upgrade) error {
 		u.DryRun = true
 		return nil
 	})
-	specRelease, err := client.Upgrade(obj.GetName(), obj.GetNamespace(), r.chrt, vals, opts...)
+	specRelease, err := client.Upgrade(obj.GetName(), r.releaseNamespace(obj), r.releaseChart(obj), vals, opts...)
 	if err != nil {
 		return currentRelease, stateError, err
 	}
-	if specRelease.Manifest != currentRelease.Manifest ||
+	if r.manifestChanged(currentRelease, specRelease) ||
 		currentRelease.Info.Status == release.StatusFailed ||
 		currentRelease.Info.Status == release.StatusSuperseded {
 		return currentRelease, stateNeedsUpgrade, nil
@@ -733,21 +3071,30 @@ func (r *Reconciler) getReleaseState(client helmclient.ActionInterface, obj meta
 }
 
 func (r *Reconciler) doInstall(actionClient helmclient.ActionInterface, u *updater.Updater, obj *unstructured.Unstructured, vals map[string]interface{}, log logr.Logger) (*release.Release, error) {
-	var opts []helmclient.InstallOption
+	description := r.describeRelease(obj)
+	opts := []helmclient.InstallOption{func(i *action.Install) error {
+		i.Description = description
+		return nil
+	}}
 	for name, annot := range r.installAnnotations {
-		if v, ok := obj.GetAnnotations()[name]; ok {
+		if _, v, ok := lookupAnnotation(obj, name, annot); ok {
 			opts = append(opts, annot.InstallOption(v))
 		}
 	}
-	rel, err := actionClient.Install(obj.GetName(), obj.GetNamespace(), r.chrt, vals, opts...)
+	opts = append(opts, r.ownershipInstallOptions(obj)...)
+	opts = append(opts, r.resourceOverrideInstallOptions()...)
+	opts = append(opts, r.propagatedAnnotationInstallOptions(obj)...)
+	opts = append(opts, r.installWaitInstallOptions()...)
+	rel, err := actionClient.Install(obj.GetName(), r.releaseNamespace(obj), r.releaseChart(obj), vals, opts...)
 	if err != nil {
 		u.UpdateStatus(
-			updater.EnsureCondition(conditions.Irreconcilable(corev1.ConditionTrue, conditions.ReasonReconcileError, err)),
+			updater.EnsureCondition(conditions.Irreconcilable(corev1.ConditionTrue, actionErrorReason(err), err)),
 			updater.EnsureCondition(conditions.ReleaseFailed(corev1.ConditionTrue, conditions.ReasonInstallError, err)),
 		)
-		return nil, err
+		return nil, newActionError("install", err)
 	}
 	r.reportOverrideEvents(obj)
+	r.reportNotes(obj, rel)
 
 	log.Info("Release installed", "name", rel.Name, "version", rel.Version)
 
@@ -760,34 +3107,60 @@ func (r *Reconciler) doInstall(actionClient helmclient.ActionInterface, u *updat
 }
 
 func (r *Reconciler) doUpgrade(actionClient helmclient.ActionInterface, u *updater.Updater, obj *unstructured.Unstructured, vals map[string]interface{}, log logr.Logger) (*release.Release, error) {
-	var opts []helmclient.UpgradeOption
+	description := r.describeRelease(obj)
+	opts := []helmclient.UpgradeOption{func(u *action.Upgrade) error {
+		u.Description = description
+		return nil
+	}, r.upgradeValuesOption()}
 	if r.maxHistory > 0 {
 		opts = append(opts, func(u *action.Upgrade) error {
 			u.MaxHistory = r.maxHistory
 			return nil
 		})
 	}
+	if r.forceUpgrade {
+		opts = append(opts, func(u *action.Upgrade) error {
+			u.Force = true
+			return nil
+		})
+	}
 	for name, annot := range r.upgradeAnnotations {
-		if v, ok := obj.GetAnnotations()[name]; ok {
+		if _, v, ok := lookupAnnotation(obj, name, annot); ok {
 			opts = append(opts, annot.UpgradeOption(v))
 		}
 	}
+	opts = append(opts, r.ownershipUpgradeOptions(obj)...)
+	opts = append(opts, r.resourceOverrideUpgradeOptions()...)
+	opts = append(opts, r.propagatedAnnotationUpgradeOptions(obj)...)
+	opts = append(opts, r.installWaitUpgradeOptions()...)
+	var forced bool
+	opts = append(opts, func(u *action.Upgrade) error {
+		forced = u.Force
+		return nil
+	})
 
 	// Get the current release so we can compare the new release in the diff if the diff is being logged.
 	curRel, err := actionClient.Get(obj.GetName())
 	if err != nil {
-		return nil, fmt.Errorf("could not get the current Helm Release: %w", err)
+		return nil, newActionError("upgrade", fmt.Errorf("could not get the current Helm Release: %w", err))
 	}
 
-	rel, err := actionClient.Upgrade(obj.GetName(), obj.GetNamespace(), r.chrt, vals, opts...)
+	rel, err := actionClient.Upgrade(obj.GetName(), r.releaseNamespace(obj), r.releaseChart(obj), vals, opts...)
 	if err != nil {
 		u.UpdateStatus(
-			updater.EnsureCondition(conditions.Irreconcilable(corev1.ConditionTrue, conditions.ReasonReconcileError, err)),
+			updater.EnsureCondition(conditions.Irreconcilable(corev1.ConditionTrue, actionErrorReason(err), err)),
 			updater.EnsureCondition(conditions.ReleaseFailed(corev1.ConditionTrue, conditions.ReasonUpgradeError, err)),
 		)
-		return nil, err
+		return nil, newActionError("upgrade", err)
 	}
 	r.reportOverrideEvents(obj)
+	if forced {
+		r.reportReplacedResources(obj, curRel, rel, log)
+	}
+	if r.upgradeDiffEvents {
+		r.reportUpgradeDiff(obj, curRel, rel, log)
+	}
+	r.reportNotes(obj, rel)
 
 	log.Info("Release upgraded", "name", rel.Name, "version", rel.Version)
 
@@ -798,6 +3171,321 @@ func (r *Reconciler) doUpgrade(actionClient helmclient.ActionInterface, u *updat
 	return rel, nil
 }
 
+// reportReplacedResources emits a Warning event for every resource of rel
+// that was replaced by a forced upgrade from curRel, i.e. for every resource
+// that is part of both releases.
+func (r *Reconciler) reportReplacedResources(obj *unstructured.Unstructured, curRel, rel *release.Release, log logr.Logger) {
+	curObjs, err := parseManifests(curRel.Manifest)
+	if err != nil {
+		log.Error(err, "Failed to parse the manifest of the previous release")
+		return
+	}
+	objs, err := parseManifests(rel.Manifest)
+	if err != nil {
+		log.Error(err, "Failed to parse the manifest of the upgraded release")
+		return
+	}
+
+	type resourceKey struct {
+		gk        schema.GroupKind
+		namespace string
+		name      string
+	}
+	existing := make(map[resourceKey]struct{}, len(curObjs))
+	for i := range curObjs {
+		o := &curObjs[i]
+		existing[resourceKey{o.GroupVersionKind().GroupKind(), o.GetNamespace(), o.GetName()}] = struct{}{}
+	}
+	for i := range objs {
+		o := &objs[i]
+		if _, ok := existing[resourceKey{o.GroupVersionKind().GroupKind(), o.GetNamespace(), o.GetName()}]; !ok {
+			continue
+		}
+		r.eventRecorder.Eventf(obj, "Warning", "ResourceReplaced",
+			"%s %q was replaced by a forced upgrade", o.GetKind(), o.GetName())
+	}
+}
+
+func (r *Reconciler) exportManifests(ctx context.Context, obj *unstructured.Unstructured, rel *release.Release) error {
+	if r.manifestSink == nil {
+		return nil
+	}
+	key := client.ObjectKeyFromObject(obj)
+	r.exportedRevisionsMu.Lock()
+	exported := r.exportedRevisions[key] == rel.Version
+	r.exportedRevisionsMu.Unlock()
+	if exported {
+		return nil
+	}
+
+	manifest := rel.Manifest
+	if r.manifestSinkRedactSecrets {
+		var err error
+		if manifest, err = redact.Secrets(manifest); err != nil {
+			return fmt.Errorf("redact secrets: %w", err)
+		}
+	}
+	if err := r.manifestSink(ctx, obj, rel.Version, []byte(manifest)); err != nil {
+		return fmt.Errorf("export manifests of revision %d: %w", rel.Version, err)
+	}
+
+	r.exportedRevisionsMu.Lock()
+	defer r.exportedRevisionsMu.Unlock()
+	if r.exportedRevisions == nil {
+		r.exportedRevisions = map[types.NamespacedName]int{}
+	}
+	r.exportedRevisions[key] = rel.Version
+	return nil
+}
+
+// renderManifests renders the release for obj with a dry run of the install
+// or upgrade and returns the rendered objects.
+func (r *Reconciler) renderManifests(actionClient helmclient.ActionInterface, obj *unstructured.Unstructured, vals map[string]interface{}, state helmReleaseState) ([]unstructured.Unstructured, error) {
+	rel, err := r.renderRelease(actionClient, obj, vals, state)
+	if err != nil {
+		return nil, err
+	}
+	return parseManifests(rel.Manifest)
+}
+
+// renderRelease renders the release for obj with a dry run of the install or
+// upgrade.
+func (r *Reconciler) renderRelease(actionClient helmclient.ActionInterface, obj *unstructured.Unstructured, vals map[string]interface{}, state helmReleaseState) (*release.Release, error) {
+	var (
+		rel *release.Release
+		err error
+	)
+	if state == stateNeedsInstall {
+		opts := []helmclient.InstallOption{}
+		for name, annot := range r.installAnnotations {
+			if _, v, ok := lookupAnnotation(obj, name, annot); ok {
+				opts = append(opts, annot.InstallOption(v))
+			}
+		}
+		opts = append(opts, r.ownershipInstallOptions(obj)...)
+		opts = append(opts, r.resourceOverrideInstallOptions()...)
+		opts = append(opts, r.propagatedAnnotationInstallOptions(obj)...)
+		opts = append(opts, func(i *action.Install) error {
+			i.DryRun = true
+			return nil
+		})
+		rel, err = actionClient.Install(obj.GetName(), r.releaseNamespace(obj), r.releaseChart(obj), vals, opts...)
+	} else {
+		opts := []helmclient.UpgradeOption{r.upgradeValuesOption()}
+		for name, annot := range r.upgradeAnnotations {
+			if _, v, ok := lookupAnnotation(obj, name, annot); ok {
+				opts = append(opts, annot.UpgradeOption(v))
+			}
+		}
+		opts = append(opts, r.ownershipUpgradeOptions(obj)...)
+		opts = append(opts, r.resourceOverrideUpgradeOptions()...)
+		opts = append(opts, r.propagatedAnnotationUpgradeOptions(obj)...)
+		opts = append(opts, func(u *action.Upgrade) error {
+			u.DryRun = true
+			return nil
+		})
+		rel, err = actionClient.Upgrade(obj.GetName(), r.releaseNamespace(obj), r.releaseChart(obj), vals, opts...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("render release: %w", err)
+	}
+	return rel, nil
+}
+
+// applyEarlyWaves applies all but the last apply wave of the rendered
+// objects of a release. It returns false if a wave is not ready yet.
+func (r *Reconciler) applyEarlyWaves(ctx context.Context, obj *unstructured.Unstructured, rendered []unstructured.Unstructured, log logr.Logger) (bool, error) {
+	groups := waves.Group(rendered, r.applyWaves)
+	for i := 0; i < len(groups)-1; i++ {
+		group := groups[i]
+		for j := range group {
+			if err := r.applyWaveObject(ctx, obj, &group[j]); err != nil {
+				return false, err
+			}
+		}
+		for j := range group {
+			if !waves.Ready(&group[j]) {
+				log.V(1).Info("Apply wave is not ready", "wave", i, "kind", group[j].GetKind(), "name", group[j].GetName())
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// applyWaveObject server-side applies o, an object of the release of obj. It
+// is labeled and annotated like an object of the release, so that Helm
+// adopts it when the release is installed or upgraded.
+func (r *Reconciler) applyWaveObject(ctx context.Context, obj *unstructured.Unstructured, o *unstructured.Unstructured) error {
+	namespaced, err := r.client.IsObjectNamespaced(o)
+	if err != nil {
+		return fmt.Errorf("get scope of %s %s: %w", o.GetKind(), o.GetName(), err)
+	}
+	if namespaced && o.GetNamespace() == "" {
+		o.SetNamespace(r.releaseNamespace(obj))
+	}
+
+	setReleaseMetadata(o, obj.GetName(), r.releaseNamespace(obj))
+
+	if err := r.client.Patch(ctx, o, client.Apply, client.FieldOwner(fieldOwner), client.ForceOwnership); err != nil {
+		return fmt.Errorf("apply %s %s: %w", o.GetKind(), o.GetName(), err)
+	}
+	return nil
+}
+
+// parseManifests parses the objects of a multi-document manifest, in the
+// order in which they appear.
+func parseManifests(manifest string) ([]unstructured.Unstructured, error) {
+	manifests := releaseutil.SplitManifests(manifest)
+	keys := make([]string, 0, len(manifests))
+	for k := range manifests {
+		keys = append(keys, k)
+	}
+	sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+
+	objs := make([]unstructured.Unstructured, 0, len(keys))
+	for _, k := range keys {
+		obj := unstructured.Unstructured{}
+		if err := yaml.Unmarshal([]byte(manifests[k]), &obj.Object); err != nil {
+			return nil, fmt.Errorf("parse manifest: %w", err)
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+		objs = append(objs, obj)
+	}
+	return objs, nil
+}
+
+// ensureCRDs creates or upgrades the CRDs of the chart according to the
+// CRD upgrade policy.
+func (r *Reconciler) ensureCRDs(ctx context.Context, obj *unstructured.Unstructured, log logr.Logger) error {
+	if r.crdUpgradePolicy == "" || r.crdUpgradePolicy == CRDUpgradePolicySkip {
+		return nil
+	}
+	for _, crdFile := range r.chrt.CRDObjects() {
+		crds, err := parseManifests(string(crdFile.File.Data))
+		if err != nil {
+			return fmt.Errorf("parse CRD file %s: %w", crdFile.Filename, err)
+		}
+		for i := range crds {
+			if err := r.ensureCRD(ctx, obj, &crds[i], log); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Reconciler) ensureCRD(ctx context.Context, obj, crd *unstructured.Unstructured, log logr.Logger) error {
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(crd.GroupVersionKind())
+	err := r.apiReader.Get(ctx, client.ObjectKeyFromObject(crd), existing)
+	if apierrors.IsNotFound(err) {
+		if err := r.client.Create(ctx, crd); err != nil {
+			return fmt.Errorf("create CRD %s: %w", crd.GetName(), err)
+		}
+		log.Info("CRD created", "name", crd.GetName())
+		r.eventRecorder.Eventf(obj, "Normal", "CRDCreated", "CRD %q was created", crd.GetName())
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get CRD %s: %w", crd.GetName(), err)
+	}
+	if r.crdUpgradePolicy != CRDUpgradePolicyUpgrade {
+		return nil
+	}
+
+	if err := r.client.Patch(ctx, crd, client.Apply, client.FieldOwner(fieldOwner), client.ForceOwnership); err != nil {
+		return fmt.Errorf("apply CRD %s: %w", crd.GetName(), err)
+	}
+	if crd.GetResourceVersion() != existing.GetResourceVersion() {
+		log.Info("CRD upgraded", "name", crd.GetName())
+		r.eventRecorder.Eventf(obj, "Warning", "CRDUpgraded", "CRD %q was upgraded", crd.GetName())
+	}
+	return nil
+}
+
+// waitingDependencies returns the dependencies of obj that are not deployed.
+func (r *Reconciler) waitingDependencies(ctx context.Context, obj *unstructured.Unstructured) ([]string, error) {
+	var waiting []string
+	for _, dep := range r.dependencies {
+		for _, key := range dep.dependsOn(obj) {
+			depObj := &unstructured.Unstructured{}
+			depObj.SetGroupVersionKind(dep.gvk)
+			err := r.client.Get(ctx, key, depObj)
+			if apierrors.IsNotFound(err) {
+				waiting = append(waiting, fmt.Sprintf("%s %s (not found)", dep.gvk.Kind, key))
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("get dependency %s %s: %w", dep.gvk.Kind, key, err)
+			}
+			if !isDeployed(depObj) {
+				waiting = append(waiting, fmt.Sprintf("%s %s", dep.gvk.Kind, key))
+			}
+		}
+	}
+	return waiting, nil
+}
+
+func isDeployed(obj *unstructured.Unstructured) bool {
+	conds, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conds {
+		cond, ok := c.(map[string]interface{})
+		if ok && cond["type"] == conditions.TypeDeployed && cond["status"] == string(corev1.ConditionTrue) {
+			return true
+		}
+	}
+	return false
+}
+
+// mapDependency returns a MapFunc that maps a changed dependency to the CRs
+// that depend on it.
+func (r *Reconciler) mapDependency(cl client.Reader, dep dependency) handler.MapFunc {
+	return func(ctx context.Context, o client.Object) []reconcile.Request {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(r.gvk.GroupVersion().WithKind(r.gvk.Kind + "List"))
+		if err := cl.List(ctx, list); err != nil {
+			r.log.Error(err, "failed to list dependents", "dependency", client.ObjectKeyFromObject(o))
+			return nil
+		}
+		key := client.ObjectKeyFromObject(o)
+		var reqs []reconcile.Request
+		for i := range list.Items {
+			for _, depKey := range dep.dependsOn(&list.Items[i]) {
+				if depKey == key {
+					reqs = append(reqs, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&list.Items[i])})
+					break
+				}
+			}
+		}
+		return reqs
+	}
+}
+
+func (r *Reconciler) describeRelease(obj *unstructured.Unstructured) string {
+	if r.releaseDescription == nil {
+		return DefaultReleaseDescription(obj)
+	}
+	return r.releaseDescription(obj)
+}
+
+// recordManagedResources reports the number of resources of each kind in
+// rel to the managed resources metric.
+func (r *Reconciler) recordManagedResources(obj *unstructured.Unstructured, rel *release.Release, log logr.Logger) {
+	objs, err := parseManifests(rel.Manifest)
+	if err != nil {
+		log.Error(err, "Failed to parse the release manifest for metrics", "name", rel.Name, "version", rel.Version)
+		return
+	}
+	counts := map[schema.GroupVersionKind]int{}
+	for i := range objs {
+		counts[objs[i].GroupVersionKind()]++
+	}
+	metrics.SetReleaseResources(*r.gvk, obj.GetNamespace(), obj.GetName(), counts)
+}
+
 func (r *Reconciler) reportOverrideEvents(obj runtime.Object) {
 	for k, v := range r.overrideValues {
 		r.eventRecorder.Eventf(obj, "Warning", "ValueOverridden",
@@ -805,6 +3493,33 @@ func (r *Reconciler) reportOverrideEvents(obj runtime.Object) {
 	}
 }
 
+func (r *Reconciler) checkChartUpgrade(ctx context.Context, u *updater.Updater, obj *unstructured.Unstructured, rel *release.Release, log logr.Logger) {
+	if rel.Chart == nil || rel.Chart.Metadata == nil {
+		return
+	}
+	current := rel.Chart.Metadata.Version
+	latest, err := r.upgradeChecker.LatestVersion(ctx, rel.Chart.Metadata.Name)
+	available := false
+	if err == nil {
+		available, err = upgradecheck.IsNewer(current, latest)
+	}
+	if err != nil {
+		log.Error(err, "failed to check for chart upgrade", "chart", rel.Chart.Metadata.Name)
+		metrics.DeleteChartUpgradeAvailable(*r.gvk, obj.GetNamespace(), obj.GetName())
+		u.UpdateStatus(updater.EnsureCondition(conditions.UpgradeAvailable(corev1.ConditionUnknown, conditions.ReasonErrorCheckingUpgrade, err)))
+		return
+	}
+
+	metrics.SetChartUpgradeAvailable(*r.gvk, obj.GetNamespace(), obj.GetName(), available)
+	if available {
+		u.UpdateStatus(updater.EnsureCondition(conditions.UpgradeAvailable(corev1.ConditionTrue, conditions.ReasonNewerChartVersion,
+			fmt.Sprintf("chart version %s is available, deployed chart version is %s", latest, current))))
+		return
+	}
+	u.UpdateStatus(updater.EnsureCondition(conditions.UpgradeAvailable(corev1.ConditionFalse, conditions.ReasonChartUpToDate,
+		fmt.Sprintf("deployed chart version %s is the latest", current))))
+}
+
 func (r *Reconciler) doReconcile(actionClient helmclient.ActionInterface, u *updater.Updater, rel *release.Release, log logr.Logger) error {
 	// If a change is made to the CR spec that causes a release failure, a
 	// ConditionReleaseFailed is added to the status conditions. If that change
@@ -816,33 +3531,89 @@ func (r *Reconciler) doReconcile(actionClient helmclient.ActionInterface, u *upd
 		updater.EnsureCondition(conditions.ReleaseFailed(corev1.ConditionFalse, "", "")),
 	)
 
+	if !r.featureEnabled(FeatureGateDriftCorrection) {
+		return nil
+	}
+
 	if err := actionClient.Reconcile(rel); err != nil {
-		u.UpdateStatus(updater.EnsureCondition(conditions.Irreconcilable(corev1.ConditionTrue, conditions.ReasonReconcileError, err)))
-		return err
+		u.UpdateStatus(updater.EnsureCondition(conditions.Irreconcilable(corev1.ConditionTrue, actionErrorReason(err), err)))
+		return newActionError("reconcile", err)
 	}
 
 	log.Info("Release reconciled", "name", rel.Name, "version", rel.Version)
 	return nil
 }
 
-func (r *Reconciler) doUninstall(actionClient helmclient.ActionInterface, u *updater.Updater, obj *unstructured.Unstructured, log logr.Logger) error {
-	var opts []helmclient.UninstallOption
-	for name, annot := range r.uninstallAnnotations {
-		if v, ok := obj.GetAnnotations()[name]; ok {
-			opts = append(opts, annot.UninstallOption(v))
+// handleReinstall uninstalls the release of obj if a reinstall is requested
+// with a reinstall annotation. It returns true if the release was
+// uninstalled, so that it is installed again.
+func (r *Reconciler) handleReinstall(actionClient helmclient.ActionInterface, u *updater.Updater, obj *unstructured.Unstructured, log logr.Logger) (bool, error) {
+	var name string
+	for n, annot := range r.reinstallAnnotations {
+		if key, v, ok := lookupAnnotation(obj, n, annot); ok && annot.Reinstall(v) {
+			name = key
+			break
 		}
 	}
+	if name == "" {
+		return false, nil
+	}
 
-	resp, err := actionClient.Uninstall(obj.GetName(), opts...)
-	if errors.Is(err, driver.ErrReleaseNotFound) {
+	if _, err := actionClient.Get(obj.GetName()); errors.Is(err, driver.ErrReleaseNotFound) {
+		log.Info("Reinstall requested, but release not found; it will be installed", "annotation", name)
+		u.Update(updater.RemoveAnnotation(name))
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("could not get the current Helm Release: %w", err)
+	}
+
+	r.eventRecorder.Eventf(obj, "Warning", "ReleaseReinstalling",
+		"Reinstall requested by annotation %q: uninstalling release %q, all of its resources will be deleted and recreated", name, obj.GetName())
+	resp, err := actionClient.Uninstall(obj.GetName(), r.uninstallOptions(obj)...)
+	if err != nil {
+		u.UpdateStatus(
+			updater.EnsureCondition(conditions.Irreconcilable(corev1.ConditionTrue, actionErrorReason(err), err)),
+			updater.EnsureCondition(conditions.ReleaseFailed(corev1.ConditionTrue, conditions.ReasonUninstallError, err)),
+		)
+		return false, err
+	}
+	log.Info("Release uninstalled for reinstall", "name", resp.Release.Name, "version", resp.Release.Version)
+	r.eventRecorder.Eventf(obj, "Normal", "ReleaseUninstalled",
+		"Release %q was uninstalled for reinstall", obj.GetName())
+
+	u.Update(updater.RemoveAnnotation(name))
+	u.UpdateStatus(
+		updater.EnsureCondition(conditions.Deployed(corev1.ConditionFalse, conditions.ReasonUninstallSuccessful, "")),
+		updater.RemoveDeployedRelease(),
+	)
+	return true, nil
+}
+
+func (r *Reconciler) doUninstall(ctx context.Context, actionClient helmclient.ActionInterface, u *updater.Updater, obj *unstructured.Unstructured, log logr.Logger) (*release.Release, error) {
+	var rel *release.Release
+	resp, err := actionClient.Uninstall(obj.GetName(), r.uninstallOptions(obj)...)
+	if errors.Is(err, driver.ErrReleaseNotFound) && r.uninstallByOwnershipLabel {
+		log.Info("Release not found, deleting resources by ownership label")
+		deleted, err := r.deleteByOwnershipLabel(ctx, obj, log)
+		if err != nil {
+			u.UpdateStatus(
+				updater.EnsureCondition(conditions.Irreconcilable(corev1.ConditionTrue, conditions.ReasonUninstallError, err)),
+				updater.EnsureCondition(conditions.ReleaseFailed(corev1.ConditionTrue, conditions.ReasonUninstallError, err)),
+			)
+			return nil, newActionError("uninstall", err)
+		}
+		r.eventRecorder.Eventf(obj, "Warning", "ResourcesDeletedByLabel",
+			"Release %q was not found, deleted %d resources by ownership label", obj.GetName(), deleted)
+	} else if errors.Is(err, driver.ErrReleaseNotFound) {
 		log.Info("Release not found, removing finalizer")
 	} else if err != nil {
 		u.UpdateStatus(
-			updater.EnsureCondition(conditions.Irreconcilable(corev1.ConditionTrue, conditions.ReasonReconcileError, err)),
+			updater.EnsureCondition(conditions.Irreconcilable(corev1.ConditionTrue, actionErrorReason(err), err)),
 			updater.EnsureCondition(conditions.ReleaseFailed(corev1.ConditionTrue, conditions.ReasonUninstallError, err)),
 		)
-		return err
+		return nil, newActionError("uninstall", err)
 	} else {
+		rel = resp.Release
 		log.Info("Release uninstalled", "name", resp.Release.Name, "version", resp.Release.Version)
 
 		// If log verbosity is higher, output Helm Release Manifest that was uninstalled
@@ -850,12 +3621,79 @@ func (r *Reconciler) doUninstall(actionClient helmclient.ActionInterface, u *upd
 			fmt.Println(diff.Generate(resp.Release.Manifest, ""))
 		}
 	}
-	u.Update(updater.RemoveFinalizer(uninstallFinalizer))
+	u.Update(updater.RemoveFinalizer(r.finalizerName()))
 	u.UpdateStatus(
 		updater.EnsureCondition(conditions.ReleaseFailed(corev1.ConditionFalse, "", "")),
 		updater.EnsureCondition(conditions.Deployed(corev1.ConditionFalse, conditions.ReasonUninstallSuccessful, "")),
 		updater.RemoveDeployedRelease(),
 	)
+	return rel, nil
+}
+
+// refreshChart fetches the chart from the chart source and replaces the
+// chart of the reconciler if its revision changed. The chart is replaced
+// between reconciliations, so that each reconciliation uses a single chart.
+func (r *Reconciler) refreshChart(ctx context.Context) error {
+	var (
+		chrt     *chart.Chart
+		revision string
+		archive  *chartArchive
+		err      error
+	)
+	if src, ok := r.chartSource.(archiveChartSource); ok {
+		chrt, revision, archive, err = src.fetchArchive(ctx)
+	} else {
+		chrt, revision, err = r.chartSource.Fetch(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("fetch chart: %w", err)
+	}
+	r.chrtMu.Lock()
+	// The provenance file may change without the chart.
+	r.chrtArchive = archive
+	if revision == r.chartRevision {
+		r.chrtMu.Unlock()
+		return nil
+	}
+	r.log.Info("Using chart", "name", chrt.Name(), "version", chrt.Metadata.Version, "revision", revision)
+	changed := r.chartRevision != ""
+	r.chrt = chrt
+	r.chartRevision = revision
+	r.chrtMu.Unlock()
+
+	if changed {
+		r.enqueueForChartChange(ctx)
+	}
+	return nil
+}
+
+func (r *Reconciler) runChartRefresh(ctx context.Context) error {
+	ticker := time.NewTicker(r.chartRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := r.refreshChart(ctx); err != nil {
+				r.log.Error(err, "failed to refresh chart")
+			}
+		}
+	}
+}
+
+// validateScope returns an error if the primary GVK is cluster-scoped and no
+// release namespace is configured. If the scope cannot be determined, e.g.
+// because the CRD is not installed yet, no error is returned.
+func (r *Reconciler) validateScope(mapper meta.RESTMapper) error {
+	mapping, err := mapper.RESTMapping(r.gvk.GroupKind(), r.gvk.Version)
+	if err != nil {
+		r.log.V(1).Info("Unable to determine scope of resource", "gvk", r.gvk, "error", err.Error())
+		return nil
+	}
+	if mapping.Scope.Name() == meta.RESTScopeNameRoot && r.releaseNS == "" {
+		return fmt.Errorf("%s is cluster-scoped, a release namespace must be configured with WithReleaseNamespace", r.gvk)
+	}
 	return nil
 }
 
@@ -863,9 +3701,18 @@ func (r *Reconciler) validate() error {
 	if r.gvk == nil {
 		return errors.New("gvk must not be nil")
 	}
-	if r.chrt == nil {
+	if r.chrt == nil && r.chartSource == nil {
 		return errors.New("chart must not be nil")
 	}
+	if r.uninstallByOwnershipLabel && r.ownershipLabel == "" {
+		return errors.New("uninstall by ownership label requires an ownership label")
+	}
+	if r.clusterResolver != nil && r.ownerReferencePolicy != "" && r.ownerReferencePolicy != helmclient.OwnerReferencePolicyNone {
+		return fmt.Errorf("a cluster resolver requires the owner reference policy %s", helmclient.OwnerReferencePolicyNone)
+	}
+	if r.installWaitTimeout > 0 && r.actionTimeout > 0 && r.actionTimeout <= r.installWaitTimeout {
+		return fmt.Errorf("action timeout %s must be longer than the install wait timeout %s", r.actionTimeout, r.installWaitTimeout)
+	}
 	return nil
 }
 
@@ -873,15 +3720,63 @@ func (r *Reconciler) addDefaults(mgr ctrl.Manager, controllerName string) error
 	if r.client == nil {
 		r.client = mgr.GetClient()
 	}
+	if r.apiReader == nil {
+		r.apiReader = mgr.GetAPIReader()
+	}
 	if r.log.GetSink() == nil {
 		r.log = ctrl.Log.WithName("controllers").WithName("Helm")
 	}
+	if r.secretKeyRefs {
+		r.secretValues = &redact.Values{}
+		r.log = r.log.WithSink(r.secretValues.LogSink(r.log.GetSink()))
+	}
+	if r.clusterResolver != nil && r.ownerReferencePolicy == "" {
+		r.ownerReferencePolicy = helmclient.OwnerReferencePolicyNone
+	}
 	if r.actionClientGetter == nil {
-		actionConfigGetter, err := helmclient.NewActionConfigGetter(mgr.GetConfig(), mgr.GetRESTMapper(), r.log)
+		ownerRefs := r.ownerReferencePolicy == "" || r.ownerReferencePolicy == helmclient.OwnerReferencePolicyController
+		acOpts := []helmclient.ActionConfigGetterOption{helmclient.DisableStorageOwnerRefInjection(!ownerRefs)}
+		if r.releaseNS != "" {
+			releaseNamespace := func(obj client.Object) (string, error) { return r.releaseNamespace(obj), nil }
+			acOpts = append(acOpts,
+				helmclient.ClientNamespaceMapper(releaseNamespace),
+				helmclient.StorageNamespaceMapper(releaseNamespace),
+			)
+		}
+		if r.releaseStorageNS != "" {
+			acOpts = append(acOpts, helmclient.SharedStorageNamespace(r.releaseStorageNS))
+		}
+		if r.podSecurityContext != nil || r.containerSecurityContext != nil {
+			acOpts = append(acOpts, helmclient.ResourceTransforms(func(obj *unstructured.Unstructured) error {
+				return securitycontext.Apply(obj, r.podSecurityContext, r.containerSecurityContext)
+			}))
+		}
+		if r.hookJobDefaults != nil {
+			acOpts = append(acOpts, helmclient.ResourceTransforms(r.hookJobDefaults.apply))
+		}
+		if r.kubeClientFactory != nil {
+			acOpts = append(acOpts, helmclient.KubeClientFactory(r.kubeClientFactory))
+		}
+		if r.clusterResolver != nil {
+			acOpts = append(acOpts, helmclient.ClusterResolver(r.clusterResolver))
+		}
+		if len(r.patchStrategies) > 0 {
+			acOpts = append(acOpts, helmclient.PatchStrategies(r.patchStrategies))
+		}
+		if r.impersonate != nil {
+			acOpts = append(acOpts, helmclient.ServiceAccountMapper(func(obj client.Object) (string, error) {
+				return r.impersonate(obj), nil
+			}))
+		}
+		actionConfigGetter, err := helmclient.NewActionConfigGetter(mgr.GetConfig(), mgr.GetRESTMapper(), r.log, acOpts...)
 		if err != nil {
 			return fmt.Errorf("creating action config getter: %w", err)
 		}
-		r.actionClientGetter, err = helmclient.NewActionClientGetter(actionConfigGetter)
+		var acgOpts []helmclient.ActionClientGetterOption
+		if r.ownerReferencePolicy != "" {
+			acgOpts = append(acgOpts, helmclient.WithOwnerReferencePolicy(r.ownerReferencePolicy))
+		}
+		r.actionClientGetter, err = helmclient.NewActionClientGetter(actionConfigGetter, acgOpts...)
 		if err != nil {
 			return fmt.Errorf("creating action client getter: %v", err)
 		}
@@ -889,6 +3784,9 @@ func (r *Reconciler) addDefaults(mgr ctrl.Manager, controllerName string) error
 	if r.eventRecorder == nil {
 		r.eventRecorder = mgr.GetEventRecorderFor(controllerName)
 	}
+	if r.secretKeyRefs {
+		r.eventRecorder = &redactingEventRecorder{EventRecorder: r.eventRecorder, values: r.secretValues}
+	}
 	if r.valueTranslator == nil {
 		r.valueTranslator = internalvalues.DefaultTranslator
 	}
@@ -899,8 +3797,78 @@ func (r *Reconciler) addDefaults(mgr ctrl.Manager, controllerName string) error
 }
 
 func (r *Reconciler) setupScheme(mgr ctrl.Manager) {
-	mgr.GetScheme().AddKnownTypeWithName(*r.gvk, &unstructured.Unstructured{})
-	metav1.AddToGroupVersion(mgr.GetScheme(), r.gvk.GroupVersion())
+	for _, gvk := range append([]schema.GroupVersionKind{*r.gvk}, r.additionalGVKs...) {
+		mgr.GetScheme().AddKnownTypeWithName(gvk, &unstructured.Unstructured{})
+		metav1.AddToGroupVersion(mgr.GetScheme(), gvk.GroupVersion())
+	}
+}
+
+// FeatureGate is the name of an experimental or optional behavior of the
+// reconciler that can be enabled or disabled with WithFeatureGates.
+type FeatureGate string
+
+const (
+	// FeatureGateDriftCorrection re-applies the manifest of a release when
+	// the release is unchanged, which reverts manual changes to the release
+	// resources. Enabled by default.
+	FeatureGateDriftCorrection FeatureGate = "DriftCorrection"
+
+	// FeatureGateGenerationChangedPredicate skips update events for CRs whose
+	// generation and annotations have not changed, like
+	// WithGenerationChangedPredicate(true). Disabled by default.
+	FeatureGateGenerationChangedPredicate FeatureGate = "GenerationChangedPredicate"
+)
+
+// DefaultFeatureGates are the known feature gates and whether they are
+// enabled by default.
+var DefaultFeatureGates = map[FeatureGate]bool{
+	FeatureGateDriftCorrection:            true,
+	FeatureGateGenerationChangedPredicate: false,
+}
+
+// WithFeatureGates is an Option that enables or disables the named feature
+// gates. Gates that are not set keep their default from DefaultFeatureGates.
+// Unknown gate names are ignored, and a warning is logged when the reconciler
+// is set up with a manager.
+//
+// This option can be used multiple times; later settings of a gate take
+// precedence.
+func WithFeatureGates(gates map[string]bool) Option {
+	return func(r *Reconciler) error {
+		if r.featureGates == nil {
+			r.featureGates = map[FeatureGate]bool{}
+		}
+		for name, enabled := range gates {
+			if _, ok := DefaultFeatureGates[FeatureGate(name)]; !ok {
+				r.unknownFeatureGates = append(r.unknownFeatureGates, name)
+				continue
+			}
+			r.featureGates[FeatureGate(name)] = enabled
+		}
+		return nil
+	}
+}
+
+// featureEnabled returns whether gate is enabled for this reconciler.
+func (r *Reconciler) featureEnabled(gate FeatureGate) bool {
+	if enabled, ok := r.featureGates[gate]; ok {
+		return enabled
+	}
+	return DefaultFeatureGates[gate]
+}
+
+// generationPredicate returns the predicate configured with
+// WithGenerationChangedPredicate, or the same predicate if the
+// GenerationChangedPredicate feature gate is enabled. It returns nil if
+// neither is set.
+func (r *Reconciler) generationPredicate() ctrlpredicate.Predicate {
+	if r.generationChangedPredicate != nil {
+		return r.generationChangedPredicate
+	}
+	if r.featureEnabled(FeatureGateGenerationChangedPredicate) {
+		return ctrlpredicate.Or(ctrlpredicate.GenerationChangedPredicate{}, ctrlpredicate.AnnotationChangedPredicate{})
+	}
+	return nil
 }
 
 func (r *Reconciler) setupWatches(mgr ctrl.Manager, c controller.Controller) error {
@@ -911,6 +3879,10 @@ func (r *Reconciler) setupWatches(mgr ctrl.Manager, c controller.Controller) err
 	if r.selectorPredicate != nil {
 		preds = append(preds, r.selectorPredicate)
 	}
+	if p := r.generationPredicate(); p != nil {
+		preds = append(preds, p)
+	}
+	preds = append(preds, r.predicates...)
 
 	if err := c.Watch(
 		source.Kind(mgr.GetCache(), obj),
@@ -920,6 +3892,18 @@ func (r *Reconciler) setupWatches(mgr ctrl.Manager, c controller.Controller) err
 		return err
 	}
 
+	if r.resync != nil {
+		if err := c.Watch(r.resyncSource(mgr.GetCache()), &handler.Funcs{}); err != nil {
+			return err
+		}
+	}
+
+	if r.chartSource != nil {
+		if err := c.Watch(r.chartChangeSource(mgr.GetCache()), &handler.Funcs{}); err != nil {
+			return err
+		}
+	}
+
 	secret := &corev1.Secret{}
 	secret.SetGroupVersionKind(schema.GroupVersionKind{
 		Group:   "",
@@ -929,18 +3913,41 @@ func (r *Reconciler) setupWatches(mgr ctrl.Manager, c controller.Controller) err
 
 	if err := c.Watch(
 		source.Kind(mgr.GetCache(), secret),
-		handler.EnqueueRequestForOwner(mgr.GetScheme(), mgr.GetRESTMapper(), obj, handler.OnlyControllerOwner()),
+		debounce.Handler(handler.EnqueueRequestForOwner(mgr.GetScheme(), mgr.GetRESTMapper(), obj, handler.OnlyControllerOwner()), r.debounceWindow),
 	); err != nil {
 		return err
 	}
 
-	if !r.skipDependentWatches {
-		r.postHooks = append([]hook.PostHook{internalhook.NewDependentResourceWatcher(c, mgr.GetRESTMapper(), mgr.GetCache(), mgr.GetScheme())}, r.postHooks...)
+	for _, dep := range r.dependencies {
+		depObj := &unstructured.Unstructured{}
+		depObj.SetGroupVersionKind(dep.gvk)
+		if err := c.Watch(
+			source.Kind(mgr.GetCache(), depObj),
+			debounce.Handler(handler.EnqueueRequestsFromMapFunc(r.mapDependency(mgr.GetClient(), dep)), r.debounceWindow),
+		); err != nil {
+			return err
+		}
+	}
+
+	for _, w := range r.watchTypes {
+		if err := c.Watch(
+			source.Kind(mgr.GetCache(), w.obj),
+			debounce.Handler(handler.EnqueueRequestsFromMapFunc(w.mapFunc), r.debounceWindow),
+		); err != nil {
+			return err
+		}
+	}
+
+	if r.ownerReferencePolicy == helmclient.OwnerReferencePolicyNone {
+		r.log.Info("Not watching dependent resources, because the owner reference policy is None")
+	} else if !r.skipDependentWatches {
+		ownerRefs := r.ownerReferencePolicy != helmclient.OwnerReferencePolicyAnnotations
+		r.postHooks = append([]hook.PostHook{internalhook.NewDependentResourceWatcher(c, mgr.GetRESTMapper(), mgr.GetCache(), mgr.GetScheme(), internalhook.WithOwnerReferences(ownerRefs), internalhook.WithDebounce(r.debounceWindow))}, r.postHooks...)
 	}
 	return nil
 }
 
-func ensureDeployedRelease(u *updater.Updater, rel *release.Release) {
+func (r *Reconciler) ensureDeployedRelease(u *updater.Updater, rel *release.Release) {
 	reason := conditions.ReasonInstallSuccessful
 	message := "release was successfully installed"
 	if rel.Version > 1 {
@@ -950,9 +3957,15 @@ func ensureDeployedRelease(u *updater.Updater, rel *release.Release) {
 	if rel.Info != nil && len(rel.Info.Notes) > 0 {
 		message = rel.Info.Notes
 	}
-	u.Update(updater.EnsureFinalizer(uninstallFinalizer))
+	if !r.observeOnly {
+		u.Update(updater.EnsureFinalizer(r.finalizerName()))
+	}
+	deployedRelease := updater.EnsureDeployedRelease(rel)
+	if r.renderNotes {
+		deployedRelease = updater.EnsureDeployedReleaseWithNotes(rel)
+	}
 	u.UpdateStatus(
 		updater.EnsureCondition(conditions.Deployed(corev1.ConditionTrue, reason, message)),
-		updater.EnsureDeployedRelease(rel),
+		deployedRelease,
 	)
 }
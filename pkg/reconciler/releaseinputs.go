@@ -0,0 +1,149 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/updater"
+)
+
+// WithSkipUnchangedReleaseDryRun is an Option that configures whether the
+// upgrade dry-run, which compares the rendered manifest with the deployed
+// release, is skipped when nothing that affects the release has changed.
+//
+// When enabled, a hash of the chart, the values and the CR fields that affect
+// the release is stored in the CR status after every successful reconcile,
+// together with the revision of the release. While neither the hash nor the
+// revision changes and the release is deployed, the release is considered
+// unchanged without rendering the chart. Values or templates that are not
+// deterministic, e.g. ones that use lookup or generate random values, are
+// therefore not re-rendered until something else changes.
+func WithSkipUnchangedReleaseDryRun(skip bool) Option {
+	return func(r *Reconciler) error {
+		r.skipUnchangedDryRun = skip
+		return nil
+	}
+}
+
+// releaseInputs are the inputs of a release that are hashed for
+// WithSkipUnchangedReleaseDryRun.
+type releaseInputs struct {
+	Name            string                 `json:"name"`
+	Namespace       string                 `json:"namespace"`
+	Chart           string                 `json:"chart"`
+	ChartRevision   string                 `json:"chartRevision,omitempty"`
+	Values          map[string]interface{} `json:"values"`
+	Annotations     map[string]string      `json:"annotations,omitempty"`
+	OwnershipLabel  string                 `json:"ownershipLabel,omitempty"`
+	ReuseValues     bool                   `json:"reuseValues,omitempty"`
+	OwnerReferences string                 `json:"ownerReferences,omitempty"`
+	Propagated      map[string]string      `json:"propagated,omitempty"`
+}
+
+// releaseInputsDigest returns a digest of the inputs of the release of obj,
+// or an empty string if it cannot be computed. vals must be the values before
+// any pre-hooks ran, since pre-hooks may modify them.
+func (r *Reconciler) releaseInputsDigest(obj *unstructured.Unstructured, vals map[string]interface{}) string {
+	if r.resourceOverrides != nil {
+		// The resource overrides are computed outside of the reconciler, so
+		// the inputs of the release are unknown.
+		return ""
+	}
+	in := releaseInputs{
+		Name:            obj.GetName(),
+		Namespace:       r.releaseNamespace(obj),
+		Chart:           chartDigest(r.chrt),
+		ChartRevision:   r.chartRevision,
+		Values:          vals,
+		OwnershipLabel:  r.ownershipLabel,
+		ReuseValues:     r.upgradeValuesPolicy == UpgradeValuesPolicyReuse,
+		OwnerReferences: string(r.ownerReferencePolicy),
+		Propagated:      r.propagatedAnnotationValues(obj),
+	}
+	for name, annot := range r.upgradeAnnotations {
+		if _, v, ok := lookupAnnotation(obj, name, annot); ok {
+			if in.Annotations == nil {
+				in.Annotations = map[string]string{}
+			}
+			in.Annotations[name] = v
+		}
+	}
+	// Maps are marshaled with sorted keys, so the encoding is deterministic.
+	data, err := json.Marshal(in)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// releaseInputsHash returns the hash that is stored in the CR status for
+// revision version of a release deployed from the inputs with digest.
+func releaseInputsHash(digest string, version int) string {
+	if digest == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s/%d", digest, version)))
+	return hex.EncodeToString(sum[:])
+}
+
+// chartDigest returns a digest of the metadata, values, values schema,
+// templates and files of c and its dependencies. It is also the revision of
+// the charts of NewChartPathSource.
+func chartDigest(c *chart.Chart) string {
+	h := sha256.New()
+	var write func(c *chart.Chart)
+	write = func(c *chart.Chart) {
+		meta, _ := json.Marshal(c.Metadata)
+		vals, _ := json.Marshal(c.Values)
+		h.Write(meta)
+		h.Write(vals)
+		h.Write(c.Schema)
+		files := append(append([]*chart.File{}, c.Templates...), c.Files...)
+		sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+		for _, f := range files {
+			h.Write([]byte(f.Name))
+			h.Write(f.Data)
+		}
+		deps := c.Dependencies()
+		sort.Slice(deps, func(i, j int) bool { return deps[i].Name() < deps[j].Name() })
+		for _, d := range deps {
+			write(d)
+		}
+	}
+	write(c)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// isUnchangedRelease returns whether rel is deployed and was deployed from
+// the same inputs as the current ones, according to the hash stored in the
+// status of obj. digest is the digest of the current inputs.
+func isUnchangedRelease(obj *unstructured.Unstructured, rel *release.Release, digest string) bool {
+	if digest == "" || rel.Info == nil || rel.Info.Status != release.StatusDeployed {
+		return false
+	}
+	return updater.ReleaseInputsHash(obj) == releaseInputsHash(digest, rel.Version)
+}
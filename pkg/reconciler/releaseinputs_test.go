@@ -0,0 +1,106 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	helmfake "github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/fake"
+)
+
+var _ = Describe("WithSkipUnchangedReleaseDryRun", func() {
+	var (
+		r    *Reconciler
+		obj  *unstructured.Unstructured
+		ac   helmfake.ActionClient
+		rel  *release.Release
+		vals map[string]interface{}
+	)
+
+	BeforeEach(func() {
+		r = &Reconciler{chrt: &chart.Chart{
+			Metadata:  &chart.Metadata{Name: "test", Version: "1.0.0"},
+			Templates: []*chart.File{{Name: "templates/cm.yaml", Data: []byte("kind: ConfigMap")}},
+		}}
+		Expect(WithSkipUnchangedReleaseDryRun(true)(r)).To(Succeed())
+		obj = &unstructured.Unstructured{}
+		obj.SetName("test")
+		obj.SetNamespace("ns")
+		vals = map[string]interface{}{"replicas": 1}
+
+		rel = &release.Release{Name: "test", Version: 3, Manifest: "old", Info: &release.Info{Status: release.StatusDeployed}}
+		ac = helmfake.NewActionClient()
+		ac.HandleGet = func() (*release.Release, error) { return rel, nil }
+		ac.HandleUpgrade = func() (*release.Release, error) { return &release.Release{Manifest: "new"}, nil }
+	})
+
+	setStoredHash := func(version int) {
+		hash := releaseInputsHash(r.releaseInputsDigest(obj, vals), version)
+		Expect(unstructured.SetNestedField(obj.Object, hash, "status", "releaseInputsHash")).To(Succeed())
+	}
+
+	It("should skip the dry-run if the stored hash matches", func() {
+		setStoredHash(3)
+		_, state, err := r.getReleaseState(&ac, obj, vals, r.releaseInputsDigest(obj, vals))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(state).To(Equal(stateUnchanged))
+		Expect(ac.Upgrades).To(BeEmpty())
+	})
+
+	It("should run the dry-run if the values changed", func() {
+		setStoredHash(3)
+		vals = map[string]interface{}{"replicas": 2}
+		_, state, err := r.getReleaseState(&ac, obj, vals, r.releaseInputsDigest(obj, vals))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(state).To(Equal(stateNeedsUpgrade))
+		Expect(ac.Upgrades).To(HaveLen(1))
+	})
+
+	It("should run the dry-run if the chart changed", func() {
+		setStoredHash(3)
+		r.chrt.Templates[0].Data = []byte("kind: Secret")
+		_, state, err := r.getReleaseState(&ac, obj, vals, r.releaseInputsDigest(obj, vals))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(state).To(Equal(stateNeedsUpgrade))
+	})
+
+	It("should run the dry-run if the release was changed by someone else", func() {
+		setStoredHash(2)
+		_, state, err := r.getReleaseState(&ac, obj, vals, r.releaseInputsDigest(obj, vals))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(state).To(Equal(stateNeedsUpgrade))
+	})
+
+	It("should run the dry-run if the release is not deployed", func() {
+		setStoredHash(3)
+		rel.Info.Status = release.StatusFailed
+		_, state, err := r.getReleaseState(&ac, obj, vals, r.releaseInputsDigest(obj, vals))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(state).To(Equal(stateNeedsUpgrade))
+	})
+
+	It("should run the dry-run without a stored hash", func() {
+		_, state, err := r.getReleaseState(&ac, obj, vals, r.releaseInputsDigest(obj, vals))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(state).To(Equal(stateNeedsUpgrade))
+		Expect(ac.Upgrades).To(HaveLen(1))
+	})
+})
@@ -0,0 +1,144 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	helmclient "github.com/operator-framework/helm-operator-plugins/pkg/client"
+	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/securitycontext"
+)
+
+// ResourceOverridesFunc returns the resource requests and limits of the
+// containers of obj, a rendered workload, that override the ones rendered by
+// the chart. The keys are of the form "<container>/requests/<resource>" or
+// "<container>/limits/<resource>", e.g. "app/requests/cpu".
+type ResourceOverridesFunc func(obj unstructured.Unstructured) map[string]resource.Quantity
+
+// WithResourceOverrides is an Option that configures a function that
+// overrides the resource requests and limits of the containers of the
+// rendered workloads of a release, e.g. with the requests computed by a
+// right-sizing controller. The function is called for every rendered Pod and
+// built-in workload resource, and only the containers and fields it returns
+// are changed.
+//
+// The overrides are applied when the chart is rendered, so they are part of
+// the manifest of the release, and a change of the overrides upgrades the
+// release at its next reconciliation. The upgrade dry-run is therefore never
+// skipped, even with WithSkipUnchangedReleaseDryRun.
+func WithResourceOverrides(f ResourceOverridesFunc) Option {
+	return func(r *Reconciler) error {
+		if f == nil {
+			return errors.New("resource overrides function must not be nil")
+		}
+		r.resourceOverrides = f
+		return nil
+	}
+}
+
+// resourceOverrideInstallOptions returns the install options that apply the
+// resource overrides to the rendered workloads.
+func (r *Reconciler) resourceOverrideInstallOptions() []helmclient.InstallOption {
+	if r.resourceOverrides == nil {
+		return nil
+	}
+	return []helmclient.InstallOption{helmclient.AppendInstallPostRenderer(resourceOverridesPostRenderer{overrides: r.resourceOverrides})}
+}
+
+// resourceOverrideUpgradeOptions returns the upgrade options that apply the
+// resource overrides to the rendered workloads.
+func (r *Reconciler) resourceOverrideUpgradeOptions() []helmclient.UpgradeOption {
+	if r.resourceOverrides == nil {
+		return nil
+	}
+	return []helmclient.UpgradeOption{helmclient.AppendUpgradePostRenderer(resourceOverridesPostRenderer{overrides: r.resourceOverrides})}
+}
+
+// resourceOverridesPostRenderer overrides the resources of the containers of
+// the rendered workloads.
+type resourceOverridesPostRenderer struct {
+	overrides ResourceOverridesFunc
+}
+
+func (pr resourceOverridesPostRenderer) Run(in *bytes.Buffer) (*bytes.Buffer, error) {
+	objs, err := parseManifests(in.String())
+	if err != nil {
+		return nil, err
+	}
+	out := &bytes.Buffer{}
+	for i := range objs {
+		if err := applyResourceOverrides(&objs[i], pr.overrides); err != nil {
+			return nil, fmt.Errorf("override resources of %s %s: %w", objs[i].GetKind(), objs[i].GetName(), err)
+		}
+		data, err := yaml.Marshal(objs[i].Object)
+		if err != nil {
+			return nil, err
+		}
+		out.WriteString("---\n")
+		out.Write(data)
+	}
+	return out, nil
+}
+
+// applyResourceOverrides sets the resources returned by overrides on the
+// containers and init containers of obj, if obj is a built-in workload.
+func applyResourceOverrides(obj *unstructured.Unstructured, overrides ResourceOverridesFunc) error {
+	path, ok := securitycontext.PodSpecPath(obj.GetKind())
+	if !ok {
+		return nil
+	}
+	quantities := overrides(*obj.DeepCopy())
+	if len(quantities) == 0 {
+		return nil
+	}
+	spec, found, err := unstructured.NestedMap(obj.Object, path...)
+	if err != nil || !found {
+		return err
+	}
+
+	containers := map[string]map[string]interface{}{}
+	for _, field := range []string{"initContainers", "containers"} {
+		list, _ := spec[field].([]interface{})
+		for _, c := range list {
+			if c, ok := c.(map[string]interface{}); ok {
+				name, _ := c["name"].(string)
+				containers[name] = c
+			}
+		}
+	}
+	for key, q := range quantities {
+		parts := strings.SplitN(key, "/", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[2] == "" || (parts[1] != "requests" && parts[1] != "limits") {
+			return fmt.Errorf("invalid resource override key %q, expected <container>/requests/<resource> or <container>/limits/<resource>", key)
+		}
+		c, ok := containers[parts[0]]
+		if !ok {
+			continue
+		}
+		if err := unstructured.SetNestedField(c, q.String(), "resources", parts[1], parts[2]); err != nil {
+			return err
+		}
+	}
+	return unstructured.SetNestedMap(obj.Object, spec, path...)
+}
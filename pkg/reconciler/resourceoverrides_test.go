@@ -0,0 +1,131 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"bytes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/chart"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var _ = Describe("WithResourceOverrides", func() {
+	const manifest = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        image: busybox
+      containers:
+      - name: app
+        image: nginx
+        resources:
+          requests:
+            cpu: 100m
+            memory: 64Mi
+      - name: sidecar
+        image: envoy
+        resources:
+          requests:
+            cpu: 50m
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  key: value
+`
+
+	var (
+		r    *Reconciler
+		objs []unstructured.Unstructured
+	)
+
+	run := func(f ResourceOverridesFunc) error {
+		Expect(WithResourceOverrides(f)(r)).To(Succeed())
+		out, err := resourceOverridesPostRenderer{overrides: r.resourceOverrides}.Run(bytes.NewBufferString(manifest))
+		if err != nil {
+			return err
+		}
+		objs, err = parseManifests(out.String())
+		Expect(err).NotTo(HaveOccurred())
+		return nil
+	}
+
+	resources := func(obj unstructured.Unstructured, field string, i int) map[string]interface{} {
+		containers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", field)
+		res, _, _ := unstructured.NestedMap(containers[i].(map[string]interface{}), "resources")
+		return res
+	}
+
+	BeforeEach(func() {
+		r = &Reconciler{}
+	})
+
+	It("should fail with a nil function", func() {
+		Expect(WithResourceOverrides(nil)(r)).NotTo(Succeed())
+	})
+
+	It("should only override the returned containers and fields", func() {
+		Expect(run(func(obj unstructured.Unstructured) map[string]resource.Quantity {
+			if obj.GetName() != "web" {
+				return nil
+			}
+			return map[string]resource.Quantity{
+				"app/requests/cpu":     resource.MustParse("250m"),
+				"app/limits/memory":    resource.MustParse("128Mi"),
+				"init/requests/cpu":    resource.MustParse("10m"),
+				"missing/requests/cpu": resource.MustParse("1"),
+			}
+		})).To(Succeed())
+		Expect(objs).To(HaveLen(2))
+		Expect(resources(objs[0], "containers", 0)).To(Equal(map[string]interface{}{
+			"requests": map[string]interface{}{"cpu": "250m", "memory": "64Mi"},
+			"limits":   map[string]interface{}{"memory": "128Mi"},
+		}))
+		Expect(resources(objs[0], "containers", 1)).To(Equal(map[string]interface{}{
+			"requests": map[string]interface{}{"cpu": "50m"},
+		}))
+		Expect(resources(objs[0], "initContainers", 0)).To(Equal(map[string]interface{}{
+			"requests": map[string]interface{}{"cpu": "10m"},
+		}))
+		Expect(objs[1].Object).To(HaveKeyWithValue("data", map[string]interface{}{"key": "value"}))
+	})
+
+	It("should fail with an invalid key", func() {
+		Expect(run(func(unstructured.Unstructured) map[string]resource.Quantity {
+			return map[string]resource.Quantity{"app/cpu": resource.MustParse("1")}
+		})).To(MatchError(ContainSubstring(`invalid resource override key "app/cpu"`)))
+	})
+
+	It("should disable skipping unchanged releases", func() {
+		r.chrt = &chart.Chart{Metadata: &chart.Metadata{Name: "test", Version: "0.1.0"}}
+		obj := &unstructured.Unstructured{}
+		obj.SetName("test")
+		Expect(r.releaseInputsDigest(obj, nil)).NotTo(BeEmpty())
+		Expect(WithResourceOverrides(func(unstructured.Unstructured) map[string]resource.Quantity { return nil })(r)).To(Succeed())
+		Expect(r.releaseInputsDigest(obj, nil)).To(BeEmpty())
+	})
+})
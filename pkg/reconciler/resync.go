@@ -0,0 +1,157 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// Resync enqueues all CRs of the Reconcilers that share it for an immediate
+// reconcile, e.g. after an upgrade of the operator, instead of waiting for
+// the reconcile period. The CRs are added to the work queue of each
+// controller, so they are reconciled by its workers within the limits
+// configured with WithMaxConcurrentReconciles and WithFairScheduler, and a CR
+// that is already queued is not queued twice.
+type Resync struct {
+	mu      sync.Mutex
+	targets []resyncTarget
+}
+
+type resyncTarget struct {
+	list  func(context.Context) ([]reconcile.Request, error)
+	queue workqueue.Interface
+}
+
+// NewResync returns a Resync without Reconcilers. Reconcilers are added with
+// the WithResync option and take part once their controller is started.
+func NewResync() *Resync {
+	return &Resync{}
+}
+
+// WithResync is an Option that adds the Reconciler to s, so that all of its
+// CRs are enqueued when s is triggered. The same Resync should be passed to
+// the Reconcilers of all GroupVersionKinds.
+func WithResync(s *Resync) Option {
+	return func(r *Reconciler) error {
+		if s == nil {
+			return errors.New("resync must not be nil")
+		}
+		r.resync = s
+		return nil
+	}
+}
+
+// Trigger enqueues all CRs of the started Reconcilers of s. The CRs are read
+// from the cache of the manager. An error listing the CRs of one Reconciler
+// does not prevent the CRs of the other Reconcilers from being enqueued.
+func (s *Resync) Trigger(ctx context.Context) error {
+	s.mu.Lock()
+	targets := append([]resyncTarget(nil), s.targets...)
+	s.mu.Unlock()
+
+	var errs []error
+	for _, t := range targets {
+		reqs, err := t.list(ctx)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, req := range reqs {
+			t.queue.Add(req)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// OnSignal returns a Runnable that triggers s whenever the process receives
+// one of sigs, e.g. syscall.SIGHUP, until the manager is stopped. Like the
+// controllers, the Runnable only runs on the leader. The signals are handled
+// from the time OnSignal is called, so that they do not terminate a process
+// that is not the leader; a signal received before the Runnable is started
+// triggers s once it starts.
+func (s *Resync) OnSignal(log logr.Logger, sigs ...os.Signal) manager.Runnable {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+	return manager.RunnableFunc(func(ctx context.Context) error {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case sig := <-ch:
+				log.Info("Resyncing all custom resources", "signal", sig.String())
+				if err := s.Trigger(ctx); err != nil {
+					log.Error(err, "Failed to resync custom resources")
+				}
+			}
+		}
+	})
+}
+
+func (s *Resync) add(t resyncTarget) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.targets = append(s.targets, t)
+}
+
+// resyncSource returns a source that adds the work queue of the controller to
+// the Resync of r when the controller is started. The CRs are listed from c
+// and filtered with the selector of r.
+func (r *Reconciler) resyncSource(c client.Reader) source.Source {
+	list := r.requestLister(c)
+	return source.Func(func(_ context.Context, _ handler.EventHandler, queue workqueue.RateLimitingInterface, _ ...predicate.Predicate) error {
+		r.resync.add(resyncTarget{list: list, queue: queue})
+		return nil
+	})
+}
+
+// requestLister returns a function that lists the requests of all CRs of r
+// from c that match the selector of r.
+func (r *Reconciler) requestLister(c client.Reader) func(context.Context) ([]reconcile.Request, error) {
+	return func(ctx context.Context) ([]reconcile.Request, error) {
+		objs := &unstructured.UnstructuredList{}
+		objs.SetGroupVersionKind(r.gvk.GroupVersion().WithKind(r.gvk.Kind + "List"))
+		if err := c.List(ctx, objs); err != nil {
+			return nil, fmt.Errorf("list %s: %w", r.gvk.Kind, err)
+		}
+		reqs := make([]reconcile.Request, 0, len(objs.Items))
+		for i := range objs.Items {
+			obj := &objs.Items[i]
+			if r.selectorPredicate != nil && !r.selectorPredicate.Generic(event.GenericEvent{Object: obj}) {
+				continue
+			}
+			reqs = append(reqs, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(obj)})
+		}
+		return reqs, nil
+	}
+}
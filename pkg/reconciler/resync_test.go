@@ -0,0 +1,128 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// listReader is a client.Reader that lists a fixed set of objects.
+type listReader struct {
+	client.Reader
+	items []unstructured.Unstructured
+	err   error
+}
+
+func (l *listReader) List(_ context.Context, list client.ObjectList, _ ...client.ListOption) error {
+	if l.err != nil {
+		return l.err
+	}
+	list.(*unstructured.UnstructuredList).Items = l.items
+	return nil
+}
+
+var _ = Describe("Resync", func() {
+	var (
+		ctx    context.Context
+		resync *Resync
+		gvk    schema.GroupVersionKind
+		queue  workqueue.RateLimitingInterface
+		reader *listReader
+	)
+
+	newObj := func(namespace, name string, labels map[string]string) unstructured.Unstructured {
+		obj := unstructured.Unstructured{}
+		obj.SetGroupVersionKind(gvk)
+		obj.SetNamespace(namespace)
+		obj.SetName(name)
+		obj.SetLabels(labels)
+		return obj
+	}
+
+	start := func(r *Reconciler, reader client.Reader, queue workqueue.RateLimitingInterface) {
+		Expect(r.resyncSource(reader).Start(ctx, nil, queue)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		resync = NewResync()
+		gvk = schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "TestApp"}
+		queue = workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+		DeferCleanup(queue.ShutDown)
+		reader = &listReader{items: []unstructured.Unstructured{
+			newObj("ns1", "a", map[string]string{"app": "a"}),
+			newObj("ns2", "b", nil),
+		}}
+	})
+
+	It("should fail with a nil resync", func() {
+		Expect(WithResync(nil)(&Reconciler{})).NotTo(Succeed())
+	})
+
+	It("should enqueue all custom resources of started reconcilers", func() {
+		r := &Reconciler{gvk: &gvk}
+		Expect(WithResync(resync)(r)).To(Succeed())
+		Expect(resync.Trigger(ctx)).To(Succeed())
+
+		start(r, reader, queue)
+		Expect(resync.Trigger(ctx)).To(Succeed())
+		Expect(queue.Len()).To(Equal(2))
+
+		// CRs that are already queued are not queued twice.
+		Expect(resync.Trigger(ctx)).To(Succeed())
+		Expect(queue.Len()).To(Equal(2))
+	})
+
+	It("should only enqueue custom resources that match the selector", func() {
+		r := &Reconciler{gvk: &gvk}
+		Expect(WithResync(resync)(r)).To(Succeed())
+		Expect(WithSelector(metav1.LabelSelector{MatchLabels: map[string]string{"app": "a"}})(r)).To(Succeed())
+		start(r, reader, queue)
+
+		Expect(resync.Trigger(ctx)).To(Succeed())
+		Expect(queue.Len()).To(Equal(1))
+		item, _ := queue.Get()
+		Expect(item).To(Equal(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "a"}}))
+	})
+
+	It("should enqueue the custom resources of other reconcilers if listing fails", func() {
+		failing := &Reconciler{gvk: &gvk}
+		Expect(WithResync(resync)(failing)).To(Succeed())
+		failingQueue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+		DeferCleanup(failingQueue.ShutDown)
+		start(failing, &listReader{err: errors.New("list failed")}, failingQueue)
+
+		r := &Reconciler{gvk: &gvk}
+		Expect(WithResync(resync)(r)).To(Succeed())
+		start(r, reader, queue)
+
+		Expect(resync.Trigger(ctx)).To(MatchError(ContainSubstring("list failed")))
+		Expect(queue.Len()).To(Equal(2))
+		Expect(failingQueue.Len()).To(Equal(0))
+	})
+})
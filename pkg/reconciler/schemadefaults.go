@@ -0,0 +1,189 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	structuralschema "k8s.io/apiextensions-apiserver/pkg/apiserver/schema"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// WithApplySchemaDefaults is an Option that configures whether the Reconciler
+// applies the defaults declared in the OpenAPI schema of the CRD to the CR
+// before deriving the values of the release. This ensures that the values are
+// derived from the same defaulted spec that a typed client would see, even
+// for fields the API server did not default when the CR was stored.
+//
+// The schema is read from the CRD when the Reconciler is set up with the
+// manager, so the CRD must be installed at that time. The defaults are not
+// persisted to the CR. It is disabled by default.
+func WithApplySchemaDefaults(enabled bool) Option {
+	return func(r *Reconciler) error {
+		r.applySchemaDefaults = enabled
+		return nil
+	}
+}
+
+// loadSchemaDefaults reads the structural schema of the version of the CRD
+// that is reconciled, so that its defaults can be applied to CRs.
+func (r *Reconciler) loadSchemaDefaults(ctx context.Context, mapper meta.RESTMapper) error {
+	if !r.applySchemaDefaults || r.schemaDefaults != nil {
+		return nil
+	}
+	mapping, err := mapper.RESTMapping(r.gvk.GroupKind(), r.gvk.Version)
+	if err != nil {
+		return fmt.Errorf("get resource mapping of %s: %w", r.gvk, err)
+	}
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"})
+	name := fmt.Sprintf("%s.%s", mapping.Resource.Resource, r.gvk.Group)
+	if err := r.apiReader.Get(ctx, client.ObjectKey{Name: name}, u); err != nil {
+		return fmt.Errorf("get CRD %s: %w", name, err)
+	}
+	crd := &apiextv1.CustomResourceDefinition{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, crd); err != nil {
+		return fmt.Errorf("convert CRD %s: %w", name, err)
+	}
+	s, err := structuralSchema(crd, r.gvk.Version)
+	if err != nil {
+		return err
+	}
+	r.schemaDefaults = s
+	return nil
+}
+
+// structuralSchema returns the structural schema of the given version of the
+// CRD, with its defaults normalized to the types the API server decodes JSON
+// numbers into.
+func structuralSchema(crd *apiextv1.CustomResourceDefinition, version string) (*structuralschema.Structural, error) {
+	for _, v := range crd.Spec.Versions {
+		if v.Name != version {
+			continue
+		}
+		if v.Schema == nil || v.Schema.OpenAPIV3Schema == nil {
+			return nil, fmt.Errorf("version %s of CRD %s has no schema", version, crd.Name)
+		}
+		internal := &apiextensions.JSONSchemaProps{}
+		if err := apiextv1.Convert_v1_JSONSchemaProps_To_apiextensions_JSONSchemaProps(v.Schema.OpenAPIV3Schema, internal, nil); err != nil {
+			return nil, fmt.Errorf("convert schema of CRD %s: %w", crd.Name, err)
+		}
+		s, err := structuralschema.NewStructural(internal)
+		if err != nil {
+			return nil, fmt.Errorf("schema of CRD %s is not structural: %w", crd.Name, err)
+		}
+		if err := normalizeDefaults(s); err != nil {
+			return nil, fmt.Errorf("normalize defaults of CRD %s: %w", crd.Name, err)
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("version %s not found in CRD %s", version, crd.Name)
+}
+
+// normalizeDefaults re-decodes the default values of s, so that integers are
+// represented as int64 like in the unstructured objects read from the API
+// server, instead of as float64.
+func normalizeDefaults(s *structuralschema.Structural) error {
+	if s == nil {
+		return nil
+	}
+	if s.Default.Object != nil {
+		data, err := json.Marshal(s.Default.Object)
+		if err != nil {
+			return err
+		}
+		var v interface{}
+		if err := utiljson.Unmarshal(data, &v); err != nil {
+			return err
+		}
+		s.Default.Object = v
+	}
+	for k, prop := range s.Properties {
+		prop := prop
+		if err := normalizeDefaults(&prop); err != nil {
+			return err
+		}
+		s.Properties[k] = prop
+	}
+	if err := normalizeDefaults(s.Items); err != nil {
+		return err
+	}
+	if s.AdditionalProperties != nil {
+		return normalizeDefaults(s.AdditionalProperties.Structural)
+	}
+	return nil
+}
+
+// applySchemaDefaultsTo applies the defaults of the CRD schema to obj, which
+// must not be the object that is updated by the Reconciler.
+func (r *Reconciler) applySchemaDefaultsTo(obj *unstructured.Unstructured) {
+	if r.schemaDefaults == nil {
+		return
+	}
+	applyDefaults(obj.Object, r.schemaDefaults)
+}
+
+// applyDefaults sets the fields of x that are missing or null to the defaults
+// of s, the same way the API server defaults CRs. Unlike the API server, it
+// does not depend on the CEL validation of the schema.
+func applyDefaults(x interface{}, s *structuralschema.Structural) {
+	if s == nil {
+		return
+	}
+	switch x := x.(type) {
+	case map[string]interface{}:
+		for k, prop := range s.Properties {
+			if prop.Default.Object == nil {
+				continue
+			}
+			if v, found := x[k]; !found || (v == nil && !prop.Nullable) {
+				x[k] = runtime.DeepCopyJSONValue(prop.Default.Object)
+			}
+		}
+		for k := range x {
+			if prop, found := s.Properties[k]; found {
+				applyDefaults(x[k], &prop)
+			} else if s.AdditionalProperties != nil && s.AdditionalProperties.Structural != nil {
+				x[k] = defaultNull(x[k], s.AdditionalProperties.Structural)
+				applyDefaults(x[k], s.AdditionalProperties.Structural)
+			}
+		}
+	case []interface{}:
+		for i := range x {
+			x[i] = defaultNull(x[i], s.Items)
+			applyDefaults(x[i], s.Items)
+		}
+	}
+}
+
+// defaultNull returns the default of s if x is null and s is not nullable,
+// and x otherwise.
+func defaultNull(x interface{}, s *structuralschema.Structural) interface{} {
+	if x != nil || s == nil || s.Nullable || s.Default.Object == nil {
+		return x
+	}
+	return runtime.DeepCopyJSONValue(s.Default.Object)
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var _ = Describe("WithApplySchemaDefaults", func() {
+	var crd *apiextv1.CustomResourceDefinition
+
+	BeforeEach(func() {
+		crd = &apiextv1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: "testapps.example.com"},
+			Spec: apiextv1.CustomResourceDefinitionSpec{
+				Versions: []apiextv1.CustomResourceDefinitionVersion{{
+					Name: "v1",
+					Schema: &apiextv1.CustomResourceValidation{OpenAPIV3Schema: &apiextv1.JSONSchemaProps{
+						Type: "object",
+						Properties: map[string]apiextv1.JSONSchemaProps{
+							"spec": {
+								Type:    "object",
+								Default: &apiextv1.JSON{Raw: []byte(`{}`)},
+								Properties: map[string]apiextv1.JSONSchemaProps{
+									"replicas": {Type: "integer", Default: &apiextv1.JSON{Raw: []byte(`3`)}},
+									"image":    {Type: "string", Default: &apiextv1.JSON{Raw: []byte(`"nginx"`)}},
+									"ports": {
+										Type: "array",
+										Items: &apiextv1.JSONSchemaPropsOrArray{Schema: &apiextv1.JSONSchemaProps{
+											Type: "object",
+											Properties: map[string]apiextv1.JSONSchemaProps{
+												"protocol": {Type: "string", Default: &apiextv1.JSON{Raw: []byte(`"TCP"`)}},
+											},
+										}},
+									},
+								},
+							},
+						},
+					}},
+				}},
+			},
+		}
+	})
+
+	It("should apply the defaults of the schema", func() {
+		s, err := structuralSchema(crd, "v1")
+		Expect(err).NotTo(HaveOccurred())
+		r := &Reconciler{schemaDefaults: s}
+
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+		r.applySchemaDefaultsTo(obj)
+		Expect(obj.Object).To(Equal(map[string]interface{}{
+			"spec": map[string]interface{}{"replicas": int64(3), "image": "nginx"},
+		}))
+	})
+
+	It("should not override set fields", func() {
+		s, err := structuralSchema(crd, "v1")
+		Expect(err).NotTo(HaveOccurred())
+		r := &Reconciler{schemaDefaults: s}
+
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"image": "httpd",
+				"ports": []interface{}{map[string]interface{}{"protocol": "UDP"}, map[string]interface{}{}},
+			},
+		}}
+		r.applySchemaDefaultsTo(obj)
+		Expect(obj.Object).To(Equal(map[string]interface{}{
+			"spec": map[string]interface{}{
+				"replicas": int64(3),
+				"image":    "httpd",
+				"ports":    []interface{}{map[string]interface{}{"protocol": "UDP"}, map[string]interface{}{"protocol": "TCP"}},
+			},
+		}))
+	})
+
+	It("should fail for an unknown version", func() {
+		_, err := structuralSchema(crd, "v2")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should do nothing without a schema", func() {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+		(&Reconciler{}).applySchemaDefaultsTo(obj)
+		Expect(obj.Object).To(BeEmpty())
+	})
+})
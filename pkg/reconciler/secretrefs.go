@@ -0,0 +1,182 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/redact"
+)
+
+// secretKeyRefKey is the key of a value that references a key of a Secret.
+const secretKeyRefKey = "secretKeyRef"
+
+// WithSecretKeyRefs is an Option that configures whether values of the form
+//
+//	password:
+//	  secretKeyRef:
+//	    name: my-secret
+//	    key: password
+//
+// are replaced by the value of the key of the Secret in the namespace of the
+// CR, e.g. to pass a password to the chart without storing it in the CR.
+// The Secrets are read on every reconcile.
+//
+// The resolved values are treated as sensitive: they are redacted, also in
+// their base64 encoded form, wherever the Reconciler would expose them, i.e.
+// in all fields of the status of the CR including the manifest of the
+// deployed release, in the messages of events and in the messages and
+// key/value pairs that are logged with the logger of the Reconciler and the
+// errors it returns. They are only stored in the release, like all values.
+// Since every occurrence is redacted, short values may make messages hard to
+// read. It is disabled by default.
+func WithSecretKeyRefs(enabled bool) Option {
+	return func(r *Reconciler) error {
+		r.secretKeyRefs = enabled
+		return nil
+	}
+}
+
+// resolveSecretKeyRefs replaces the secret key references in vals with the
+// values of the referenced Secret keys, and records the resolved values of obj
+// as sensitive values.
+func (r *Reconciler) resolveSecretKeyRefs(ctx context.Context, obj *unstructured.Unstructured, vals chartutil.Values) error {
+	namespace := obj.GetNamespace()
+	if namespace == "" {
+		namespace = r.releaseNS
+	}
+	var resolved []string
+	err := resolveSecretKeyRefsIn(vals, "", func(ref secretKeyRef) (string, error) {
+		if namespace == "" {
+			return "", errors.New("secret key references of cluster-scoped custom resources require a release namespace")
+		}
+		secret := &corev1.Secret{}
+		if err := r.apiReader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: ref.Name}, secret); err != nil {
+			return "", fmt.Errorf("get secret %s/%s: %w", namespace, ref.Name, err)
+		}
+		value, ok := secret.Data[ref.Key]
+		if !ok {
+			return "", fmt.Errorf("key %q not found in secret %s/%s", ref.Key, namespace, ref.Name)
+		}
+		resolved = append(resolved, string(value))
+		return string(value), nil
+	})
+	// The values resolved so far are recorded even on failure, since they may
+	// already be part of the values.
+	r.secretValues.Set(client.ObjectKeyFromObject(obj).String(), resolved)
+	return err
+}
+
+type secretKeyRef struct {
+	Name string
+	Key  string
+}
+
+// resolveSecretKeyRefsIn replaces the secret key references in the maps and
+// slices of v in place with the values returned by resolve. Errors are
+// prefixed with the path of the reference, starting with path.
+func resolveSecretKeyRefsIn(v interface{}, path string, resolve func(secretKeyRef) (string, error)) error {
+	resolveElem := func(e interface{}, path string, set func(string)) error {
+		ref, ok, err := asSecretKeyRef(e)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if !ok {
+			return resolveSecretKeyRefsIn(e, path, resolve)
+		}
+		value, err := resolve(ref)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		set(value)
+		return nil
+	}
+	switch v := v.(type) {
+	case chartutil.Values:
+		return resolveSecretKeyRefsIn(map[string]interface{}(v), path, resolve)
+	case map[string]interface{}:
+		for k, e := range v {
+			k := k
+			elemPath := k
+			if path != "" {
+				elemPath = path + "." + k
+			}
+			if err := resolveElem(e, elemPath, func(value string) { v[k] = value }); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, e := range v {
+			i := i
+			if err := resolveElem(e, fmt.Sprintf("%s[%d]", path, i), func(value string) { v[i] = value }); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// asSecretKeyRef returns the secret key reference of v, if v is a map with
+// the single key secretKeyRef.
+func asSecretKeyRef(v interface{}) (secretKeyRef, bool, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok || len(m) != 1 {
+		return secretKeyRef{}, false, nil
+	}
+	refVal, ok := m[secretKeyRefKey]
+	if !ok {
+		return secretKeyRef{}, false, nil
+	}
+	ref, ok := refVal.(map[string]interface{})
+	if !ok {
+		return secretKeyRef{}, false, fmt.Errorf("%s must be an object", secretKeyRefKey)
+	}
+	name, _ := ref["name"].(string)
+	key, _ := ref["key"].(string)
+	if name == "" || key == "" || len(ref) != 2 {
+		return secretKeyRef{}, false, fmt.Errorf("%s must have exactly the fields name and key", secretKeyRefKey)
+	}
+	return secretKeyRef{Name: name, Key: key}, true, nil
+}
+
+// redactingEventRecorder is a record.EventRecorder that redacts the sensitive
+// values of the Reconciler in the messages of events.
+type redactingEventRecorder struct {
+	record.EventRecorder
+	values *redact.Values
+}
+
+func (e *redactingEventRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	e.EventRecorder.Event(object, eventtype, reason, e.values.String(message))
+}
+
+func (e *redactingEventRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	e.EventRecorder.Event(object, eventtype, reason, e.values.String(fmt.Sprintf(messageFmt, args...)))
+}
+
+func (e *redactingEventRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	e.EventRecorder.AnnotatedEventf(object, annotations, eventtype, reason, "%s", e.values.String(fmt.Sprintf(messageFmt, args...)))
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/chartutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/redact"
+)
+
+var _ = Describe("WithSecretKeyRefs", func() {
+	var (
+		r   *Reconciler
+		obj *unstructured.Unstructured
+	)
+
+	ref := func(name, key string) map[string]interface{} {
+		return map[string]interface{}{"secretKeyRef": map[string]interface{}{"name": name, "key": key}}
+	}
+
+	BeforeEach(func() {
+		r = &Reconciler{secretValues: &redact.Values{}}
+		Expect(WithSecretKeyRefs(true)(r)).To(Succeed())
+		r.apiReader = fake.NewClientBuilder().WithObjects(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "db"},
+			Data:       map[string][]byte{"password": []byte("s3cr3t")},
+		}).Build()
+		obj = &unstructured.Unstructured{}
+		obj.SetNamespace("ns")
+		obj.SetName("test")
+	})
+
+	It("should resolve the references and redact the values", func() {
+		vals := chartutil.Values{
+			"db":    map[string]interface{}{"password": ref("db", "password")},
+			"users": []interface{}{ref("db", "password"), "plain"},
+		}
+		Expect(r.resolveSecretKeyRefs(context.Background(), obj, vals)).To(Succeed())
+		Expect(vals).To(Equal(chartutil.Values{
+			"db":    map[string]interface{}{"password": "s3cr3t"},
+			"users": []interface{}{"s3cr3t", "plain"},
+		}))
+		Expect(r.secretValues.String("password: s3cr3t")).To(Equal("password: REDACTED"))
+	})
+
+	It("should fail if the key does not exist", func() {
+		vals := chartutil.Values{"db": map[string]interface{}{"password": ref("db", "missing")}}
+		Expect(r.resolveSecretKeyRefs(context.Background(), obj, vals)).To(MatchError(ContainSubstring(`db.password: key "missing" not found`)))
+	})
+
+	It("should fail if the secret does not exist", func() {
+		vals := chartutil.Values{"users": []interface{}{ref("missing", "password")}}
+		Expect(r.resolveSecretKeyRefs(context.Background(), obj, vals)).To(MatchError(ContainSubstring("users[0]: get secret ns/missing")))
+	})
+
+	It("should fail with an invalid reference", func() {
+		vals := chartutil.Values{"db": map[string]interface{}{"secretKeyRef": map[string]interface{}{"name": "db"}}}
+		Expect(r.resolveSecretKeyRefs(context.Background(), obj, vals)).To(MatchError(ContainSubstring("db: secretKeyRef must have exactly the fields name and key")))
+	})
+
+	It("should not treat maps with other keys as references", func() {
+		vals := chartutil.Values{"db": map[string]interface{}{"secretKeyRef": "name", "other": "value"}}
+		Expect(r.resolveSecretKeyRefs(context.Background(), obj, vals)).To(Succeed())
+		Expect(vals).To(Equal(chartutil.Values{"db": map[string]interface{}{"secretKeyRef": "name", "other": "value"}}))
+	})
+
+	It("should redact the messages of events", func() {
+		r.secretValues.Set("ns/test", []string{"s3cr3t"})
+		fakeRecorder := record.NewFakeRecorder(1)
+		recorder := &redactingEventRecorder{EventRecorder: fakeRecorder, values: r.secretValues}
+		recorder.Eventf(obj, "Warning", "Failed", "failed with %s", "s3cr3t")
+		Expect(<-fakeRecorder.Events).To(Equal("Warning Failed failed with REDACTED"))
+	})
+})
@@ -0,0 +1,135 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"helm.sh/helm/v3/pkg/release"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// maxUpgradeDiffEventLength bounds the length of the messages of upgrade diff
+// events.
+const maxUpgradeDiffEventLength = 1024
+
+// WithUpgradeDiffEvents is an Option that configures whether a Normal event
+// that summarizes the resources added, removed and modified by an upgrade is
+// recorded on the CR after every upgrade, as a lightweight audit trail.
+//
+// The event only names the changed resources and never includes their
+// contents, so the data of Secrets is not disclosed. Long lists of resources
+// are truncated.
+func WithUpgradeDiffEvents(enabled bool) Option {
+	return func(r *Reconciler) error {
+		r.upgradeDiffEvents = enabled
+		return nil
+	}
+}
+
+func (r *Reconciler) reportUpgradeDiff(obj *unstructured.Unstructured, curRel, rel *release.Release, log logr.Logger) {
+	added, removed, modified, err := diffManifests(curRel.Manifest, rel.Manifest)
+	if err != nil {
+		log.Error(err, "Failed to compute the diff of the upgraded release")
+		return
+	}
+	r.eventRecorder.Event(obj, "Normal", "UpgradeDiff", upgradeDiffMessage(rel.Version, added, removed, modified))
+}
+
+// diffManifests returns the names of the resources that were added, removed
+// and modified between the manifests cur and next, in sorted order.
+func diffManifests(cur, next string) (added, removed, modified []string, err error) {
+	curObjs, err := parseManifests(cur)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("parse previous manifest: %w", err)
+	}
+	nextObjs, err := parseManifests(next)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("parse upgraded manifest: %w", err)
+	}
+
+	existing := make(map[string]*unstructured.Unstructured, len(curObjs))
+	for i := range curObjs {
+		existing[resourceName(&curObjs[i])] = &curObjs[i]
+	}
+	for i := range nextObjs {
+		o := &nextObjs[i]
+		name := resourceName(o)
+		c, ok := existing[name]
+		switch {
+		case !ok:
+			added = append(added, name)
+		case !equality.Semantic.DeepEqual(c.Object, o.Object):
+			modified = append(modified, name)
+		}
+		delete(existing, name)
+	}
+	for name := range existing {
+		removed = append(removed, name)
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(modified)
+	return added, removed, modified, nil
+}
+
+// resourceName returns the kind, namespace and name of o, e.g.
+// "ConfigMap ns/name".
+func resourceName(o *unstructured.Unstructured) string {
+	if o.GetNamespace() == "" {
+		return fmt.Sprintf("%s %s", o.GetKind(), o.GetName())
+	}
+	return fmt.Sprintf("%s %s/%s", o.GetKind(), o.GetNamespace(), o.GetName())
+}
+
+// upgradeDiffMessage formats an upgrade diff, truncating the lists of
+// resources so that the message does not exceed maxUpgradeDiffEventLength.
+func upgradeDiffMessage(version int, added, removed, modified []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Release upgraded to version %d: %d added, %d removed, %d modified",
+		version, len(added), len(removed), len(modified))
+	omitted := 0
+	for _, section := range []struct {
+		label string
+		names []string
+	}{
+		{"added", added},
+		{"removed", removed},
+		{"modified", modified},
+	} {
+		for i, name := range section.names {
+			entry := "; " + section.label + ": " + name
+			if i > 0 {
+				entry = ", " + name
+			}
+			// Keep room for the omission suffix.
+			if omitted > 0 || b.Len()+len(entry) > maxUpgradeDiffEventLength-32 {
+				omitted++
+				continue
+			}
+			b.WriteString(entry)
+		}
+	}
+	if omitted > 0 {
+		fmt.Fprintf(&b, " (%d more omitted)", omitted)
+	}
+	return b.String()
+}
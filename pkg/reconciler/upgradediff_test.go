@@ -0,0 +1,105 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"fmt"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WithUpgradeDiffEvents", func() {
+	It("should enable upgrade diff events", func() {
+		r := &Reconciler{}
+		Expect(WithUpgradeDiffEvents(true)(r)).To(Succeed())
+		Expect(r.upgradeDiffEvents).To(BeTrue())
+	})
+})
+
+var _ = Describe("diffManifests", func() {
+	It("should report added, removed and modified resources", func() {
+		cur := `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: kept
+  namespace: ns
+data:
+  a: "1"
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: changed
+  namespace: ns
+data:
+  password: b2xk
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: gone
+  namespace: ns
+`
+		next := `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: kept
+  namespace: ns
+data:
+  a: "1"
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: changed
+  namespace: ns
+data:
+  password: bmV3
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: new
+`
+		added, removed, modified, err := diffManifests(cur, next)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(added).To(Equal([]string{"ClusterRole new"}))
+		Expect(removed).To(Equal([]string{"ConfigMap ns/gone"}))
+		Expect(modified).To(Equal([]string{"Secret ns/changed"}))
+
+		msg := upgradeDiffMessage(2, added, removed, modified)
+		Expect(msg).To(Equal("Release upgraded to version 2: 1 added, 1 removed, 1 modified; " +
+			"added: ClusterRole new; removed: ConfigMap ns/gone; modified: Secret ns/changed"))
+		Expect(msg).NotTo(ContainSubstring("bmV3"))
+	})
+
+	It("should bound the length of the message", func() {
+		var added []string
+		for i := 0; i < 200; i++ {
+			added = append(added, fmt.Sprintf("ConfigMap ns/config-%d", i))
+		}
+		msg := upgradeDiffMessage(3, added, nil, nil)
+		Expect(len(msg)).To(BeNumerically("<=", maxUpgradeDiffEventLength))
+		Expect(msg).To(HavePrefix("Release upgraded to version 3: 200 added"))
+		Expect(msg).To(MatchRegexp(`\(\d+ more omitted\)$`))
+		Expect(strings.Count(msg, "ConfigMap")).To(BeNumerically("<", 200))
+	})
+})
@@ -0,0 +1,212 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chart"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/conditions"
+	"github.com/operator-framework/helm-operator-plugins/pkg/reconciler/internal/updater"
+)
+
+var (
+	// schemaTypeErrorRegexp matches the type errors of the validation of the
+	// values against the schema of a chart, e.g.
+	// "- image.tag: Invalid type. Expected: string, given: integer".
+	schemaTypeErrorRegexp = regexp.MustCompile(`^- (.+?): Invalid type\. Expected: (.+?), given: (\S+)$`)
+
+	// templateErrorRegexp matches the action and the error of a template
+	// execution error, e.g. `at <.Values.replicas>: wrong type for value`.
+	templateErrorRegexp = regexp.MustCompile(`at <([^>]*)>: (.*)`)
+
+	valuesPathRegexp       = regexp.MustCompile(`\.Values((?:\.[\w-]+)+)`)
+	wrongTypeRegexp        = regexp.MustCompile(`wrong type for value; expected (.+?); got (.+)$`)
+	cannotEvaluateRegexp   = regexp.MustCompile(`can't evaluate field ([\w-]+) in type (.+)$`)
+	cannotIterateRegexp    = regexp.MustCompile(`range can't iterate over`)
+	incompatibleTypeRegexp = regexp.MustCompile(`incompatible types for comparison|invalid type for comparison`)
+)
+
+// valuesTypeMismatch is a value whose type does not match the type that the
+// chart expects. The types are empty if they cannot be derived.
+type valuesTypeMismatch struct {
+	path     string
+	expected string
+	given    string
+}
+
+func (m valuesTypeMismatch) String() string {
+	switch {
+	case m.expected != "" && m.given != "":
+		return fmt.Sprintf("value %s has type %s, but the chart expects %s", m.path, m.given, m.expected)
+	case m.expected != "":
+		return fmt.Sprintf("value %s does not have the type %s that the chart expects", m.path, m.expected)
+	case m.given != "":
+		return fmt.Sprintf("value %s has type %s, which the chart does not expect", m.path, m.given)
+	default:
+		return fmt.Sprintf("value %s does not have the type that the chart expects", m.path)
+	}
+}
+
+// updateValuesTypeMismatch sets the ValuesTypeMismatch condition if err, the
+// result of a reconciliation that rendered the chart, is caused by values of
+// the wrong type, and removes it otherwise.
+func (r *Reconciler) updateValuesTypeMismatch(u *updater.Updater, err error) {
+	var mismatches []valuesTypeMismatch
+	if err != nil {
+		mismatches = valuesTypeMismatches(err.Error(), r.chrt)
+	}
+	if len(mismatches) == 0 {
+		u.UpdateStatus(updater.RemoveCondition(conditions.TypeValuesTypeMismatch))
+		return
+	}
+	msgs := make([]string, 0, len(mismatches))
+	for _, m := range mismatches {
+		msgs = append(msgs, m.String())
+	}
+	u.UpdateStatus(updater.EnsureCondition(conditions.ValuesTypeMismatch(corev1.ConditionTrue, conditions.ReasonInvalidValueType, strings.Join(msgs, "; "))))
+}
+
+// valuesTypeMismatches returns the values of the wrong type described by msg,
+// the message of a schema validation or template execution error of chrt.
+// The expected types are taken from the schema of chrt where possible.
+func valuesTypeMismatches(msg string, chrt *chart.Chart) []valuesTypeMismatch {
+	var (
+		mismatches []valuesTypeMismatch
+		subchart   string
+	)
+	for _, line := range strings.Split(msg, "\n") {
+		// The schema errors of a subchart follow a line with its name.
+		if name, ok := strings.CutSuffix(line, ":"); ok && !strings.Contains(name, " ") {
+			subchart = ""
+			if chrt != nil && name != chrt.Name() {
+				subchart = name
+			}
+			continue
+		}
+		if m := schemaTypeErrorRegexp.FindStringSubmatch(line); m != nil {
+			path := m[1]
+			if subchart != "" {
+				path = subchart + "." + path
+				if m[1] == "(root)" {
+					path = subchart
+				}
+			}
+			mismatches = append(mismatches, valuesTypeMismatch{path: path, expected: m[2], given: m[3]})
+		}
+	}
+	if len(mismatches) > 0 {
+		return mismatches
+	}
+
+	m := templateErrorRegexp.FindStringSubmatch(msg)
+	if m == nil {
+		return nil
+	}
+	pm := valuesPathRegexp.FindStringSubmatch(m[1])
+	if pm == nil {
+		return nil
+	}
+	mismatch := valuesTypeMismatch{path: strings.TrimPrefix(pm[1], ".")}
+	switch cause := m[2]; {
+	case wrongTypeRegexp.MatchString(cause):
+		tm := wrongTypeRegexp.FindStringSubmatch(cause)
+		mismatch.expected, mismatch.given = valueKind(tm[1]), valueKind(tm[2])
+	case cannotEvaluateRegexp.MatchString(cause):
+		// The value that contains the field is not a map.
+		em := cannotEvaluateRegexp.FindStringSubmatch(cause)
+		path, ok := strings.CutSuffix(mismatch.path, "."+em[1])
+		if !ok {
+			return nil
+		}
+		mismatch.path, mismatch.expected, mismatch.given = path, "object", valueKind(em[2])
+	case cannotIterateRegexp.MatchString(cause):
+		mismatch.expected = "array or object"
+	case incompatibleTypeRegexp.MatchString(cause):
+	default:
+		return nil
+	}
+	if t := schemaType(chrt, mismatch.path); t != "" {
+		mismatch.expected = t
+	}
+	return []valuesTypeMismatch{mismatch}
+}
+
+// valueKind returns the JSON kind of the Go type t of a value, e.g. "number"
+// for "float64", or t if it has no JSON kind.
+func valueKind(t string) string {
+	switch t {
+	case "string", "boolean", "number", "integer", "object", "array", "null":
+		return t
+	case "bool":
+		return "boolean"
+	case "int", "int64", "int32":
+		return "integer"
+	case "float64", "float32":
+		return "number"
+	case "map[string]interface {}", "chartutil.Values":
+		return "object"
+	case "[]interface {}":
+		return "array"
+	case "<nil>":
+		return "null"
+	case "interface {}":
+		return ""
+	}
+	return t
+}
+
+// schemaType returns the type of the value at the dotted path in the schema
+// of chrt, or an empty string if chrt has no schema or the schema does not
+// define the type.
+func schemaType(chrt *chart.Chart, path string) string {
+	if chrt == nil || len(chrt.Schema) == 0 {
+		return ""
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(chrt.Schema, &schema); err != nil {
+		return ""
+	}
+	if path != "" {
+		for _, key := range strings.Split(path, ".") {
+			props, _ := schema["properties"].(map[string]interface{})
+			next, ok := props[key].(map[string]interface{})
+			if !ok {
+				return ""
+			}
+			schema = next
+		}
+	}
+	switch t := schema["type"].(type) {
+	case string:
+		return t
+	case []interface{}:
+		types := make([]string, 0, len(t))
+		for _, e := range t {
+			if s, ok := e.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return strings.Join(types, " or ")
+	}
+	return ""
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+var _ = Describe("valuesTypeMismatches", func() {
+	var chrt *chart.Chart
+
+	BeforeEach(func() {
+		chrt = &chart.Chart{
+			Metadata: &chart.Metadata{Name: "test-chart"},
+			Schema:   []byte(`{"properties": {"replicaCount": {"type": "integer"}, "image": {"type": "object", "properties": {"tag": {"type": ["string", "null"]}}}}}`),
+		}
+	})
+
+	DescribeTable("should describe values of the wrong type",
+		func(msg string, expected []valuesTypeMismatch) {
+			Expect(valuesTypeMismatches(msg, chrt)).To(Equal(expected))
+		},
+		Entry("in schema errors",
+			"values don't meet the specifications of the schema(s) in the following chart(s):\ntest-chart:\n- replicaCount: Invalid type. Expected: integer, given: string\n- image.tag: Invalid type. Expected: string, given: integer\n",
+			[]valuesTypeMismatch{{path: "replicaCount", expected: "integer", given: "string"}, {path: "image.tag", expected: "string", given: "integer"}}),
+		Entry("in schema errors of subcharts",
+			"values don't meet the specifications of the schema(s) in the following chart(s):\ndb:\n- port: Invalid type. Expected: integer, given: string\n",
+			[]valuesTypeMismatch{{path: "db.port", expected: "integer", given: "string"}}),
+		Entry("in template errors with the expected type",
+			`template: test-chart/templates/deployment.yaml:8:16: executing "test-chart/templates/deployment.yaml" at <.Values.workers>: wrong type for value; expected int; got string`,
+			[]valuesTypeMismatch{{path: "workers", expected: "integer", given: "string"}}),
+		Entry("in template errors with the type from the schema",
+			`template: test-chart/templates/deployment.yaml:8:16: executing "test-chart/templates/deployment.yaml" at <add .Values.replicaCount 1>: error calling add: wrong type for value; expected int64; got string`,
+			[]valuesTypeMismatch{{path: "replicaCount", expected: "integer", given: "string"}}),
+		Entry("in template errors of fields of values that are not maps",
+			`template: test-chart/templates/deployment.yaml:20:24: executing "test-chart/templates/deployment.yaml" at <.Values.image.tag>: can't evaluate field tag in type string`,
+			[]valuesTypeMismatch{{path: "image", expected: "object", given: "string"}}),
+		Entry("in template errors of ranges",
+			`template: test-chart/templates/service.yaml:10:12: executing "test-chart/templates/service.yaml" at <.Values.ports>: range can't iterate over 8080`,
+			[]valuesTypeMismatch{{path: "ports", expected: "array or object"}}),
+	)
+
+	It("should ignore other errors", func() {
+		Expect(valuesTypeMismatches(`template: test-chart/templates/deployment.yaml:8:16: executing "test-chart/templates/deployment.yaml" at <required "name is required" .Values.name>: error calling required: name is required`, chrt)).To(BeEmpty())
+		Expect(valuesTypeMismatches("timed out waiting for the condition", chrt)).To(BeEmpty())
+	})
+
+	It("should describe mismatches", func() {
+		Expect(valuesTypeMismatch{path: "replicaCount", expected: "integer", given: "string"}.String()).
+			To(Equal("value replicaCount has type string, but the chart expects integer"))
+		Expect(valuesTypeMismatch{path: "ports", expected: "array or object"}.String()).
+			To(Equal("value ports does not have the type array or object that the chart expects"))
+	})
+})
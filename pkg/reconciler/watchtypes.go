@@ -0,0 +1,95 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"errors"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// WatchOwnerNameLabel is the label of the objects of the types watched
+	// with WithWatchTypes that holds the name of the CR they map to.
+	WatchOwnerNameLabel = "helm.sdk.operatorframework.io/owner-name"
+
+	// WatchOwnerNamespaceLabel is the label of the objects of the types
+	// watched with WithWatchTypes that holds the namespace of the CR they map
+	// to. It defaults to the namespace of the object.
+	WatchOwnerNamespaceLabel = "helm.sdk.operatorframework.io/owner-namespace"
+)
+
+type watchType struct {
+	obj     client.Object
+	mapFunc handler.MapFunc
+}
+
+// WithWatchTypes is an Option that configures the reconciler to watch
+// additional types of objects that are not part of the releases, e.g. an
+// externally managed resource whose readiness gates the CRs. A change of an
+// object reconciles the CR named by its WatchOwnerNameLabel and
+// WatchOwnerNamespaceLabel labels; objects without the name label are
+// ignored. Use WithWatchTypeMapper to map the objects of a type differently.
+//
+// Unstructured objects must have their GroupVersionKind set. The operator
+// needs permission to list and watch the types.
+func WithWatchTypes(objs ...client.Object) Option {
+	return func(r *Reconciler) error {
+		for _, obj := range objs {
+			if obj == nil {
+				return errors.New("watched object must not be nil")
+			}
+			r.watchTypes = append(r.watchTypes, watchType{obj: obj, mapFunc: mapByOwnerLabels})
+		}
+		return nil
+	}
+}
+
+// WithWatchTypeMapper is an Option that configures the reconciler to watch
+// the type of obj, like WithWatchTypes, and to reconcile the CRs returned by
+// mapFunc when an object of the type changes.
+func WithWatchTypeMapper(obj client.Object, mapFunc handler.MapFunc) Option {
+	return func(r *Reconciler) error {
+		if obj == nil {
+			return errors.New("watched object must not be nil")
+		}
+		if mapFunc == nil {
+			return errors.New("watch type map function must not be nil")
+		}
+		r.watchTypes = append(r.watchTypes, watchType{obj: obj, mapFunc: mapFunc})
+		return nil
+	}
+}
+
+// mapByOwnerLabels maps obj to the CR named by its WatchOwnerNameLabel and
+// WatchOwnerNamespaceLabel labels.
+func mapByOwnerLabels(_ context.Context, obj client.Object) []reconcile.Request {
+	labels := obj.GetLabels()
+	name, ok := labels[WatchOwnerNameLabel]
+	if !ok || name == "" {
+		return nil
+	}
+	namespace, ok := labels[WatchOwnerNamespaceLabel]
+	if !ok {
+		namespace = obj.GetNamespace()
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: namespace, Name: name}}}
+}
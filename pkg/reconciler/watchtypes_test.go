@@ -0,0 +1,68 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("WithWatchTypes", func() {
+	var (
+		r   *Reconciler
+		obj *corev1.ConfigMap
+	)
+
+	BeforeEach(func() {
+		r = &Reconciler{}
+		obj = &corev1.ConfigMap{}
+		obj.SetNamespace("external")
+	})
+
+	It("should fail with a nil object", func() {
+		Expect(WithWatchTypes(nil)(r)).NotTo(Succeed())
+		Expect(WithWatchTypeMapper(nil, mapByOwnerLabels)(r)).NotTo(Succeed())
+		Expect(WithWatchTypeMapper(obj, nil)(r)).NotTo(Succeed())
+	})
+
+	It("should add a watch for every type", func() {
+		Expect(WithWatchTypes(obj, &corev1.Service{})(r)).To(Succeed())
+		Expect(WithWatchTypeMapper(&corev1.Secret{}, func(context.Context, client.Object) []reconcile.Request { return nil })(r)).To(Succeed())
+		Expect(r.watchTypes).To(HaveLen(3))
+	})
+
+	It("should map objects by their owner labels", func() {
+		obj.SetLabels(map[string]string{WatchOwnerNameLabel: "cr", WatchOwnerNamespaceLabel: "ns"})
+		Expect(mapByOwnerLabels(context.Background(), obj)).To(ConsistOf(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "cr"}}))
+	})
+
+	It("should default to the namespace of the object", func() {
+		obj.SetLabels(map[string]string{WatchOwnerNameLabel: "cr"})
+		Expect(mapByOwnerLabels(context.Background(), obj)).To(ConsistOf(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "external", Name: "cr"}}))
+	})
+
+	It("should ignore objects without the name label", func() {
+		obj.SetLabels(map[string]string{WatchOwnerNamespaceLabel: "ns"})
+		Expect(mapByOwnerLabels(context.Background(), obj)).To(BeEmpty())
+	})
+})
@@ -0,0 +1,93 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package values
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// ExitCodeRetryable is the exit code with which an executable used by
+// ExecTranslator signals a temporary failure that should be retried. It
+// matches EX_TEMPFAIL of sysexits.h.
+const ExitCodeRetryable = 75
+
+// ExecError is returned by the Translator created by ExecTranslator when the
+// executable exits with a non-zero exit code.
+type ExecError struct {
+	Path     string
+	ExitCode int
+	Stderr   string
+}
+
+func (e *ExecError) Error() string {
+	msg := fmt.Sprintf("values executable %q exited with code %d", e.Path, e.ExitCode)
+	if e.Stderr != "" {
+		msg = fmt.Sprintf("%s: %s", msg, e.Stderr)
+	}
+	return msg
+}
+
+// Retryable reports whether the executable signaled a temporary failure by
+// exiting with ExitCodeRetryable.
+func (e *ExecError) Retryable() bool {
+	return e.ExitCode == ExitCodeRetryable
+}
+
+// ExecTranslator returns a Translator that runs the executable at path with
+// args. The custom resource is passed as JSON on stdin, and the executable
+// must write the values as a JSON object to stdout. A non-zero exit code
+// results in an *ExecError.
+func ExecTranslator(path string, args ...string) Translator {
+	return TranslatorFunc(func(ctx context.Context, u *unstructured.Unstructured) (chartutil.Values, error) {
+		in, err := json.Marshal(u.Object)
+		if err != nil {
+			return nil, fmt.Errorf("marshal custom resource: %w", err)
+		}
+
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, path, args...)
+		cmd.Stdin = bytes.NewReader(in)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) && ctx.Err() == nil {
+				return nil, &ExecError{
+					Path:     path,
+					ExitCode: exitErr.ExitCode(),
+					Stderr:   strings.TrimSpace(stderr.String()),
+				}
+			}
+			return nil, fmt.Errorf("run values executable %q: %w", path, err)
+		}
+
+		vals := chartutil.Values{}
+		if err := json.Unmarshal(stdout.Bytes(), &vals); err != nil {
+			return nil, fmt.Errorf("parse output of values executable %q: %w", path, err)
+		}
+		return vals, nil
+	})
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package values_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/operator-framework/helm-operator-plugins/pkg/values"
+)
+
+var _ = Describe("ExecTranslator", func() {
+	var obj *unstructured.Unstructured
+
+	BeforeEach(func() {
+		obj = &unstructured.Unstructured{Object: map[string]interface{}{
+			"spec": map[string]interface{}{"replicas": int64(2)},
+		}}
+	})
+
+	It("should pass the custom resource on stdin and read values from stdout", func() {
+		t := values.ExecTranslator("/bin/sh", "-c", `sed 's/"spec"/"fromStdin"/'`)
+		vals, err := t.Translate(context.TODO(), obj)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(vals).To(Equal(chartutil.Values{"fromStdin": map[string]interface{}{"replicas": float64(2)}}))
+	})
+
+	It("should return a retryable error for the retryable exit code", func() {
+		t := values.ExecTranslator("/bin/sh", "-c", "echo try again >&2; exit 75")
+		_, err := t.Translate(context.TODO(), obj)
+		var execErr *values.ExecError
+		Expect(errors.As(err, &execErr)).To(BeTrue())
+		Expect(execErr.Retryable()).To(BeTrue())
+		Expect(execErr.Stderr).To(Equal("try again"))
+	})
+
+	It("should return a fatal error for other exit codes", func() {
+		t := values.ExecTranslator("/bin/sh", "-c", "exit 1")
+		_, err := t.Translate(context.TODO(), obj)
+		var execErr *values.ExecError
+		Expect(errors.As(err, &execErr)).To(BeTrue())
+		Expect(execErr.Retryable()).To(BeFalse())
+		Expect(execErr.ExitCode).To(Equal(1))
+	})
+
+	It("should fail if the output is not a JSON object", func() {
+		t := values.ExecTranslator("/bin/sh", "-c", "echo not-json")
+		_, err := t.Translate(context.TODO(), obj)
+		Expect(err).To(MatchError(ContainSubstring("parse output")))
+	})
+})
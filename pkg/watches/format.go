@@ -0,0 +1,100 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watches
+
+import (
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+// SchemaVersionV2 is the schema version of the versioned format of watches
+// files.
+const SchemaVersionV2 = "v2"
+
+// watchesFileV2 is a watches file in the versioned format.
+type watchesFileV2 struct {
+	SchemaVersion string    `json:"schemaVersion"`
+	Watches       []watchV2 `json:"watches"`
+}
+
+// watchV2 is a watch of a watches file in the versioned format. The group and
+// version of its GVK are given together in APIVersion.
+type watchV2 struct {
+	APIVersion string `json:"apiVersion"`
+	Watch      `json:",inline"`
+}
+
+// decodeWatches decodes the watches of a watches file in either format. If
+// the file contains unknown fields, they are ignored and warn is called.
+func decodeWatches(b []byte, warn func(msg string)) ([]Watch, error) {
+	var probe interface{}
+	if err := yaml.Unmarshal(b, &probe); err != nil {
+		return nil, err
+	}
+	switch p := probe.(type) {
+	case nil, []interface{}:
+		watches := []Watch{}
+		if err := unmarshalWatches(b, &watches, warn); err != nil {
+			return nil, err
+		}
+		return watches, nil
+	case map[string]interface{}:
+		version, ok := p["schemaVersion"]
+		if !ok {
+			return nil, errors.New("unknown watches file format: expected a list of watches or a schemaVersion")
+		}
+		if version != SchemaVersionV2 {
+			return nil, fmt.Errorf("unsupported watches file schema version %v", version)
+		}
+		var file watchesFileV2
+		if err := unmarshalWatches(b, &file, warn); err != nil {
+			return nil, err
+		}
+		watches := make([]Watch, 0, len(file.Watches))
+		for _, w := range file.Watches {
+			if w.Group != "" || w.Version != "" {
+				return nil, fmt.Errorf("invalid watch for kind %s: group and version must be set with apiVersion", w.Kind)
+			}
+			gv, err := schema.ParseGroupVersion(w.APIVersion)
+			if err != nil {
+				return nil, fmt.Errorf("invalid watch for kind %s: %w", w.Kind, err)
+			}
+			w.Watch.GroupVersionKind = gv.WithKind(w.Kind)
+			watches = append(watches, w.Watch)
+		}
+		return watches, nil
+	default:
+		return nil, errors.New("unknown watches file format: expected a list of watches or a schemaVersion")
+	}
+}
+
+// unmarshalWatches unmarshals b into out, ignoring unknown fields, for which
+// warn is called.
+func unmarshalWatches(b []byte, out interface{}, warn func(msg string)) error {
+	strictErr := yaml.UnmarshalStrict(b, out)
+	if strictErr == nil {
+		return nil
+	}
+	if err := yaml.Unmarshal(b, out); err != nil {
+		return err
+	}
+	warn(fmt.Sprintf("ignoring unknown fields: %v", strictErr))
+	return nil
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2023 The Operator-SDK Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watches
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var _ = Describe("Watches file formats", func() {
+	const (
+		legacy = `---
+- group: mygroup
+  version: v1alpha1
+  kind: Legacy
+  chart: ../../pkg/internal/testdata/test-chart
+`
+		versioned = `---
+schemaVersion: v2
+watches:
+- apiVersion: mygroup/v1alpha1
+  kind: Versioned
+  chart: ../../pkg/internal/testdata/test-chart
+  watchDependentResources: false
+`
+	)
+
+	var warnings []string
+	warn := WithWarningHandler(func(msg string) { warnings = append(warnings, msg) })
+
+	BeforeEach(func() {
+		warnings = nil
+	})
+
+	It("should load the versioned format", func() {
+		watches, err := LoadReader(bytes.NewBufferString(versioned), warn)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(watches).To(HaveLen(1))
+		Expect(watches[0].GroupVersionKind).To(Equal(schema.GroupVersionKind{Group: "mygroup", Version: "v1alpha1", Kind: "Versioned"}))
+		Expect(*watches[0].WatchDependentResources).To(BeFalse())
+		Expect(watches[0].Chart).NotTo(BeNil())
+		Expect(warnings).To(BeEmpty())
+	})
+
+	It("should support core kinds in the versioned format", func() {
+		watches, err := LoadReader(bytes.NewBufferString(`schemaVersion: v2
+watches:
+- apiVersion: v1
+  kind: ConfigMap
+  chart: ../../pkg/internal/testdata/test-chart
+`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(watches[0].GroupVersionKind).To(Equal(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}))
+	})
+
+	It("should error for an unsupported schema version", func() {
+		_, err := LoadReader(bytes.NewBufferString("schemaVersion: v3\nwatches: []\n"))
+		Expect(err).To(MatchError(ContainSubstring("unsupported watches file schema version v3")))
+	})
+
+	It("should error if the group is set in the versioned format", func() {
+		_, err := LoadReader(bytes.NewBufferString(`schemaVersion: v2
+watches:
+- apiVersion: mygroup/v1alpha1
+  group: mygroup
+  kind: Versioned
+  chart: ../../pkg/internal/testdata/test-chart
+`))
+		Expect(err).To(MatchError(ContainSubstring("group and version must be set with apiVersion")))
+	})
+
+	It("should warn about unknown fields", func() {
+		watches, err := LoadReader(bytes.NewBufferString(legacy+"  unknownField: true\n"), warn)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(watches).To(HaveLen(1))
+		Expect(warnings).To(ConsistOf(ContainSubstring("unknownField")))
+	})
+
+	It("should load a directory with mixed formats", func() {
+		dir, err := os.MkdirTemp("", "watches")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+		Expect(os.WriteFile(filepath.Join(dir, "a-legacy.yaml"), []byte(legacy), 0o600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "b-versioned.yml"), []byte(versioned+"  extra: 1\n"), 0o600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a watches file"), 0o600)).To(Succeed())
+
+		watches, err := Load(dir, warn)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(watches).To(HaveLen(2))
+		Expect(watches[0].Kind).To(Equal("Legacy"))
+		Expect(watches[1].Kind).To(Equal("Versioned"))
+		Expect(warnings).To(ConsistOf(ContainSubstring("b-versioned.yml")))
+	})
+
+	It("should error for duplicate GVKs across files", func() {
+		dir, err := os.MkdirTemp("", "watches")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+		Expect(os.WriteFile(filepath.Join(dir, "a.yaml"), []byte(legacy), 0o600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "b.yaml"), []byte(legacy), 0o600)).To(Succeed())
+
+		_, err = Load(dir)
+		Expect(err).To(MatchError(ContainSubstring("duplicate GVK")))
+	})
+})
@@ -16,63 +16,285 @@ package watches
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"sync"
 	"text/template"
 
 	sprig "github.com/go-task/slim-sprig"
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chart/loader"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
-	"sigs.k8s.io/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/operator-framework/helm-operator-plugins/pkg/chartrepo"
+	"github.com/operator-framework/helm-operator-plugins/pkg/gitsource"
 )
 
+// Watch configures the reconciliation of a GVK with a chart.
+//
+// OverrideValuesLayers is an ordered list of override values that are
+// deep-merged in sequence on top of the CR spec, so that a base layer can be
+// combined with more specific tweaks. Nested maps are merged key by key, while
+// lists and scalar values of later layers replace those of earlier layers.
+// OverrideValues are applied after all layers and take precedence over them.
+//
+// The chart is read from ChartPath, or from a Git repository if Git is set.
+// Chart is only loaded for watches that use ChartPath.
 type Watch struct {
 	schema.GroupVersionKind `json:",inline"`
 	ChartPath               string `json:"chart"`
 
-	WatchDependentResources *bool                 `json:"watchDependentResources,omitempty"`
-	OverrideValues          map[string]string     `json:"overrideValues,omitempty"`
-	ReconcilePeriod         *metav1.Duration      `json:"reconcilePeriod,omitempty"`
-	MaxConcurrentReconciles *int                  `json:"maxConcurrentReconciles,omitempty"`
-	Selector                *metav1.LabelSelector `json:"selector,omitempty"`
-	Chart                   *chart.Chart          `json:"-"`
+	WatchDependentResources *bool                    `json:"watchDependentResources,omitempty"`
+	OverrideValues          map[string]string        `json:"overrideValues,omitempty"`
+	OverrideValuesLayers    []map[string]interface{} `json:"overrideValuesLayers,omitempty"`
+	ReconcilePeriod         *metav1.Duration         `json:"reconcilePeriod,omitempty"`
+	MaxConcurrentReconciles *int                     `json:"maxConcurrentReconciles,omitempty"`
+	MaxReleaseHistory       *int                     `json:"maxReleaseHistory,omitempty"`
+	Selector                *metav1.LabelSelector    `json:"selector,omitempty"`
+	UpgradeCheck            *UpgradeCheck            `json:"upgradeCheck,omitempty"`
+	Git                     *GitSource               `json:"git,omitempty"`
+	Chart                   *chart.Chart             `json:"-"`
+}
+
+// UpgradeCheck configures a periodic check for newer versions of a watch's
+// chart in a chart repository. The check is purely informational and never
+// upgrades a release.
+type UpgradeCheck struct {
+	// Repository is the URL of the chart repository to check.
+	Repository string `json:"repository"`
+
+	// Interval is the time between two lookups of the repository index.
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// TLS configures the TLS connection to the repository.
+	TLS *RepositoryTLS `json:"tls,omitempty"`
+}
+
+// RepositoryTLS configures the TLS connection to a chart repository or OCI
+// registry. The CA bundle, client certificate and key are read either from
+// files or from the ca.crt, tls.crt and tls.key keys of a Secret.
+type RepositoryTLS struct {
+	CAFile                string                  `json:"caFile,omitempty"`
+	CertFile              string                  `json:"certFile,omitempty"`
+	KeyFile               string                  `json:"keyFile,omitempty"`
+	SecretRef             *corev1.SecretReference `json:"secretRef,omitempty"`
+	InsecureSkipTLSVerify bool                    `json:"insecureSkipTLSVerify,omitempty"`
+}
+
+// Config returns the chartrepo.TLSConfig described by t. If t references a
+// Secret, the Secret is read using reader.
+func (t RepositoryTLS) Config(ctx context.Context, reader client.Reader) (chartrepo.TLSConfig, error) {
+	var (
+		cfg chartrepo.TLSConfig
+		err error
+	)
+	if t.SecretRef != nil {
+		secret := &corev1.Secret{}
+		key := client.ObjectKey{Namespace: t.SecretRef.Namespace, Name: t.SecretRef.Name}
+		if err := reader.Get(ctx, key, secret); err != nil {
+			return chartrepo.TLSConfig{}, fmt.Errorf("get TLS secret %s: %w", key, err)
+		}
+		cfg = chartrepo.TLSConfigFromSecret(secret)
+	} else if cfg, err = chartrepo.TLSConfigFromFiles(t.CAFile, t.CertFile, t.KeyFile); err != nil {
+		return chartrepo.TLSConfig{}, err
+	}
+	cfg.InsecureSkipTLSVerify = t.InsecureSkipTLSVerify
+	return cfg, nil
+}
+
+func (t RepositoryTLS) verify() error {
+	if t.SecretRef == nil {
+		return nil
+	}
+	if t.CAFile != "" || t.CertFile != "" || t.KeyFile != "" {
+		return errors.New("secretRef must not be set together with caFile, certFile or keyFile")
+	}
+	if t.SecretRef.Name == "" || t.SecretRef.Namespace == "" {
+		return errors.New("secretRef must specify a name and namespace")
+	}
+	return nil
+}
+
+// GitSource configures a chart that is read from a directory of a Git
+// repository. Credentials for HTTP(S) repositories are read from the username
+// and password keys of a Secret. The git command line client must be available in
+// the operator image.
+type GitSource struct {
+	// Repository is the URL of the Git repository.
+	Repository string `json:"repository"`
+
+	// Ref is the branch, tag or commit to read the chart from.
+	Ref string `json:"ref"`
+
+	// Path is the directory of the chart, relative to the repository root.
+	Path string `json:"path,omitempty"`
+
+	// SecretRef references the Secret with the repository credentials.
+	SecretRef *corev1.SecretReference `json:"secretRef,omitempty"`
+
+	// Interval is the time between two fetches of the ref.
+	Interval *metav1.Duration `json:"interval,omitempty"`
+}
+
+// Source returns the gitsource.Source described by g. If g references a
+// Secret, the Secret is read using reader.
+func (g GitSource) Source(ctx context.Context, reader client.Reader) (*gitsource.Source, error) {
+	var opts []gitsource.Option
+	if g.SecretRef != nil {
+		secret := &corev1.Secret{}
+		key := client.ObjectKey{Namespace: g.SecretRef.Namespace, Name: g.SecretRef.Name}
+		if err := reader.Get(ctx, key, secret); err != nil {
+			return nil, fmt.Errorf("get Git secret %s: %w", key, err)
+		}
+		opts = append(opts, gitsource.WithBasicAuth(string(secret.Data["username"]), string(secret.Data["password"])))
+	}
+	return gitsource.New(g.Repository, g.Ref, g.Path, opts...)
+}
+
+func (g GitSource) verify() error {
+	if g.Repository == "" {
+		return errors.New("repository must not be empty")
+	}
+	if g.Ref == "" {
+		return errors.New("ref must not be empty")
+	}
+	if err := gitsource.ValidatePath(g.Path); err != nil {
+		return err
+	}
+	if g.SecretRef != nil && (g.SecretRef.Name == "" || g.SecretRef.Namespace == "") {
+		return errors.New("secretRef must specify a name and namespace")
+	}
+	return nil
 }
 
 // Load loads a slice of Watches from the watch file at `path`. For each entry
 // in the watches file, it verifies the configuration. If an error is
 // encountered loading the file or verifying the configuration, it will be
 // returned.
-func Load(path string) ([]Watch, error) {
-	f, err := os.Open(path)
+//
+// If path is a directory, the watches of all .yaml and .yml files in it are
+// loaded, in the order of their names. Each file may use either format of
+// watches files, see LoadReader.
+func Load(path string, opts ...LoadOption) ([]Watch, error) {
+	fi, err := os.Stat(path)
 	if err != nil {
 		return nil, fmt.Errorf("could not open watches file: %w", err)
 	}
-	w, err := LoadReader(f)
+	if !fi.IsDir() {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not open watches file: %w", err)
+		}
+		w, err := LoadReader(f, opts...)
 
-	// Make sure to close the file, regardless of the error returned by
-	// LoadReader.
-	if err := f.Close(); err != nil {
-		return nil, fmt.Errorf("could not close watches file: %w", err)
+		// Make sure to close the file, regardless of the error returned by
+		// LoadReader.
+		if err := f.Close(); err != nil {
+			return nil, fmt.Errorf("could not close watches file: %w", err)
+		}
+		return w, err
+	}
+
+	o := newLoadOptions(opts)
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read watches directory: %w", err)
+	}
+	watches := []Watch{}
+	for _, e := range entries {
+		if e.IsDir() || (filepath.Ext(e.Name()) != ".yaml" && filepath.Ext(e.Name()) != ".yml") {
+			continue
+		}
+		name := filepath.Join(path, e.Name())
+		b, err := os.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("could not read watches file: %w", err)
+		}
+		w, err := decodeWatches(b, func(msg string) { o.warn(fmt.Sprintf("%s: %s", name, msg)) })
+		if err != nil {
+			return nil, fmt.Errorf("invalid watches file %s: %w", name, err)
+		}
+		watches = append(watches, w...)
+	}
+	return verifyWatches(watches, o)
+}
+
+// LoadOption configures how watches are loaded.
+type LoadOption func(o *loadOptions)
+
+type loadOptions struct {
+	maxConcurrentChartLoads int
+	warn                    func(msg string)
+}
+
+func newLoadOptions(opts []LoadOption) loadOptions {
+	o := loadOptions{maxConcurrentChartLoads: 1, warn: func(string) {}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.maxConcurrentChartLoads < 1 {
+		o.maxConcurrentChartLoads = 1
+	}
+	return o
+}
+
+// WithMaxConcurrentChartLoads configures the number of charts that are
+// loaded concurrently. By default, charts are loaded one at a time.
+func WithMaxConcurrentChartLoads(n int) LoadOption {
+	return func(o *loadOptions) {
+		o.maxConcurrentChartLoads = n
+	}
+}
+
+// WithWarningHandler configures a function that is called with a warning for
+// every watches file that contains unknown fields. Unknown fields are ignored,
+// so that watches files can be migrated between formats gradually. By
+// default, the warnings are discarded.
+func WithWarningHandler(f func(msg string)) LoadOption {
+	return func(o *loadOptions) {
+		o.warn = f
 	}
-	return w, err
 }
 
-func LoadReader(reader io.Reader) ([]Watch, error) {
+// LoadReader loads a slice of Watches from reader, like Load. All watches are
+// verified before their charts are loaded, and errors loading the charts of
+// several watches are returned together.
+//
+// Two formats are supported. The legacy format is a list of watches. The
+// versioned format is a document with a schemaVersion, which must be v2, and
+// a list of watches, which name the group and version of their GVK together
+// in an apiVersion field:
+//
+//	schemaVersion: v2
+//	watches:
+//	- apiVersion: cache.example.com/v1alpha1
+//	  kind: Memcached
+//	  chart: helm-charts/memcached
+func LoadReader(reader io.Reader, opts ...LoadOption) ([]Watch, error) {
+	o := newLoadOptions(opts)
+
 	b, err := io.ReadAll(reader)
 	if err != nil {
 		return nil, err
 	}
 
-	watches := []Watch{}
-	err = yaml.Unmarshal(b, &watches)
+	watches, err := decodeWatches(b, o.warn)
 	if err != nil {
 		return nil, err
 	}
+	return verifyWatches(watches, o)
+}
 
+// verifyWatches verifies watches, sets their defaults and loads their charts.
+func verifyWatches(watches []Watch, o loadOptions) ([]Watch, error) {
+	var err error
 	watchesMap := make(map[schema.GroupVersionKind]struct{})
 	for i, w := range watches {
 		gvk := w.GroupVersionKind
@@ -81,11 +303,22 @@ func LoadReader(reader io.Reader) ([]Watch, error) {
 			return nil, fmt.Errorf("invalid GVK: %s: %w", gvk, err)
 		}
 
-		cl, err := loader.Load(w.ChartPath)
-		if err != nil {
-			return nil, fmt.Errorf("invalid chart %s: %w", w.ChartPath, err)
+		if w.Git != nil {
+			if w.ChartPath != "" {
+				return nil, fmt.Errorf("invalid watch for GVK %s: chart must not be set together with git", gvk)
+			}
+			if err := w.Git.verify(); err != nil {
+				return nil, fmt.Errorf("invalid git source for GVK %s: %w", gvk, err)
+			}
+		}
+
+		if w.ReconcilePeriod != nil && w.ReconcilePeriod.Duration < 0 {
+			return nil, fmt.Errorf("invalid watch for GVK %s: reconcilePeriod must not be negative", gvk)
+		}
+
+		if w.MaxReleaseHistory != nil && *w.MaxReleaseHistory < 0 {
+			return nil, fmt.Errorf("invalid watch for GVK %s: maxReleaseHistory must not be negative", gvk)
 		}
-		w.Chart = cl
 
 		if _, ok := watchesMap[gvk]; ok {
 			return nil, fmt.Errorf("duplicate GVK: %s", gvk)
@@ -101,6 +334,17 @@ func LoadReader(reader io.Reader) ([]Watch, error) {
 			w.Selector = &metav1.LabelSelector{}
 		}
 
+		if w.UpgradeCheck != nil {
+			if w.UpgradeCheck.Repository == "" {
+				return nil, fmt.Errorf("invalid upgrade check for GVK %s: repository must not be empty", gvk)
+			}
+			if w.UpgradeCheck.TLS != nil {
+				if err := w.UpgradeCheck.TLS.verify(); err != nil {
+					return nil, fmt.Errorf("invalid upgrade check TLS config for GVK %s: %w", gvk, err)
+				}
+			}
+		}
+
 		w.OverrideValues, err = expandOverrideValues(w.OverrideValues)
 		if err != nil {
 			return nil, fmt.Errorf("failed to expand override values")
@@ -108,9 +352,43 @@ func LoadReader(reader io.Reader) ([]Watch, error) {
 
 		watches[i] = w
 	}
+	if err := loadCharts(watches, o.maxConcurrentChartLoads); err != nil {
+		return nil, err
+	}
 	return watches, nil
 }
 
+// loadCharts loads the charts of all watches that use a chart path, with at
+// most maxConcurrent charts being loaded at the same time.
+func loadCharts(watches []Watch, maxConcurrent int) error {
+	var (
+		wg   sync.WaitGroup
+		sem  = make(chan struct{}, maxConcurrent)
+		errs = make([]error, len(watches))
+	)
+	for i := range watches {
+		if watches[i].Git != nil {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(w *Watch, err *error) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			cl, loadErr := loader.Load(w.ChartPath)
+			if loadErr != nil {
+				*err = fmt.Errorf("invalid chart %s: %w", w.ChartPath, loadErr)
+				return
+			}
+			w.Chart = cl
+		}(&watches[i], &errs[i])
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
 func expandOverrideValues(in map[string]string) (map[string]string, error) {
 	if in == nil {
 		return nil, nil
@@ -16,12 +16,18 @@ package watches
 
 import (
 	"bytes"
+	"context"
 	"os"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/operator-framework/helm-operator-plugins/pkg/chartrepo"
 )
 
 var _ = Describe("LoadReader", func() {
@@ -207,6 +213,265 @@ var _ = Describe("LoadReader", func() {
 		verifyEqualWatches(expectedWatches, watches)
 	})
 
+	It("should create valid watches with an upgrade check", func() {
+		data = `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../pkg/internal/testdata/test-chart
+  upgradeCheck:
+    repository: https://charts.example.com
+    interval: 1h
+`
+		expectedWatches = []Watch{
+			{
+				GroupVersionKind:        schema.GroupVersionKind{Group: "mygroup", Version: "v1alpha1", Kind: "MyKind"},
+				ChartPath:               "../../pkg/internal/testdata/test-chart",
+				WatchDependentResources: &trueVal,
+				UpgradeCheck: &UpgradeCheck{
+					Repository: "https://charts.example.com",
+					Interval:   &v1.Duration{Duration: time.Hour},
+				},
+			},
+		}
+		watchesData := bytes.NewBufferString(data)
+		watches, err := LoadReader(watchesData)
+		Expect(err).NotTo(HaveOccurred())
+		verifyEqualWatches(expectedWatches, watches)
+	})
+
+	It("should error because the upgrade check has no repository", func() {
+		data = `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../pkg/internal/testdata/test-chart
+  upgradeCheck:
+    interval: 1h
+`
+		watchesData := bytes.NewBufferString(data)
+		watches, err := LoadReader(watchesData)
+		Expect(err).To(HaveOccurred())
+		Expect(watches).To(BeNil())
+	})
+
+	It("should create valid watches with an upgrade check TLS secret", func() {
+		data = `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../pkg/internal/testdata/test-chart
+  upgradeCheck:
+    repository: oci://registry.example.com/charts
+    tls:
+      secretRef:
+        namespace: operators
+        name: registry-tls
+      insecureSkipTLSVerify: true
+`
+		expectedWatches = []Watch{
+			{
+				GroupVersionKind:        schema.GroupVersionKind{Group: "mygroup", Version: "v1alpha1", Kind: "MyKind"},
+				ChartPath:               "../../pkg/internal/testdata/test-chart",
+				WatchDependentResources: &trueVal,
+				UpgradeCheck: &UpgradeCheck{
+					Repository: "oci://registry.example.com/charts",
+					TLS: &RepositoryTLS{
+						SecretRef:             &corev1.SecretReference{Namespace: "operators", Name: "registry-tls"},
+						InsecureSkipTLSVerify: true,
+					},
+				},
+			},
+		}
+		watchesData := bytes.NewBufferString(data)
+		watches, err := LoadReader(watchesData)
+		Expect(err).NotTo(HaveOccurred())
+		verifyEqualWatches(expectedWatches, watches)
+	})
+
+	It("should error because the upgrade check TLS config mixes files and secret", func() {
+		data = `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../pkg/internal/testdata/test-chart
+  upgradeCheck:
+    repository: https://charts.example.com
+    tls:
+      caFile: /etc/ssl/ca.crt
+      secretRef:
+        namespace: operators
+        name: registry-tls
+`
+		watchesData := bytes.NewBufferString(data)
+		watches, err := LoadReader(watchesData)
+		Expect(err).To(HaveOccurred())
+		Expect(watches).To(BeNil())
+	})
+
+	It("should create valid watches with override values layers", func() {
+		data = `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../pkg/internal/testdata/test-chart
+  overrideValuesLayers:
+  - image:
+      repository: nginx
+      tag: "1.0"
+    replicas: 1
+  - image:
+      tag: "2.0"
+    ports: [80, 443]
+`
+		expectedWatches = []Watch{
+			{
+				GroupVersionKind:        schema.GroupVersionKind{Group: "mygroup", Version: "v1alpha1", Kind: "MyKind"},
+				ChartPath:               "../../pkg/internal/testdata/test-chart",
+				WatchDependentResources: &trueVal,
+				OverrideValuesLayers: []map[string]interface{}{
+					{"image": map[string]interface{}{"repository": "nginx", "tag": "1.0"}, "replicas": float64(1)},
+					{"image": map[string]interface{}{"tag": "2.0"}, "ports": []interface{}{float64(80), float64(443)}},
+				},
+			},
+		}
+
+		watchesData := bytes.NewBufferString(data)
+		watches, err := LoadReader(watchesData)
+		Expect(err).NotTo(HaveOccurred())
+		verifyEqualWatches(expectedWatches, watches)
+	})
+
+	It("should create valid watches with a git source", func() {
+		data = `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  git:
+    repository: https://git.example.com/charts.git
+    ref: v1.0.0
+    path: charts/my-chart
+    secretRef:
+      namespace: operators
+      name: git-credentials
+    interval: 10m
+`
+		expectedWatches = []Watch{
+			{
+				GroupVersionKind:        schema.GroupVersionKind{Group: "mygroup", Version: "v1alpha1", Kind: "MyKind"},
+				WatchDependentResources: &trueVal,
+				Git: &GitSource{
+					Repository: "https://git.example.com/charts.git",
+					Ref:        "v1.0.0",
+					Path:       "charts/my-chart",
+					SecretRef:  &corev1.SecretReference{Namespace: "operators", Name: "git-credentials"},
+					Interval:   &v1.Duration{Duration: 10 * time.Minute},
+				},
+			},
+		}
+		watchesData := bytes.NewBufferString(data)
+		watches, err := LoadReader(watchesData)
+		Expect(err).NotTo(HaveOccurred())
+		verifyEqualWatches(expectedWatches, watches)
+		Expect(watches[0].Chart).To(BeNil())
+	})
+
+	It("should error because the git source is set together with a chart", func() {
+		data = `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../pkg/internal/testdata/test-chart
+  git:
+    repository: https://git.example.com/charts.git
+    ref: main
+`
+		watchesData := bytes.NewBufferString(data)
+		watches, err := LoadReader(watchesData)
+		Expect(err).To(HaveOccurred())
+		Expect(watches).To(BeNil())
+	})
+
+	It("should error because the git source path leaves the repository", func() {
+		data = `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  git:
+    repository: https://git.example.com/charts.git
+    ref: main
+    path: ../charts
+`
+		watchesData := bytes.NewBufferString(data)
+		watches, err := LoadReader(watchesData)
+		Expect(err).To(HaveOccurred())
+		Expect(watches).To(BeNil())
+	})
+
+	It("should load charts concurrently and report all invalid charts", func() {
+		data = `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../pkg/internal/testdata/test-chart
+- group: mygroup
+  version: v1alpha1
+  kind: MyFirstMissingKind
+  chart: ../../pkg/internal/testdata/missing-chart-1
+- group: mygroup
+  version: v1alpha1
+  kind: MySecondMissingKind
+  chart: ../../pkg/internal/testdata/missing-chart-2
+`
+		watchesData := bytes.NewBufferString(data)
+		watches, err := LoadReader(watchesData, WithMaxConcurrentChartLoads(2))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("missing-chart-1"))
+		Expect(err.Error()).To(ContainSubstring("missing-chart-2"))
+		Expect(watches).To(BeNil())
+	})
+
+	It("should error because of a negative reconcile period", func() {
+		data = `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../pkg/internal/testdata/test-chart
+  reconcilePeriod: -1m
+`
+		watchesData := bytes.NewBufferString(data)
+		watches, err := LoadReader(watchesData)
+		Expect(err).To(MatchError(ContainSubstring("reconcilePeriod must not be negative")))
+		Expect(watches).To(BeNil())
+	})
+
+	It("should load the maximum release history", func() {
+		data = `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../pkg/internal/testdata/test-chart
+  maxReleaseHistory: 10
+`
+		watches, err := LoadReader(bytes.NewBufferString(data))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(watches).To(HaveLen(1))
+		Expect(watches[0].MaxReleaseHistory).To(HaveValue(Equal(10)))
+	})
+
+	It("should error because of a negative maximum release history", func() {
+		data = `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../pkg/internal/testdata/test-chart
+  maxReleaseHistory: -1
+`
+		watches, err := LoadReader(bytes.NewBufferString(data))
+		Expect(err).To(MatchError(ContainSubstring("maxReleaseHistory must not be negative")))
+		Expect(watches).To(BeNil())
+	})
+
 	It("should error because of duplicate gvk", func() {
 		data = `---
 - group: mygroup
@@ -328,6 +593,63 @@ var _ = Describe("Load", func() {
 	})
 })
 
+var _ = Describe("RepositoryTLS", func() {
+	It("should read the TLS config from a secret", func() {
+		secret := &corev1.Secret{
+			ObjectMeta: v1.ObjectMeta{Namespace: "operators", Name: "registry-tls"},
+			Data:       map[string][]byte{"ca.crt": []byte("ca")},
+		}
+		cl := fake.NewClientBuilder().WithObjects(secret).Build()
+		t := RepositoryTLS{
+			SecretRef:             &corev1.SecretReference{Namespace: "operators", Name: "registry-tls"},
+			InsecureSkipTLSVerify: true,
+		}
+		Expect(t.Config(context.Background(), cl)).To(Equal(chartrepo.TLSConfig{CA: []byte("ca"), InsecureSkipTLSVerify: true}))
+	})
+	It("should fail if the secret does not exist", func() {
+		cl := fake.NewClientBuilder().Build()
+		t := RepositoryTLS{SecretRef: &corev1.SecretReference{Namespace: "operators", Name: "registry-tls"}}
+		_, err := t.Config(context.Background(), cl)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should read the TLS config from files", func() {
+		f, err := os.CreateTemp("", "osdk-test-ca")
+		Expect(err).NotTo(HaveOccurred())
+		defer removeFile(f)
+		_, err = f.WriteString("ca")
+		Expect(err).NotTo(HaveOccurred())
+
+		t := RepositoryTLS{CAFile: f.Name()}
+		Expect(t.Config(context.Background(), nil)).To(Equal(chartrepo.TLSConfig{CA: []byte("ca")}))
+	})
+})
+
+var _ = Describe("GitSource", func() {
+	It("should fail if the secret does not exist", func() {
+		cl := fake.NewClientBuilder().Build()
+		g := GitSource{
+			Repository: "https://git.example.com/charts.git",
+			Ref:        "main",
+			SecretRef:  &corev1.SecretReference{Namespace: "operators", Name: "git-credentials"},
+		}
+		_, err := g.Source(context.Background(), cl)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should create a source with credentials from a secret", func() {
+		secret := &corev1.Secret{
+			ObjectMeta: v1.ObjectMeta{Namespace: "operators", Name: "git-credentials"},
+			Data:       map[string][]byte{"username": []byte("user"), "password": []byte("token")},
+		}
+		cl := fake.NewClientBuilder().WithObjects(secret).Build()
+		g := GitSource{
+			Repository: "https://git.example.com/charts.git",
+			Ref:        "main",
+			SecretRef:  &corev1.SecretReference{Namespace: "operators", Name: "git-credentials"},
+		}
+		Expect(g.Source(context.Background(), cl)).NotTo(BeNil())
+	})
+})
+
 func verifyEqualWatches(expectedWatch, obtainedWatch []Watch) {
 	Expect(len(expectedWatch)).To(BeEquivalentTo(len(obtainedWatch)))
 	for i := range expectedWatch {
@@ -337,8 +659,12 @@ func verifyEqualWatches(expectedWatch, obtainedWatch []Watch) {
 		Expect(expectedWatch[i].ChartPath).To(BeEquivalentTo(obtainedWatch[i].ChartPath))
 		Expect(expectedWatch[i].WatchDependentResources).To(BeEquivalentTo(obtainedWatch[i].WatchDependentResources))
 		Expect(expectedWatch[i].OverrideValues).To(BeEquivalentTo(obtainedWatch[i].OverrideValues))
+		Expect(expectedWatch[i].OverrideValuesLayers).To(BeEquivalentTo(obtainedWatch[i].OverrideValuesLayers))
 		Expect(expectedWatch[i].MaxConcurrentReconciles).To(BeEquivalentTo(obtainedWatch[i].MaxConcurrentReconciles))
+		Expect(expectedWatch[i].MaxReleaseHistory).To(BeEquivalentTo(obtainedWatch[i].MaxReleaseHistory))
 		Expect(expectedWatch[i].ReconcilePeriod).To(BeEquivalentTo(obtainedWatch[i].ReconcilePeriod))
+		Expect(expectedWatch[i].UpgradeCheck).To(BeEquivalentTo(obtainedWatch[i].UpgradeCheck))
+		Expect(expectedWatch[i].Git).To(BeEquivalentTo(obtainedWatch[i].Git))
 		if expectedWatch[i].Selector == nil {
 			Expect(&v1.LabelSelector{}).To(BeEquivalentTo(obtainedWatch[i].Selector))
 		} else {